@@ -2,6 +2,7 @@ package main
 
 import (
 	"go-basic/webook/events"
+	"go-basic/webook/internal/job"
 
 	"github.com/gin-gonic/gin"
 	"github.com/robfig/cron/v3"
@@ -11,4 +12,5 @@ type App struct {
 	server    *gin.Engine
 	consumers []events.Consumer
 	cron      *cron.Cron
+	scheduler *job.Scheduler
 }
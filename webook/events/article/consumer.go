@@ -30,7 +30,7 @@ func (r *InteractiveReadEventConsumer) Start() error {
 		return err
 	}
 	go func() {
-		err := cg.Consume(context.Background(), []string{"read_article"}, saramax.NewHandler[ReadEvent](r.l, r.Consume))
+		err := cg.Consume(context.Background(), []string{TopicReadEvent}, saramax.NewHandler[ReadEvent](r.l, r.Consume))
 		if err != nil {
 			r.l.Error("退出了消费循环异常", logger.Error(err))
 		}
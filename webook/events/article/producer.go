@@ -7,9 +7,16 @@ import (
 	"github.com/IBM/sarama"
 )
 
+const (
+	TopicReadEvent    = "read_article"
+	TopicPublishEvent = "publish_article"
+)
+
 type Producer interface {
 	ProduceReadEvent(ctx context.Context, evt ReadEvent) error
 	ProduceReadEventV1(ctx context.Context, evt ReadEventV1)
+	// ProducePublishEvent 发表和撤回文章
+	ProducePublishEvent(ctx context.Context, evt PublishEvent) error
 }
 
 type KafkaProducer struct {
@@ -33,7 +40,19 @@ func (k *KafkaProducer) ProduceReadEvent(ctx context.Context, evt ReadEvent) err
 		return err
 	}
 	_, _, err = k.producer.SendMessage(&sarama.ProducerMessage{
-		Topic: "read_article",
+		Topic: TopicReadEvent,
+		Value: sarama.ByteEncoder(data),
+	})
+	return err
+}
+
+func (k *KafkaProducer) ProducePublishEvent(ctx context.Context, evt PublishEvent) error {
+	data, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+	_, _, err = k.producer.SendMessage(&sarama.ProducerMessage{
+		Topic: TopicPublishEvent,
 		Value: sarama.ByteEncoder(data),
 	})
 	return err
@@ -48,3 +67,10 @@ type ReadEventV1 struct {
 	Uids []int64
 	Aids []int64
 }
+
+type PublishEvent struct {
+	Aid int64
+	Uid int64
+	// 撤回的时候是 domain.ArticleStatusPrivate
+	Status uint8
+}
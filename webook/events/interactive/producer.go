@@ -0,0 +1,53 @@
+package interactive
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/IBM/sarama"
+)
+
+const TopicInteractiveEvent = "interactive_events"
+
+const (
+	ActionLike       = "like"
+	ActionCancelLike = "cancel_like"
+	ActionCollect    = "collect"
+)
+
+type Producer interface {
+	ProduceInteractiveEvent(ctx context.Context, evt InteractiveEvent) error
+}
+
+type KafkaProducer struct {
+	producer sarama.SyncProducer
+}
+
+func NewKafkaProducer(pc sarama.SyncProducer) Producer {
+	return &KafkaProducer{
+		producer: pc,
+	}
+}
+
+func (k *KafkaProducer) ProduceInteractiveEvent(ctx context.Context, evt InteractiveEvent) error {
+	data, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+	_, _, err = k.producer.SendMessage(&sarama.ProducerMessage{
+		Topic: TopicInteractiveEvent,
+		// 同一个资源的事件发到同一个分区，保证顺序
+		Key:   sarama.StringEncoder(fmt.Sprintf("%s:%d", evt.Biz, evt.BizId)),
+		Value: sarama.ByteEncoder(data),
+	})
+	return err
+}
+
+// InteractiveEvent 点赞、取消点赞和收藏，阅读事件量太大，单独发送
+type InteractiveEvent struct {
+	Biz    string
+	BizId  int64
+	Uid    int64
+	Action string
+}
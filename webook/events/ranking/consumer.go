@@ -0,0 +1,120 @@
+package ranking
+
+import (
+	"context"
+	"encoding/json"
+	"go-basic/webook/events/article"
+	"go-basic/webook/events/interactive"
+	"go-basic/webook/internal/domain"
+	"go-basic/webook/internal/service"
+	"go-basic/webook/pkg/logger"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// RankingEventConsumer 消费阅读、互动和发表事件，增量更新热榜。
+// 每个节点都要看到全部事件，所以不使用消费者组，直接从最新的位置消费所有分区，
+// 也不会提交偏移量。重启期间丢掉的事件由 Warmup 从数据库补回来
+type RankingEventConsumer struct {
+	client sarama.Client
+	svc    service.StreamingRankingService
+	l      logger.Logger
+}
+
+func NewRankingEventConsumer(client sarama.Client, svc service.StreamingRankingService, l logger.Logger) *RankingEventConsumer {
+	return &RankingEventConsumer{
+		client: client,
+		svc:    svc,
+		l:      l,
+	}
+}
+
+func (r *RankingEventConsumer) Start() error {
+	consumer, err := sarama.NewConsumerFromClient(r.client)
+	if err != nil {
+		return err
+	}
+	for _, topic := range []string{
+		article.TopicReadEvent,
+		article.TopicPublishEvent,
+		interactive.TopicInteractiveEvent,
+	} {
+		partitions, err := consumer.Partitions(topic)
+		if err != nil {
+			return err
+		}
+		for _, partition := range partitions {
+			pc, err := consumer.ConsumePartition(topic, partition, sarama.OffsetNewest)
+			if err != nil {
+				return err
+			}
+			go r.consumePartition(pc)
+		}
+	}
+	// 先开始消费再从数据库预热，预热期间的事件不会丢
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		er := r.svc.Warmup(ctx)
+		if er != nil {
+			r.l.Error("预热热榜失败", logger.Error(er))
+		}
+	}()
+	return nil
+}
+
+func (r *RankingEventConsumer) consumePartition(pc sarama.PartitionConsumer) {
+	for msg := range pc.Messages() {
+		err := r.Consume(msg)
+		if err != nil {
+			// 热榜可以容忍丢少量事件，不重试
+			r.l.Error("消费热榜事件失败", logger.Error(err), logger.String("topic", msg.Topic),
+				logger.Int32("partition", msg.Partition), logger.Int64("offset", msg.Offset))
+		}
+	}
+}
+
+func (r *RankingEventConsumer) Consume(msg *sarama.ConsumerMessage) error {
+	at := msg.Timestamp
+	if at.IsZero() {
+		// 老版本的 Kafka 没有时间戳
+		at = time.Now()
+	}
+	switch msg.Topic {
+	case article.TopicReadEvent:
+		var evt article.ReadEvent
+		if err := json.Unmarshal(msg.Value, &evt); err != nil {
+			return err
+		}
+		r.svc.OnInteractive(evt.Aid, domain.InteractiveActionRead, 1, at)
+	case article.TopicPublishEvent:
+		var evt article.PublishEvent
+		if err := json.Unmarshal(msg.Value, &evt); err != nil {
+			return err
+		}
+		if domain.ArticleStatus(evt.Status) == domain.ArticleStatusPublished {
+			r.svc.OnPublish(evt.Aid, at)
+		} else {
+			r.svc.OnWithdraw(evt.Aid)
+		}
+	case interactive.TopicInteractiveEvent:
+		var evt interactive.InteractiveEvent
+		if err := json.Unmarshal(msg.Value, &evt); err != nil {
+			return err
+		}
+		// 目前只有文章的热榜
+		if evt.Biz != "article" {
+			return nil
+		}
+		switch evt.Action {
+		case interactive.ActionLike:
+			r.svc.OnInteractive(evt.BizId, domain.InteractiveActionLike, 1, at)
+		case interactive.ActionCancelLike:
+			r.svc.OnInteractive(evt.BizId, domain.InteractiveActionLike, -1, at)
+		case interactive.ActionCollect:
+			r.svc.OnInteractive(evt.BizId, domain.InteractiveActionCollect, 1, at)
+		}
+	}
+	return nil
+}
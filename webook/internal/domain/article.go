@@ -7,9 +7,11 @@ type Article struct {
 	Title   string
 	Content string
 	Author  Author
-	Status  ArticleStatus
-	Ctime   time.Time
-	Utime   time.Time
+	// 分类，热榜会按照分类分别计算，为空表示未分类
+	Category string
+	Status   ArticleStatus
+	Ctime    time.Time
+	Utime    time.Time
 }
 
 // Abstract 返回文章内容的摘要
@@ -1,5 +1,7 @@
 package domain
 
+import "time"
+
 type Interactive struct {
 	Biz        string
 	BizId      int64
@@ -9,3 +11,74 @@ type Interactive struct {
 	Liked      bool
 	Collected  bool
 }
+
+// UserLike 一条点赞记录
+type UserLike struct {
+	// 分页使用的游标
+	Id    int64
+	Biz   string
+	BizId int64
+	Uid   int64
+	// 点赞的时间
+	Utime time.Time
+}
+
+// LikeCursor 按照点赞时间倒序翻页的游标，取消之后重新点赞的时间会更新，
+// 时间相同的按照 Id 倒序。零值表示第一页
+type LikeCursor struct {
+	Utime time.Time
+	Id    int64
+}
+
+// InteractiveCntDiff 计数表和明细表对不上的一条记录
+type InteractiveCntDiff struct {
+	Biz   string
+	BizId int64
+	// 计数表中的值
+	LikeCnt    int64
+	CollectCnt int64
+	// 根据明细重新计算出来的值
+	ExpectLikeCnt    int64
+	ExpectCollectCnt int64
+	// 是否已经修正，试运行或者对账期间数据又发生了变化都不会修正
+	Fixed bool
+}
+
+// InteractiveAction 业务支持的互动行为
+type InteractiveAction uint8
+
+const (
+	InteractiveActionRead InteractiveAction = iota + 1
+	InteractiveActionLike
+	InteractiveActionCollect
+)
+
+func (a InteractiveAction) String() string {
+	switch a {
+	case InteractiveActionRead:
+		return "read"
+	case InteractiveActionLike:
+		return "like"
+	case InteractiveActionCollect:
+		return "collect"
+	default:
+		return "unknown"
+	}
+}
+
+// InteractiveBiz 接入互动服务的业务
+type InteractiveBiz struct {
+	Name    string
+	Actions []InteractiveAction
+	// 计数缓存的过期时间，为 0 使用默认值
+	CacheTTL time.Duration
+}
+
+func (b InteractiveBiz) Allow(action InteractiveAction) bool {
+	for _, a := range b.Actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
@@ -2,24 +2,409 @@ package domain
 
 import (
 	"time"
+	// 容器里面不一定有时区数据，任务的时区不能依赖部署环境
+	_ "time/tzdata"
 
 	"github.com/robfig/cron/v3"
 )
 
 type Job struct {
-	Id         int64
-	Name       string
-	Cron       string
-	Executor   string
-	Cfg        string
-	Version    int
+	Id   int64
+	Name string
+	Cron string
+	// IANA 时区，例如 Asia/Shanghai，为空表示服务器的时区
+	Timezone string
+	// 所有实例都停机错过了执行时间之后怎么处理
+	Misfire  MisfirePolicy
+	Executor string
+	Cfg      string
+	// 数字越大越先执行，严格按照优先级抢占
+	Priority int
+	// 任务所属的分组，例如业务方。同样优先级的任务在分组之间公平地抢占
+	Group   string
+	Version int
+	Status  JobStatus
+	Mode    JobMode
+	// 分片模式下每次执行分成多少片
+	ShardCount int
+	// 执行某一个分片的时候才有，Total 为 0 表示执行的是整个任务
+	Shard JobShard
+	Retry RetryPolicy
+	// 单次执行的超时时间，0 表示不限制。分片任务的每个分片也受这个限制
+	Timeout time.Duration
+	// 连续失败之后第几次尝试，从 1 开始，执行成功之后重新计数
+	Attempt int
+	// 下一次调度的时间
+	NextRunTime time.Time
+	Ctime       time.Time
+	Utime       time.Time
+	// 抢占的是别的实例没有续约的任务，说明那个实例很可能已经崩溃了
+	Reclaimed bool
+	// 续约的时候发现任务已经被别人抢走了，就会关闭这个 channel，执行中的任务应该尽快停下来
+	LeaseLost <-chan struct{}
+	// 管理接口取消了这一次执行，就会关闭这个 channel
+	Cancelled  <-chan struct{}
 	CancelFunc func() error
 }
 
+// JobStatus 和数据库里面的取值保持一致
+type JobStatus uint8
+
+const (
+	JobStatusWaiting JobStatus = iota
+	JobStatusRunning
+	JobStatusPaused
+	// 重试耗尽或者遇到不可重试的错误，需要人工处理之后恢复
+	JobStatusDead
+)
+
+func (s JobStatus) ToUint8() uint8 {
+	return uint8(s)
+}
+
+func (s JobStatus) String() string {
+	switch s {
+	case JobStatusWaiting:
+		return "waiting"
+	case JobStatusRunning:
+		return "running"
+	case JobStatusPaused:
+		return "paused"
+	case JobStatusDead:
+		return "dead"
+	default:
+		return "unknown"
+	}
+}
+
+// JobMode 任务的执行方式
+type JobMode uint8
+
+const (
+	// JobModeNormal 一个实例抢到之后整个执行
+	JobModeNormal JobMode = iota
+	// JobModeSharded 每次执行拆成多个分片，分片各自被抢占，全部完成才算执行完毕
+	JobModeSharded
+	// JobModeBroadcast 每个存活的实例都执行一次，例如刷新本地缓存
+	JobModeBroadcast
+)
+
+func (m JobMode) ToUint8() uint8 {
+	return uint8(m)
+}
+
+func (m JobMode) Valid() bool {
+	return m <= JobModeBroadcast
+}
+
+func (m JobMode) String() string {
+	switch m {
+	case JobModeNormal:
+		return "normal"
+	case JobModeSharded:
+		return "sharded"
+	case JobModeBroadcast:
+		return "broadcast"
+	default:
+		return "unknown"
+	}
+}
+
+// JobModeFromString 为空的时候是 JobModeNormal，不认识的返回一个不合法的值
+func JobModeFromString(s string) JobMode {
+	switch s {
+	case "", "normal":
+		return JobModeNormal
+	case "sharded":
+		return JobModeSharded
+	case "broadcast":
+		return JobModeBroadcast
+	default:
+		return JobMode(255)
+	}
+}
+
+// JobShard 分片或者广播任务某一次执行中的一片
+type JobShard struct {
+	Id    int64
+	JobId int64
+	// 同一次执行的分片 Round 相同，用的是抢占任务之后的版本号
+	Round int
+	// 从 0 开始
+	Index int
+	Total int
+	// 广播任务只能由这个实例执行，分片任务为空
+	Instance string
+	Status   JobShardStatus
+	Version  int
+	ErrMsg   string
+}
+
+type JobShardStatus uint8
+
+const (
+	JobShardStatusUnknown JobShardStatus = iota
+	JobShardStatusWaiting
+	JobShardStatusRunning
+	JobShardStatusSuccess
+	JobShardStatusFailed
+	// 广播的目标实例下线了，或者这一轮被新的一轮取代了
+	JobShardStatusCancelled
+)
+
+func (s JobShardStatus) ToUint8() uint8 {
+	return uint8(s)
+}
+
+// Finished 成功、失败和取消都是终态
+func (s JobShardStatus) Finished() bool {
+	return s == JobShardStatusSuccess || s == JobShardStatusFailed || s == JobShardStatusCancelled
+}
+
+func (s JobShardStatus) String() string {
+	switch s {
+	case JobShardStatusWaiting:
+		return "waiting"
+	case JobShardStatusRunning:
+		return "running"
+	case JobShardStatusSuccess:
+		return "success"
+	case JobShardStatusFailed:
+		return "failed"
+	case JobShardStatusCancelled:
+		return "cancelled"
+	default:
+		return "unknown"
+	}
+}
+
+// RetryPolicy 任务执行失败之后怎么重试，MaxAttempts 为 0 表示不重试，失败之后按照 cron 继续调度
+type RetryPolicy struct {
+	// 最多尝试多少次，包括第一次
+	MaxAttempts int
+	Backoff     BackoffType
+	// 固定间隔重试的间隔，或者指数退避的初始间隔
+	Interval time.Duration
+	// 指数退避的最大间隔，为 0 表示不限制
+	MaxInterval time.Duration
+}
+
+func (p RetryPolicy) Enabled() bool {
+	return p.MaxAttempts > 0
+}
+
+// Valid 开启了重试的时候，间隔必须大于 0
+func (p RetryPolicy) Valid() bool {
+	if p.MaxAttempts < 0 {
+		return false
+	}
+	if !p.Enabled() {
+		return true
+	}
+	return p.Backoff.Valid() && p.Interval > 0 && p.MaxInterval >= 0
+}
+
+// NextInterval 第 attempt 次尝试失败之后，隔多久再试
+func (p RetryPolicy) NextInterval(attempt int) time.Duration {
+	if p.Backoff != BackoffExponential || attempt <= 1 {
+		return p.Interval
+	}
+	interval := p.Interval
+	for i := 1; i < attempt; i++ {
+		interval *= 2
+		if p.MaxInterval > 0 && interval >= p.MaxInterval {
+			return p.MaxInterval
+		}
+	}
+	return interval
+}
+
+type BackoffType uint8
+
+const (
+	BackoffUnknown BackoffType = iota
+	BackoffFixed
+	BackoffExponential
+)
+
+func (b BackoffType) ToUint8() uint8 {
+	return uint8(b)
+}
+
+func (b BackoffType) Valid() bool {
+	return b == BackoffFixed || b == BackoffExponential
+}
+
+func (b BackoffType) String() string {
+	switch b {
+	case BackoffFixed:
+		return "fixed"
+	case BackoffExponential:
+		return "exponential"
+	default:
+		return "unknown"
+	}
+}
+
+func BackoffTypeFromString(s string) BackoffType {
+	switch s {
+	case "fixed":
+		return BackoffFixed
+	case "exponential":
+		return BackoffExponential
+	default:
+		return BackoffUnknown
+	}
+}
+
 var parse = cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
 
-func (j Job) NextTime() time.Time {
-	// 根据cron表达式计算下一次执行时间
-	s, _ := parse.Parse(j.Cron)
-	return s.Next(time.Now())
+// ParseCron 保存任务之前用它校验 cron 表达式，和调度的时候用的是同一个解析器
+func ParseCron(expr string) (cron.Schedule, error) {
+	return parse.Parse(expr)
+}
+
+// Location 任务的时区，为空的时候用服务器的时区
+func (j Job) Location() (*time.Location, error) {
+	if j.Timezone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(j.Timezone)
+}
+
+// NextTime 按照任务的时区计算 after 之后的下一次执行时间，永远不会执行的时候返回零值
+func (j Job) NextTime(after time.Time) (time.Time, error) {
+	s, err := ParseCron(j.Cron)
+	if err != nil {
+		return time.Time{}, err
+	}
+	loc, err := j.Location()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return s.Next(after.In(loc)), nil
+}
+
+// MisfireThreshold 计划的执行时间过去这么久还没有执行，才算错过了
+const MisfireThreshold = time.Minute
+
+// Misfired 错过了计划的执行时间，一般是所有实例都停机了。
+// 重试和从别的实例收回来的任务不算，它们本来就应该尽快执行
+func (j Job) Misfired(now time.Time) bool {
+	return !j.Reclaimed && j.Attempt <= 1 && j.NextRunTime.Before(now.Add(-MisfireThreshold))
+}
+
+// MisfirePolicy 错过执行时间之后怎么处理
+type MisfirePolicy uint8
+
+const (
+	// MisfireFireOnce 不管错过了几次，都只补执行一次，然后从现在开始按照 cron 调度
+	MisfireFireOnce MisfirePolicy = iota
+	// MisfireFireAll 错过的每一次都补执行，一次接一次，直到追上现在
+	MisfireFireAll
+	// MisfireSkip 错过的都不执行，等下一次
+	MisfireSkip
+)
+
+func (p MisfirePolicy) ToUint8() uint8 {
+	return uint8(p)
+}
+
+func (p MisfirePolicy) Valid() bool {
+	return p <= MisfireSkip
+}
+
+func (p MisfirePolicy) String() string {
+	switch p {
+	case MisfireFireOnce:
+		return "fire_once"
+	case MisfireFireAll:
+		return "fire_all"
+	case MisfireSkip:
+		return "skip"
+	default:
+		return "unknown"
+	}
+}
+
+// MisfirePolicyFromString 为空的时候是 MisfireFireOnce，不认识的返回一个不合法的值
+func MisfirePolicyFromString(s string) MisfirePolicy {
+	switch s {
+	case "", "fire_once":
+		return MisfireFireOnce
+	case "fire_all":
+		return MisfireFireAll
+	case "skip":
+		return MisfireSkip
+	default:
+		return MisfirePolicy(255)
+	}
+}
+
+// JobRun 任务的一次执行记录
+type JobRun struct {
+	Id    int64
+	JobId int64
+	// 执行任务的实例
+	Instance string
+	// 第几次尝试，从 1 开始
+	Attempt   int
+	Status    JobRunStatus
+	ErrMsg    string
+	StartTime time.Time
+	// 还在执行的时候是零值
+	EndTime time.Time
+}
+
+func (r JobRun) Duration() time.Duration {
+	if r.EndTime.IsZero() {
+		return 0
+	}
+	return r.EndTime.Sub(r.StartTime)
+}
+
+type JobRunStatus uint8
+
+const (
+	JobRunStatusUnknown JobRunStatus = iota
+	JobRunStatusRunning
+	JobRunStatusSuccess
+	JobRunStatusFailed
+	// JobRunStatusAbandoned 执行的实例丢了租约，任务被别的实例收回，不知道这一次的结果
+	JobRunStatusAbandoned
+)
+
+func (s JobRunStatus) ToUint8() uint8 {
+	return uint8(s)
+}
+
+func (s JobRunStatus) String() string {
+	switch s {
+	case JobRunStatusRunning:
+		return "running"
+	case JobRunStatusSuccess:
+		return "success"
+	case JobRunStatusFailed:
+		return "failed"
+	case JobRunStatusAbandoned:
+		return "abandoned"
+	default:
+		return "unknown"
+	}
+}
+
+// JobRunStatusFromString 前端用字符串过滤执行记录
+func JobRunStatusFromString(s string) JobRunStatus {
+	switch s {
+	case "running":
+		return JobRunStatusRunning
+	case "success":
+		return JobRunStatusSuccess
+	case "failed":
+		return JobRunStatusFailed
+	case "abandoned":
+		return JobRunStatusAbandoned
+	default:
+		return JobRunStatusUnknown
+	}
 }
@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// RankingWindow 热榜统计的时间窗口，只统计窗口内发表的文章
+type RankingWindow string
+
+const (
+	RankingWindowDay   RankingWindow = "24h"
+	RankingWindowWeek  RankingWindow = "7d"
+	RankingWindowMonth RankingWindow = "30d"
+)
+
+// RankingWindows 所有的时间窗口，从短到长
+var RankingWindows = []RankingWindow{RankingWindowDay, RankingWindowWeek, RankingWindowMonth}
+
+func (w RankingWindow) Duration() time.Duration {
+	switch w {
+	case RankingWindowDay:
+		return time.Hour * 24
+	case RankingWindowWeek:
+		return time.Hour * 24 * 7
+	case RankingWindowMonth:
+		return time.Hour * 24 * 30
+	default:
+		return 0
+	}
+}
+
+func (w RankingWindow) Valid() bool {
+	return w.Duration() > 0
+}
+
+// RankingKey 一个榜单，Category 为空表示所有分类
+type RankingKey struct {
+	Category string
+	Window   RankingWindow
+}
+
+// DefaultRankingKey 不指定分类和窗口的时候使用的榜单
+var DefaultRankingKey = RankingKey{Window: RankingWindowWeek}
+
+func (k RankingKey) String() string {
+	if k.Category == "" {
+		return fmt.Sprintf("all:%s", k.Window)
+	}
+	return fmt.Sprintf("category:%s:%s", k.Category, k.Window)
+}
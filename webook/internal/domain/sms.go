@@ -2,12 +2,47 @@ package domain
 
 import "time"
 
+// SMS 同步发送失败之后保存下来，由异步任务重试的短信
 type SMS struct {
-	Id       int64
-	Biz      string
-	Args     []string
-	Numbers  []string
-	Status   int8
+	Id      int64
+	Biz     string
+	Args    []string
+	Numbers []string
+	Status  SMSStatus
+	// 异步任务已经尝试发送了几次
 	RetryCnt int32
-	Ctime    time.Time
+	// 最近一次失败的原因
+	ErrMsg string
+	// 乐观锁，抢占之后只有持有这个版本号的实例可以修改
+	Version int
+	Ctime   time.Time
+}
+
+// SMSStatus 和数据库里面的取值保持一致
+type SMSStatus int8
+
+const (
+	SMSStatusWaiting SMSStatus = iota
+	SMSStatusSending
+	SMSStatusSuccess
+	SMSStatusFailed
+)
+
+func (s SMSStatus) ToInt8() int8 {
+	return int8(s)
+}
+
+func (s SMSStatus) String() string {
+	switch s {
+	case SMSStatusWaiting:
+		return "waiting"
+	case SMSStatusSending:
+		return "sending"
+	case SMSStatusSuccess:
+		return "success"
+	case SMSStatusFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
 }
@@ -0,0 +1,65 @@
+package domain
+
+import "time"
+
+// SMSDelivery 一条发给一个号码的短信。服务商接受请求之后是 Accepted，
+// 收到回执之后才知道有没有送达
+type SMSDelivery struct {
+	Id       int64
+	Provider string
+	// Biz 模板注册中心里面的模板名字
+	Biz       string
+	Number    string
+	MessageId string
+	Status    SMSDeliveryStatus
+	ErrCode   string
+	ErrMsg    string
+	// ReceiptTime 服务商回执里面的时间
+	ReceiptTime time.Time
+	Ctime       time.Time
+	Utime       time.Time
+}
+
+type SMSDeliveryStatus uint8
+
+const (
+	SMSDeliveryStatusUnknown SMSDeliveryStatus = iota
+	SMSDeliveryStatusAccepted
+	SMSDeliveryStatusDelivered
+	SMSDeliveryStatusFailed
+)
+
+func (s SMSDeliveryStatus) ToUint8() uint8 {
+	return uint8(s)
+}
+
+func (s SMSDeliveryStatus) String() string {
+	switch s {
+	case SMSDeliveryStatusAccepted:
+		return "accepted"
+	case SMSDeliveryStatusDelivered:
+		return "delivered"
+	case SMSDeliveryStatusFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+// SMSDeliveryStats 一个服务商的一个模板在一段时间内的送达情况，
+// Total 里面还没有收到回执的就是 Total - Delivered - Failed
+type SMSDeliveryStats struct {
+	Provider  string
+	Biz       string
+	Total     int64
+	Delivered int64
+	Failed    int64
+}
+
+// DeliveryRate 送达率，分母是发送的总数
+func (s SMSDeliveryStats) DeliveryRate() float64 {
+	if s.Total == 0 {
+		return 0
+	}
+	return float64(s.Delivered) / float64(s.Total)
+}
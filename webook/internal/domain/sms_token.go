@@ -0,0 +1,38 @@
+package domain
+
+import "time"
+
+// SMSBizToken 发给内部业务方的短信令牌。一个令牌只能用一个模板，
+// 业务方每天能发的短信总数受 DailyQuota 限制，0 表示不限制
+type SMSBizToken struct {
+	Id         int64
+	Biz        string
+	Tpl        string
+	DailyQuota int
+	Status     SMSBizTokenStatus
+	Ctime      time.Time
+	Utime      time.Time
+}
+
+type SMSBizTokenStatus uint8
+
+const (
+	SMSBizTokenStatusUnknown SMSBizTokenStatus = iota
+	SMSBizTokenStatusActive
+	SMSBizTokenStatusRevoked
+)
+
+func (s SMSBizTokenStatus) ToUint8() uint8 {
+	return uint8(s)
+}
+
+func (s SMSBizTokenStatus) String() string {
+	switch s {
+	case SMSBizTokenStatusActive:
+		return "active"
+	case SMSBizTokenStatusRevoked:
+		return "revoked"
+	default:
+		return "unknown"
+	}
+}
@@ -11,6 +11,8 @@ type User struct {
 	Nickname string
 	Birthday string
 	Desc     string
+	// 隐私设置，不公开自己的点赞记录
+	HideLikes bool
 	// 不要组合，万一将来有同名字段，会有问题
 	WechatInfo WechatInfo
 }
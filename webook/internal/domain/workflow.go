@@ -0,0 +1,165 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// WorkflowExecutor 工作流本身也是一个任务，执行器是 workflow，Cfg 是 Workflow 的 JSON。
+// 这样 cron 调度、手动触发、暂停恢复都和普通任务一样
+const WorkflowExecutor = "workflow"
+
+// Workflow 由多个任务组成的 DAG，上游全部成功之后才会执行下游
+type Workflow struct {
+	Nodes []WorkflowNode `json:"nodes"`
+}
+
+type WorkflowNode struct {
+	JobId int64 `json:"jobId"`
+	// 依赖的上游任务
+	Upstreams []int64 `json:"upstreams"`
+}
+
+// ParseWorkflow 解析并且校验工作流的定义
+func ParseWorkflow(cfg string) (Workflow, error) {
+	var wf Workflow
+	err := json.Unmarshal([]byte(cfg), &wf)
+	if err != nil {
+		return Workflow{}, err
+	}
+	return wf, wf.Validate()
+}
+
+// Validate 节点不能重复，上游必须是工作流里面的节点，并且不能有环
+func (w Workflow) Validate() error {
+	if len(w.Nodes) == 0 {
+		return errors.New("工作流没有节点")
+	}
+	indegree := make(map[int64]int, len(w.Nodes))
+	for _, n := range w.Nodes {
+		if _, ok := indegree[n.JobId]; ok {
+			return fmt.Errorf("节点 %d 重复", n.JobId)
+		}
+		indegree[n.JobId] = len(n.Upstreams)
+	}
+	downstreams := make(map[int64][]int64, len(w.Nodes))
+	for _, n := range w.Nodes {
+		for _, up := range n.Upstreams {
+			if _, ok := indegree[up]; !ok {
+				return fmt.Errorf("节点 %d 的上游 %d 不在工作流里面", n.JobId, up)
+			}
+			downstreams[up] = append(downstreams[up], n.JobId)
+		}
+	}
+	// 拓扑排序，能排完说明没有环
+	queue := make([]int64, 0, len(w.Nodes))
+	for _, n := range w.Nodes {
+		if indegree[n.JobId] == 0 {
+			queue = append(queue, n.JobId)
+		}
+	}
+	visited := 0
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		visited++
+		for _, down := range downstreams[id] {
+			indegree[down]--
+			if indegree[down] == 0 {
+				queue = append(queue, down)
+			}
+		}
+	}
+	if visited != len(w.Nodes) {
+		return errors.New("工作流里面有环")
+	}
+	return nil
+}
+
+// WorkflowRun 工作流的一次执行
+type WorkflowRun struct {
+	Id int64
+	// 工作流对应的任务 ID
+	WorkflowId int64
+	Status     WorkflowRunStatus
+	Nodes      []WorkflowNodeRun
+	StartTime  time.Time
+	// 还在执行的时候是零值
+	EndTime time.Time
+}
+
+type WorkflowRunStatus uint8
+
+const (
+	WorkflowRunStatusUnknown WorkflowRunStatus = iota
+	WorkflowRunStatusRunning
+	WorkflowRunStatusSuccess
+	WorkflowRunStatusFailed
+)
+
+func (s WorkflowRunStatus) ToUint8() uint8 {
+	return uint8(s)
+}
+
+func (s WorkflowRunStatus) String() string {
+	switch s {
+	case WorkflowRunStatusRunning:
+		return "running"
+	case WorkflowRunStatusSuccess:
+		return "success"
+	case WorkflowRunStatusFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+// WorkflowNodeRun 一次执行里面某个节点的状态
+type WorkflowNodeRun struct {
+	Id    int64
+	RunId int64
+	JobId int64
+	// 上游
+	Upstreams []int64
+	Status    WorkflowNodeStatus
+	// 这个节点已经尝试了几次
+	Attempt   int
+	ErrMsg    string
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+type WorkflowNodeStatus uint8
+
+const (
+	WorkflowNodeStatusUnknown WorkflowNodeStatus = iota
+	WorkflowNodeStatusWaiting
+	WorkflowNodeStatusRunning
+	WorkflowNodeStatusSuccess
+	WorkflowNodeStatusFailed
+	// 上游失败了，这个节点不会执行
+	WorkflowNodeStatusSkipped
+)
+
+func (s WorkflowNodeStatus) ToUint8() uint8 {
+	return uint8(s)
+}
+
+func (s WorkflowNodeStatus) String() string {
+	switch s {
+	case WorkflowNodeStatusWaiting:
+		return "waiting"
+	case WorkflowNodeStatusRunning:
+		return "running"
+	case WorkflowNodeStatusSuccess:
+		return "success"
+	case WorkflowNodeStatusFailed:
+		return "failed"
+	case WorkflowNodeStatusSkipped:
+		return "skipped"
+	default:
+		return "unknown"
+	}
+}
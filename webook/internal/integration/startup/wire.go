@@ -53,11 +53,18 @@ func InitWebServer() *gin.Engine {
 
 		// repository 部分
 		repository.NewCodeRepository,
+		dao.NewSMSAysncReqDAO,
+		repository.NewSMSAysncReqRepository,
+		dao.NewGORMSMSDeliveryDAO,
+		repository.NewSMSDeliveryRepository,
 		articleRepository.NewArticleRepository,
 
 		// Service 部分
+		ioc.InitSMSTemplateRegistry,
 		ioc.InitSMSService,
 		service.NewCodeService,
+		ioc.InitEmailService,
+		ioc.InitEmailCodeService,
 		// InitWechatService,
 
 		// handler 部分
@@ -34,9 +34,16 @@ func InitWebServer() *gin.Engine {
 	userService := service.NewUserService(userRepository, logger)
 	codeCache := cache.NewCodeCache(cmdable)
 	codeRepository := repository.NewCodeRepository(codeCache)
-	smsService := ioc.InitSMSService(cmdable)
+	smsdao := dao.NewSMSAysncReqDAO(db)
+	smsRepository := repository.NewSMSAysncReqRepository(smsdao)
+	registry := ioc.InitSMSTemplateRegistry()
+	smsDeliveryDAO := dao.NewGORMSMSDeliveryDAO(db)
+	smsDeliveryRepository := repository.NewSMSDeliveryRepository(smsDeliveryDAO)
+	smsService := ioc.InitSMSService(cmdable, smsRepository, smsDeliveryRepository, registry, logger)
 	codeService := service.NewCodeService(codeRepository, smsService)
-	userHandler := web.NewUserHandler(userService, codeService, handler, logger)
+	emailService := ioc.InitEmailService()
+	emailCodeService := ioc.InitEmailCodeService(cmdable, emailService)
+	userHandler := web.NewUserHandler(userService, codeService, emailCodeService, handler, logger)
 	wechatService := ioc.InitOAuth2WechatService()
 	stateConfig := ioc.NewWechatHandlerConfig()
 	oAuth2WechatHandler := web.NewOAuth2WechatHandler(wechatService, userService, handler, stateConfig)
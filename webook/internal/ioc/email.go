@@ -0,0 +1,77 @@
+package ioc
+
+import (
+	"go-basic/webook/internal/repository"
+	"go-basic/webook/internal/repository/cache"
+	"go-basic/webook/internal/service"
+	"go-basic/webook/internal/service/email"
+	"go-basic/webook/internal/service/email/memory"
+	"go-basic/webook/internal/service/email/quota"
+	"go-basic/webook/internal/service/email/smtp"
+	limiter "go-basic/webook/pkg/ratelimit"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+)
+
+// InitEmailService SMTP 通过 email.smtp 配置，没有配置地址的时候用内存实现
+func InitEmailService() email.Service {
+	type Config struct {
+		Addr     string `yaml:"addr"`
+		Username string `yaml:"username"`
+		Password string `yaml:"password"`
+		From     string `yaml:"from"`
+	}
+	var cfg Config
+	err := viper.UnmarshalKey("email.smtp", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	if cfg.Addr == "" {
+		return memory.NewService()
+	}
+	svc, err := smtp.NewService(cfg.Addr, cfg.Username, cfg.Password, cfg.From)
+	if err != nil {
+		panic(err)
+	}
+	return svc
+}
+
+// InitEmailCodeService 邮件验证码和短信验证码用同一套存储和校验逻辑，key 的前缀不一样。
+// 发送验证码的接口不需要登录，按照邮箱和 IP 限制发送数量
+func InitEmailCodeService(cmd redis.Cmdable, svc email.Service) service.EmailCodeService {
+	return service.NewEmailCodeService(repository.NewCodeRepository(cache.NewEmailCodeCache(cmd)),
+		initEmailQuota(cmd, svc))
+}
+
+// initEmailQuota 配额通过 email.quota 配置，Rate 小于等于 0 的时候不限制
+func initEmailQuota(cmd redis.Cmdable, svc email.Service) email.Service {
+	type Config struct {
+		Address quotaConfig `yaml:"address"`
+		IP      quotaConfig `yaml:"ip"`
+	}
+	cfg := Config{
+		Address: quotaConfig{Interval: time.Hour * 24, Rate: 10},
+		IP:      quotaConfig{Interval: time.Hour, Rate: 20},
+	}
+	err := viper.UnmarshalKey("email.quota", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	// 后包装的先判断，先判断 IP，被拦下来的请求不占用邮箱的配额
+	layers := []struct {
+		cfg quotaConfig
+		fn  func(svc email.Service, limiter limiter.Limiter) email.Service
+	}{
+		{cfg: cfg.Address, fn: quota.NewAddressQuotaService},
+		{cfg: cfg.IP, fn: quota.NewIPQuotaService},
+	}
+	for _, layer := range layers {
+		if layer.cfg.Rate <= 0 {
+			continue
+		}
+		svc = layer.fn(svc, limiter.NewRedisSlidingWindowLimiter(cmd, layer.cfg.Interval, layer.cfg.Rate))
+	}
+	return svc
+}
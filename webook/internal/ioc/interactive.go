@@ -0,0 +1,53 @@
+package ioc
+
+import (
+	"context"
+	"go-basic/webook/internal/domain"
+	"go-basic/webook/internal/job"
+	"go-basic/webook/internal/repository/cache"
+	"go-basic/webook/internal/service"
+	"go-basic/webook/pkg/logger"
+	"time"
+
+	rlock "github.com/gotomicro/redis-lock"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+)
+
+// InitInteractiveBizRegistry 新的业务接入互动服务，在这里注册就可以
+func InitInteractiveBizRegistry(artSvc service.ArticleService) *service.InteractiveBizRegistry {
+	registry := service.NewInteractiveBizRegistry()
+	registry.Register(domain.InteractiveBiz{
+		Name: "article",
+		Actions: []domain.InteractiveAction{
+			domain.InteractiveActionRead,
+			domain.InteractiveActionLike,
+			domain.InteractiveActionCollect,
+		},
+		CacheTTL: time.Minute * 15,
+	}, func(ctx context.Context, id int64) (bool, error) {
+		// 只有已经发表的文章可以互动
+		arts, err := artSvc.ListPubByIds(ctx, []int64{id})
+		return len(arts) > 0, err
+	})
+	return registry
+}
+
+func InitInteractiveCache(client redis.Cmdable, registry *service.InteractiveBizRegistry) cache.InteractiveCache {
+	return cache.NewInteractiveRedisCache(client, registry.CacheTTL)
+}
+
+func InitInteractiveReconcileJob(svc service.InteractiveReconcileService, rlockClient *rlock.Client, l logger.Logger) *job.InteractiveReconcileJob {
+	type Config struct {
+		// 只对账这些业务，为空表示所有业务
+		Bizs   []string `yaml:"bizs"`
+		DryRun bool     `yaml:"dryRun"`
+	}
+	var cfg Config
+	err := viper.UnmarshalKey("interactive.reconcile", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	// 全表扫描，超时时间给得长一些
+	return job.NewInteractiveReconcileJob(svc, rlockClient, time.Minute*30, cfg.Bizs, cfg.DryRun, l)
+}
@@ -3,6 +3,7 @@ package ioc
 import (
 	"go-basic/webook/events"
 	"go-basic/webook/events/article"
+	"go-basic/webook/events/ranking"
 
 	"github.com/IBM/sarama"
 	"github.com/spf13/viper"
@@ -34,6 +35,11 @@ func InitSyncProducer(c sarama.Client) sarama.SyncProducer {
 	return p
 }
 
-func InitConsumers(c *article.InteractiveReadEventBatchConsumer) []events.Consumer {
-	return []events.Consumer{c}
+func InitConsumers(c *article.InteractiveReadEventBatchConsumer, rankingConsumer *ranking.RankingEventConsumer) []events.Consumer {
+	res := []events.Consumer{c}
+	// 只有增量计算热榜才需要消费互动事件
+	if viper.GetString("ranking.mode") == "stream" {
+		res = append(res, rankingConsumer)
+	}
+	return res
 }
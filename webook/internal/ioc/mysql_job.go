@@ -2,19 +2,85 @@ package ioc
 
 import (
 	"context"
+	"fmt"
 	"go-basic/webook/internal/domain"
 	"go-basic/webook/internal/job"
 	"go-basic/webook/internal/service"
 	"go-basic/webook/pkg/logger"
+	"net/http"
+	"os"
 	"time"
+
+	"github.com/spf13/viper"
 )
 
-func InitScheduler(l logger.Logger, svc service.JobService, local *job.LocalFuncExecter) *job.Scheduler {
-	res := job.NewScheduler(svc, l)
+// InitScheduler 广播任务的目标实例就是上报负载的那些节点，节点 ID 和调度器的实例 ID 一样。
+// 退出的时候等待执行中任务的时间和每个执行器的并发上限通过 job.scheduler 配置
+func InitScheduler(l logger.Logger, svc service.JobService, runSvc service.JobRunService,
+	shardSvc service.JobShardService, nodes *job.NodeLoadReporter,
+	local *job.LocalFuncExecter, httpExec *job.HttpExecutor, wfExec *job.WorkflowExecutor) *job.Scheduler {
+	type Config struct {
+		DrainTimeout time.Duration `yaml:"drainTimeout"`
+		// 执行器名字到并发上限，没有配置的只受总数限制
+		ExecutorLimits map[string]int `yaml:"executorLimits"`
+	}
+	cfg := Config{
+		DrainTimeout: time.Second * 30,
+	}
+	err := viper.UnmarshalKey("job.scheduler", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	res := job.NewScheduler(svc, runSvc, shardSvc, nodes, job.NewLogAlerter(l), instanceId(), cfg.DrainTimeout, l)
+	res.RegisterExecutor(local)
+	res.RegisterExecutor(httpExec)
+	res.RegisterExecutor(wfExec)
+	for name, limit := range cfg.ExecutorLimits {
+		res.LimitExecutor(name, limit)
+	}
+	return res
+}
+
+// InitWorkflowExecutor 工作流的节点可以用本地函数和 HTTP 执行器
+func InitWorkflowExecutor(l logger.Logger, svc service.WorkflowService, jobSvc service.JobService,
+	local *job.LocalFuncExecter, httpExec *job.HttpExecutor) *job.WorkflowExecutor {
+	res := job.NewWorkflowExecutor(svc, jobSvc, l)
 	res.RegisterExecutor(local)
+	res.RegisterExecutor(httpExec)
 	return res
 }
 
+// InitHttpExecutor HTTP 任务的签名密钥和超时时间通过 job.http 配置
+func InitHttpExecutor(l logger.Logger) *job.HttpExecutor {
+	type Config struct {
+		Secret string `yaml:"secret"`
+		// 任务没有配置超时时间的时候使用
+		Timeout time.Duration `yaml:"timeout"`
+		// 长任务查询结果的间隔
+		PollInterval time.Duration `yaml:"pollInterval"`
+		// 允许请求的主机名
+		AllowedHosts []string `yaml:"allowedHosts"`
+	}
+	cfg := Config{
+		Timeout:      time.Minute,
+		PollInterval: time.Second * 5,
+	}
+	err := viper.UnmarshalKey("job.http", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	if cfg.Secret == "" {
+		panic("没有配置 HTTP 任务的签名密钥")
+	}
+	client := &http.Client{
+		// 不跟随重定向，否则白名单里面的服务可以把带签名的请求转到任意地址
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return job.NewHttpExecutor(client, []byte(cfg.Secret), cfg.Timeout, cfg.PollInterval, cfg.AllowedHosts, l)
+}
+
 func InitLocalFuncExecutor(svc service.RankingService) *job.LocalFuncExecter {
 	res := job.NewLocalFuncExecter()
 	res.RegisterFunc("ranking", func(ctx context.Context, j domain.Job) error {
@@ -24,3 +90,21 @@ func InitLocalFuncExecutor(svc service.RankingService) *job.LocalFuncExecter {
 	})
 	return res
 }
+
+// InitJobRunCleanupJob 执行记录默认保留 30 天，通过 job.history.retention 配置
+func InitJobRunCleanupJob(svc service.JobRunService, l logger.Logger) *job.JobRunCleanupJob {
+	retention := viper.GetDuration("job.history.retention")
+	if retention <= 0 {
+		retention = time.Hour * 24 * 30
+	}
+	return job.NewJobRunCleanupJob(svc, retention, l)
+}
+
+// instanceId 主机名加上进程 ID，同一台机器上可以部署多个实例
+func instanceId() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		panic(err)
+	}
+	return fmt.Sprintf("%s_%d", hostname, os.Getpid())
+}
@@ -1,28 +1,113 @@
 package ioc
 
 import (
+	"go-basic/webook/events/ranking"
 	"go-basic/webook/internal/job"
+	"go-basic/webook/internal/repository"
 	"go-basic/webook/internal/service"
 	"go-basic/webook/pkg/logger"
 	"time"
 
+	"github.com/IBM/sarama"
 	rlock "github.com/gotomicro/redis-lock"
+	"github.com/redis/go-redis/v9"
 	"github.com/robfig/cron/v3"
+	"github.com/spf13/viper"
 )
 
-func InitRankingJob(svc service.RankingService, l logger.Logger, rlockClient *rlock.Client) (*job.RankingJob, func()) {
-	j := job.NewRankingJob(svc, time.Second*30, rlockClient, l)
+// InitRankingScorer 热榜算法通过 ranking.algorithm 配置，默认是 gravity
+func InitRankingScorer() service.RankingScorer {
+	scorer, err := service.NewRankingScorer(viper.GetString("ranking.algorithm"))
+	if err != nil {
+		panic(err)
+	}
+	return scorer
+}
+
+// InitRankingService ranking.mode 为 stream 的时候根据事件增量计算热榜，默认定时扫描所有文章
+func InitRankingService(artSvc service.ArticleService, intrSvc service.InteractiveService,
+	repo repository.RankingRepository, scorer service.RankingScorer,
+	stream service.StreamingRankingService) service.RankingService {
+	if viper.GetString("ranking.mode") == "stream" {
+		return stream
+	}
+	return service.NewBatchRankingService(artSvc, intrSvc, repo, scorer)
+}
+
+func InitStreamingRankingService(artSvc service.ArticleService, intrSvc service.InteractiveService,
+	repo repository.RankingRepository) service.StreamingRankingService {
+	type Config struct {
+		// 候选集的上限
+		Capacity int           `yaml:"capacity"`
+		HalfLife time.Duration `yaml:"halfLife"`
+		// 启动的时候从数据库加载多久之内发表的文章
+		WarmupWindow time.Duration `yaml:"warmupWindow"`
+	}
+	cfg := Config{
+		Capacity:     10000,
+		HalfLife:     time.Hour * 24,
+		WarmupWindow: time.Hour * 24 * 7,
+	}
+	err := viper.UnmarshalKey("ranking.stream", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	return service.NewStreamingRankingService(artSvc, intrSvc, repo, cfg.Capacity, cfg.HalfLife, cfg.WarmupWindow)
+}
+
+func InitRankingEventConsumer(client sarama.Client, svc service.StreamingRankingService, l logger.Logger) *ranking.RankingEventConsumer {
+	return ranking.NewRankingEventConsumer(client, svc, l)
+}
+
+// InitNodeLoadReporter 节点定时上报负载
+func InitNodeLoadReporter(client redis.Cmdable, l logger.Logger) (*job.NodeLoadReporter, func()) {
+	interval := viper.GetDuration("ranking.job.loadReportInterval")
+	if interval <= 0 {
+		interval = time.Second * 10
+	}
+	r := job.NewNodeLoadReporter(client, "cron_job:node", instanceId(), job.SystemLoad, interval, l)
+	r.Start()
+	return r, func() {
+		r.Close()
+	}
+}
+
+func InitRankingJob(svc service.RankingService, l logger.Logger, rlockClient *rlock.Client,
+	loads *job.NodeLoadReporter) (*job.RankingJob, func()) {
+	type Config struct {
+		// 负载高出最低节点多少之后让出锁，负载是平均负载除以 CPU 核数
+		StepDownThreshold float64 `yaml:"stepDownThreshold"`
+	}
+	cfg := Config{
+		StepDownThreshold: 0.5,
+	}
+	err := viper.UnmarshalKey("ranking.job", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	j := job.NewRankingJob(svc, time.Second*30, rlockClient, loads, cfg.StepDownThreshold, l)
 	return j, func() {
 		j.Close()
 	}
 }
 
-func InitJob(l logger.Logger, rankingJob *job.RankingJob) *cron.Cron {
+func InitJob(l logger.Logger, rankingJob *job.RankingJob, reconcileJob *job.InteractiveReconcileJob,
+	jobRunCleanupJob *job.JobRunCleanupJob) *cron.Cron {
 	res := cron.New(cron.WithSeconds())
 	cbd := job.NewCronJobBuilder(l)
 	_, err := res.AddJob("0 */3 * * * ?", cbd.Build(rankingJob))
 	if err != nil {
 		l.Error("添加任务失败", logger.Error(err))
 	}
+	// 每天凌晨三点对账
+	_, err = res.AddJob("0 0 3 * * ?", cbd.Build(reconcileJob))
+	if err != nil {
+		l.Error("添加任务失败", logger.Error(err))
+	}
+	// 每天凌晨四点清理过期的任务执行记录
+	_, err = res.AddJob("0 0 4 * * ?", cbd.Build(jobRunCleanupJob))
+	if err != nil {
+		l.Error("添加任务失败", logger.Error(err))
+	}
 	return res
 }
@@ -1,18 +1,219 @@
 package ioc
 
 import (
+	"context"
+	"fmt"
+	"go-basic/webook/internal/repository"
+	"go-basic/webook/internal/service"
 	"go-basic/webook/internal/service/ratelimit"
 	"go-basic/webook/internal/service/sms"
+	"go-basic/webook/internal/service/sms/aliyun"
+	"go-basic/webook/internal/service/sms/async"
+	"go-basic/webook/internal/service/sms/auth"
+	"go-basic/webook/internal/service/sms/delivery"
+	"go-basic/webook/internal/service/sms/failover"
+	"go-basic/webook/internal/service/sms/gateway"
 	"go-basic/webook/internal/service/sms/memory"
 	"go-basic/webook/internal/service/sms/metrics"
+	"go-basic/webook/internal/service/sms/quota"
+	"go-basic/webook/internal/service/sms/template"
+	"go-basic/webook/internal/web"
+	"go-basic/webook/pkg/logger"
 	limiter "go-basic/webook/pkg/ratelimit"
+	"net/http"
 	"time"
 
 	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
 )
 
-func InitSMSService(cmd redis.Cmdable) sms.Service {
-	// 换内存还是换短信服务，只需要修改这里
-	svc := memory.NewService()
-	return metrics.NewPrometheusDecorator(ratelimit.NewRatelimitSMSService(svc, limiter.NewRedisSlidingWindowLimiter(cmd, time.Second, 100)))
+// smsProviderConfig 一个短信服务商的配置，Type 决定用下面哪一组配置
+type smsProviderConfig struct {
+	Name   string `yaml:"name"`
+	Type   string `yaml:"type"`
+	Weight int    `yaml:"weight"`
+	// 调用服务商接口的超时时间
+	Timeout time.Duration `yaml:"timeout"`
+
+	Aliyun struct {
+		Endpoint        string `yaml:"endpoint"`
+		AccessKeyId     string `yaml:"accessKeyId"`
+		AccessKeySecret string `yaml:"accessKeySecret"`
+		SignName        string `yaml:"signName"`
+		// 回执地址上带的 token，在阿里云控制台配置回执地址 /sms/receipts?provider=名字&token=xxx
+		ReceiptToken string `yaml:"receiptToken"`
+	} `yaml:"aliyun"`
+
+	Gateway struct {
+		URL         string `yaml:"url"`
+		AppKey      string `yaml:"appKey"`
+		Secret      string `yaml:"secret"`
+		SuccessCode string `yaml:"successCode"`
+		// 网关错误码到错误类型
+		ErrorCodes map[string]string `yaml:"errorCodes"`
+	} `yaml:"gateway"`
+}
+
+// InitSMSService 服务商通过 sms.providers 配置，按照健康程度在服务商之间分配流量；
+// 没有配置的时候用内存实现。发送之前按照模板注册中心校验参数，再换成每个服务商自己的模板 ID。
+// 同步发送失败的短信落库，由异步任务重试。
+// 异步任务跟着进程退出，发送到一半的短信等租约过期之后由别的实例接着发送
+func InitSMSService(cmd redis.Cmdable, repo repository.SMSRepository, deliveryRepo repository.SMSDeliveryRepository,
+	registry *template.Registry, l logger.Logger) sms.Service {
+	cfgs := smsProviderConfigs()
+	providers := make([]failover.Provider, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		// 投递记录里面保存的是业务模板，方便按照模板统计送达率
+		p := delivery.NewSMSService(
+			template.NewProviderSMSService(initSMSProvider(cfg, registry), registry, cfg.Name),
+			deliveryRepo, cfg.Name, l)
+		providers = append(providers, failover.Provider{
+			Name:   cfg.Name,
+			Svc:    p,
+			Weight: max(cfg.Weight, 1),
+		})
+	}
+	svc := failover.NewHealthRoutingSMSService(providers, time.Minute, l)
+	res := async.NewSMSService(metrics.NewPrometheusDecorator(
+		ratelimit.NewRatelimitSMSService(svc, limiter.NewRedisSlidingWindowLimiter(cmd, time.Second, 100))), repo, l)
+	res.StartAysnc(context.Background())
+	// 配额在异步重试前面判断，超过配额的直接返回给调用方，不会落库重试；
+	// 参数不合法的请求不占用配额
+	return template.NewValidateSMSService(initSMSQuota(cmd, res), registry)
+}
+
+// quotaConfig Rate 小于等于 0 的时候不限制
+type quotaConfig struct {
+	Interval time.Duration `yaml:"interval"`
+	Rate     int           `yaml:"rate"`
+}
+
+// initSMSQuota 配额通过 sms.quota 配置，分别限制每个手机号码、每个 IP 和每个业务
+func initSMSQuota(cmd redis.Cmdable, svc sms.Service) sms.Service {
+	type Config struct {
+		Phone quotaConfig `yaml:"phone"`
+		IP    quotaConfig `yaml:"ip"`
+		Biz   quotaConfig `yaml:"biz"`
+	}
+	cfg := Config{
+		Phone: quotaConfig{Interval: time.Hour * 24, Rate: 10},
+		IP:    quotaConfig{Interval: time.Hour, Rate: 20},
+		Biz:   quotaConfig{Interval: time.Minute, Rate: 1000},
+	}
+	err := viper.UnmarshalKey("sms.quota", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	// 后包装的先判断，依次是 IP、业务、手机号码，被前面拦下来的请求不占用后面的配额
+	layers := []struct {
+		cfg quotaConfig
+		fn  func(svc sms.Service, limiter limiter.Limiter) sms.Service
+	}{
+		{cfg: cfg.Phone, fn: quota.NewPhoneQuotaSMSService},
+		{cfg: cfg.Biz, fn: quota.NewBizQuotaSMSService},
+		{cfg: cfg.IP, fn: quota.NewIPQuotaSMSService},
+	}
+	for _, layer := range layers {
+		if layer.cfg.Rate <= 0 {
+			continue
+		}
+		svc = layer.fn(svc, limiter.NewRedisSlidingWindowLimiter(cmd, layer.cfg.Interval, layer.cfg.Rate))
+	}
+	return svc
+}
+
+// InitSMSReceiptParsers 服务商名字到回执解析，内存实现没有回执
+func InitSMSReceiptParsers() map[string]sms.ReceiptParser {
+	res := make(map[string]sms.ReceiptParser)
+	for _, cfg := range smsProviderConfigs() {
+		switch cfg.Type {
+		case "aliyun":
+			res[cfg.Name] = aliyun.NewReceiptParser(cfg.Aliyun.ReceiptToken)
+		case "gateway":
+			res[cfg.Name] = gateway.NewReceiptParser(cfg.Gateway.AppKey, []byte(cfg.Gateway.Secret))
+		}
+	}
+	return res
+}
+
+// InitSMSTemplateRegistry 模板通过 sms.templates 配置，每个模板都要配置所有服务商的模板 ID
+func InitSMSTemplateRegistry() *template.Registry {
+	var tpls []template.Template
+	err := viper.UnmarshalKey("sms.templates", &tpls)
+	if err != nil {
+		panic(err)
+	}
+	cfgs := smsProviderConfigs()
+	names := make([]string, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		names = append(names, cfg.Name)
+	}
+	res, err := template.NewRegistry(tpls, names)
+	if err != nil {
+		panic(err)
+	}
+	return res
+}
+
+// InitSMSBizTokenService 签发令牌的密钥通过 sms.token.key 配置
+func InitSMSBizTokenService(repo repository.SMSBizTokenRepository, registry *template.Registry) service.SMSBizTokenService {
+	key := viper.GetString("sms.token.key")
+	if key == "" {
+		panic("没有配置短信令牌的签名密钥")
+	}
+	return service.NewSMSBizTokenService(repo, registry, []byte(key))
+}
+
+// InitSMSHandler 内部业务方发短信的时候先用令牌换成模板，校验参数之后再扣令牌的额度
+func InitSMSHandler(tokens service.SMSBizTokenService, svc sms.Service, registry *template.Registry,
+	l logger.Logger) *web.SMSHandler {
+	return web.NewSMSHandler(tokens, auth.NewSMSService(svc, tokens, registry), l)
+}
+
+func smsProviderConfigs() []smsProviderConfig {
+	var cfgs []smsProviderConfig
+	err := viper.UnmarshalKey("sms.providers", &cfgs)
+	if err != nil {
+		panic(err)
+	}
+	if len(cfgs) == 0 {
+		cfgs = append(cfgs, smsProviderConfig{Name: "memory", Type: "memory"})
+	}
+	return cfgs
+}
+
+// initSMSProvider 服务商收到的 biz 已经是它自己的模板 ID
+func initSMSProvider(cfg smsProviderConfig, registry *template.Registry) sms.Service {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = time.Second * 5
+	}
+	client := &http.Client{Timeout: timeout}
+	switch cfg.Type {
+	case "memory":
+		return memory.NewService()
+	case "aliyun":
+		c := cfg.Aliyun
+		if c.Endpoint == "" {
+			c.Endpoint = aliyun.DefaultEndpoint
+		}
+		// 阿里云按照名字传参数，参数名来自模板注册中心
+		tpls := make(map[string]aliyun.Template)
+		for code, params := range registry.ParamNames(cfg.Name) {
+			tpls[code] = aliyun.Template{Code: code, Params: params}
+		}
+		return aliyun.NewService(client, c.Endpoint, c.AccessKeyId, c.AccessKeySecret, c.SignName, tpls)
+	case "gateway":
+		c := cfg.Gateway
+		if c.SuccessCode == "" {
+			c.SuccessCode = "0"
+		}
+		res, err := gateway.NewService(client, c.URL, c.AppKey, []byte(c.Secret), nil, c.SuccessCode, c.ErrorCodes)
+		if err != nil {
+			panic(fmt.Errorf("短信服务商 %s 配置有误: %w", cfg.Name, err))
+		}
+		return res
+	default:
+		panic(fmt.Sprintf("不支持的短信服务商类型 %s", cfg.Type))
+	}
 }
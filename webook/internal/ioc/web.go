@@ -14,14 +14,28 @@ import (
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
 )
 
-func InitWebServer(mdls []gin.HandlerFunc, userHdl *web.UserHandler, oauth2WechatHdl *web.OAuth2WechatHandler, articleHdl *web.ArticleHandler) *gin.Engine {
+func InitWebServer(mdls []gin.HandlerFunc, userHdl *web.UserHandler, oauth2WechatHdl *web.OAuth2WechatHandler,
+	articleHdl *web.ArticleHandler, intrHdl *web.InteractiveHandler, rankingHdl *web.RankingHandler,
+	jobHdl *web.JobHandler, smsHdl *web.SMSHandler, smsDeliveryHdl *web.SMSDeliveryHandler) *gin.Engine {
 	server := gin.Default()
+	// 默认信任所有代理，客户端自己带上 X-Forwarded-For 就能换 IP，绕过按照 IP 的限流和配额。
+	// 只有 web.trustedProxies 里面的代理转发过来的时候才读取请求头，没有配置的时候用连接的地址
+	err := server.SetTrustedProxies(viper.GetStringSlice("web.trustedProxies"))
+	if err != nil {
+		panic(err)
+	}
 	server.Use(mdls...)
 	userHdl.RegisterRoutes(server)
 	oauth2WechatHdl.RegisterRoutes(server)
 	articleHdl.RegisterRoutes(server)
+	intrHdl.RegisterRoutes(server)
+	rankingHdl.RegisterRoutes(server)
+	jobHdl.RegisterRoutes(server)
+	smsHdl.RegisterRoutes(server)
+	smsDeliveryHdl.RegisterRoutes(server)
 	(&web.ObservabilityHandler{}).RegisterRoutes(server)
 	return server
 }
@@ -44,14 +58,36 @@ func InitMiddlewares(redisClient redis.Cmdable, jwtHdl ijwt.Handler, l loggerx.L
 			IgnorePaths("/users/signup").
 			IgnorePaths("/users/login_sms/code/send").
 			IgnorePaths("/users/login_sms").
+			IgnorePaths("/users/signup/code/send").
+			IgnorePaths("/users/login_email/code/send").
+			IgnorePaths("/users/login_email").
 			IgnorePaths("/users/refresh_token").
 			IgnorePaths("/oauth2/wechat/authurl").
 			IgnorePaths("/oauth2/wechat/callback").
+			IgnorePaths("/articles/ranking").
+			IgnorePaths("/sms/receipts").
+			IgnorePaths("/sms/send").
 			Build(),
+		initAdminMiddleware(),
 		ratelimit.NewBuilder(ratelimitx.NewRedisSlidingWindowLimiter(redisClient, time.Second, 100)).Build(),
 	}
 }
 
+// initAdminMiddleware 管理接口只允许 admin.uids 里面的用户访问，
+// 内部业务方发短信的 /sms/send 用令牌鉴权，不在这里
+func initAdminMiddleware() gin.HandlerFunc {
+	var uids []int64
+	err := viper.UnmarshalKey("admin.uids", &uids)
+	if err != nil {
+		panic(err)
+	}
+	return middleware.NewAdminMiddlewareBuilder(uids).
+		Prefix("/jobs/").
+		Prefix("/sms/tokens/").
+		Prefix("/sms/delivery/").
+		Build()
+}
+
 func corsHdl() gin.HandlerFunc {
 	return cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:3000"},
@@ -0,0 +1,32 @@
+package job
+
+import (
+	"context"
+	"go-basic/webook/internal/domain"
+	"go-basic/webook/pkg/logger"
+)
+
+// Alerter 任务死掉之后通知负责人，例如发短信、发到 IM 群里面
+type Alerter interface {
+	Alert(ctx context.Context, j domain.Job, err error) error
+}
+
+// LogAlerter 只打日志，接入告警系统之前使用
+type LogAlerter struct {
+	l logger.Logger
+}
+
+func NewLogAlerter(l logger.Logger) *LogAlerter {
+	return &LogAlerter{
+		l: l,
+	}
+}
+
+func (a *LogAlerter) Alert(ctx context.Context, j domain.Job, err error) error {
+	a.l.Error("任务已经死掉，需要人工处理",
+		logger.Int64("job_id", j.Id),
+		logger.String("name", j.Name),
+		logger.Int64("attempt", int64(j.Attempt)),
+		logger.Error(err))
+	return nil
+}
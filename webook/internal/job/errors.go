@@ -0,0 +1,39 @@
+package job
+
+import "errors"
+
+var (
+	// ErrJobTimeout 执行时间超过了任务配置的超时时间，按照普通的失败重试
+	ErrJobTimeout = errors.New("任务执行超时")
+	// ErrJobCancelled 管理接口取消了这一次执行，不算失败，按照 cron 调度下一次
+	ErrJobCancelled = errors.New("任务被取消")
+	// ErrSchedulerStopped 调度器退出的时候还没有执行完的任务，释放之后由别的实例重新执行
+	ErrSchedulerStopped = errors.New("调度器已经停止")
+)
+
+// fatalError 重试也不会成功的错误，例如配置有误、对方拒绝了请求
+type fatalError struct {
+	err error
+}
+
+func (f fatalError) Error() string {
+	return f.err.Error()
+}
+
+func (f fatalError) Unwrap() error {
+	return f.err
+}
+
+// Fatal 执行器用它包装不需要重试的错误。配置了重试策略的任务不再重试，直接标记为死掉；
+// 没有配置重试策略的任务本来就不会重试，和普通失败一样按照 cron 调度下一次
+func Fatal(err error) error {
+	if err == nil {
+		return nil
+	}
+	return fatalError{err: err}
+}
+
+func IsFatal(err error) bool {
+	var fe fatalError
+	return errors.As(err, &fe)
+}
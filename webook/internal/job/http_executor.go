@@ -0,0 +1,257 @@
+package job
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-basic/webook/internal/domain"
+	"go-basic/webook/pkg/logger"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// HttpJobTimestampHeader 签名用的时间戳，Unix 秒
+	HttpJobTimestampHeader = "X-Webook-Timestamp"
+	// HttpJobSignatureHeader hex(HMAC-SHA256(secret, timestamp + "\n" + body))
+	HttpJobSignatureHeader = "X-Webook-Signature"
+)
+
+const (
+	HttpJobStatusRunning = "running"
+	HttpJobStatusSuccess = "success"
+	HttpJobStatusFailed  = "failed"
+)
+
+var (
+	ErrHttpJobFailed         = errors.New("HTTP 任务执行失败")
+	ErrHttpJobHostNotAllowed = errors.New("HTTP 任务的地址不在白名单里面")
+)
+
+// HttpJobCfg HTTP 任务的 Cfg 是一个 JSON
+type HttpJobCfg struct {
+	// 接收任务的地址，必填
+	Endpoint string `json:"endpoint"`
+	// 整个任务的超时时间，包括长任务轮询的时间，为空使用执行器的默认值
+	Timeout string `json:"timeout"`
+	// 原样发给对方
+	Params json.RawMessage `json:"params"`
+}
+
+// HttpJobRequest 发给对方的请求体
+type HttpJobRequest struct {
+	JobId int64  `json:"jobId"`
+	Name  string `json:"name"`
+	// 同一个任务每次执行都不一样，对方可以用来去重
+	RunId  string          `json:"runId"`
+	Params json.RawMessage `json:"params,omitempty"`
+	// 分片和广播任务才有，对方按照分片处理自己那一部分数据
+	Shard *HttpJobShard `json:"shard,omitempty"`
+}
+
+type HttpJobShard struct {
+	// 从 0 开始
+	Index int `json:"index"`
+	Total int `json:"total"`
+}
+
+// HttpJobResponse 对方的响应。
+// 返回 2xx 表示执行成功；返回 202 表示是长任务，之后用 StatusUrl 轮询结果，直到 Status 不是 running
+type HttpJobResponse struct {
+	Status    string `json:"status"`
+	Message   string `json:"message"`
+	StatusUrl string `json:"statusUrl"`
+}
+
+// HttpExecutor 把任务通过 HTTP 发给别的服务执行，这样任务不需要编译进 webook 里面
+type HttpExecutor struct {
+	client *http.Client
+	secret []byte
+	// 默认超时时间
+	timeout time.Duration
+	// 长任务轮询的间隔
+	pollInterval time.Duration
+	// 只会请求这些主机，请求带着调度器的签名，不能让任务把它发到任意地址
+	allowedHosts map[string]struct{}
+	l            logger.Logger
+}
+
+// NewHttpExecutor allowedHosts 是允许请求的主机名，不包含端口，为空的时候所有 HTTP 任务都会失败
+func NewHttpExecutor(client *http.Client, secret []byte, timeout time.Duration,
+	pollInterval time.Duration, allowedHosts []string, l logger.Logger) *HttpExecutor {
+	hosts := make(map[string]struct{}, len(allowedHosts))
+	for _, host := range allowedHosts {
+		hosts[strings.ToLower(host)] = struct{}{}
+	}
+	return &HttpExecutor{
+		client:       client,
+		secret:       secret,
+		timeout:      timeout,
+		pollInterval: pollInterval,
+		allowedHosts: hosts,
+		l:            l,
+	}
+}
+
+func (h *HttpExecutor) Name() string {
+	return "http"
+}
+
+func (h *HttpExecutor) Exec(ctx context.Context, j domain.Job) error {
+	var cfg HttpJobCfg
+	err := json.Unmarshal([]byte(j.Cfg), &cfg)
+	if err != nil {
+		return Fatal(fmt.Errorf("HTTP 任务配置有误: %w", err))
+	}
+	if cfg.Endpoint == "" {
+		return Fatal(errors.New("HTTP 任务没有配置 endpoint"))
+	}
+	err = h.checkURL(cfg.Endpoint)
+	if err != nil {
+		return Fatal(err)
+	}
+	timeout := h.timeout
+	if cfg.Timeout != "" {
+		timeout, err = time.ParseDuration(cfg.Timeout)
+		if err != nil {
+			return Fatal(fmt.Errorf("HTTP 任务超时时间有误: %w", err))
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req := HttpJobRequest{
+		JobId:  j.Id,
+		Name:   j.Name,
+		RunId:  fmt.Sprintf("%d_%d", j.Id, j.Version),
+		Params: cfg.Params,
+	}
+	if j.Shard.Total > 0 {
+		req.RunId = fmt.Sprintf("%d_%d_%d", j.Id, j.Version, j.Shard.Index)
+		req.Shard = &HttpJobShard{Index: j.Shard.Index, Total: j.Shard.Total}
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	code, resp, err := h.do(ctx, http.MethodPost, cfg.Endpoint, body)
+	if err != nil {
+		// 网络错误和超时都可以重试
+		return err
+	}
+	switch {
+	case code == http.StatusAccepted:
+		if resp.StatusUrl == "" {
+			return Fatal(errors.New("长任务没有返回 statusUrl"))
+		}
+		// statusUrl 是对方返回的，同样要检查
+		err = h.checkURL(resp.StatusUrl)
+		if err != nil {
+			return Fatal(err)
+		}
+		return h.poll(ctx, resp.StatusUrl)
+	case code >= 200 && code < 300:
+		return h.result(resp)
+	case code >= 400 && code < 500:
+		// 签名不对、参数不对，重试也没用
+		return Fatal(fmt.Errorf("%w: HTTP 状态码 %d, %s", ErrHttpJobFailed, code, resp.Message))
+	default:
+		return fmt.Errorf("%w: HTTP 状态码 %d, %s", ErrHttpJobFailed, code, resp.Message)
+	}
+}
+
+// poll 轮询长任务的结果，直到任务结束或者超时
+func (h *HttpExecutor) poll(ctx context.Context, statusUrl string) error {
+	ticker := time.NewTicker(h.pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		code, resp, err := h.do(ctx, http.MethodGet, statusUrl, nil)
+		if err != nil {
+			// 网络抖动不影响任务本身，下一次再查
+			h.l.Warn("查询 HTTP 任务状态失败", logger.Error(err), logger.String("url", statusUrl))
+			continue
+		}
+		if code < 200 || code >= 300 {
+			h.l.Warn("查询 HTTP 任务状态失败", logger.Int64("code", int64(code)), logger.String("url", statusUrl))
+			continue
+		}
+		if resp.Status == HttpJobStatusRunning {
+			continue
+		}
+		return h.result(resp)
+	}
+}
+
+// checkURL 只允许 http 和 https，并且主机在白名单里面
+func (h *HttpExecutor) checkURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("HTTP 任务的地址有误: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: %s", ErrHttpJobHostNotAllowed, raw)
+	}
+	if _, ok := h.allowedHosts[strings.ToLower(u.Hostname())]; !ok {
+		return fmt.Errorf("%w: %s", ErrHttpJobHostNotAllowed, raw)
+	}
+	return nil
+}
+
+func (h *HttpExecutor) result(resp HttpJobResponse) error {
+	if resp.Status == HttpJobStatusFailed {
+		return fmt.Errorf("%w: %s", ErrHttpJobFailed, resp.Message)
+	}
+	return nil
+}
+
+func (h *HttpExecutor) do(ctx context.Context, method string, target string, body []byte) (int, HttpJobResponse, error) {
+	var resp HttpJobResponse
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return 0, resp, err
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HttpJobTimestampHeader, ts)
+	req.Header.Set(HttpJobSignatureHeader, SignHttpJob(h.secret, ts, body))
+	httpResp, err := h.client.Do(req)
+	if err != nil {
+		return 0, resp, err
+	}
+	defer httpResp.Body.Close()
+	data, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return 0, resp, err
+	}
+	// 允许对方不返回响应体
+	if len(bytes.TrimSpace(data)) > 0 {
+		err = json.Unmarshal(data, &resp)
+		if err != nil {
+			resp.Message = string(data)
+		}
+	}
+	return httpResp.StatusCode, resp, nil
+}
+
+// SignHttpJob 接收任务的服务用同样的方法计算签名，和 X-Webook-Signature 比较
+func SignHttpJob(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("\n"))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,209 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-basic/webook/internal/domain"
+	"go-basic/webook/pkg/logger"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testHttpJobSecret = []byte("test-secret")
+
+// newTestHttpJobServer 校验签名之后再交给 handler
+func newTestHttpJobServer(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		ts := r.Header.Get(HttpJobTimestampHeader)
+		if r.Header.Get(HttpJobSignatureHeader) != SignHttpJob(testHttpJobSecret, ts, body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Method == http.MethodPost {
+			var req HttpJobRequest
+			require.NoError(t, json.Unmarshal(body, &req))
+			assert.Equal(t, int64(1), req.JobId)
+			assert.Equal(t, "1_3", req.RunId)
+			assert.JSONEq(t, `{"day": "2024-01-01"}`, string(req.Params))
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHttpExecutor_Exec(t *testing.T) {
+	testCases := []struct {
+		name    string
+		handler func(polls *atomic.Int64) http.HandlerFunc
+		secret  []byte
+		timeout string
+
+		wantErr   error
+		wantFatal bool
+		wantPolls int64
+	}{
+		{
+			name: "执行成功",
+			handler: func(polls *atomic.Int64) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusOK)
+				}
+			},
+			secret: testHttpJobSecret,
+		},
+		{
+			name: "statusUrl 不在白名单里面",
+			handler: func(polls *atomic.Int64) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusAccepted)
+					w.Write([]byte(`{"statusUrl": "http://169.254.169.254/latest/meta-data"}`))
+				}
+			},
+			secret:    testHttpJobSecret,
+			wantErr:   ErrHttpJobHostNotAllowed,
+			wantFatal: true,
+		},
+		{
+			name: "对方返回失败",
+			handler: func(polls *atomic.Int64) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusOK)
+					w.Write([]byte(`{"status": "failed", "message": "数据库不可用"}`))
+				}
+			},
+			secret:  testHttpJobSecret,
+			wantErr: ErrHttpJobFailed,
+		},
+		{
+			name: "对方返回 500",
+			handler: func(polls *atomic.Int64) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+			},
+			secret:  testHttpJobSecret,
+			wantErr: ErrHttpJobFailed,
+		},
+		{
+			name: "签名不对",
+			handler: func(polls *atomic.Int64) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusOK)
+				}
+			},
+			secret:    []byte("wrong-secret"),
+			wantErr:   ErrHttpJobFailed,
+			wantFatal: true,
+		},
+		{
+			name: "超时",
+			handler: func(polls *atomic.Int64) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					time.Sleep(time.Millisecond * 200)
+					w.WriteHeader(http.StatusOK)
+				}
+			},
+			secret:  testHttpJobSecret,
+			timeout: "50ms",
+			wantErr: context.DeadlineExceeded,
+		},
+		{
+			name: "长任务轮询到成功",
+			handler: func(polls *atomic.Int64) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					if r.Method == http.MethodPost {
+						w.WriteHeader(http.StatusAccepted)
+						w.Write([]byte(`{"statusUrl": "http://` + r.Host + `/status"}`))
+						return
+					}
+					if polls.Add(1) < 3 {
+						w.Write([]byte(`{"status": "running"}`))
+						return
+					}
+					w.Write([]byte(`{"status": "success"}`))
+				}
+			},
+			secret:    testHttpJobSecret,
+			wantPolls: 3,
+		},
+		{
+			name: "长任务失败",
+			handler: func(polls *atomic.Int64) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					if r.Method == http.MethodPost {
+						w.WriteHeader(http.StatusAccepted)
+						w.Write([]byte(`{"statusUrl": "http://` + r.Host + `/status"}`))
+						return
+					}
+					polls.Add(1)
+					w.Write([]byte(`{"status": "failed", "message": "处理到一半出错"}`))
+				}
+			},
+			secret:    testHttpJobSecret,
+			wantErr:   ErrHttpJobFailed,
+			wantPolls: 1,
+		},
+		{
+			name: "长任务一直没有结束",
+			handler: func(polls *atomic.Int64) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					if r.Method == http.MethodPost {
+						w.WriteHeader(http.StatusAccepted)
+						w.Write([]byte(`{"statusUrl": "http://` + r.Host + `/status"}`))
+						return
+					}
+					w.Write([]byte(`{"status": "running"}`))
+				}
+			},
+			secret:  testHttpJobSecret,
+			timeout: "100ms",
+			wantErr: context.DeadlineExceeded,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var polls atomic.Int64
+			server := newTestHttpJobServer(t, tc.handler(&polls))
+			cfg, err := json.Marshal(HttpJobCfg{
+				Endpoint: server.URL + "/run",
+				Timeout:  tc.timeout,
+				Params:   json.RawMessage(`{"day": "2024-01-01"}`),
+			})
+			require.NoError(t, err)
+			exec := NewHttpExecutor(server.Client(), tc.secret, time.Second*5, time.Millisecond*10,
+				[]string{"127.0.0.1"}, logger.NewNopLogger())
+			err = exec.Exec(context.Background(), domain.Job{Id: 1, Name: "sitemap", Version: 3, Cfg: string(cfg)})
+			assert.True(t, errors.Is(err, tc.wantErr), "err: %v", err)
+			assert.Equal(t, tc.wantFatal, IsFatal(err))
+			assert.Equal(t, tc.wantPolls, polls.Load())
+		})
+	}
+}
+
+func TestHttpExecutor_InvalidCfg(t *testing.T) {
+	exec := NewHttpExecutor(http.DefaultClient, testHttpJobSecret, time.Second, time.Second,
+		[]string{"jobs.webook.com"}, logger.NewNopLogger())
+	// 配置有误重试也没用
+	err := exec.Exec(context.Background(), domain.Job{Cfg: "not json"})
+	assert.True(t, IsFatal(err))
+	err = exec.Exec(context.Background(), domain.Job{Cfg: `{"timeout": "1s"}`})
+	assert.True(t, IsFatal(err))
+	// 不在白名单里面的地址不会发出请求
+	for _, endpoint := range []string{"http://10.0.0.1/run", "file:///etc/passwd", "http://jobs.webook.com.evil.com/run"} {
+		err = exec.Exec(context.Background(), domain.Job{Cfg: `{"endpoint": "` + endpoint + `"}`})
+		assert.True(t, IsFatal(err))
+		assert.ErrorIs(t, err, ErrHttpJobHostNotAllowed)
+	}
+}
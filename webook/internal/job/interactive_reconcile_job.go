@@ -0,0 +1,96 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"go-basic/webook/internal/domain"
+	"go-basic/webook/internal/service"
+	"go-basic/webook/pkg/logger"
+	"strconv"
+	"time"
+
+	rlock "github.com/gotomicro/redis-lock"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type InteractiveReconcileJob struct {
+	svc     service.InteractiveReconcileService
+	client  *rlock.Client
+	key     string
+	timeout time.Duration
+	// 只对账这些业务，为空表示所有业务
+	bizs []string
+	// 试运行，只报告差异不修正
+	dryRun bool
+	l      logger.Logger
+	vector *prometheus.CounterVec
+}
+
+func NewInteractiveReconcileJob(svc service.InteractiveReconcileService, client *rlock.Client,
+	timeout time.Duration, bizs []string, dryRun bool, l logger.Logger) *InteractiveReconcileJob {
+	vector := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "webook",
+		Subsystem: "job",
+		Name:      "interactive_reconcile_rows",
+		Help:      "统计计数对账发现和修正的记录数",
+	}, []string{"biz", "fixed"})
+	prometheus.MustRegister(vector)
+	return &InteractiveReconcileJob{
+		svc:     svc,
+		client:  client,
+		key:     "rlock:cron_job:interactive_reconcile",
+		timeout: timeout,
+		bizs:    bizs,
+		dryRun:  dryRun,
+		l:       l,
+		vector:  vector,
+	}
+}
+
+func (r *InteractiveReconcileJob) Name() string {
+	return "InteractiveReconcile"
+}
+
+func (r *InteractiveReconcileJob) Run() error {
+	// 对账是全表扫描，只需要一个节点执行，拿不到锁说明别的节点在执行
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	lock, err := r.client.Lock(ctx, r.key, r.timeout, &rlock.FixIntervalRetry{
+		Interval: time.Millisecond * 100,
+		Max:      0,
+	}, time.Second)
+	cancel()
+	if errors.Is(err, rlock.ErrFailedToPreemptLock) {
+		return nil
+	}
+	// Redis 出问题了不能当成别的节点在执行，不然对账一直不会执行
+	if err != nil {
+		r.l.Error("获取对账任务的锁失败", logger.Error(err))
+		return err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		er := lock.Unlock(ctx)
+		if er != nil {
+			r.l.Error("释放对账任务的锁失败", logger.Error(er))
+		}
+	}()
+
+	ctx, cancel = context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+	var total, fixed int64
+	err = r.svc.Reconcile(ctx, r.bizs, r.dryRun, func(diffs []domain.InteractiveCntDiff) {
+		for _, diff := range diffs {
+			if diff.Fixed {
+				fixed++
+			}
+			r.vector.WithLabelValues(diff.Biz, strconv.FormatBool(diff.Fixed)).Inc()
+		}
+		total += int64(len(diffs))
+	})
+	r.l.Info("计数对账完成",
+		logger.Int64("diff", total),
+		logger.Int64("fixed", fixed),
+		logger.Field{Key: "dry_run", Value: r.dryRun})
+	return err
+}
@@ -0,0 +1,38 @@
+package job
+
+import (
+	"context"
+	"go-basic/webook/internal/service"
+	"go-basic/webook/pkg/logger"
+	"time"
+)
+
+// JobRunCleanupJob 清理过期的任务执行记录。删除是幂等的，多个节点同时执行也没有问题
+type JobRunCleanupJob struct {
+	svc service.JobRunService
+	// 执行记录保留多久
+	retention time.Duration
+	timeout   time.Duration
+	l         logger.Logger
+}
+
+func NewJobRunCleanupJob(svc service.JobRunService, retention time.Duration, l logger.Logger) *JobRunCleanupJob {
+	return &JobRunCleanupJob{
+		svc:       svc,
+		retention: retention,
+		timeout:   time.Minute * 10,
+		l:         l,
+	}
+}
+
+func (c *JobRunCleanupJob) Name() string {
+	return "JobRunCleanup"
+}
+
+func (c *JobRunCleanupJob) Run() error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	cnt, err := c.svc.Cleanup(ctx, time.Now().Add(-c.retention))
+	c.l.Info("清理任务执行记录", logger.Int64("deleted", cnt))
+	return err
+}
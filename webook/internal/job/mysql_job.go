@@ -2,12 +2,16 @@ package job
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"go-basic/webook/internal/domain"
 	"go-basic/webook/internal/service"
 	"go-basic/webook/pkg/logger"
+	"sort"
+	"sync"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"golang.org/x/sync/semaphore"
 )
 
@@ -16,8 +20,6 @@ type Executor interface {
 	Name() string
 	// 具体执行任务
 	Exec(ctx context.Context, j domain.Job) error
-	// 注册任务执行函数
-	RegisterFunc(name string, fn func(ctx context.Context, j domain.Job) error)
 }
 
 type LocalFuncExecter struct {
@@ -41,25 +43,92 @@ func (l *LocalFuncExecter) RegisterFunc(name string, fn func(ctx context.Context
 func (l *LocalFuncExecter) Exec(ctx context.Context, j domain.Job) error {
 	fn, ok := l.funcs[j.Name]
 	if !ok {
-		return fmt.Errorf("未找到任务执行函数: %s", j.Name)
+		return Fatal(fmt.Errorf("未找到任务执行函数: %s", j.Name))
 	}
 	return fn(ctx, j)
 }
 
+// NodeRegistry 广播任务需要知道哪些实例还活着，实例 ID 和调度器的 instance 保持一致
+type NodeRegistry interface {
+	LiveNodes(ctx context.Context) ([]string, error)
+}
+
 type Scheduler struct {
-	execs   map[string]Executor
-	svc     service.JobService
-	l       logger.Logger
-	limiter *semaphore.Weighted
+	execs    map[string]Executor
+	svc      service.JobService
+	runSvc   service.JobRunService
+	shardSvc service.JobShardService
+	nodes    NodeRegistry
+	// 任务死掉之后告警
+	alerter Alerter
+	// 执行记录里面的实例 ID
+	instance string
+	l        logger.Logger
+	limiter  *semaphore.Weighted
+	// 没有任务可以抢或者数据库出错的时候，从 idleInterval 开始每次翻倍，最多等 maxIdleInterval
+	idleInterval    time.Duration
+	maxIdleInterval time.Duration
+	// 退出的时候最多等这么久让执行中的任务自己结束，超时之后取消它们
+	drainTimeout time.Duration
+	// 分片和广播任务隔多久检查一次分片的进度
+	shardPollInterval time.Duration
+	// 统计收回了多少租约过期的任务，以及自己丢了多少租约
+	leaseCounter *prometheus.CounterVec
+
+	// 每个执行器在这个实例上最多同时执行多少个任务，没有配置的只受总数限制
+	execLimits map[string]int
+	mu         sync.Mutex
+	inflight   map[string]int
+	// 有任务执行完的时候通知调度循环，执行器满了的时候不用干等
+	freed chan struct{}
+	// 从计划的执行时间到被抢占的延迟，以及每个执行器正在执行的任务数量
+	queueDelay   *prometheus.HistogramVec
+	runningGauge *prometheus.GaugeVec
 }
 
-func NewScheduler(svc service.JobService, l logger.Logger) *Scheduler {
+func NewScheduler(svc service.JobService, runSvc service.JobRunService, shardSvc service.JobShardService,
+	nodes NodeRegistry, alerter Alerter, instance string, drainTimeout time.Duration, l logger.Logger) *Scheduler {
+	leaseCounter := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "webook",
+		Subsystem: "job",
+		Name:      "lease",
+		Help:      "统计租约过期被收回的任务",
+	}, []string{"event"})
+	queueDelay := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "webook",
+		Subsystem: "job",
+		Name:      "queue_delay_seconds",
+		Help:      "任务从计划的执行时间到被抢占的延迟",
+		Buckets:   prometheus.ExponentialBuckets(0.1, 2, 14),
+	}, []string{"executor"})
+	runningGauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "webook",
+		Subsystem: "job",
+		Name:      "running",
+		Help:      "这个实例上每个执行器正在执行的任务数量",
+	}, []string{"executor"})
+	prometheus.MustRegister(leaseCounter, queueDelay, runningGauge)
 	return &Scheduler{
-		execs: make(map[string]Executor),
-		svc:   svc,
+		execs:    make(map[string]Executor),
+		svc:      svc,
+		runSvc:   runSvc,
+		shardSvc: shardSvc,
+		nodes:    nodes,
+		alerter:  alerter,
+		instance: instance,
 		// 控制任务数量200个
-		limiter: semaphore.NewWeighted(200),
-		l:       l,
+		limiter:           semaphore.NewWeighted(200),
+		idleInterval:      time.Second,
+		maxIdleInterval:   time.Second * 30,
+		drainTimeout:      drainTimeout,
+		shardPollInterval: time.Second * 5,
+		leaseCounter:      leaseCounter,
+		execLimits:        make(map[string]int),
+		inflight:          make(map[string]int),
+		freed:             make(chan struct{}, 1),
+		queueDelay:        queueDelay,
+		runningGauge:      runningGauge,
+		l:                 l,
 	}
 }
 
@@ -67,8 +136,21 @@ func (s *Scheduler) RegisterExecutor(exec Executor) {
 	s.execs[exec.Name()] = exec
 }
 
-// 调度器
+// LimitExecutor 限制这个实例上同一个执行器同时执行的任务数量，避免一种长任务占满所有的并发
+func (s *Scheduler) LimitExecutor(name string, limit int) {
+	s.execLimits[name] = limit
+}
+
+// Scheduler 调度循环，ctx 被取消之后不再抢新的任务，等执行中的任务结束并且释放之后才返回
 func (s *Scheduler) Scheduler(ctx context.Context) error {
+	// 执行中的任务不直接用 ctx，退出的时候先给它们一点时间自己结束
+	runCtx, stopRuns := context.WithCancelCause(context.Background())
+	defer stopRuns(nil)
+	var wg sync.WaitGroup
+	defer s.drain(&wg, stopRuns)
+
+	// 连续多少次没有抢到任务
+	idle := 0
 	for {
 		if ctx.Err() != nil {
 			// main 函数退出调度循环
@@ -77,45 +159,476 @@ func (s *Scheduler) Scheduler(ctx context.Context) error {
 		// 限制并发数，acquire(ctx,1) 的意思是获取一个信号量，如果没有信号量，会阻塞
 		err := s.limiter.Acquire(ctx, 1)
 		if err != nil {
-			return err
+			return nil
 		}
-		// 抢占任务
-		dbCtx, cancel := context.WithTimeout(context.Background(), time.Second)
-		j, err := s.svc.Preempt(dbCtx)
-		cancel()
+		executors := s.available()
+		if len(executors) == 0 {
+			s.limiter.Release(1)
+			// 所有执行器都满了，等有任务执行完再抢
+			if !s.waitFreed(ctx) {
+				return nil
+			}
+			continue
+		}
+		j, err := s.preempt(executors)
 		if err != nil {
-			// 继续下一轮
-			s.l.Error("抢占任务失败", logger.Error(err))
+			s.limiter.Release(1)
+			if err != service.ErrJobNotFound {
+				s.l.Error("抢占任务失败", logger.Error(err))
+			}
+			// 没有可以执行的任务，或者数据库出错了，等一会再抢
+			idle++
+			if !s.backoff(ctx, idle) {
+				return nil
+			}
+			continue
 		}
 
+		// 整个任务抢占的时候已经按照执行器过滤过了，只有分片会走到下面两个分支
 		exec, ok := s.execs[j.Executor]
 		if !ok {
+			s.limiter.Release(1)
 			s.l.Error("未找到执行器", logger.String("executor", j.Executor))
+			// 释放任务，让有这个执行器的节点去执行
+			s.release(j)
+			// 释放之后马上又会抢到同一个任务，也要等一会
+			idle++
+			if !s.backoff(ctx, idle) {
+				return nil
+			}
+			continue
+		}
+		if !s.acquireExecutor(j.Executor) {
+			s.limiter.Release(1)
+			s.release(j)
+			idle++
+			if !s.backoff(ctx, idle) {
+				return nil
+			}
 			continue
 		}
+		idle = 0
+
+		if j.Reclaimed {
+			s.leaseCounter.WithLabelValues("reclaimed").Inc()
+		} else if j.Shard.Total == 0 {
+			s.queueDelay.WithLabelValues(j.Executor).Observe(time.Since(j.NextRunTime).Seconds())
+		}
 
 		// 接下来就是执行任务，异步执行任务，不阻塞主流程
+		wg.Add(1)
 		go func() {
-			// 执行完毕后释放任务
-			s.limiter.Release(1)
+			defer wg.Done()
+			// 执行完毕后释放信号量
+			defer s.limiter.Release(1)
+			defer s.releaseExecutor(j.Executor)
 			defer func() {
 				er := j.CancelFunc()
+				if errors.Is(er, service.ErrJobLeaseLost) {
+					// 租约丢了，任务已经归别人了；分片执行完之后也会走到这里
+					return
+				}
 				if er != nil {
 					s.l.Error("释放任务失败", logger.Error(er), logger.Int64("job_id", j.Id))
 				}
 			}()
-			// 执行任务，如果失败，记录日志
-			er := exec.Exec(ctx, j)
-			if er != nil {
-				s.l.Error("执行任务失败", logger.Error(er))
-			}
-			// 考虑下一次调度
-			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
-			defer cancel()
-			er = s.svc.ResetNextTime(ctx, j)
-			if er != nil {
-				s.l.Error("设置下次执行时间失败", logger.Error(er), logger.Int64("job_id", j.Id))
+			if j.Shard.Total > 0 {
+				s.runShard(runCtx, exec, j)
+				return
 			}
+			s.runJob(runCtx, exec, j)
 		}()
 	}
 }
+
+// release 抢到了但是这个实例执行不了，释放给别的实例
+func (s *Scheduler) release(j domain.Job) {
+	err := j.CancelFunc()
+	if err != nil {
+		s.l.Error("释放任务失败", logger.Error(err), logger.Int64("job_id", j.Id))
+	}
+}
+
+// available 还没有满的执行器，抢占的时候只抢这些执行器的任务，这个实例没有的执行器也不会抢
+func (s *Scheduler) available() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := make([]string, 0, len(s.execs))
+	for name := range s.execs {
+		limit := s.execLimits[name]
+		if limit > 0 && s.inflight[name] >= limit {
+			continue
+		}
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// acquireExecutor 执行器已经满了就返回 false
+func (s *Scheduler) acquireExecutor(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	limit := s.execLimits[name]
+	if limit > 0 && s.inflight[name] >= limit {
+		return false
+	}
+	s.inflight[name]++
+	s.runningGauge.WithLabelValues(name).Set(float64(s.inflight[name]))
+	return true
+}
+
+func (s *Scheduler) releaseExecutor(name string) {
+	s.mu.Lock()
+	s.inflight[name]--
+	s.runningGauge.WithLabelValues(name).Set(float64(s.inflight[name]))
+	s.mu.Unlock()
+	select {
+	case s.freed <- struct{}{}:
+	default:
+	}
+}
+
+// waitFreed 等到有任务执行完，返回 false 说明 ctx 已经取消了
+func (s *Scheduler) waitFreed(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-s.freed:
+		return true
+	case <-time.After(s.maxIdleInterval):
+		// 兜底，配置变了或者漏了通知也不会一直等下去
+		return true
+	}
+}
+
+// backoff 第 n 次没有抢到任务之后等待，返回 false 说明 ctx 已经取消了
+func (s *Scheduler) backoff(ctx context.Context, n int) bool {
+	interval := s.idleInterval
+	for i := 1; i < n && interval < s.maxIdleInterval; i++ {
+		interval *= 2
+	}
+	interval = min(interval, s.maxIdleInterval)
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(interval):
+		return true
+	}
+}
+
+// drain 等执行中的任务结束，超过 drainTimeout 就取消它们。
+// 任务结束之后会释放租约，没有执行完的任务别的实例马上就能抢到
+func (s *Scheduler) drain(wg *sync.WaitGroup, stopRuns context.CancelCauseFunc) {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return
+	case <-time.After(s.drainTimeout):
+	}
+	s.l.Warn("等待任务执行完毕超时，取消剩下的任务")
+	stopRuns(ErrSchedulerStopped)
+	select {
+	case <-done:
+	case <-time.After(s.drainTimeout):
+		// 不响应取消的任务只能等租约过期
+		s.l.Error("有任务没有响应取消，租约过期之后由别的实例重新执行")
+	}
+}
+
+// preempt 先抢整个任务，没有的话再抢分片
+func (s *Scheduler) preempt(executors []string) (domain.Job, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	j, err := s.svc.Preempt(ctx, executors)
+	if err != service.ErrJobNotFound {
+		return j, err
+	}
+	return s.shardSvc.Preempt(ctx, s.instance)
+}
+
+// execCtx 租约丢了、被取消或者超时之后取消执行，context.Cause 可以拿到具体的原因
+func (s *Scheduler) execCtx(ctx context.Context, j domain.Job) (context.Context, context.CancelFunc) {
+	execCtx, cancelCause := context.WithCancelCause(ctx)
+	go func() {
+		select {
+		case <-j.LeaseLost:
+			cancelCause(service.ErrJobLeaseLost)
+		case <-j.Cancelled:
+			cancelCause(ErrJobCancelled)
+		case <-execCtx.Done():
+		}
+	}()
+	if j.Timeout <= 0 {
+		return execCtx, func() { cancelCause(nil) }
+	}
+	timeoutCtx, cancel := context.WithTimeoutCause(execCtx, j.Timeout, ErrJobTimeout)
+	return timeoutCtx, func() {
+		cancel()
+		cancelCause(nil)
+	}
+}
+
+// interrupted 执行失败的时候带上被打断的原因，方便区分超时、取消和退出
+func interrupted(ctx context.Context, err error) error {
+	cause := context.Cause(ctx)
+	if err == nil || cause == nil || errors.Is(err, cause) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", cause, err)
+}
+
+func (s *Scheduler) runJob(ctx context.Context, exec Executor, j domain.Job) {
+	if j.Misfire == domain.MisfireSkip && j.Misfired(time.Now()) {
+		s.l.Warn("任务错过了执行时间，跳过这一次", logger.Int64("job_id", j.Id),
+			logger.String("next_time", j.NextRunTime.Format(time.DateTime)))
+		s.scheduleNext(j, nil)
+		return
+	}
+	execCtx, cancel := s.execCtx(ctx, j)
+	defer cancel()
+	if j.Reclaimed {
+		s.abandonRuns(j)
+	}
+	run := s.startRun(j, j.Attempt)
+	// 执行任务，如果失败，记录日志
+	er := interrupted(execCtx, s.execute(execCtx, exec, j))
+	if er != nil {
+		s.l.Error("执行任务失败", logger.Error(er), logger.Int64("job_id", j.Id),
+			logger.Int64("attempt", int64(j.Attempt)))
+	}
+	select {
+	case <-j.LeaseLost:
+		// 别的实例已经在执行了，不能再修改任务的调度信息
+		s.leaseCounter.WithLabelValues("lost").Inc()
+		if er == nil {
+			er = service.ErrJobLeaseLost
+		} else if !errors.Is(er, service.ErrJobLeaseLost) {
+			er = fmt.Errorf("%w: %w", service.ErrJobLeaseLost, er)
+		}
+		s.finishRun(run, er)
+		return
+	default:
+	}
+	s.finishRun(run, er)
+	switch {
+	case errors.Is(er, ErrSchedulerStopped):
+		// 调度信息保持不变，释放之后别的实例马上重新执行
+	case errors.Is(er, ErrJobCancelled):
+		// 取消不算失败，按照 cron 调度下一次
+		s.scheduleNext(j, nil)
+	default:
+		s.scheduleNext(j, er)
+	}
+}
+
+// execute 普通任务直接交给执行器，分片和广播任务由抢到任务的实例负责拆分，然后等待所有分片完成
+func (s *Scheduler) execute(ctx context.Context, exec Executor, j domain.Job) error {
+	if j.Mode == domain.JobModeNormal {
+		return exec.Exec(ctx, j)
+	}
+	return s.coordinate(ctx, j)
+}
+
+func (s *Scheduler) coordinate(ctx context.Context, j domain.Job) error {
+	var instances []string
+	if j.Mode == domain.JobModeBroadcast {
+		var err error
+		instances, err = s.nodes.LiveNodes(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	err := s.shardSvc.Dispatch(ctx, j, instances)
+	if err != nil {
+		return err
+	}
+	ticker := time.NewTicker(s.shardPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			s.stopShards(j, context.Cause(ctx))
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		shards, err := s.shardSvc.Progress(ctx, j.Id, j.Version)
+		if err != nil {
+			s.l.Warn("查询分片进度失败", logger.Error(err), logger.Int64("job_id", j.Id))
+			continue
+		}
+		if j.Mode == domain.JobModeBroadcast {
+			s.cancelOffline(ctx, j, shards)
+		}
+		var finished, failed int
+		for _, shard := range shards {
+			if shard.Status.Finished() {
+				finished++
+			}
+			if shard.Status == domain.JobShardStatusFailed {
+				failed++
+			}
+		}
+		if finished < len(shards) {
+			continue
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d/%d 个分片执行失败", failed, len(shards))
+		}
+		return nil
+	}
+}
+
+// cancelOffline 广播的目标实例下线了，它的分片永远不会执行，直接取消。
+// 这里修改的是 shards 本身，取消之后算作已经完成
+func (s *Scheduler) cancelOffline(ctx context.Context, j domain.Job, shards []domain.JobShard) {
+	nodes, err := s.nodes.LiveNodes(ctx)
+	if err != nil {
+		s.l.Warn("查询存活实例失败", logger.Error(err), logger.Int64("job_id", j.Id))
+		return
+	}
+	live := make(map[string]struct{}, len(nodes))
+	for _, node := range nodes {
+		live[node] = struct{}{}
+	}
+	var ids []int64
+	for i, shard := range shards {
+		if shard.Status.Finished() {
+			continue
+		}
+		if _, ok := live[shard.Instance]; ok {
+			continue
+		}
+		ids = append(ids, shard.Id)
+		shards[i].Status = domain.JobShardStatusCancelled
+	}
+	if len(ids) == 0 {
+		return
+	}
+	s.l.Warn("广播的目标实例已经下线", logger.Int64("job_id", j.Id), logger.Int64("count", int64(len(ids))))
+	err = s.shardSvc.Cancel(ctx, ids)
+	if err != nil {
+		s.l.Error("取消分片失败", logger.Error(err), logger.Int64("job_id", j.Id))
+	}
+}
+
+// stopShards 取消或者超时之后，还没有执行完的分片也不用再执行了。
+// 调度器退出或者丢了租约的时候不处理，接手的实例重新分派的时候会取消它们
+func (s *Scheduler) stopShards(j domain.Job, cause error) {
+	if !errors.Is(cause, ErrJobCancelled) && !errors.Is(cause, ErrJobTimeout) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	shards, err := s.shardSvc.Progress(ctx, j.Id, j.Version)
+	if err != nil {
+		s.l.Error("查询分片进度失败", logger.Error(err), logger.Int64("job_id", j.Id))
+		return
+	}
+	var ids []int64
+	for _, shard := range shards {
+		if !shard.Status.Finished() {
+			ids = append(ids, shard.Id)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+	err = s.shardSvc.Cancel(ctx, ids)
+	if err != nil {
+		s.l.Error("取消分片失败", logger.Error(err), logger.Int64("job_id", j.Id))
+	}
+}
+
+func (s *Scheduler) runShard(ctx context.Context, exec Executor, j domain.Job) {
+	execCtx, cancel := s.execCtx(ctx, j)
+	defer cancel()
+	er := interrupted(execCtx, exec.Exec(execCtx, j))
+	select {
+	case <-j.LeaseLost:
+		s.leaseCounter.WithLabelValues("lost").Inc()
+		s.l.Warn("分片的租约已经丢了", logger.Int64("job_id", j.Id), logger.Int64("shard", int64(j.Shard.Index)))
+		return
+	default:
+	}
+	if errors.Is(er, ErrSchedulerStopped) {
+		// 释放之后别的实例重新执行这个分片
+		return
+	}
+	if er != nil {
+		s.l.Error("执行分片失败", logger.Error(er), logger.Int64("job_id", j.Id),
+			logger.Int64("shard", int64(j.Shard.Index)))
+	}
+	dbCtx, dbCancel := context.WithTimeout(context.Background(), time.Second)
+	defer dbCancel()
+	er = s.shardSvc.Finish(dbCtx, j, er)
+	if er != nil {
+		s.l.Error("记录分片执行结果失败", logger.Error(er), logger.Int64("job_id", j.Id),
+			logger.Int64("shard", int64(j.Shard.Index)))
+	}
+}
+
+// scheduleNext 执行成功按照 cron 调度下一次，失败了按照重试策略处理
+func (s *Scheduler) scheduleNext(j domain.Job, execErr error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if execErr == nil {
+		err := s.svc.ResetNextTime(ctx, j)
+		if err != nil {
+			s.l.Error("设置下次执行时间失败", logger.Error(err), logger.Int64("job_id", j.Id))
+		}
+		return
+	}
+	dead, err := s.svc.Fail(ctx, j, IsFatal(execErr))
+	if err != nil {
+		s.l.Error("处理任务失败", logger.Error(err), logger.Int64("job_id", j.Id))
+		return
+	}
+	if dead {
+		err = s.alerter.Alert(ctx, j, execErr)
+		if err != nil {
+			s.l.Error("任务告警失败", logger.Error(err), logger.Int64("job_id", j.Id))
+		}
+	}
+}
+
+// abandonRuns 上一个实例崩溃了，它的执行记录永远不会结束，在开始新的一次之前关掉
+func (s *Scheduler) abandonRuns(j domain.Job) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	cnt, err := s.runSvc.Abandon(ctx, j.Id)
+	if err != nil {
+		s.l.Error("关闭被放弃的执行记录失败", logger.Error(err), logger.Int64("job_id", j.Id))
+		return
+	}
+	if cnt > 0 {
+		s.l.Warn("关闭了被放弃的执行记录", logger.Int64("job_id", j.Id), logger.Int64("cnt", cnt))
+	}
+}
+
+// startRun 执行记录写不进去不影响任务执行
+func (s *Scheduler) startRun(j domain.Job, attempt int) domain.JobRun {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	run, err := s.runSvc.Start(ctx, j, s.instance, attempt)
+	if err != nil {
+		s.l.Error("记录任务执行失败", logger.Error(err), logger.Int64("job_id", j.Id))
+	}
+	return run
+}
+
+func (s *Scheduler) finishRun(run domain.JobRun, execErr error) {
+	if run.Id == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := s.runSvc.Finish(ctx, run, execErr)
+	if err != nil {
+		s.l.Error("记录任务执行结果失败", logger.Error(err), logger.Int64("run_id", run.Id))
+	}
+}
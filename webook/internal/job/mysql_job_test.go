@@ -0,0 +1,343 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"go-basic/webook/internal/domain"
+	"go-basic/webook/internal/service"
+	svcmocks "go-basic/webook/internal/service/mocks"
+	"go-basic/webook/pkg/logger"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sync/semaphore"
+)
+
+type testNodeRegistry struct {
+	// 每次调用返回下一个，最后一个一直重复
+	nodes [][]string
+}
+
+func (r *testNodeRegistry) LiveNodes(ctx context.Context) ([]string, error) {
+	res := r.nodes[0]
+	if len(r.nodes) > 1 {
+		r.nodes = r.nodes[1:]
+	}
+	return res, nil
+}
+
+func TestScheduler_Coordinate(t *testing.T) {
+	testCases := []struct {
+		name  string
+		mock  func(ctrl *gomock.Controller) service.JobShardService
+		nodes [][]string
+		job   domain.Job
+
+		wantErr string
+	}{
+		{
+			name: "所有分片执行成功",
+			mock: func(ctrl *gomock.Controller) service.JobShardService {
+				svc := svcmocks.NewMockJobShardService(ctrl)
+				svc.EXPECT().Dispatch(gomock.Any(), gomock.Any(), []string(nil)).Return(nil)
+				gomock.InOrder(
+					svc.EXPECT().Progress(gomock.Any(), int64(1), 3).Return([]domain.JobShard{
+						{Id: 1, Status: domain.JobShardStatusSuccess},
+						{Id: 2, Status: domain.JobShardStatusRunning},
+					}, nil),
+					svc.EXPECT().Progress(gomock.Any(), int64(1), 3).Return([]domain.JobShard{
+						{Id: 1, Status: domain.JobShardStatusSuccess},
+						{Id: 2, Status: domain.JobShardStatusSuccess},
+					}, nil),
+				)
+				return svc
+			},
+			job: domain.Job{Id: 1, Version: 3, Mode: domain.JobModeSharded, ShardCount: 2},
+		},
+		{
+			name: "有分片执行失败",
+			mock: func(ctrl *gomock.Controller) service.JobShardService {
+				svc := svcmocks.NewMockJobShardService(ctrl)
+				svc.EXPECT().Dispatch(gomock.Any(), gomock.Any(), []string(nil)).Return(nil)
+				svc.EXPECT().Progress(gomock.Any(), int64(1), 3).Return([]domain.JobShard{
+					{Id: 1, Status: domain.JobShardStatusSuccess},
+					{Id: 2, Status: domain.JobShardStatusFailed},
+				}, nil)
+				return svc
+			},
+			job:     domain.Job{Id: 1, Version: 3, Mode: domain.JobModeSharded, ShardCount: 2},
+			wantErr: "1/2 个分片执行失败",
+		},
+		{
+			name: "广播的目标实例下线",
+			mock: func(ctrl *gomock.Controller) service.JobShardService {
+				svc := svcmocks.NewMockJobShardService(ctrl)
+				svc.EXPECT().Dispatch(gomock.Any(), gomock.Any(), []string{"a", "b"}).Return(nil)
+				svc.EXPECT().Progress(gomock.Any(), int64(1), 3).Return([]domain.JobShard{
+					{Id: 1, Instance: "a", Status: domain.JobShardStatusSuccess},
+					{Id: 2, Instance: "b", Status: domain.JobShardStatusWaiting},
+				}, nil)
+				svc.EXPECT().Cancel(gomock.Any(), []int64{2}).Return(nil)
+				return svc
+			},
+			nodes: [][]string{{"a", "b"}, {"a"}},
+			job:   domain.Job{Id: 1, Version: 3, Mode: domain.JobModeBroadcast},
+		},
+		{
+			name: "分派失败",
+			mock: func(ctrl *gomock.Controller) service.JobShardService {
+				svc := svcmocks.NewMockJobShardService(ctrl)
+				svc.EXPECT().Dispatch(gomock.Any(), gomock.Any(), []string{}).Return(service.ErrNoLiveInstance)
+				return svc
+			},
+			nodes:   [][]string{{}},
+			job:     domain.Job{Id: 1, Version: 3, Mode: domain.JobModeBroadcast},
+			wantErr: service.ErrNoLiveInstance.Error(),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			s := &Scheduler{
+				shardSvc:          tc.mock(ctrl),
+				nodes:             &testNodeRegistry{nodes: tc.nodes},
+				shardPollInterval: time.Millisecond * 10,
+				l:                 logger.NewNopLogger(),
+			}
+			err := s.coordinate(context.Background(), tc.job)
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestScheduler_CoordinateCanceled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	svc := svcmocks.NewMockJobShardService(ctrl)
+	svc.EXPECT().Dispatch(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+	svc.EXPECT().Progress(gomock.Any(), int64(1), 3).Return([]domain.JobShard{
+		{Id: 1, Status: domain.JobShardStatusRunning},
+	}, nil).AnyTimes()
+	s := &Scheduler{
+		shardSvc:          svc,
+		shardPollInterval: time.Millisecond * 10,
+		l:                 logger.NewNopLogger(),
+	}
+	// 租约丢了或者退出的时候，不再等待分片
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	err := s.coordinate(ctx, domain.Job{Id: 1, Version: 3, Mode: domain.JobModeSharded, ShardCount: 1})
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+// blockingExecutor 一直执行到被取消
+type blockingExecutor struct{}
+
+func (e blockingExecutor) Name() string {
+	return "blocking"
+}
+
+func (e blockingExecutor) Exec(ctx context.Context, j domain.Job) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestScheduler_RunJob(t *testing.T) {
+	closed := make(chan struct{})
+	close(closed)
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) service.JobService
+		ctx  func() context.Context
+		job  domain.Job
+
+		wantErr error
+	}{
+		{
+			name: "执行超时，按照失败处理",
+			mock: func(ctrl *gomock.Controller) service.JobService {
+				svc := svcmocks.NewMockJobService(ctrl)
+				svc.EXPECT().Fail(gomock.Any(), gomock.Any(), false).Return(false, nil)
+				return svc
+			},
+			ctx:     context.Background,
+			job:     domain.Job{Id: 1, Timeout: time.Millisecond * 20},
+			wantErr: ErrJobTimeout,
+		},
+		{
+			name: "被取消，按照 cron 调度下一次",
+			mock: func(ctrl *gomock.Controller) service.JobService {
+				svc := svcmocks.NewMockJobService(ctrl)
+				svc.EXPECT().ResetNextTime(gomock.Any(), gomock.Any()).Return(nil)
+				return svc
+			},
+			ctx:     context.Background,
+			job:     domain.Job{Id: 1, Cancelled: closed},
+			wantErr: ErrJobCancelled,
+		},
+		{
+			name: "调度器退出，不修改调度信息",
+			mock: func(ctrl *gomock.Controller) service.JobService {
+				return svcmocks.NewMockJobService(ctrl)
+			},
+			ctx: func() context.Context {
+				ctx, cancel := context.WithCancelCause(context.Background())
+				cancel(ErrSchedulerStopped)
+				return ctx
+			},
+			job:     domain.Job{Id: 1},
+			wantErr: ErrSchedulerStopped,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			runSvc := svcmocks.NewMockJobRunService(ctrl)
+			runSvc.EXPECT().Start(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+				Return(domain.JobRun{Id: 1}, nil)
+			runSvc.EXPECT().Finish(gomock.Any(), gomock.Any(), gomock.Any()).
+				DoAndReturn(func(ctx context.Context, run domain.JobRun, err error) error {
+					assert.ErrorIs(t, err, tc.wantErr)
+					return nil
+				})
+			s := &Scheduler{
+				svc:    tc.mock(ctrl),
+				runSvc: runSvc,
+				l:      logger.NewNopLogger(),
+			}
+			s.runJob(tc.ctx(), blockingExecutor{}, tc.job)
+		})
+	}
+}
+
+func TestScheduler_RunJobReclaimed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	svc := svcmocks.NewMockJobService(ctrl)
+	svc.EXPECT().Fail(gomock.Any(), gomock.Any(), false).Return(false, nil)
+	runSvc := svcmocks.NewMockJobRunService(ctrl)
+	// 收回来的任务先关掉上一个实例留下的执行记录，再开始新的一次
+	gomock.InOrder(
+		runSvc.EXPECT().Abandon(gomock.Any(), int64(1)).Return(int64(1), nil),
+		runSvc.EXPECT().Start(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(domain.JobRun{Id: 2}, nil),
+		runSvc.EXPECT().Finish(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil),
+	)
+	s := &Scheduler{
+		svc:    svc,
+		runSvc: runSvc,
+		l:      logger.NewNopLogger(),
+	}
+	s.runJob(context.Background(), blockingExecutor{}, domain.Job{
+		Id: 1, Reclaimed: true, Timeout: time.Millisecond * 20,
+	})
+}
+
+func TestScheduler_RunJobMisfire(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	svc := svcmocks.NewMockJobService(ctrl)
+	// 跳过的时候不执行，也不记录执行记录，直接调度下一次
+	svc.EXPECT().ResetNextTime(gomock.Any(), gomock.Any()).Return(nil)
+	s := &Scheduler{
+		svc:    svc,
+		runSvc: svcmocks.NewMockJobRunService(ctrl),
+		l:      logger.NewNopLogger(),
+	}
+	s.runJob(context.Background(), blockingExecutor{}, domain.Job{
+		Id: 1, Attempt: 1, Misfire: domain.MisfireSkip, NextRunTime: time.Now().Add(-time.Hour),
+	})
+}
+
+func TestScheduler_Drain(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	svc := svcmocks.NewMockJobService(ctrl)
+	shardSvc := svcmocks.NewMockJobShardService(ctrl)
+	runSvc := svcmocks.NewMockJobRunService(ctrl)
+	released := make(chan struct{})
+	gomock.InOrder(
+		svc.EXPECT().Preempt(gomock.Any(), []string{"blocking"}).Return(domain.Job{
+			Id: 1, Executor: "blocking", CancelFunc: func() error {
+				close(released)
+				return nil
+			},
+		}, nil),
+		svc.EXPECT().Preempt(gomock.Any(), []string{"blocking"}).Return(domain.Job{}, service.ErrJobNotFound).AnyTimes(),
+	)
+	shardSvc.EXPECT().Preempt(gomock.Any(), gomock.Any()).Return(domain.Job{}, service.ErrJobNotFound).AnyTimes()
+	runSvc.EXPECT().Start(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(domain.JobRun{Id: 1}, nil)
+	runSvc.EXPECT().Finish(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, run domain.JobRun, err error) error {
+			assert.ErrorIs(t, err, ErrSchedulerStopped)
+			return nil
+		})
+	s := &Scheduler{
+		execs:           map[string]Executor{"blocking": blockingExecutor{}},
+		svc:             svc,
+		runSvc:          runSvc,
+		shardSvc:        shardSvc,
+		l:               logger.NewNopLogger(),
+		limiter:         semaphore.NewWeighted(10),
+		idleInterval:    time.Millisecond * 10,
+		maxIdleInterval: time.Millisecond * 40,
+		drainTimeout:    time.Millisecond * 50,
+		execLimits:      map[string]int{},
+		inflight:        map[string]int{},
+		freed:           make(chan struct{}, 1),
+		queueDelay:      prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "test_queue_delay"}, []string{"executor"}),
+		runningGauge:    prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_running"}, []string{"executor"}),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+	err := s.Scheduler(ctx)
+	assert.NoError(t, err)
+	// 返回之前任务已经被取消并且释放了
+	select {
+	case <-released:
+	default:
+		t.Fatal("任务没有释放")
+	}
+}
+
+func TestScheduler_ExecutorLimit(t *testing.T) {
+	s := &Scheduler{
+		execs: map[string]Executor{
+			"blocking": blockingExecutor{},
+			"local":    NewLocalFuncExecter(),
+		},
+		execLimits:   map[string]int{},
+		inflight:     map[string]int{},
+		freed:        make(chan struct{}, 1),
+		runningGauge: prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_running"}, []string{"executor"}),
+	}
+	s.LimitExecutor("blocking", 1)
+	assert.Equal(t, []string{"blocking", "local"}, s.available())
+
+	// blocking 满了之后只抢 local 的任务，local 没有单独的上限
+	assert.True(t, s.acquireExecutor("blocking"))
+	assert.False(t, s.acquireExecutor("blocking"))
+	assert.True(t, s.acquireExecutor("local"))
+	assert.True(t, s.acquireExecutor("local"))
+	assert.Equal(t, []string{"local"}, s.available())
+
+	// 执行完之后通知调度循环
+	s.releaseExecutor("blocking")
+	assert.Equal(t, []string{"blocking", "local"}, s.available())
+	select {
+	case <-s.freed:
+	default:
+		t.Fatal("没有通知调度循环")
+	}
+}
@@ -0,0 +1,178 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"go-basic/webook/pkg/logger"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrNoNodeLoad 还没有任何节点上报负载
+var ErrNoNodeLoad = errors.New("没有节点上报负载")
+
+// LoadFunc 计算本节点的负载，越大越忙
+type LoadFunc func() float64
+
+// SystemLoad 一分钟的平均负载除以 CPU 核数，读不到的时候返回 0
+func SystemLoad() float64 {
+	data, err := os.ReadFile("/proc/loadavg")
+	if err != nil {
+		return 0
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0
+	}
+	load, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0
+	}
+	return load / float64(runtime.NumCPU())
+}
+
+// NodeLoadReporter 每个节点定时把自己的负载上报到 Redis，用来挑选负载最低的节点执行任务。
+// 负载放在一个 ZSET 里面，上报时间放在另一个 ZSET 里面，长时间没有上报的节点会被清理掉
+type NodeLoadReporter struct {
+	client       redis.Cmdable
+	loadKey      string
+	heartbeatKey string
+	nodeId       string
+	load         LoadFunc
+	interval     time.Duration
+	// 超过这个时间没有上报，认为节点已经下线
+	expiration time.Duration
+	l          logger.Logger
+
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+func NewNodeLoadReporter(client redis.Cmdable, key string, nodeId string, load LoadFunc,
+	interval time.Duration, l logger.Logger) *NodeLoadReporter {
+	return &NodeLoadReporter{
+		client:       client,
+		loadKey:      key + ":load",
+		heartbeatKey: key + ":heartbeat",
+		nodeId:       nodeId,
+		load:         load,
+		interval:     interval,
+		expiration:   interval * 3,
+		l:            l,
+		stop:         make(chan struct{}),
+	}
+}
+
+func (r *NodeLoadReporter) NodeId() string {
+	return r.nodeId
+}
+
+// Start 先同步上报一次，之后在后台定时上报
+func (r *NodeLoadReporter) Start() {
+	r.reportOnce()
+	go func() {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				r.reportOnce()
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (r *NodeLoadReporter) reportOnce() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := r.Report(ctx)
+	if err != nil {
+		r.l.Error("上报节点负载失败", logger.Error(err), logger.String("node", r.nodeId))
+	}
+}
+
+// Report 上报自己的负载，顺便清理已经下线的节点
+func (r *NodeLoadReporter) Report(ctx context.Context) error {
+	now := time.Now()
+	pipe := r.client.TxPipeline()
+	pipe.ZAdd(ctx, r.loadKey, redis.Z{Score: r.load(), Member: r.nodeId})
+	pipe.ZAdd(ctx, r.heartbeatKey, redis.Z{Score: float64(now.UnixMilli()), Member: r.nodeId})
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return err
+	}
+	expired, err := r.client.ZRangeByScore(ctx, r.heartbeatKey, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(now.Add(-r.expiration).UnixMilli(), 10),
+	}).Result()
+	if err != nil || len(expired) == 0 {
+		return err
+	}
+	members := make([]any, 0, len(expired))
+	for _, node := range expired {
+		members = append(members, node)
+	}
+	pipe = r.client.TxPipeline()
+	pipe.ZRem(ctx, r.loadKey, members...)
+	pipe.ZRem(ctx, r.heartbeatKey, members...)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// MinLoad 负载最低的节点
+func (r *NodeLoadReporter) MinLoad(ctx context.Context) (string, float64, error) {
+	res, err := r.client.ZRangeWithScores(ctx, r.loadKey, 0, 0).Result()
+	if err != nil {
+		return "", 0, err
+	}
+	if len(res) == 0 {
+		return "", 0, ErrNoNodeLoad
+	}
+	node, _ := res[0].Member.(string)
+	return node, res[0].Score, nil
+}
+
+// LiveNodes 还在上报心跳的节点，按照节点 ID 排序
+func (r *NodeLoadReporter) LiveNodes(ctx context.Context) ([]string, error) {
+	nodes, err := r.client.ZRangeByScore(ctx, r.heartbeatKey, &redis.ZRangeBy{
+		Min: strconv.FormatInt(time.Now().Add(-r.expiration).UnixMilli(), 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(nodes)
+	return nodes, nil
+}
+
+// Load 本节点最近一次上报的负载
+func (r *NodeLoadReporter) Load(ctx context.Context) (float64, error) {
+	load, err := r.client.ZScore(ctx, r.loadKey, r.nodeId).Result()
+	if err == redis.Nil {
+		return 0, ErrNoNodeLoad
+	}
+	return load, err
+}
+
+// Close 停止上报，并且把自己从候选节点里面删掉
+func (r *NodeLoadReporter) Close() error {
+	r.closeOnce.Do(func() {
+		close(r.stop)
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	pipe := r.client.TxPipeline()
+	pipe.ZRem(ctx, r.loadKey, r.nodeId)
+	pipe.ZRem(ctx, r.heartbeatKey, r.nodeId)
+	_, err := pipe.Exec(ctx)
+	return err
+}
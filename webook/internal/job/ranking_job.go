@@ -11,16 +11,24 @@ import (
 )
 
 type RankingJob struct {
-	svc       service.RankingService
-	timeout   time.Duration
-	client    *rlock.Client
-	key       string
-	l         logger.Logger
-	lock      *rlock.Lock
-	localLock *sync.Mutex
+	svc     service.RankingService
+	timeout time.Duration
+	client  *rlock.Client
+	key     string
+	l       logger.Logger
+	lock    *rlock.Lock
+	// lockCtx 在锁丢失或者释放的时候取消，正在计算的 TopN 跟着停下来，不会再写入结果
+	lockCtx    context.Context
+	lockCancel context.CancelFunc
+	localLock  *sync.Mutex
+
+	loads *NodeLoadReporter
+	// 别的节点的负载比自己低这么多的时候，自己不再去抢锁，持有锁的话就主动让出
+	threshold float64
 }
 
-func NewRankingJob(svc service.RankingService, timeout time.Duration, client *rlock.Client, l logger.Logger) *RankingJob {
+func NewRankingJob(svc service.RankingService, timeout time.Duration, client *rlock.Client,
+	loads *NodeLoadReporter, threshold float64, l logger.Logger) *RankingJob {
 	return &RankingJob{
 		svc: svc,
 		// 根据数据量来，如果七天内贴子很多，可以适当调大
@@ -29,6 +37,8 @@ func NewRankingJob(svc service.RankingService, timeout time.Duration, client *rl
 		key:       "rolock:cron_job:ranking",
 		l:         l,
 		localLock: &sync.Mutex{},
+		loads:     loads,
+		threshold: threshold,
 	}
 }
 
@@ -40,7 +50,12 @@ func (r *RankingJob) Run() error {
 	// 本地锁，防止多个定时任务同时执行，防止并发
 	r.localLock.Lock()
 	defer r.localLock.Unlock()
+	overloaded := r.overloaded()
 	if r.lock == nil {
+		if overloaded {
+			// 有负载更低的节点，让它去抢
+			return nil
+		}
 		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 		defer cancel()
 		lock, err := r.client.Lock(ctx, r.key, r.timeout, &rlock.FixIntervalRetry{
@@ -52,23 +67,76 @@ func (r *RankingJob) Run() error {
 			return nil
 		}
 		r.lock = lock
+		r.lockCtx, r.lockCancel = context.WithCancel(context.Background())
 		// 保证这里一直拿到这个锁，使用协程自动续期
-		go func() {
-			// 本地锁，防止多个定时任务同时执行，防止并发
-			r.localLock.Lock()
-			defer r.localLock.Unlock()
+		go r.refresh(lock, r.lockCancel)
+	}
+	ctx, cancel := context.WithTimeout(r.lockCtx, r.timeout)
+	defer cancel()
+	err := r.svc.TopN(ctx)
+	if overloaded {
+		// 这一轮已经算完了，再让出锁，负载低的节点下一轮就能拿到
+		r.stepDown()
+	}
+	return err
+}
+
+// refresh 一直续约到锁被释放或者续约失败。
+// 续约的时候不能持有本地锁，不然 Run 和 Close 都会被卡住
+func (r *RankingJob) refresh(lock *rlock.Lock, cancel context.CancelFunc) {
+	err := lock.AutoRefresh(r.timeout/2, time.Second)
+	// 先取消再拿本地锁，Run 正在计算的时候持有本地锁，要靠取消让它尽快结束
+	cancel()
+	r.localLock.Lock()
+	defer r.localLock.Unlock()
+	// 锁可能已经被主动释放，甚至已经重新抢到了新的锁，这时候不能清掉
+	if r.lock != lock {
+		return
+	}
+	// 续约失败，说明锁已经丢了，下一次运行的时候重新抢
+	r.lock = nil
+	if err != nil {
+		r.l.Error("热榜任务续约分布式锁失败", logger.Error(err))
+	}
+}
 
-			er := lock.AutoRefresh(r.timeout/2, time.Second)
-			// 如果续期失败，说明锁被释放了
-			if er != nil {
-				// 如果失败了，就释放锁，争取下次能拿到
-				r.lock = nil
-			}
-		}()
+// overloaded 有别的节点负载比自己低超过阈值。查不到负载的时候按照没有负载感知处理
+func (r *RankingJob) overloaded() bool {
+	if r.loads == nil {
+		return false
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
-	return r.svc.TopN(ctx)
+	minNode, minLoad, err := r.loads.MinLoad(ctx)
+	if err != nil {
+		return false
+	}
+	if minNode == r.loads.NodeId() {
+		return false
+	}
+	load, err := r.loads.Load(ctx)
+	if err != nil {
+		return false
+	}
+	return load-minLoad > r.threshold
+}
+
+// stepDown 主动释放锁，调用者需要持有本地锁
+func (r *RankingJob) stepDown() {
+	lock := r.lock
+	if lock == nil {
+		return
+	}
+	r.lock = nil
+	r.lockCancel()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := lock.Unlock(ctx)
+	if err != nil {
+		r.l.Error("热榜任务释放分布式锁失败", logger.Error(err))
+		return
+	}
+	r.l.Info("负载过高，热榜任务让出分布式锁", logger.String("node", r.loads.NodeId()))
 }
 
 // Close 关闭锁
@@ -77,6 +145,9 @@ func (r *RankingJob) Close() error {
 	lock := r.lock
 	r.lock = nil
 	r.localLock.Unlock()
+	if lock == nil {
+		return nil
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
 	return lock.Unlock(ctx)
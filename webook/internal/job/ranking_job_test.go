@@ -0,0 +1,206 @@
+package job
+
+import (
+	"context"
+	svcmocks "go-basic/webook/internal/service/mocks"
+	"go-basic/webook/pkg/logger"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/golang/mock/gomock"
+	rlock "github.com/gotomicro/redis-lock"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testRankingNode struct {
+	job   *RankingJob
+	loads *NodeLoadReporter
+	load  atomic.Value
+	runs  atomic.Int64
+}
+
+func newTestRankingNode(t *testing.T, ctrl *gomock.Controller, client redis.Cmdable, nodeId string, load float64) *testRankingNode {
+	node := &testRankingNode{}
+	node.load.Store(load)
+	svc := svcmocks.NewMockRankingService(ctrl)
+	svc.EXPECT().TopN(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		node.runs.Add(1)
+		return nil
+	}).AnyTimes()
+	node.loads = NewNodeLoadReporter(client, "test:node", nodeId, func() float64 {
+		return node.load.Load().(float64)
+	}, time.Second, logger.NewNopLogger())
+	require.NoError(t, node.loads.Report(context.Background()))
+	node.job = NewRankingJob(svc, time.Second*3, rlock.NewClient(client), node.loads, 0.5, logger.NewNopLogger())
+	t.Cleanup(func() {
+		node.job.Close()
+	})
+	return node
+}
+
+func (n *testRankingNode) holding() bool {
+	n.job.localLock.Lock()
+	defer n.job.localLock.Unlock()
+	return n.job.lock != nil
+}
+
+func TestRankingJob_LeastLoadedNodeWins(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	busy := newTestRankingNode(t, ctrl, client, "busy", 2)
+	idle := newTestRankingNode(t, ctrl, client, "idle", 0.2)
+
+	// 负载高的节点先运行也不会去抢锁
+	require.NoError(t, busy.job.Run())
+	require.NoError(t, idle.job.Run())
+	assert.False(t, busy.holding())
+	assert.True(t, idle.holding())
+	assert.Equal(t, int64(0), busy.runs.Load())
+	assert.Equal(t, int64(1), idle.runs.Load())
+
+	// 负载差距没有超过阈值，不切换
+	idle.load.Store(1.8)
+	require.NoError(t, idle.loads.Report(context.Background()))
+	require.NoError(t, busy.job.Run())
+	require.NoError(t, idle.job.Run())
+	assert.False(t, busy.holding())
+	assert.True(t, idle.holding())
+	assert.Equal(t, int64(2), idle.runs.Load())
+}
+
+func TestRankingJob_StepDown(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	holder := newTestRankingNode(t, ctrl, client, "holder", 0.1)
+	other := newTestRankingNode(t, ctrl, client, "other", 0.3)
+	require.NoError(t, holder.job.Run())
+	assert.True(t, holder.holding())
+
+	// 持有锁的节点变忙了，这一轮还是它算，算完让出锁
+	holder.load.Store(3.0)
+	require.NoError(t, holder.loads.Report(context.Background()))
+	require.NoError(t, holder.job.Run())
+	assert.Equal(t, int64(2), holder.runs.Load())
+	assert.False(t, holder.holding())
+	assert.False(t, mr.Exists("rolock:cron_job:ranking"))
+
+	// 下一轮负载低的节点接手
+	require.NoError(t, holder.job.Run())
+	require.NoError(t, other.job.Run())
+	assert.False(t, holder.holding())
+	assert.True(t, other.holding())
+	assert.Equal(t, int64(2), holder.runs.Load())
+	assert.Equal(t, int64(1), other.runs.Load())
+}
+
+func TestRankingJob_LockLost(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	node := newTestRankingNode(t, ctrl, client, "node", 0.1)
+	require.NoError(t, node.job.Run())
+	require.True(t, node.holding())
+
+	// 模拟锁过期被别人抢走，续约失败之后要清掉本地持有的锁
+	mr.Set("rolock:cron_job:ranking", "someone-else")
+	require.Eventually(t, func() bool {
+		return !node.holding()
+	}, time.Second*5, time.Millisecond*100)
+
+	// 本地锁没有被续约的协程占住，Run 可以正常返回
+	done := make(chan error, 1)
+	go func() {
+		done <- node.job.Run()
+	}()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(time.Second * 3):
+		t.Fatal("Run 被卡住了")
+	}
+	assert.False(t, node.holding())
+
+	// 别人的锁过期之后重新抢到
+	mr.Del("rolock:cron_job:ranking")
+	require.NoError(t, node.job.Run())
+	assert.True(t, node.holding())
+}
+
+func TestRankingJob_LockLostCancelsTopN(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	svc := svcmocks.NewMockRankingService(ctrl)
+	started := make(chan struct{})
+	svc.EXPECT().TopN(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		close(started)
+		// 一直算到 ctx 被取消
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	j := NewRankingJob(svc, time.Second*10, rlock.NewClient(client), nil, 0.5, logger.NewNopLogger())
+	t.Cleanup(func() {
+		j.Close()
+	})
+	done := make(chan error, 1)
+	go func() {
+		done <- j.Run()
+	}()
+	<-started
+	// 计算的时候锁被别人抢走
+	mr.Set("rolock:cron_job:ranking", "someone-else")
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second * 8):
+		t.Fatal("锁丢了之后 TopN 没有被取消")
+	}
+}
+
+func TestNodeLoadReporter_RemoveExpiredNodes(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	ctx := context.Background()
+
+	gone := NewNodeLoadReporter(client, "test:node", "gone", func() float64 {
+		return 0.1
+	}, time.Second, logger.NewNopLogger())
+	alive := NewNodeLoadReporter(client, "test:node", "alive", func() float64 {
+		return 1
+	}, time.Second, logger.NewNopLogger())
+	require.NoError(t, gone.Report(ctx))
+	require.NoError(t, alive.Report(ctx))
+	node, load, err := alive.MinLoad(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "gone", node)
+	assert.Equal(t, 0.1, load)
+
+	// 把 gone 的上报时间改成很久以前，下一次上报会把它清理掉
+	mr.ZAdd("test:node:heartbeat", float64(time.Now().Add(-time.Minute).UnixMilli()), "gone")
+	nodes, err := alive.LiveNodes(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alive"}, nodes)
+	require.NoError(t, alive.Report(ctx))
+	node, _, err = alive.MinLoad(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "alive", node)
+
+	// 下线的时候删掉自己
+	require.NoError(t, alive.Close())
+	_, _, err = alive.MinLoad(ctx)
+	assert.Equal(t, ErrNoNodeLoad, err)
+}
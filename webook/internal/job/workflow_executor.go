@@ -0,0 +1,237 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-basic/webook/internal/domain"
+	"go-basic/webook/internal/service"
+	"go-basic/webook/pkg/logger"
+	"time"
+)
+
+var ErrWorkflowFailed = errors.New("工作流执行失败")
+
+// WorkflowExecutor 执行工作流，节点是普通的任务，用注册进来的执行器在本实例上执行。
+// 每一步的状态都落库，实例崩溃之后别的实例抢到工作流会接着执行
+type WorkflowExecutor struct {
+	execs  map[string]Executor
+	svc    service.WorkflowService
+	jobSvc service.JobService
+	l      logger.Logger
+}
+
+func NewWorkflowExecutor(svc service.WorkflowService, jobSvc service.JobService, l logger.Logger) *WorkflowExecutor {
+	return &WorkflowExecutor{
+		execs:  make(map[string]Executor),
+		svc:    svc,
+		jobSvc: jobSvc,
+		l:      l,
+	}
+}
+
+func (w *WorkflowExecutor) Name() string {
+	return domain.WorkflowExecutor
+}
+
+// RegisterExecutor 节点可以使用的执行器
+func (w *WorkflowExecutor) RegisterExecutor(exec Executor) {
+	w.execs[exec.Name()] = exec
+}
+
+type workflowNodeResult struct {
+	idx     int
+	attempt int
+	err     error
+}
+
+func (w *WorkflowExecutor) Exec(ctx context.Context, j domain.Job) error {
+	wf, err := domain.ParseWorkflow(j.Cfg)
+	if err != nil {
+		return Fatal(fmt.Errorf("工作流定义有误: %w", err))
+	}
+	run, err := w.svc.Start(ctx, j.Id, wf)
+	if err != nil {
+		return err
+	}
+	index := w.index(run)
+	for i, n := range run.Nodes {
+		// 上一次执行到一半的节点重新执行
+		if n.Status == domain.WorkflowNodeStatusRunning {
+			run.Nodes[i].Status = domain.WorkflowNodeStatusWaiting
+		}
+	}
+
+	results := make(chan workflowNodeResult, len(run.Nodes))
+	running := 0
+	for {
+		w.skipBroken(run, index)
+		for i, n := range run.Nodes {
+			if n.Status != domain.WorkflowNodeStatusWaiting || !w.ready(run, index, n) {
+				continue
+			}
+			run.Nodes[i].Status = domain.WorkflowNodeStatusRunning
+			run.Nodes[i].StartTime = time.Now()
+			run.Nodes[i].ErrMsg = ""
+			w.updateNode(run.Nodes[i])
+			running++
+			go func(idx int, node domain.WorkflowNodeRun) {
+				attempt, err := w.runNode(ctx, j, node)
+				results <- workflowNodeResult{idx: idx, attempt: attempt, err: err}
+			}(i, run.Nodes[i])
+		}
+		if running == 0 {
+			break
+		}
+		var res workflowNodeResult
+		select {
+		case <-ctx.Done():
+			return w.interrupt(ctx, run)
+		case res = <-results:
+		}
+		running--
+		node := &run.Nodes[res.idx]
+		node.EndTime = time.Now()
+		node.Attempt = res.attempt
+		node.Status = domain.WorkflowNodeStatusSuccess
+		if res.err != nil {
+			node.Status = domain.WorkflowNodeStatusFailed
+			node.ErrMsg = res.err.Error()
+			w.l.Error("工作流节点执行失败", logger.Error(res.err),
+				logger.Int64("workflow_id", j.Id), logger.Int64("job_id", node.JobId))
+		}
+		w.updateNode(*node)
+	}
+
+	run = w.finish(run)
+	if run.Status != domain.WorkflowRunStatusSuccess {
+		return fmt.Errorf("%w: run_id %d", ErrWorkflowFailed, run.Id)
+	}
+	return nil
+}
+
+// interrupt 取消或者超时的时候，执行中的节点算作失败，这一次执行直接结束；
+// 调度器退出或者丢了租约的时候节点的状态保持 running，下一次恢复的时候重新执行
+func (w *WorkflowExecutor) interrupt(ctx context.Context, run domain.WorkflowRun) error {
+	cause := context.Cause(ctx)
+	if !errors.Is(cause, ErrJobCancelled) && !errors.Is(cause, ErrJobTimeout) {
+		return ctx.Err()
+	}
+	for i, n := range run.Nodes {
+		if n.Status != domain.WorkflowNodeStatusRunning {
+			continue
+		}
+		run.Nodes[i].Status = domain.WorkflowNodeStatusFailed
+		run.Nodes[i].ErrMsg = cause.Error()
+		run.Nodes[i].EndTime = time.Now()
+		w.updateNode(run.Nodes[i])
+	}
+	w.skipBroken(run, w.index(run))
+	w.finish(run)
+	return ctx.Err()
+}
+
+func (w *WorkflowExecutor) finish(run domain.WorkflowRun) domain.WorkflowRun {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	res, err := w.svc.Finish(ctx, run)
+	if err != nil {
+		w.l.Error("记录工作流执行结果失败", logger.Error(err), logger.Int64("run_id", run.Id))
+	}
+	return res
+}
+
+func (w *WorkflowExecutor) index(run domain.WorkflowRun) map[int64]int {
+	res := make(map[int64]int, len(run.Nodes))
+	for i, n := range run.Nodes {
+		res[n.JobId] = i
+	}
+	return res
+}
+
+// ready 上游全部成功
+func (w *WorkflowExecutor) ready(run domain.WorkflowRun, index map[int64]int, n domain.WorkflowNodeRun) bool {
+	for _, up := range n.Upstreams {
+		if run.Nodes[index[up]].Status != domain.WorkflowNodeStatusSuccess {
+			return false
+		}
+	}
+	return true
+}
+
+// skipBroken 上游失败或者被跳过的节点不会再执行，跳过会一直传递到最下游
+func (w *WorkflowExecutor) skipBroken(run domain.WorkflowRun, index map[int64]int) {
+	for changed := true; changed; {
+		changed = false
+		for i, n := range run.Nodes {
+			if n.Status != domain.WorkflowNodeStatusWaiting {
+				continue
+			}
+			for _, up := range n.Upstreams {
+				status := run.Nodes[index[up]].Status
+				if status == domain.WorkflowNodeStatusFailed || status == domain.WorkflowNodeStatusSkipped {
+					run.Nodes[i].Status = domain.WorkflowNodeStatusSkipped
+					w.updateNode(run.Nodes[i])
+					changed = true
+					break
+				}
+			}
+		}
+	}
+}
+
+// runNode 按照节点任务自己的重试策略和超时时间执行，返回一共尝试了几次
+func (w *WorkflowExecutor) runNode(ctx context.Context, wf domain.Job, node domain.WorkflowNodeRun) (int, error) {
+	j, err := w.jobSvc.FindById(ctx, node.JobId)
+	if err != nil {
+		return 0, err
+	}
+	// 创建的时候节点是暂停的，后面被恢复了就会按照自己的 cron 执行，这里不能再执行一次
+	if j.Status != domain.JobStatusPaused {
+		return 0, Fatal(fmt.Errorf("工作流节点 %d 没有暂停，有自己的调度", j.Id))
+	}
+	exec, ok := w.execs[j.Executor]
+	if !ok {
+		return 0, fmt.Errorf("未找到执行器: %s", j.Executor)
+	}
+	// 节点总是整个执行，不分片；版本号用工作流的，这样每次执行的 RunId 都不一样
+	j.Version = wf.Version
+	j.Shard = domain.JobShard{}
+	maxAttempts := max(j.Retry.MaxAttempts, 1)
+	for attempt := 1; ; attempt++ {
+		j.Attempt = attempt
+		node.Attempt = attempt
+		w.updateNode(node)
+		err = w.execOnce(ctx, exec, j)
+		if err == nil || IsFatal(err) || attempt >= maxAttempts {
+			return attempt, err
+		}
+		w.l.Warn("工作流节点执行失败，准备重试", logger.Error(err),
+			logger.Int64("job_id", j.Id), logger.Int64("attempt", int64(attempt)))
+		select {
+		case <-ctx.Done():
+			return attempt, ctx.Err()
+		case <-time.After(j.Retry.NextInterval(attempt)):
+		}
+	}
+}
+
+// execOnce 超时时间对每一次尝试生效
+func (w *WorkflowExecutor) execOnce(ctx context.Context, exec Executor, j domain.Job) error {
+	if j.Timeout <= 0 {
+		return exec.Exec(ctx, j)
+	}
+	ctx, cancel := context.WithTimeoutCause(ctx, j.Timeout, ErrJobTimeout)
+	defer cancel()
+	return interrupted(ctx, exec.Exec(ctx, j))
+}
+
+// updateNode 状态写不进去不影响执行，最坏的情况是恢复的时候重新执行这个节点
+func (w *WorkflowExecutor) updateNode(n domain.WorkflowNodeRun) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := w.svc.UpdateNode(ctx, n)
+	if err != nil {
+		w.l.Error("记录工作流节点状态失败", logger.Error(err), logger.Int64("node_id", n.Id))
+	}
+}
@@ -0,0 +1,164 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"go-basic/webook/internal/domain"
+	svcmocks "go-basic/webook/internal/service/mocks"
+	"go-basic/webook/pkg/logger"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+// testExecutor 按照任务 ID 返回预设的错误，并且记录执行的顺序
+type testExecutor struct {
+	mu     sync.Mutex
+	errs   map[int64][]error
+	called []int64
+}
+
+func (e *testExecutor) Name() string {
+	return "test"
+}
+
+func (e *testExecutor) Exec(ctx context.Context, j domain.Job) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.called = append(e.called, j.Id)
+	errs := e.errs[j.Id]
+	if len(errs) == 0 {
+		return nil
+	}
+	e.errs[j.Id] = errs[1:]
+	return errs[0]
+}
+
+func TestWorkflowExecutor_Exec(t *testing.T) {
+	testCases := []struct {
+		name string
+		cfg  string
+		// 已经有的执行，为空表示新建
+		run  []domain.WorkflowNodeRun
+		errs map[int64][]error
+		jobs map[int64]domain.Job
+
+		wantCalled []int64
+		wantStatus map[int64]domain.WorkflowNodeStatus
+		wantErr    error
+	}{
+		{
+			name:       "按照依赖顺序执行",
+			cfg:        `{"nodes":[{"jobId":3,"upstreams":[2]},{"jobId":2,"upstreams":[1]},{"jobId":1}]}`,
+			wantCalled: []int64{1, 2, 3},
+			wantStatus: map[int64]domain.WorkflowNodeStatus{
+				1: domain.WorkflowNodeStatusSuccess,
+				2: domain.WorkflowNodeStatusSuccess,
+				3: domain.WorkflowNodeStatusSuccess,
+			},
+		},
+		{
+			name:       "上游失败，下游跳过",
+			cfg:        `{"nodes":[{"jobId":1},{"jobId":2,"upstreams":[1]},{"jobId":3,"upstreams":[2]}]}`,
+			errs:       map[int64][]error{1: {Fatal(errors.New("参数有误"))}},
+			wantCalled: []int64{1},
+			wantStatus: map[int64]domain.WorkflowNodeStatus{
+				1: domain.WorkflowNodeStatusFailed,
+				2: domain.WorkflowNodeStatusSkipped,
+				3: domain.WorkflowNodeStatusSkipped,
+			},
+			wantErr: ErrWorkflowFailed,
+		},
+		{
+			name: "节点按照自己的策略重试",
+			cfg:  `{"nodes":[{"jobId":1},{"jobId":2,"upstreams":[1]}]}`,
+			errs: map[int64][]error{1: {errors.New("超时")}},
+			jobs: map[int64]domain.Job{
+				1: {Id: 1, Executor: "test", Status: domain.JobStatusPaused, Retry: domain.RetryPolicy{MaxAttempts: 2, Interval: time.Millisecond}},
+			},
+			wantCalled: []int64{1, 1, 2},
+			wantStatus: map[int64]domain.WorkflowNodeStatus{
+				1: domain.WorkflowNodeStatusSuccess,
+				2: domain.WorkflowNodeStatusSuccess,
+			},
+		},
+		{
+			name: "节点被恢复了调度，不再由工作流执行",
+			cfg:  `{"nodes":[{"jobId":1},{"jobId":2,"upstreams":[1]}]}`,
+			jobs: map[int64]domain.Job{
+				1: {Id: 1, Executor: "test", Status: domain.JobStatusWaiting},
+			},
+			wantStatus: map[int64]domain.WorkflowNodeStatus{
+				1: domain.WorkflowNodeStatusFailed,
+				2: domain.WorkflowNodeStatusSkipped,
+			},
+			wantErr: ErrWorkflowFailed,
+		},
+		{
+			name: "从上一次中断的地方恢复",
+			cfg:  `{"nodes":[{"jobId":1},{"jobId":2,"upstreams":[1]}]}`,
+			run: []domain.WorkflowNodeRun{
+				{Id: 1, JobId: 1, Status: domain.WorkflowNodeStatusSuccess},
+				{Id: 2, JobId: 2, Upstreams: []int64{1}, Status: domain.WorkflowNodeStatusRunning},
+			},
+			wantCalled: []int64{2},
+			wantStatus: map[int64]domain.WorkflowNodeStatus{
+				1: domain.WorkflowNodeStatusSuccess,
+				2: domain.WorkflowNodeStatusSuccess,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := svcmocks.NewMockWorkflowService(ctrl)
+			jobSvc := svcmocks.NewMockJobService(ctrl)
+			svc.EXPECT().Start(gomock.Any(), int64(10), gomock.Any()).
+				DoAndReturn(func(ctx context.Context, id int64, wf domain.Workflow) (domain.WorkflowRun, error) {
+					run := domain.WorkflowRun{Id: 100, WorkflowId: id, Status: domain.WorkflowRunStatusRunning, Nodes: tc.run}
+					if run.Nodes == nil {
+						for _, n := range wf.Nodes {
+							run.Nodes = append(run.Nodes, domain.WorkflowNodeRun{
+								Id: n.JobId, JobId: n.JobId, Upstreams: n.Upstreams, Status: domain.WorkflowNodeStatusWaiting,
+							})
+						}
+					}
+					return run, nil
+				})
+			svc.EXPECT().UpdateNode(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			jobSvc.EXPECT().FindById(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(ctx context.Context, id int64) (domain.Job, error) {
+					if j, ok := tc.jobs[id]; ok {
+						return j, nil
+					}
+					return domain.Job{Id: id, Executor: "test", Status: domain.JobStatusPaused}, nil
+				}).AnyTimes()
+			var status map[int64]domain.WorkflowNodeStatus
+			svc.EXPECT().Finish(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(ctx context.Context, r domain.WorkflowRun) (domain.WorkflowRun, error) {
+					status = make(map[int64]domain.WorkflowNodeStatus, len(r.Nodes))
+					r.Status = domain.WorkflowRunStatusSuccess
+					for _, n := range r.Nodes {
+						status[n.JobId] = n.Status
+						if n.Status != domain.WorkflowNodeStatusSuccess {
+							r.Status = domain.WorkflowRunStatusFailed
+						}
+					}
+					return r, nil
+				})
+
+			exec := &testExecutor{errs: tc.errs}
+			w := NewWorkflowExecutor(svc, jobSvc, logger.NewNopLogger())
+			w.RegisterExecutor(exec)
+			err := w.Exec(context.Background(), domain.Job{Id: 10, Version: 1, Executor: domain.WorkflowExecutor, Cfg: tc.cfg})
+			assert.ErrorIs(t, err, tc.wantErr)
+			assert.Equal(t, tc.wantCalled, exec.called)
+			assert.Equal(t, tc.wantStatus, status)
+		})
+	}
+}
@@ -23,6 +23,7 @@ type ArticleRepository interface {
 	GetById(ctx context.Context, id int64) (domain.Article, error)
 	GetPublishedById(ctx context.Context, id int64) (domain.Article, error)
 	ListPub(ctx context.Context, offset, limit int, start time.Time) ([]domain.Article, error)
+	ListPubByIds(ctx context.Context, ids []int64) ([]domain.Article, error)
 }
 
 type CacheArticleRepository struct {
@@ -58,6 +59,16 @@ func (c *CacheArticleRepository) ListPub(ctx context.Context, offset, limit int,
 	}), nil
 }
 
+func (c *CacheArticleRepository) ListPubByIds(ctx context.Context, ids []int64) ([]domain.Article, error) {
+	res, err := c.dao.GetPubByIds(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map(res, func(idx int, src dao.PublishedArticle) domain.Article {
+		return c.entityToDomain(ctx, dao.Article(src))
+	}), nil
+}
+
 func (c *CacheArticleRepository) GetPublishedById(ctx context.Context, id int64) (domain.Article, error) {
 	// 读取线上库数据，如果内容放在oss上，让前端直接访问oss
 	art, err := c.dao.GetPubById(ctx, id)
@@ -67,10 +78,11 @@ func (c *CacheArticleRepository) GetPublishedById(ctx context.Context, id int64)
 	// 组装 user，适合单体架构
 	user, err := c.userRepo.FindById(ctx, art.AuthorId)
 	res := domain.Article{
-		Id:      art.Id,
-		Title:   art.Title,
-		Content: art.Content,
-		Status:  domain.ArticleStatus(art.Status),
+		Id:       art.Id,
+		Title:    art.Title,
+		Content:  art.Content,
+		Category: art.Category,
+		Status:   domain.ArticleStatus(art.Status),
 		Author: domain.Author{
 			Id:   user.Id,
 			Name: user.Nickname,
@@ -183,6 +195,7 @@ func (c *CacheArticleRepository) domainToEntity(ctx context.Context, art domain.
 		Title:    art.Title,
 		Content:  art.Content,
 		AuthorId: art.Author.Id,
+		Category: art.Category,
 		Status:   art.Status.ToUint8(),
 	}
 }
@@ -195,9 +208,10 @@ func (c *CacheArticleRepository) entityToDomain(ctx context.Context, art dao.Art
 		Author: domain.Author{
 			Id: art.AuthorId,
 		},
-		Status: domain.ArticleStatus(art.Status),
-		Ctime:  time.UnixMilli(art.Ctime),
-		Utime:  time.UnixMilli(art.Utime),
+		Category: art.Category,
+		Status:   domain.ArticleStatus(art.Status),
+		Ctime:    time.UnixMilli(art.Ctime),
+		Utime:    time.UnixMilli(art.Utime),
 	}
 }
 
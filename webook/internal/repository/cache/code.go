@@ -28,11 +28,22 @@ type CodeCache interface {
 
 type RedisCodeCache struct {
 	client redis.Cmdable
+	// prefix 区分不同渠道的验证码
+	prefix string
 }
 
 func NewCodeCache(client redis.Cmdable) CodeCache {
 	return &RedisCodeCache{
 		client: client,
+		prefix: "phone_code",
+	}
+}
+
+// NewEmailCodeCache 邮件验证码，存储和校验的逻辑和短信验证码一样
+func NewEmailCodeCache(client redis.Cmdable) CodeCache {
+	return &RedisCodeCache{
+		client: client,
+		prefix: "email_code",
 	}
 }
 
@@ -71,6 +82,6 @@ func (c *RedisCodeCache) Verify(ctx context.Context, biz, phone, code string) (b
 	}
 }
 
-func (c *RedisCodeCache) key(biz, phone string) string {
-	return fmt.Sprintf("phone_code:%s:%s", biz, phone)
+func (c *RedisCodeCache) key(biz, target string) string {
+	return fmt.Sprintf("%s:%s:%s", c.prefix, biz, target)
 }
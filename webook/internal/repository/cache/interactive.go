@@ -29,14 +29,24 @@ type InteractiveCache interface {
 	Set(ctx context.Context, biz string, bizId int64, res domain.Interactive) error
 }
 
+// InteractiveTTLFunc 不同业务的计数缓存可以有不同的过期时间
+type InteractiveTTLFunc func(biz string) time.Duration
+
 type InteractiveRedisCache struct {
 	client     redis.Cmdable
-	expiration time.Duration
+	expiration InteractiveTTLFunc
 }
 
-func NewInteractiveRedisCache(client redis.Cmdable) InteractiveCache {
+// NewInteractiveRedisCache expiration 为 nil 时所有业务的过期时间都是 15 分钟
+func NewInteractiveRedisCache(client redis.Cmdable, expiration InteractiveTTLFunc) InteractiveCache {
+	if expiration == nil {
+		expiration = func(biz string) time.Duration {
+			return time.Minute * 15
+		}
+	}
 	return &InteractiveRedisCache{
-		client: client,
+		client:     client,
+		expiration: expiration,
 	}
 }
 
@@ -85,7 +95,7 @@ func (c *InteractiveRedisCache) Set(ctx context.Context, biz string, bizId int64
 	if err != nil {
 		return err
 	}
-	return c.client.Expire(ctx, key, time.Minute*15).Err()
+	return c.client.Expire(ctx, key, c.expiration(biz)).Err()
 }
 
 func (i *InteractiveRedisCache) key(biz string, bizId int64) string {
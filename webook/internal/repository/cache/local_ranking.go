@@ -4,42 +4,54 @@ import (
 	"context"
 	"errors"
 	"go-basic/webook/internal/domain"
+	"sync"
 	"time"
-
-	"github.com/ecodeclub/ekit/syncx/atomicx"
 )
 
 type RankingLocalCache struct {
-	topN       *atomicx.Value[[]domain.Article]
-	ddl        *atomicx.Value[time.Time]
+	mu         sync.RWMutex
+	lists      map[domain.RankingKey]localRankingList
 	expiration time.Duration
 }
 
+type localRankingList struct {
+	arts []domain.Article
+	ddl  time.Time
+}
+
 func NewRankingLocalCache() *RankingLocalCache {
 	return &RankingLocalCache{
-		topN: atomicx.NewValue[[]domain.Article](),
-		ddl:  atomicx.NewValueOf(time.Now()),
-		// 本地缓存过期时间非常长，或永不过期
-		expiration: time.Hour * 24,
+		lists: make(map[domain.RankingKey]localRankingList),
+		// 只有计算热榜的节点会更新本地缓存，别的节点靠过期之后从 Redis 重新加载，
+		// 所以过期时间要比计算间隔（3 分钟）短，Redis 出问题的时候再用过期的数据兜底
+		expiration: time.Minute,
 	}
 }
 
-func (r *RankingLocalCache) Set(ctx context.Context, arts []domain.Article) error {
-	r.topN.Store(arts)
-	ddl := time.Now().Add(r.expiration)
-	r.ddl.Store(ddl)
+func (r *RankingLocalCache) Set(ctx context.Context, key domain.RankingKey, arts []domain.Article) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lists[key] = localRankingList{
+		arts: arts,
+		ddl:  time.Now().Add(r.expiration),
+	}
 	return nil
 }
 
-func (r *RankingLocalCache) Get(ctx context.Context) ([]domain.Article, error) {
-	ddl := r.ddl.Load()
-	arts := r.topN.Load()
-	if len(arts) == 0 || ddl.Before(time.Now()) {
+func (r *RankingLocalCache) Get(ctx context.Context, key domain.RankingKey) ([]domain.Article, error) {
+	r.mu.RLock()
+	list, ok := r.lists[key]
+	r.mu.RUnlock()
+	// 空榜单也是计算出来的结果，不能当成没有缓存，否则会回源拿到旧数据
+	if !ok || list.ddl.Before(time.Now()) {
 		return nil, errors.New("本地缓存不存在或已过期")
 	}
-	return r.topN.Load(), nil
+	return list.arts, nil
 }
 
-func (r *RankingLocalCache) ForceGet(ctx context.Context) ([]domain.Article, error) {
-	return r.topN.Load(), nil
+// ForceGet 不管有没有过期都返回
+func (r *RankingLocalCache) ForceGet(ctx context.Context, key domain.RankingKey) ([]domain.Article, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.lists[key].arts, nil
 }
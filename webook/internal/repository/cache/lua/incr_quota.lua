@@ -0,0 +1,18 @@
+-- 业务方当天的发送计数 sms_quota:biz:20240101
+local key = KEYS[1]
+-- 这次要发几条
+local delta = tonumber(ARGV[1])
+local quota = tonumber(ARGV[2])
+-- 计数保留的时间，比一天略长就可以
+local ttl = tonumber(ARGV[3])
+
+local cnt = tonumber(redis.call("GET", key) or "0")
+if cnt + delta > quota then
+    -- 超过额度，这次一条都不发
+    return -1
+end
+redis.call("INCRBY", key, delta)
+if cnt == 0 then
+    redis.call("EXPIRE", key, ttl)
+end
+return 0
@@ -0,0 +1,50 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./webook/internal/repository/cache/sms_quota.go
+
+// Package cachemocks is a generated GoMock package.
+package cachemocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockSMSQuotaCache is a mock of SMSQuotaCache interface.
+type MockSMSQuotaCache struct {
+	ctrl     *gomock.Controller
+	recorder *MockSMSQuotaCacheMockRecorder
+}
+
+// MockSMSQuotaCacheMockRecorder is the mock recorder for MockSMSQuotaCache.
+type MockSMSQuotaCacheMockRecorder struct {
+	mock *MockSMSQuotaCache
+}
+
+// NewMockSMSQuotaCache creates a new mock instance.
+func NewMockSMSQuotaCache(ctrl *gomock.Controller) *MockSMSQuotaCache {
+	mock := &MockSMSQuotaCache{ctrl: ctrl}
+	mock.recorder = &MockSMSQuotaCacheMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSMSQuotaCache) EXPECT() *MockSMSQuotaCacheMockRecorder {
+	return m.recorder
+}
+
+// Incr mocks base method.
+func (m *MockSMSQuotaCache) Incr(ctx context.Context, tokenId int64, day time.Time, n, quota int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Incr", ctx, tokenId, day, n, quota)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Incr indicates an expected call of Incr.
+func (mr *MockSMSQuotaCacheMockRecorder) Incr(ctx, tokenId, day, n, quota interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Incr", reflect.TypeOf((*MockSMSQuotaCache)(nil).Incr), ctx, tokenId, day, n, quota)
+}
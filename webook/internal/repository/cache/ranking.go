@@ -10,8 +10,10 @@ import (
 )
 
 type RankingCache interface {
-	Set(ctx context.Context, arts []domain.Article) error
-	Get(ctx context.Context) ([]domain.Article, error)
+	Set(ctx context.Context, key domain.RankingKey, arts []domain.Article) error
+	Get(ctx context.Context, key domain.RankingKey) ([]domain.Article, error)
+	// Categories 写过榜单的分类，换了计算的节点之后也要知道哪些分类需要用空榜单覆盖
+	Categories(ctx context.Context) ([]string, error)
 }
 
 type RankingRedisCache struct {
@@ -19,11 +21,14 @@ type RankingRedisCache struct {
 	key    string
 }
 
-func NewRankingRedisCache() RankingCache {
-	return &RankingRedisCache{}
+func NewRankingRedisCache(client redis.Cmdable) RankingCache {
+	return &RankingRedisCache{
+		client: client,
+		key:    "ranking:top_n",
+	}
 }
 
-func (r *RankingRedisCache) Set(ctx context.Context, arts []domain.Article) error {
+func (r *RankingRedisCache) Set(ctx context.Context, key domain.RankingKey, arts []domain.Article) error {
 	for i := 0; i < len(arts); i++ {
 		arts[i].Content = ""
 	}
@@ -31,11 +36,28 @@ func (r *RankingRedisCache) Set(ctx context.Context, arts []domain.Article) erro
 	if err != nil {
 		return err
 	}
-	return r.client.Set(ctx, r.key, val, time.Minute*10).Err()
+	if key.Category == "" {
+		return r.client.Set(ctx, r.redisKey(key), val, time.Minute*10).Err()
+	}
+	// 分类的集合不过期，分类的数量有限
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, r.redisKey(key), val, time.Minute*10)
+		pipe.SAdd(ctx, r.categoriesKey(), key.Category)
+		return nil
+	})
+	return err
+}
+
+func (r *RankingRedisCache) Categories(ctx context.Context) ([]string, error) {
+	return r.client.SMembers(ctx, r.categoriesKey()).Result()
 }
 
-func (r *RankingRedisCache) Get(ctx context.Context) ([]domain.Article, error) {
-	data, err := r.client.Get(ctx, r.key).Bytes()
+func (r *RankingRedisCache) categoriesKey() string {
+	return r.key + ":categories"
+}
+
+func (r *RankingRedisCache) Get(ctx context.Context, key domain.RankingKey) ([]domain.Article, error) {
+	data, err := r.client.Get(ctx, r.redisKey(key)).Bytes()
 	if err != nil {
 		return nil, err
 	}
@@ -43,3 +65,8 @@ func (r *RankingRedisCache) Get(ctx context.Context) ([]domain.Article, error) {
 	err = json.Unmarshal(data, &res)
 	return res, err
 }
+
+// redisKey 例如 ranking:top_n:all:7d 和 ranking:top_n:category:go:24h
+func (r *RankingRedisCache) redisKey(key domain.RankingKey) string {
+	return r.key + ":" + key.String()
+}
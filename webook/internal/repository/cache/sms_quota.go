@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var ErrSMSQuotaExceeded = errors.New("短信额度已经用完")
+
+//go:embed lua/incr_quota.lua
+var luaIncrQuota string
+
+// SMSQuotaCache 按天统计每个令牌发送的短信数量
+type SMSQuotaCache interface {
+	// Incr 当天的数量加上 n 之后超过 quota 返回 ErrSMSQuotaExceeded，这个时候不会计数
+	Incr(ctx context.Context, tokenId int64, day time.Time, n, quota int) error
+}
+
+type RedisSMSQuotaCache struct {
+	client redis.Cmdable
+}
+
+func NewRedisSMSQuotaCache(client redis.Cmdable) SMSQuotaCache {
+	return &RedisSMSQuotaCache{
+		client: client,
+	}
+}
+
+func (c *RedisSMSQuotaCache) Incr(ctx context.Context, tokenId int64, day time.Time, n, quota int) error {
+	res, err := c.client.Eval(ctx, luaIncrQuota, []string{c.key(tokenId, day)},
+		n, quota, int64((time.Hour * 25).Seconds())).Int()
+	if err != nil {
+		return err
+	}
+	if res == -1 {
+		return ErrSMSQuotaExceeded
+	}
+	return nil
+}
+
+func (c *RedisSMSQuotaCache) key(tokenId int64, day time.Time) string {
+	return fmt.Sprintf("sms_quota:token:%d:%s", tokenId, day.Format("20060102"))
+}
@@ -9,9 +9,11 @@ type Article struct {
 	Content string `gorm:"type=BLOB" bson:"content,omitempty"`
 	// 作者
 	AuthorId int64 `gorm:"index" bson:"author_id,omitempty"`
-	Status   uint8 `bson:"status,omitempty"`
-	Ctime    int64 `bson:"ctime,omitempty"`
-	Utime    int64 `bson:"utime,omitempty"`
+	// 分类，热榜按照分类计算。热榜扫描所有文章再按照分类分组，没有按照分类查询，不需要索引
+	Category string `gorm:"type:varchar(64)" bson:"category,omitempty"`
+	Status   uint8  `bson:"status,omitempty"`
+	Ctime    int64  `bson:"ctime,omitempty"`
+	Utime    int64  `bson:"utime,omitempty"`
 }
 
 // PublishedArticle 衍生类型，偷个懒
@@ -3,6 +3,7 @@ package article
 import (
 	"context"
 	"fmt"
+	"go-basic/webook/internal/domain"
 	"time"
 
 	"gorm.io/gorm"
@@ -64,10 +65,11 @@ func (dao *GORMArticleDAO) Sync(ctx context.Context, art Article) (int64, error)
 		// ID 冲突的时候。实际上，在 MYSQL 里面你写不写都可以
 		Columns: []clause.Column{{Name: "id"}},
 		DoUpdates: clause.Assignments(map[string]interface{}{
-			"title":   art.Title,
-			"content": art.Content,
-			"status":  art.Status,
-			"utime":   now,
+			"title":    art.Title,
+			"content":  art.Content,
+			"category": art.Category,
+			"status":   art.Status,
+			"utime":    now,
 		}),
 	}).Create(&publishArt).Error
 	if err != nil {
@@ -85,10 +87,11 @@ func (dao *GORMArticleDAO) Upsert(ctx context.Context, art PublishedArticle) err
 	err := dao.db.Clauses(clause.OnConflict{
 		// MySQL 只会关心这里
 		DoUpdates: clause.Assignments(map[string]interface{}{
-			"title":   art.Title,
-			"content": art.Content,
-			"utime":   now,
-			"status":  art.Status,
+			"title":    art.Title,
+			"content":  art.Content,
+			"category": art.Category,
+			"utime":    now,
+			"status":   art.Status,
 		}),
 	}).Create(&art).Error
 	return err
@@ -107,10 +110,11 @@ func (dao *GORMArticleDAO) UpdateById(ctx context.Context, art Article) error {
 	art.Utime = now
 	// 显示指定更新字段，避免更新了不该更新的字段
 	res := dao.db.WithContext(ctx).Model(&Article{}).Where("id=? AND author_id=?", art.Id, art.AuthorId).Updates(map[string]any{
-		"title":   art.Title,
-		"content": art.Content,
-		"utime":   art.Utime,
-		"status":  art.Status,
+		"title":    art.Title,
+		"content":  art.Content,
+		"category": art.Category,
+		"utime":    art.Utime,
+		"status":   art.Status,
 	})
 	// 检查是否有更新到数据
 	if res.Error != nil {
@@ -136,8 +140,18 @@ func (dao *GORMArticleDAO) GetPubById(ctx context.Context, id int64) (PublishedA
 	return PublishedArticle{}, nil
 }
 
+func (dao *GORMArticleDAO) GetPubByIds(ctx context.Context, ids []int64) ([]PublishedArticle, error) {
+	var res []PublishedArticle
+	err := dao.db.WithContext(ctx).Where("id IN ? AND status = ?", ids, domain.ArticleStatusPublished.ToUint8()).
+		Find(&res).Error
+	return res, err
+}
+
 func (dao *GORMArticleDAO) ListPub(ctx context.Context, start time.Time, offset int, limit int) ([]Article, error) {
 	var res []Article
-	err := dao.db.WithContext(ctx).Where("utime<?", start.UnixMilli()).Order("utime DESC").Offset(offset).Limit(limit).Find(&res).Error
+	// 只查询线上库里面已经发表的文章，按照第一次发表的时间倒序，编辑不会改变顺序
+	err := dao.db.WithContext(ctx).Model(&PublishedArticle{}).
+		Where("ctime<? AND status=?", start.UnixMilli(), domain.ArticleStatusPublished.ToUint8()).
+		Order("ctime DESC").Offset(offset).Limit(limit).Find(&res).Error
 	return res, err
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"go-basic/webook/internal/domain"
 	"time"
 
 	"github.com/bwmarrin/snowflake"
@@ -47,6 +48,7 @@ func (m *MongoDBDAO) Insert(ctx context.Context, art Article) (int64, error) {
 		"id":        art.Id,
 		"title":     art.Title,
 		"content":   art.Content,
+		"category":  art.Category,
 		"author_id": art.AuthorId,
 		"status":    art.Status,
 		"ctime":     art.Ctime,
@@ -63,10 +65,11 @@ func (m *MongoDBDAO) Insert(ctx context.Context, art Article) (int64, error) {
 func (m *MongoDBDAO) UpdateById(ctx context.Context, art Article) error {
 	filter := bson.M{"id": art.Id, "author_id": art.AuthorId}
 	update := bson.D{bson.E{"$set", bson.M{
-		"title":   art.Title,
-		"content": art.Content,
-		"utime":   time.Now().UnixMilli(),
-		"status":  art.Status,
+		"title":    art.Title,
+		"content":  art.Content,
+		"category": art.Category,
+		"utime":    time.Now().UnixMilli(),
+		"status":   art.Status,
 	}}}
 
 	res, err := m.col.UpdateOne(ctx, filter, update)
@@ -110,6 +113,7 @@ func (m *MongoDBDAO) Sync(ctx context.Context, art Article) (int64, error) {
 			"id":        id,
 			"title":     art.Title,
 			"content":   art.Content,
+			"category":  art.Category,
 			"author_id": art.AuthorId,
 			"status":    art.Status,
 			"utime":     now,
@@ -136,6 +140,7 @@ func (m *MongoDBDAO) Upsert(ctx context.Context, art PublishedArticle) error {
 			"id":        art.Id,
 			"title":     art.Title,
 			"content":   art.Content,
+			"category":  art.Category,
 			"author_id": art.AuthorId,
 			"utime":     now,
 			"status":    art.Status,
@@ -165,6 +170,20 @@ func (m *MongoDBDAO) GetPubById(ctx context.Context, id int64) (PublishedArticle
 	panic("implement me")
 }
 
+func (m *MongoDBDAO) GetPubByIds(ctx context.Context, ids []int64) ([]PublishedArticle, error) {
+	filter := bson.M{
+		"id":     bson.M{"$in": ids},
+		"status": domain.ArticleStatusPublished.ToUint8(),
+	}
+	cursor, err := m.liveCol.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	var res []PublishedArticle
+	err = cursor.All(ctx, &res)
+	return res, err
+}
+
 func (m *MongoDBDAO) GetByAuthor(ctx context.Context, author int64, offset, limit int) ([]Article, error) {
 	//TODO implement me
 	panic("implement me")
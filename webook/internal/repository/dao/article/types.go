@@ -14,6 +14,8 @@ type ArticleDAO interface {
 	GetByAuthor(ctx context.Context, author int64, offset, limit int) ([]Article, error)
 	GetById(ctx context.Context, id int64) (Article, error)
 	GetPubById(ctx context.Context, id int64) (PublishedArticle, error)
+	// GetPubByIds 批量查询已发表的文章，不存在或者已经撤回的文章不会返回
+	GetPubByIds(ctx context.Context, ids []int64) ([]PublishedArticle, error)
 	Sync(ctx context.Context, art Article) (int64, error)
 	SyncStatus(ctx context.Context, author, id int64, status uint8) error
 	ListPub(ctx context.Context, start time.Time, offset int, limit int) ([]Article, error)
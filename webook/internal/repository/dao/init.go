@@ -15,6 +15,8 @@ func InitTable(db *gorm.DB) error {
 	return db.AutoMigrate(
 		&User{},
 		&SMSAysncReq{},
+		&SMSBizToken{},
+		&SMSDelivery{},
 		&article.Article{},
 		&article.PublishedArticle{},
 		&Interactive{},
@@ -22,6 +24,10 @@ func InitTable(db *gorm.DB) error {
 		&UserCollectionBiz{},
 		&UserRecordBiz{},
 		&Job{},
+		&JobRun{},
+		&JobShard{},
+		&WorkflowRun{},
+		&WorkflowNodeRun{},
 	)
 }
 
@@ -19,8 +19,17 @@ type InteractiveDAO interface {
 	GetLikeInfo(ctx context.Context, biz string, id int64, uid int64) (UserLikeBiz, error)
 	GetCollectInfo(ctx context.Context, biz string, id int64, uid int64) (UserCollectionBiz, error)
 	Get(ctx context.Context, biz string, id int64) (Interactive, error)
+	GetByIds(ctx context.Context, biz string, ids []int64) ([]Interactive, error)
 	BatchIncrReadCnt(ctx context.Context, biz []string, bizIds []int64) error
 	AddRecord(ctx context.Context, uid int64, aid int64) error
+	// cursor 是上一页最后一条记录的 id，为 0 表示从头开始
+	ListLikesByBiz(ctx context.Context, biz string, bizId int64, cursorUtime, cursorId int64, limit int) ([]UserLikeBiz, error)
+	ListLikesByUid(ctx context.Context, uid int64, cursor int64, limit int) ([]UserLikeBiz, error)
+	// 下面是对账使用的方法
+	ListByMinId(ctx context.Context, bizs []string, minId int64, limit int) ([]Interactive, error)
+	CountLikeByBizIds(ctx context.Context, biz string, bizIds []int64) (map[int64]int64, error)
+	CountCollectByBizIds(ctx context.Context, biz string, bizIds []int64) (map[int64]int64, error)
+	UpdateCntIfUnchanged(ctx context.Context, src Interactive, likeCnt int64, collectCnt int64) (bool, error)
 }
 
 type GORMInteractiveDAO struct {
@@ -70,6 +79,14 @@ func (dao *GORMInteractiveDAO) Get(ctx context.Context, biz string, id int64) (I
 	return res, err
 }
 
+func (dao *GORMInteractiveDAO) GetByIds(ctx context.Context, biz string, ids []int64) ([]Interactive, error) {
+	var res []Interactive
+	err := dao.db.WithContext(ctx).
+		Where("biz = ? AND biz_id IN ?", biz, ids).
+		Find(&res).Error
+	return res, err
+}
+
 func (dao *GORMInteractiveDAO) InsertCollectionBiz(ctx context.Context, cb UserCollectionBiz) error {
 	now := time.Now().UnixMilli()
 	cb.Ctime = now
@@ -164,6 +181,86 @@ func (dao *GORMInteractiveDAO) InsertLikeInfo(ctx context.Context, biz string, i
 	})
 }
 
+// ListLikesByBiz 某个资源的点赞记录，按照点赞的时间倒序，时间相同的按照 id 倒序。
+// 游标是上一页最后一条记录的 utime 和 id，cursorId 为 0 表示第一页
+func (dao *GORMInteractiveDAO) ListLikesByBiz(ctx context.Context, biz string, bizId int64,
+	cursorUtime, cursorId int64, limit int) ([]UserLikeBiz, error) {
+	var res []UserLikeBiz
+	db := dao.db.WithContext(ctx).Where("biz = ? AND biz_id = ? AND status = ?", biz, bizId, 1)
+	if cursorId > 0 {
+		db = db.Where("utime < ? OR (utime = ? AND id < ?)", cursorUtime, cursorUtime, cursorId)
+	}
+	err := db.Order("utime DESC, id DESC").Limit(limit).Find(&res).Error
+	return res, err
+}
+
+// ListLikesByUid 某个用户点赞过的所有资源，不区分业务
+func (dao *GORMInteractiveDAO) ListLikesByUid(ctx context.Context, uid int64, cursor int64, limit int) ([]UserLikeBiz, error) {
+	var res []UserLikeBiz
+	db := dao.db.WithContext(ctx).Where("uid = ? AND status = ?", uid, 1)
+	if cursor > 0 {
+		db = db.Where("id < ?", cursor)
+	}
+	err := db.Order("id DESC").Limit(limit).Find(&res).Error
+	return res, err
+}
+
+// ListByMinId 按照主键分批扫描计数表，bizs 为空表示不过滤业务
+func (dao *GORMInteractiveDAO) ListByMinId(ctx context.Context, bizs []string, minId int64, limit int) ([]Interactive, error) {
+	var res []Interactive
+	db := dao.db.WithContext(ctx).Where("id > ?", minId)
+	if len(bizs) > 0 {
+		db = db.Where("biz IN ?", bizs)
+	}
+	err := db.Order("id ASC").Limit(limit).Find(&res).Error
+	return res, err
+}
+
+// CountLikeByBizIds 从点赞明细中统计有效的点赞数
+func (dao *GORMInteractiveDAO) CountLikeByBizIds(ctx context.Context, biz string, bizIds []int64) (map[int64]int64, error) {
+	return dao.countByBizIds(dao.db.WithContext(ctx).Model(&UserLikeBiz{}).
+		Where("biz = ? AND biz_id IN ? AND status = ?", biz, bizIds, 1))
+}
+
+// CountCollectByBizIds 从收藏明细中统计收藏数
+func (dao *GORMInteractiveDAO) CountCollectByBizIds(ctx context.Context, biz string, bizIds []int64) (map[int64]int64, error) {
+	return dao.countByBizIds(dao.db.WithContext(ctx).Model(&UserCollectionBiz{}).
+		Where("biz = ? AND biz_id IN ?", biz, bizIds))
+}
+
+func (dao *GORMInteractiveDAO) countByBizIds(db *gorm.DB) (map[int64]int64, error) {
+	type bizCnt struct {
+		BizId int64
+		Cnt   int64
+	}
+	var cnts []bizCnt
+	err := db.Select("biz_id, COUNT(*) AS cnt").Group("biz_id").Scan(&cnts).Error
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[int64]int64, len(cnts))
+	for _, c := range cnts {
+		res[c.BizId] = c.Cnt
+	}
+	return res, nil
+}
+
+// UpdateCntIfUnchanged 修正点赞数和收藏数，只有计数和读取时一致才会更新，
+// 避免覆盖对账期间用户新的点赞和收藏，返回值表示是否真的更新了
+func (dao *GORMInteractiveDAO) UpdateCntIfUnchanged(ctx context.Context, src Interactive, likeCnt int64, collectCnt int64) (bool, error) {
+	res := dao.db.WithContext(ctx).Model(&Interactive{}).
+		Where("id = ? AND like_cnt = ? AND collect_cnt = ?", src.Id, src.LikeCnt, src.CollectCnt).
+		Updates(map[string]any{
+			"like_cnt":    likeCnt,
+			"collect_cnt": collectCnt,
+			"utime":       time.Now().UnixMilli(),
+		})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
 func (dao *GORMInteractiveDAO) IncrReadCnt(ctx context.Context, biz string, bizId int64) error {
 	// 这里是一个upsert操作，如果没有记录则插入，有记录则更新
 	now := time.Now().UnixMilli()
@@ -195,11 +292,13 @@ type Interactive struct {
 
 type UserLikeBiz struct {
 	Id    int64  `gorm:"primaryKey,autoIncrement"`
-	Biz   string `gorm:"type:varchar(128);index:uid_biz_id_type,unique"`
-	BizId int64  `gorm:"index:uid_biz_id_type,unique"`
-	Uid   int64  `gorm:"index:uid_biz_id_type,unique"`
+	Biz   string `gorm:"type:varchar(128);index:uid_biz_id_type,unique;index:biz_id_utime"`
+	BizId int64  `gorm:"index:uid_biz_id_type,unique;index:biz_id_utime"`
+	// 单独的 uid 索引用于查询用户点赞过的资源
+	Uid   int64 `gorm:"index:uid_biz_id_type,unique;index"`
 	Ctime int64
-	Utime int64
+	// biz_id_utime 用于按照点赞时间查询某个资源的点赞记录
+	Utime int64 `gorm:"index:biz_id_utime"`
 	// 软删除，是存储状态，业务层面没有感知
 	Status int8
 }
@@ -0,0 +1,70 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gormMysql "gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func TestGORMInteractiveDAO_ListLikesByBiz(t *testing.T) {
+	testCases := []struct {
+		name        string
+		mock        func(t *testing.T) *sql.DB
+		cursorUtime int64
+		cursorId    int64
+
+		wantRes []UserLikeBiz
+	}{
+		{
+			name: "第一页按照点赞时间倒序",
+			mock: func(t *testing.T) *sql.DB {
+				mockDB, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectQuery("SELECT \\* FROM `user_like_bizs` WHERE .* ORDER BY utime DESC, id DESC LIMIT \\?").
+					WithArgs("article", 1, 1, 2).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "uid", "utime"}).AddRow(3, 11, 90))
+				return mockDB
+			},
+			wantRes: []UserLikeBiz{{Id: 3, Uid: 11, Utime: 90}},
+		},
+		{
+			// 取消之后重新点赞的记录 id 不变，只看 id 会漏掉或者重复
+			name: "按照点赞时间和 id 翻页",
+			mock: func(t *testing.T) *sql.DB {
+				mockDB, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectQuery("SELECT \\* FROM `user_like_bizs` WHERE .* AND \\(utime < \\? OR \\(utime = \\? AND id < \\?\\)\\) "+
+					"ORDER BY utime DESC, id DESC LIMIT \\?").
+					WithArgs("article", 1, 1, 100, 100, 8, 2).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "uid", "utime"}).AddRow(3, 11, 90))
+				return mockDB
+			},
+			cursorUtime: 100,
+			cursorId:    8,
+			wantRes:     []UserLikeBiz{{Id: 3, Uid: 11, Utime: 90}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, err := gorm.Open(gormMysql.New(gormMysql.Config{
+				Conn:                      tc.mock(t),
+				SkipInitializeWithVersion: true,
+			}), &gorm.Config{
+				DisableAutomaticPing:   true,
+				SkipDefaultTransaction: true,
+			})
+			require.NoError(t, err)
+			d := NewGORMInteractiveDAO(db)
+			res, err := d.ListLikesByBiz(context.Background(), "article", 1, tc.cursorUtime, tc.cursorId, 2)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantRes, res)
+		})
+	}
+}
@@ -2,17 +2,50 @@ package dao
 
 import (
 	"context"
+	"errors"
 	"time"
 
+	"github.com/go-sql-driver/mysql"
 	"gorm.io/gorm"
 )
 
+var (
+	ErrJobDuplicateName = errors.New("任务名称已存在")
+	ErrJobNotFound      = gorm.ErrRecordNotFound
+	// ErrJobLeaseLost 版本号对不上，任务已经被别的实例重新抢走了
+	ErrJobLeaseLost = errors.New("任务租约已经失效")
+)
+
 type JobDAO interface {
-	Preempt(ctx context.Context) (Job, error)
+	// Preempt 抢占到期的任务，或者 utime 超过 leaseTimeout 没有更新的执行中任务。
+	// 返回的 Status 是抢占之前的状态，JobStatusRunning 说明是从别的实例手里收回来的
+	// executors 不为空的时候只抢这些执行器的任务
+	Preempt(ctx context.Context, leaseTimeout time.Duration, executors []string) (Job, error)
+	// 下面这些是持有任务的实例调用的，版本号对不上说明已经丢了租约，返回 ErrJobLeaseLost
 	Release(ctx context.Context, id int64, version int) error
-	UpdateUtime(ctx context.Context, id int64) error
-	UpdateNextTime(ctx context.Context, id int64, next time.Time) error
+	UpdateUtime(ctx context.Context, id int64, version int) error
+	UpdateNextTime(ctx context.Context, id int64, version int, next time.Time) error
 	Stop(ctx context.Context, id int64) error
+	// Cancelled 管理接口是不是要求取消这一次执行
+	Cancelled(ctx context.Context, id int64, version int) (bool, error)
+
+	// 下面是管理接口使用的
+	Insert(ctx context.Context, j Job) (int64, error)
+	Update(ctx context.Context, j Job) error
+	Delete(ctx context.Context, id int64) error
+	FindById(ctx context.Context, id int64) (Job, error)
+	List(ctx context.Context, offset, limit int) ([]Job, error)
+	// Resume 只有暂停或者死掉的任务可以恢复
+	Resume(ctx context.Context, id int64, next time.Time) error
+	// Trigger 只有等待中的任务可以立刻执行
+	Trigger(ctx context.Context, id int64) error
+	// Cancel 只有执行中的任务可以取消，取消的是当前这一次执行
+	Cancel(ctx context.Context, id int64) error
+
+	// Retry 失败之后在 next 重试，不重置尝试次数
+	Retry(ctx context.Context, id int64, version int, next time.Time) error
+	// MarkDead 只有还持有任务的实例可以标记
+	MarkDead(ctx context.Context, id int64, version int) error
 }
 
 type GORMJobDAO struct {
@@ -25,18 +58,62 @@ func NewGORMJobDAO(db *gorm.DB) JobDAO {
 	}
 }
 
-func (g *GORMJobDAO) UpdateUtime(ctx context.Context, id int64) error {
-	return g.db.WithContext(ctx).Model(&Job{}).
-		Where("id =?", id).Updates(map[string]any{
+// UpdateUtime 续约，utime 就是租约的心跳。
+// 只按照版本号判断，执行期间被暂停的任务状态已经不是执行中了，这一次还是要执行完
+func (g *GORMJobDAO) UpdateUtime(ctx context.Context, id int64, version int) error {
+	res := g.db.WithContext(ctx).Model(&Job{}).
+		Where("id = ? AND version = ?", id, version).Updates(map[string]any{
 		"utime": time.Now().UnixMilli(),
-	}).Error
+	})
+	return fenced(res)
 }
 
-func (g *GORMJobDAO) UpdateNextTime(ctx context.Context, id int64, next time.Time) error {
-	return g.db.WithContext(ctx).Model(&Job{}).
-		Where("id = ?", id).Updates(map[string]any{
+// UpdateNextTime 正常调度下一次执行，尝试次数重新计数
+func (g *GORMJobDAO) UpdateNextTime(ctx context.Context, id int64, version int, next time.Time) error {
+	res := g.db.WithContext(ctx).Model(&Job{}).
+		Where("id = ? AND version = ?", id, version).Updates(map[string]any{
 		"next_time": next.UnixMilli(),
-	}).Error
+		"attempt":   0,
+	})
+	return fenced(res)
+}
+
+func (g *GORMJobDAO) Retry(ctx context.Context, id int64, version int, next time.Time) error {
+	res := g.db.WithContext(ctx).Model(&Job{}).
+		Where("id = ? AND version = ?", id, version).Updates(map[string]any{
+		"next_time": next.UnixMilli(),
+		"utime":     time.Now().UnixMilli(),
+	})
+	return fenced(res)
+}
+
+// fenced 按照版本号更新，一行都没有更新到说明任务已经被别人抢走或者删掉了
+func fenced(res *gorm.DB) error {
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrJobLeaseLost
+	}
+	return nil
+}
+
+// MarkDead 执行期间被暂停的任务保留暂停的状态，恢复的时候会重新计数
+func (g *GORMJobDAO) MarkDead(ctx context.Context, id int64, version int) error {
+	res := g.db.WithContext(ctx).Model(&Job{}).
+		Where("id = ? AND version = ?", id, version).Updates(map[string]any{
+		"status": gorm.Expr("CASE WHEN status = ? THEN ? ELSE status END",
+			JobStatusRunning, JobStatusDead),
+		"utime": time.Now().UnixMilli(),
+	})
+	return fenced(res)
+}
+
+func (g *GORMJobDAO) Cancelled(ctx context.Context, id int64, version int) (bool, error) {
+	var cnt int64
+	err := g.db.WithContext(ctx).Model(&Job{}).
+		Where("id = ? AND cancel_version = ?", id, version).Count(&cnt).Error
+	return cnt > 0, err
 }
 
 func (g *GORMJobDAO) Stop(ctx context.Context, id int64) error {
@@ -48,39 +125,53 @@ func (g *GORMJobDAO) Stop(ctx context.Context, id int64) error {
 
 func (g *GORMJobDAO) Release(ctx context.Context, id int64, version int) error {
 	now := time.Now().UnixMilli()
-	// 释放任务，检查任务ID、状态和版本号是否匹配
-	result := g.db.WithContext(ctx).Model(&Job{}).Where("id = ? AND status = ? AND version = ?",
-		id, JobStatusRunning, version).Updates(map[string]any{
-		"status":  JobStatusWaiting,
+	// 释放任务，检查任务ID和版本号是否匹配。
+	// 执行期间任务可能被暂停或者标记为死掉了，这时候保留原来的状态
+	result := g.db.WithContext(ctx).Model(&Job{}).Where("id = ? AND version = ?",
+		id, version).Updates(map[string]any{
+		"status": gorm.Expr("CASE WHEN status = ? THEN ? ELSE status END",
+			JobStatusRunning, JobStatusWaiting),
 		"utime":   now,
 		"version": gorm.Expr("version + 1"),
 	})
-	if result.Error != nil {
-		return result.Error
-	}
-	// 检查是否有行被更新，如果没有，说明任务不存在或状态/版本不匹配
-	if result.RowsAffected == 0 {
-		return gorm.ErrRecordNotFound
-	}
-	return nil
+	return fenced(result)
 }
 
-// Preempt 抢占任务
-func (g *GORMJobDAO) Preempt(ctx context.Context) (Job, error) {
-	db := g.db.WithContext(ctx).Model(&Job{})
+// preemptBatchSize 每次抢占查出来这么多候选任务，在里面挑一个最公平的
+const preemptBatchSize = 32
+
+// Preempt 抢占任务，优先级高的先抢，同样的优先级里面挑正在执行的任务最少的分组
+func (g *GORMJobDAO) Preempt(ctx context.Context, leaseTimeout time.Duration, executors []string) (Job, error) {
 	for {
+		// 每一轮都要用新的查询，不然上一轮的条件会叠加进来
+		db := g.db.WithContext(ctx).Model(&Job{})
 		now := time.Now().UnixMilli()
-		var j Job
-		// 查询下一个需要执行的任务
-		err := db.Where("status = ? AND next_time <= ?", JobStatusWaiting, now).First(&j).Error
+		// 查询需要执行的任务。
+		// 持有任务的实例崩溃了就不会再续约，utime 太久没有更新的任务也可以抢
+		db = db.Where("((status = ? AND next_time <= ?) OR (status = ? AND utime <= ?))",
+			JobStatusWaiting, now, JobStatusRunning, now-leaseTimeout.Milliseconds())
+		if len(executors) > 0 {
+			db = db.Where("executor IN ?", executors)
+		}
+		var candidates []Job
+		err := db.Order("priority DESC, next_time ASC").Limit(preemptBatchSize).Find(&candidates).Error
+		if err != nil {
+			return Job{}, err
+		}
+		if len(candidates) == 0 {
+			return Job{}, ErrJobNotFound
+		}
+		j, err := g.fairest(ctx, candidates)
 		if err != nil {
 			return Job{}, err
 		}
 		// 抢占任务
-		res := db.Where("id = ? AND version = ?", j.Id, j.Version).Updates(map[string]any{
+		// 抢占的时候就增加尝试次数，执行到一半崩溃了也算一次
+		res := g.db.WithContext(ctx).Model(&Job{}).Where("id = ? AND version = ?", j.Id, j.Version).Updates(map[string]any{
 			"status":  JobStatusRunning,
 			"utime":   now,
 			"version": j.Version + 1,
+			"attempt": j.Attempt + 1,
 		})
 		if res.Error != nil {
 			return Job{}, res.Error
@@ -89,10 +180,183 @@ func (g *GORMJobDAO) Preempt(ctx context.Context) (Job, error) {
 			// 说明任务已经被抢占, 继续下一轮
 			continue
 		}
+		// 抢占的时候版本号已经加一了，释放的时候要用新的版本号
+		j.Version = j.Version + 1
+		j.Attempt = j.Attempt + 1
 		return j, nil
 	}
 }
 
+// fairest 在优先级最高的候选任务里面，挑所在分组正在执行的任务最少的那个，
+// 避免一个分组同时到期的大量任务把别的分组饿死。candidates 已经按照优先级和 next_time 排好序了
+func (g *GORMJobDAO) fairest(ctx context.Context, candidates []Job) (Job, error) {
+	top := candidates[0].Priority
+	groups := make([]string, 0, len(candidates))
+	seen := make(map[string]struct{}, len(candidates))
+	for _, c := range candidates {
+		if c.Priority != top {
+			break
+		}
+		if _, ok := seen[c.GroupName]; !ok {
+			seen[c.GroupName] = struct{}{}
+			groups = append(groups, c.GroupName)
+		}
+	}
+	if len(groups) == 1 {
+		return candidates[0], nil
+	}
+	var counts []struct {
+		GroupName string
+		Cnt       int
+	}
+	err := g.db.WithContext(ctx).Model(&Job{}).Select("group_name, COUNT(*) AS cnt").
+		Where("status = ? AND group_name IN ?", JobStatusRunning, groups).
+		Group("group_name").Scan(&counts).Error
+	if err != nil {
+		return Job{}, err
+	}
+	running := make(map[string]int, len(counts))
+	for _, c := range counts {
+		running[c.GroupName] = c.Cnt
+	}
+	best := candidates[0]
+	for _, c := range candidates[1:] {
+		if c.Priority != top {
+			break
+		}
+		// 同样少的时候保留 next_time 更早的
+		if running[c.GroupName] < running[best.GroupName] {
+			best = c
+		}
+	}
+	return best, nil
+}
+
+func (g *GORMJobDAO) Insert(ctx context.Context, j Job) (int64, error) {
+	now := time.Now().UnixMilli()
+	j.Ctime = now
+	j.Utime = now
+	err := g.db.WithContext(ctx).Create(&j).Error
+	if mysqlErr, ok := err.(*mysql.MySQLError); ok {
+		const uniqueConflictErrNo uint16 = 1062
+		if mysqlErr.Number == uniqueConflictErrNo {
+			return 0, ErrJobDuplicateName
+		}
+	}
+	return j.Id, err
+}
+
+// Update 修改任务的定义，不修改状态和版本号，正在执行的任务不受影响
+func (g *GORMJobDAO) Update(ctx context.Context, j Job) error {
+	res := g.db.WithContext(ctx).Model(&Job{}).Where("id = ?", j.Id).Updates(map[string]any{
+		"name":       j.Name,
+		"cron":       j.Cron,
+		"timezone":   j.Timezone,
+		"misfire":    j.Misfire,
+		"executor":   j.Executor,
+		"cfg":        j.Cfg,
+		"priority":   j.Priority,
+		"group_name": j.GroupName,
+		"next_time":  j.NextTime,
+		"utime":      time.Now().UnixMilli(),
+
+		"mode":               j.Mode,
+		"shard_count":        j.ShardCount,
+		"max_attempts":       j.MaxAttempts,
+		"backoff":            j.Backoff,
+		"retry_interval":     j.RetryInterval,
+		"max_retry_interval": j.MaxRetryInterval,
+		"timeout":            j.Timeout,
+	})
+	if mysqlErr, ok := res.Error.(*mysql.MySQLError); ok {
+		const uniqueConflictErrNo uint16 = 1062
+		if mysqlErr.Number == uniqueConflictErrNo {
+			return ErrJobDuplicateName
+		}
+	}
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrJobNotFound
+	}
+	return nil
+}
+
+func (g *GORMJobDAO) Delete(ctx context.Context, id int64) error {
+	res := g.db.WithContext(ctx).Where("id = ?", id).Delete(&Job{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrJobNotFound
+	}
+	return nil
+}
+
+func (g *GORMJobDAO) FindById(ctx context.Context, id int64) (Job, error) {
+	var j Job
+	err := g.db.WithContext(ctx).Where("id = ?", id).First(&j).Error
+	return j, err
+}
+
+func (g *GORMJobDAO) List(ctx context.Context, offset, limit int) ([]Job, error) {
+	var res []Job
+	err := g.db.WithContext(ctx).Order("id DESC").
+		Offset(offset).Limit(limit).Find(&res).Error
+	return res, err
+}
+
+func (g *GORMJobDAO) Resume(ctx context.Context, id int64, next time.Time) error {
+	res := g.db.WithContext(ctx).Model(&Job{}).
+		Where("id = ? AND status IN ?", id, []int{JobStatusPaused, JobStatusDead}).Updates(map[string]any{
+		"status":    JobStatusWaiting,
+		"next_time": next.UnixMilli(),
+		"attempt":   0,
+		"utime":     time.Now().UnixMilli(),
+	})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrJobNotFound
+	}
+	return nil
+}
+
+func (g *GORMJobDAO) Trigger(ctx context.Context, id int64) error {
+	now := time.Now().UnixMilli()
+	res := g.db.WithContext(ctx).Model(&Job{}).
+		Where("id = ? AND status = ?", id, JobStatusWaiting).Updates(map[string]any{
+		"next_time": now,
+		"utime":     now,
+	})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrJobNotFound
+	}
+	return nil
+}
+
+// Cancel 记下当前执行的版本号，持有任务的实例发现版本号对上了就会停下来。
+// 下一次抢占的时候版本号就变了，不会影响后面的执行
+func (g *GORMJobDAO) Cancel(ctx context.Context, id int64) error {
+	res := g.db.WithContext(ctx).Model(&Job{}).
+		Where("id = ? AND status = ?", id, JobStatusRunning).Updates(map[string]any{
+		"cancel_version": gorm.Expr("version"),
+		"utime":          time.Now().UnixMilli(),
+	})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrJobNotFound
+	}
+	return nil
+}
+
 type Job struct {
 	Id int64 `gorm:"primaryKey,autoIncrement"`
 	// 用于乐观锁，防止并发问题
@@ -101,12 +365,36 @@ type Job struct {
 	// 用状态来标志哪些任务可以抢，那些任务已经被抢，哪些任务永远不会执行
 	Status int
 	Cron   string
-	Name   string `gorm:"unique"`
+	// 为空表示服务器的时区
+	Timezone string `gorm:"type:varchar(64)"`
+	// 错过执行时间之后的处理方式
+	Misfire uint8
+	Name    string `gorm:"unique"`
 	// 定时任务，下一次被调度的时间
 	NextTime int64 `gorm:"index"`
 	Executor string
-	Ctime    int64
-	Utime    int64
+	// 数字越大越先执行
+	Priority int
+	// 任务所属的分组，例如业务方。同样优先级的任务在分组之间公平地抢占
+	GroupName string `gorm:"type:varchar(64)"`
+	// 执行方式，分片模式下 ShardCount 是分片的数量
+	Mode       uint8
+	ShardCount int
+
+	// 重试策略，MaxAttempts 为 0 表示不重试
+	MaxAttempts      int
+	Backoff          uint8
+	RetryInterval    int64
+	MaxRetryInterval int64
+	// 连续失败了多少次
+	Attempt int
+	// 单次执行的超时时间，毫秒，0 表示不限制
+	Timeout int64
+	// 要求取消的那一次执行的版本号
+	CancelVersion int
+
+	Ctime int64
+	Utime int64
 }
 
 const (
@@ -115,4 +403,6 @@ const (
 	JobStatusRunning
 	// 任务暂停调度
 	JobStatusPaused
+	// 重试耗尽，需要人工恢复
+	JobStatusDead
 )
@@ -0,0 +1,90 @@
+package dao
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type JobRunDAO interface {
+	Insert(ctx context.Context, r JobRun) (int64, error)
+	// Finish 只更新还在执行的记录
+	Finish(ctx context.Context, id int64, status uint8, errMsg string, endTime int64) error
+	// Abandon 把任务所有还在执行的记录标记为放弃，返回更新的条数
+	Abandon(ctx context.Context, jobId int64, errMsg string, endTime int64) (int64, error)
+	// List status 为 0 表示不过滤
+	List(ctx context.Context, jobId int64, status uint8, offset, limit int) ([]JobRun, error)
+	// DeleteBefore 删除 start_time 早于 before 的记录，一次最多删除 limit 条
+	DeleteBefore(ctx context.Context, before int64, limit int) (int64, error)
+}
+
+type GORMJobRunDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMJobRunDAO(db *gorm.DB) JobRunDAO {
+	return &GORMJobRunDAO{
+		db: db,
+	}
+}
+
+func (g *GORMJobRunDAO) Insert(ctx context.Context, r JobRun) (int64, error) {
+	err := g.db.WithContext(ctx).Create(&r).Error
+	return r.Id, err
+}
+
+func (g *GORMJobRunDAO) Finish(ctx context.Context, id int64, status uint8, errMsg string, endTime int64) error {
+	return g.db.WithContext(ctx).Model(&JobRun{}).
+		Where("id = ? AND status = ?", id, JobRunStatusRunning).Updates(map[string]any{
+		"status":   status,
+		"err_msg":  errMsg,
+		"end_time": endTime,
+	}).Error
+}
+
+func (g *GORMJobRunDAO) Abandon(ctx context.Context, jobId int64, errMsg string, endTime int64) (int64, error) {
+	res := g.db.WithContext(ctx).Model(&JobRun{}).
+		Where("job_id = ? AND status = ?", jobId, JobRunStatusRunning).Updates(map[string]any{
+		"status":   JobRunStatusAbandoned,
+		"err_msg":  errMsg,
+		"end_time": endTime,
+	})
+	return res.RowsAffected, res.Error
+}
+
+func (g *GORMJobRunDAO) List(ctx context.Context, jobId int64, status uint8, offset, limit int) ([]JobRun, error) {
+	db := g.db.WithContext(ctx).Where("job_id = ?", jobId)
+	if status > 0 {
+		db = db.Where("status = ?", status)
+	}
+	var res []JobRun
+	err := db.Order("id DESC").Offset(offset).Limit(limit).Find(&res).Error
+	return res, err
+}
+
+func (g *GORMJobRunDAO) DeleteBefore(ctx context.Context, before int64, limit int) (int64, error) {
+	// MySQL 的 DELETE 支持 LIMIT，分批删除避免长事务
+	res := g.db.WithContext(ctx).Where("start_time < ?", before).Limit(limit).Delete(&JobRun{})
+	return res.RowsAffected, res.Error
+}
+
+type JobRun struct {
+	Id int64 `gorm:"primaryKey,autoIncrement"`
+	// 按照任务查询执行记录
+	JobId    int64  `gorm:"index:job_id_status"`
+	Status   uint8  `gorm:"index:job_id_status"`
+	Instance string `gorm:"type:varchar(128)"`
+	Attempt  int
+	ErrMsg   string `gorm:"type:varchar(1024)"`
+	// 清理过期记录用
+	StartTime int64 `gorm:"index"`
+	EndTime   int64
+}
+
+const (
+	JobRunStatusUnknown uint8 = iota
+	JobRunStatusRunning
+	JobRunStatusSuccess
+	JobRunStatusFailed
+	JobRunStatusAbandoned
+)
@@ -0,0 +1,159 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type JobShardDAO interface {
+	// Dispatch 取消这个任务之前没有完成的分片，然后插入新一轮的分片
+	Dispatch(ctx context.Context, jobId int64, shards []JobShard) error
+	// Preempt 抢占等待中的分片，或者租约过期的分片。广播的分片只能被目标实例抢占
+	Preempt(ctx context.Context, instance string, leaseTimeout time.Duration) (JobShard, error)
+	// 下面这些是持有分片的实例调用的，版本号对不上返回 ErrJobLeaseLost
+	UpdateUtime(ctx context.Context, id int64, version int) error
+	// Release 还没有执行完就放回去，让别的实例执行
+	Release(ctx context.Context, id int64, version int) error
+	Finish(ctx context.Context, id int64, version int, status uint8, errMsg string) error
+
+	FindByRound(ctx context.Context, jobId int64, round int) ([]JobShard, error)
+	// Cancel 取消还没有完成的分片
+	Cancel(ctx context.Context, ids []int64) error
+}
+
+type GORMJobShardDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMJobShardDAO(db *gorm.DB) JobShardDAO {
+	return &GORMJobShardDAO{
+		db: db,
+	}
+}
+
+func (g *GORMJobShardDAO) Dispatch(ctx context.Context, jobId int64, shards []JobShard) error {
+	now := time.Now().UnixMilli()
+	for i := range shards {
+		shards[i].Status = JobShardStatusWaiting
+		shards[i].Ctime = now
+		shards[i].Utime = now
+	}
+	return g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 上一轮的协调者崩溃了，剩下的分片不用再执行了
+		err := tx.Model(&JobShard{}).
+			Where("job_id = ? AND status IN ?", jobId, []uint8{JobShardStatusWaiting, JobShardStatusRunning}).
+			Updates(map[string]any{
+				"status":  JobShardStatusCancelled,
+				"version": gorm.Expr("version + 1"),
+				"utime":   now,
+			}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(&shards).Error
+	})
+}
+
+func (g *GORMJobShardDAO) Preempt(ctx context.Context, instance string, leaseTimeout time.Duration) (JobShard, error) {
+	for {
+		now := time.Now().UnixMilli()
+		var s JobShard
+		err := g.db.WithContext(ctx).
+			Where("instance IN ?", []string{"", instance}).
+			Where("status = ? OR (status = ? AND utime <= ?)",
+				JobShardStatusWaiting, JobShardStatusRunning, now-leaseTimeout.Milliseconds()).
+			First(&s).Error
+		if err != nil {
+			return JobShard{}, err
+		}
+		res := g.db.WithContext(ctx).Model(&JobShard{}).
+			Where("id = ? AND version = ?", s.Id, s.Version).Updates(map[string]any{
+			"status":  JobShardStatusRunning,
+			"utime":   now,
+			"version": s.Version + 1,
+		})
+		if res.Error != nil {
+			return JobShard{}, res.Error
+		}
+		if res.RowsAffected == 0 {
+			// 被别人抢走了，继续下一轮
+			continue
+		}
+		s.Status = JobShardStatusRunning
+		s.Version = s.Version + 1
+		return s, nil
+	}
+}
+
+func (g *GORMJobShardDAO) UpdateUtime(ctx context.Context, id int64, version int) error {
+	res := g.db.WithContext(ctx).Model(&JobShard{}).
+		Where("id = ? AND status = ? AND version = ?", id, JobShardStatusRunning, version).Updates(map[string]any{
+		"utime": time.Now().UnixMilli(),
+	})
+	return fenced(res)
+}
+
+func (g *GORMJobShardDAO) Release(ctx context.Context, id int64, version int) error {
+	res := g.db.WithContext(ctx).Model(&JobShard{}).
+		Where("id = ? AND status = ? AND version = ?", id, JobShardStatusRunning, version).Updates(map[string]any{
+		"status":  JobShardStatusWaiting,
+		"version": gorm.Expr("version + 1"),
+		"utime":   time.Now().UnixMilli(),
+	})
+	return fenced(res)
+}
+
+func (g *GORMJobShardDAO) Finish(ctx context.Context, id int64, version int, status uint8, errMsg string) error {
+	res := g.db.WithContext(ctx).Model(&JobShard{}).
+		Where("id = ? AND status = ? AND version = ?", id, JobShardStatusRunning, version).Updates(map[string]any{
+		"status":  status,
+		"err_msg": errMsg,
+		"utime":   time.Now().UnixMilli(),
+	})
+	return fenced(res)
+}
+
+func (g *GORMJobShardDAO) FindByRound(ctx context.Context, jobId int64, round int) ([]JobShard, error) {
+	var res []JobShard
+	err := g.db.WithContext(ctx).Where("job_id = ? AND round = ?", jobId, round).
+		Order("shard_index ASC").Find(&res).Error
+	return res, err
+}
+
+func (g *GORMJobShardDAO) Cancel(ctx context.Context, ids []int64) error {
+	return g.db.WithContext(ctx).Model(&JobShard{}).
+		Where("id IN ? AND status IN ?", ids, []uint8{JobShardStatusWaiting, JobShardStatusRunning}).
+		Updates(map[string]any{
+			"status":  JobShardStatusCancelled,
+			"version": gorm.Expr("version + 1"),
+			"utime":   time.Now().UnixMilli(),
+		}).Error
+}
+
+type JobShard struct {
+	Id int64 `gorm:"primaryKey,autoIncrement"`
+	// 协调者按照 job_id 和 round 查询进度
+	JobId      int64 `gorm:"uniqueIndex:job_round_shard"`
+	Round      int   `gorm:"uniqueIndex:job_round_shard"`
+	ShardIndex int   `gorm:"uniqueIndex:job_round_shard"`
+	Total      int
+	// 广播的目标实例，分片任务为空
+	Instance string `gorm:"type:varchar(128)"`
+	Status   uint8  `gorm:"index"`
+	Version  int
+	ErrMsg   string `gorm:"type:varchar(1024)"`
+	Ctime    int64
+	// 租约的心跳
+	Utime int64
+}
+
+const (
+	JobShardStatusUnknown uint8 = iota
+	JobShardStatusWaiting
+	JobShardStatusRunning
+	JobShardStatusSuccess
+	JobShardStatusFailed
+	JobShardStatusCancelled
+)
@@ -0,0 +1,162 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gormMysql "gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func TestGORMJobDAO_UpdateUtime(t *testing.T) {
+	testCases := []struct {
+		name    string
+		mock    func(t *testing.T) *sql.DB
+		wantErr error
+	}{
+		{
+			name: "续约成功",
+			mock: func(t *testing.T) *sql.DB {
+				mockDB, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectExec("UPDATE `jobs` SET .*").WillReturnResult(sqlmock.NewResult(0, 1))
+				return mockDB
+			},
+		},
+		{
+			// 暂停不改版本号，这一次要执行完，租约还要续
+			name: "执行期间被暂停，照样续约",
+			mock: func(t *testing.T) *sql.DB {
+				mockDB, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectExec("UPDATE `jobs` SET .* WHERE id = \\? AND version = \\?$").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				return mockDB
+			},
+		},
+		{
+			name: "版本号对不上，租约已经丢了",
+			mock: func(t *testing.T) *sql.DB {
+				mockDB, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectExec("UPDATE `jobs` SET .*").WillReturnResult(sqlmock.NewResult(0, 0))
+				return mockDB
+			},
+			wantErr: ErrJobLeaseLost,
+		},
+		{
+			name: "数据库错误",
+			mock: func(t *testing.T) *sql.DB {
+				mockDB, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectExec("UPDATE `jobs` SET .*").WillReturnError(errors.New("mock db error"))
+				return mockDB
+			},
+			wantErr: errors.New("mock db error"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, err := gorm.Open(gormMysql.New(gormMysql.Config{
+				Conn:                      tc.mock(t),
+				SkipInitializeWithVersion: true,
+			}), &gorm.Config{
+				DisableAutomaticPing:   true,
+				SkipDefaultTransaction: true,
+			})
+			require.NoError(t, err)
+			d := NewGORMJobDAO(db)
+			err = d.UpdateUtime(context.Background(), 1, 3)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func TestGORMJobDAO_Preempt(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(t *testing.T) *sql.DB
+
+		wantId  int64
+		wantErr error
+	}{
+		{
+			name: "只有一个分组，直接抢最早到期的",
+			mock: func(t *testing.T) *sql.DB {
+				mockDB, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectQuery("SELECT \\* FROM `jobs` WHERE .* ORDER BY priority DESC, next_time ASC").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "version", "priority", "group_name"}).
+						AddRow(1, 3, 0, "a").AddRow(2, 3, 0, "a"))
+				mock.ExpectExec("UPDATE `jobs` SET .*").WillReturnResult(sqlmock.NewResult(0, 1))
+				return mockDB
+			},
+			wantId: 1,
+		},
+		{
+			name: "挑正在执行的任务最少的分组",
+			mock: func(t *testing.T) *sql.DB {
+				mockDB, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectQuery("SELECT \\* FROM `jobs` WHERE .* ORDER BY priority DESC, next_time ASC").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "version", "priority", "group_name"}).
+						AddRow(1, 3, 5, "a").AddRow(2, 3, 5, "b").AddRow(3, 3, 0, "c"))
+				mock.ExpectQuery("SELECT group_name, COUNT\\(\\*\\) AS cnt FROM `jobs` .* GROUP BY `group_name`").
+					WillReturnRows(sqlmock.NewRows([]string{"group_name", "cnt"}).AddRow("a", 10).AddRow("b", 1))
+				mock.ExpectExec("UPDATE `jobs` SET .*").WillReturnResult(sqlmock.NewResult(0, 1))
+				return mockDB
+			},
+			wantId: 2,
+		},
+		{
+			name: "被别人抢走了，重新查一遍",
+			mock: func(t *testing.T) *sql.DB {
+				mockDB, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectQuery("SELECT \\* FROM `jobs` WHERE .*").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "version"}).AddRow(1, 3))
+				mock.ExpectExec("UPDATE `jobs` SET .*").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery("SELECT \\* FROM `jobs` WHERE .*").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "version"}).AddRow(2, 3))
+				mock.ExpectExec("UPDATE `jobs` SET .*").WillReturnResult(sqlmock.NewResult(0, 1))
+				return mockDB
+			},
+			wantId: 2,
+		},
+		{
+			name: "没有可以抢的任务",
+			mock: func(t *testing.T) *sql.DB {
+				mockDB, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectQuery("SELECT \\* FROM `jobs` WHERE .*").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "version"}))
+				return mockDB
+			},
+			wantErr: ErrJobNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, err := gorm.Open(gormMysql.New(gormMysql.Config{
+				Conn:                      tc.mock(t),
+				SkipInitializeWithVersion: true,
+			}), &gorm.Config{
+				DisableAutomaticPing:   true,
+				SkipDefaultTransaction: true,
+			})
+			require.NoError(t, err)
+			d := NewGORMJobDAO(db)
+			j, err := d.Preempt(context.Background(), time.Minute, []string{"http"})
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantId, j.Id)
+		})
+	}
+}
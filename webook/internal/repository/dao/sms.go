@@ -2,13 +2,28 @@ package dao
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+var (
+	ErrSMSNotFound = gorm.ErrRecordNotFound
+	// ErrSMSLeaseLost 版本号对不上，这条短信已经被别的实例重新抢走了
+	ErrSMSLeaseLost = errors.New("短信租约已经失效")
+)
+
 type SMSDAO interface {
 	Insert(ctx context.Context, req SMSAysncReq) error
+	// Preempt 抢占到了重试时间的短信，或者发送中但是 utime 超过 leaseTimeout 没有更新的短信
+	Preempt(ctx context.Context, leaseTimeout time.Duration) (SMSAysncReq, error)
+	// 下面这些按照版本号更新，对不上返回 ErrSMSLeaseLost
+	MarkSuccess(ctx context.Context, id int64, version int) error
+	Retry(ctx context.Context, id int64, version int, next time.Time, errMsg string) error
+	MarkFailed(ctx context.Context, id int64, version int, errMsg string) error
+	// CountPending 还没有发送成功也没有放弃的短信数量
+	CountPending(ctx context.Context) (int64, error)
 }
 
 type SMSAysncReqDAO struct {
@@ -25,23 +40,105 @@ func (dao *SMSAysncReqDAO) Insert(ctx context.Context, req SMSAysncReq) error {
 	now := time.Now().UnixMilli()
 	req.Ctime = now
 	req.Utime = now
+	req.NextTime = now
 	err := dao.db.WithContext(ctx).Create(&req).Error
 	return err
 }
 
-func (dao *SMSAysncReqDAO) FindByStatus(ctx context.Context, status int8) ([]SMSAysncReq, error) {
-	var resp []SMSAysncReq
-	err := dao.db.WithContext(ctx).Where("status = ?", status).Find(&resp).Error
-	return resp, err
+func (dao *SMSAysncReqDAO) Preempt(ctx context.Context, leaseTimeout time.Duration) (SMSAysncReq, error) {
+	for {
+		now := time.Now().UnixMilli()
+		var req SMSAysncReq
+		err := dao.db.WithContext(ctx).
+			Where("(status = ? AND next_time <= ?) OR (status = ? AND utime <= ?)",
+				SMSStatusWaiting, now, SMSStatusSending, now-leaseTimeout.Milliseconds()).
+			Order("next_time ASC").First(&req).Error
+		if err != nil {
+			return SMSAysncReq{}, err
+		}
+		// 抢占的时候就算一次重试，发送到一半崩溃了也算
+		res := dao.db.WithContext(ctx).Model(&SMSAysncReq{}).
+			Where("id = ? AND version = ?", req.Id, req.Version).Updates(map[string]any{
+			"status":    SMSStatusSending,
+			"version":   req.Version + 1,
+			"retry_cnt": req.RetryCnt + 1,
+			"utime":     now,
+		})
+		if res.Error != nil {
+			return SMSAysncReq{}, res.Error
+		}
+		if res.RowsAffected == 0 {
+			// 被别的实例抢走了，继续下一轮
+			continue
+		}
+		req.Status = SMSStatusSending
+		req.Version = req.Version + 1
+		req.RetryCnt = req.RetryCnt + 1
+		return req, nil
+	}
+}
+
+func (dao *SMSAysncReqDAO) MarkSuccess(ctx context.Context, id int64, version int) error {
+	return dao.update(ctx, id, version, map[string]any{
+		"status": SMSStatusSuccess,
+	})
+}
+
+func (dao *SMSAysncReqDAO) Retry(ctx context.Context, id int64, version int, next time.Time, errMsg string) error {
+	return dao.update(ctx, id, version, map[string]any{
+		"status":    SMSStatusWaiting,
+		"next_time": next.UnixMilli(),
+		"err_msg":   errMsg,
+	})
+}
+
+func (dao *SMSAysncReqDAO) MarkFailed(ctx context.Context, id int64, version int, errMsg string) error {
+	return dao.update(ctx, id, version, map[string]any{
+		"status":  SMSStatusFailed,
+		"err_msg": errMsg,
+	})
+}
+
+// update 只有还在发送中并且版本号对得上才能修改
+func (dao *SMSAysncReqDAO) update(ctx context.Context, id int64, version int, fields map[string]any) error {
+	fields["utime"] = time.Now().UnixMilli()
+	res := dao.db.WithContext(ctx).Model(&SMSAysncReq{}).
+		Where("id = ? AND version = ? AND status = ?", id, version, SMSStatusSending).Updates(fields)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrSMSLeaseLost
+	}
+	return nil
+}
+
+func (dao *SMSAysncReqDAO) CountPending(ctx context.Context) (int64, error) {
+	var cnt int64
+	err := dao.db.WithContext(ctx).Model(&SMSAysncReq{}).
+		Where("status IN ?", []int8{SMSStatusWaiting, SMSStatusSending}).Count(&cnt).Error
+	return cnt, err
 }
 
 type SMSAysncReq struct {
-	Id       int64 `gorm:"primaryKey;autoIncrement"`
-	Biz      string
+	Id  int64 `gorm:"primaryKey;autoIncrement"`
+	Biz string
+	// JSON 数组
 	Args     string
 	Numbers  string
-	Status   int8 `gorm:"type:tinyint;not null;default:0;comment:状态:0-等待发送,1-发送中,2-发送成功,3-发送失败"`
+	Status   int8 `gorm:"type:tinyint;not null;default:0;index:status_next_time;comment:状态:0-等待发送,1-发送中,2-发送成功,3-发送失败"`
 	RetryCnt int32
+	// 下一次可以重试的时间
+	NextTime int64 `gorm:"index:status_next_time"`
+	Version  int
+	ErrMsg   string `gorm:"type:varchar(1024)"`
 	Ctime    int64
 	Utime    int64
 }
+
+const (
+	SMSStatusWaiting int8 = iota
+	SMSStatusSending
+	SMSStatusSuccess
+	SMSStatusFailed
+)
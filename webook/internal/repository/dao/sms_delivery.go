@@ -0,0 +1,101 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrSMSDeliveryNotFound = gorm.ErrRecordNotFound
+
+type SMSDeliveryDAO interface {
+	Insert(ctx context.Context, ds []SMSDelivery) error
+	// MarkReceipt 只更新还没有收到回执的记录，重复推送的回执和找不到的记录返回 ErrSMSDeliveryNotFound
+	MarkReceipt(ctx context.Context, d SMSDelivery) error
+	// Stats 按照服务商和模板统计 [start, end) 之间发送的短信
+	Stats(ctx context.Context, start, end int64) ([]SMSDeliveryStats, error)
+}
+
+type GORMSMSDeliveryDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMSMSDeliveryDAO(db *gorm.DB) SMSDeliveryDAO {
+	return &GORMSMSDeliveryDAO{
+		db: db,
+	}
+}
+
+func (g *GORMSMSDeliveryDAO) Insert(ctx context.Context, ds []SMSDelivery) error {
+	now := time.Now().UnixMilli()
+	for i := range ds {
+		ds[i].Ctime = now
+		ds[i].Utime = now
+	}
+	return g.db.WithContext(ctx).Create(&ds).Error
+}
+
+func (g *GORMSMSDeliveryDAO) MarkReceipt(ctx context.Context, d SMSDelivery) error {
+	res := g.db.WithContext(ctx).Model(&SMSDelivery{}).
+		Where("provider = ? AND message_id = ? AND number = ? AND status = ?",
+			d.Provider, d.MessageId, d.Number, SMSDeliveryStatusAccepted).
+		Updates(map[string]any{
+			"status":       d.Status,
+			"err_code":     d.ErrCode,
+			"err_msg":      d.ErrMsg,
+			"receipt_time": d.ReceiptTime,
+			"utime":        time.Now().UnixMilli(),
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrSMSDeliveryNotFound
+	}
+	return nil
+}
+
+func (g *GORMSMSDeliveryDAO) Stats(ctx context.Context, start, end int64) ([]SMSDeliveryStats, error) {
+	var res []SMSDeliveryStats
+	err := g.db.WithContext(ctx).Model(&SMSDelivery{}).
+		Select("provider, biz, COUNT(*) AS total, "+
+			"SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS delivered, "+
+			"SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS failed",
+			SMSDeliveryStatusDelivered, SMSDeliveryStatusFailed).
+		Where("ctime >= ? AND ctime < ?", start, end).
+		Group("provider, biz").
+		Order("provider, biz").
+		Scan(&res).Error
+	return res, err
+}
+
+// SMSDelivery 回执按照服务商、消息 ID 和号码找到对应的记录
+type SMSDelivery struct {
+	Id          int64  `gorm:"primaryKey,autoIncrement"`
+	Provider    string `gorm:"type:varchar(64);index:provider_message_number,priority:1"`
+	Biz         string `gorm:"type:varchar(64)"`
+	Number      string `gorm:"type:varchar(32);index:provider_message_number,priority:3"`
+	MessageId   string `gorm:"type:varchar(128);index:provider_message_number,priority:2"`
+	Status      uint8
+	ErrCode     string `gorm:"type:varchar(64)"`
+	ErrMsg      string `gorm:"type:varchar(1024)"`
+	ReceiptTime int64
+	Ctime       int64 `gorm:"index"`
+	Utime       int64
+}
+
+type SMSDeliveryStats struct {
+	Provider  string
+	Biz       string
+	Total     int64
+	Delivered int64
+	Failed    int64
+}
+
+const (
+	SMSDeliveryStatusUnknown uint8 = iota
+	SMSDeliveryStatusAccepted
+	SMSDeliveryStatusDelivered
+	SMSDeliveryStatusFailed
+)
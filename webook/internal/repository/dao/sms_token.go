@@ -0,0 +1,79 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrSMSBizTokenNotFound = gorm.ErrRecordNotFound
+
+type SMSBizTokenDAO interface {
+	Insert(ctx context.Context, t SMSBizToken) (int64, error)
+	FindById(ctx context.Context, id int64) (SMSBizToken, error)
+	// Revoke 只能吊销还有效的令牌，没有这样的令牌返回 ErrSMSBizTokenNotFound
+	Revoke(ctx context.Context, id int64) error
+	List(ctx context.Context, offset, limit int) ([]SMSBizToken, error)
+}
+
+type GORMSMSBizTokenDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMSMSBizTokenDAO(db *gorm.DB) SMSBizTokenDAO {
+	return &GORMSMSBizTokenDAO{
+		db: db,
+	}
+}
+
+func (g *GORMSMSBizTokenDAO) Insert(ctx context.Context, t SMSBizToken) (int64, error) {
+	now := time.Now().UnixMilli()
+	t.Ctime = now
+	t.Utime = now
+	err := g.db.WithContext(ctx).Create(&t).Error
+	return t.Id, err
+}
+
+func (g *GORMSMSBizTokenDAO) FindById(ctx context.Context, id int64) (SMSBizToken, error) {
+	var res SMSBizToken
+	err := g.db.WithContext(ctx).Where("id = ?", id).First(&res).Error
+	return res, err
+}
+
+func (g *GORMSMSBizTokenDAO) Revoke(ctx context.Context, id int64) error {
+	res := g.db.WithContext(ctx).Model(&SMSBizToken{}).
+		Where("id = ? AND status = ?", id, SMSBizTokenStatusActive).Updates(map[string]any{
+		"status": SMSBizTokenStatusRevoked,
+		"utime":  time.Now().UnixMilli(),
+	})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrSMSBizTokenNotFound
+	}
+	return nil
+}
+
+func (g *GORMSMSBizTokenDAO) List(ctx context.Context, offset, limit int) ([]SMSBizToken, error) {
+	var res []SMSBizToken
+	err := g.db.WithContext(ctx).Order("id DESC").Offset(offset).Limit(limit).Find(&res).Error
+	return res, err
+}
+
+type SMSBizToken struct {
+	Id         int64  `gorm:"primaryKey,autoIncrement"`
+	Biz        string `gorm:"type:varchar(64);index"`
+	Tpl        string `gorm:"type:varchar(64)"`
+	DailyQuota int
+	Status     uint8
+	Ctime      int64
+	Utime      int64
+}
+
+const (
+	SMSBizTokenStatusUnknown uint8 = iota
+	SMSBizTokenStatusActive
+	SMSBizTokenStatusRevoked
+)
@@ -22,6 +22,8 @@ type UserDAO interface {
 	FindById(ctx context.Context, id int64) (User, error)
 	UpdateById(ctx context.Context, entity User) error
 	FindByWechat(ctx context.Context, openID string) (User, error)
+	FindByIds(ctx context.Context, ids []int64) ([]User, error)
+	UpdatePrivacy(ctx context.Context, id int64, hideLikes bool) error
 }
 type GORMUserDAO struct {
 	db *gorm.DB
@@ -82,6 +84,20 @@ func (dao *GORMUserDAO) FindByWechat(ctx context.Context, openID string) (User,
 	return u, err
 }
 
+func (dao *GORMUserDAO) FindByIds(ctx context.Context, ids []int64) ([]User, error) {
+	var res []User
+	err := dao.db.WithContext(ctx).Where("id IN ?", ids).Find(&res).Error
+	return res, err
+}
+
+func (dao *GORMUserDAO) UpdatePrivacy(ctx context.Context, id int64, hideLikes bool) error {
+	return dao.db.WithContext(ctx).Model(&User{}).Where("id = ?", id).
+		Updates(map[string]any{
+			"utime":      time.Now().UnixMilli(),
+			"hide_likes": hideLikes,
+		}).Error
+}
+
 // 直接对应数据库表结构
 type User struct {
 	Id int64 `gorm:"primaryKey,autoIncrement"`
@@ -94,6 +110,8 @@ type User struct {
 	Desc          string
 	WechatUnionID sql.NullString `gorm:"unique"`
 	WechatOpenID  sql.NullString `gorm:"unique"`
+	// 不公开点赞记录
+	HideLikes bool
 	// 时间，存毫秒
 	Ctime int64
 	Utime int64
@@ -0,0 +1,154 @@
+package dao
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+var ErrWorkflowRunNotFound = gorm.ErrRecordNotFound
+
+type WorkflowRunDAO interface {
+	// Insert 创建一次执行，同时创建所有节点
+	Insert(ctx context.Context, r WorkflowRun, nodes []WorkflowNodeRun) (int64, error)
+	// FindUnfinished 这个工作流最近一次还没有结束的执行
+	FindUnfinished(ctx context.Context, workflowId int64) (WorkflowRun, error)
+	FindById(ctx context.Context, id int64) (WorkflowRun, error)
+	FindNodes(ctx context.Context, runIds []int64) ([]WorkflowNodeRun, error)
+	UpdateNode(ctx context.Context, n WorkflowNodeRun) error
+	Finish(ctx context.Context, id int64, status uint8, endTime int64) error
+	List(ctx context.Context, workflowId int64, offset, limit int) ([]WorkflowRun, error)
+	// Retry 失败的执行重新开始，失败和跳过的节点重新等待执行
+	Retry(ctx context.Context, id int64) error
+}
+
+type GORMWorkflowRunDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMWorkflowRunDAO(db *gorm.DB) WorkflowRunDAO {
+	return &GORMWorkflowRunDAO{
+		db: db,
+	}
+}
+
+func (g *GORMWorkflowRunDAO) Insert(ctx context.Context, r WorkflowRun, nodes []WorkflowNodeRun) (int64, error) {
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Create(&r).Error
+		if err != nil {
+			return err
+		}
+		for i := range nodes {
+			nodes[i].RunId = r.Id
+		}
+		return tx.Create(&nodes).Error
+	})
+	return r.Id, err
+}
+
+func (g *GORMWorkflowRunDAO) FindUnfinished(ctx context.Context, workflowId int64) (WorkflowRun, error) {
+	var r WorkflowRun
+	err := g.db.WithContext(ctx).
+		Where("workflow_id = ? AND status = ?", workflowId, WorkflowRunStatusRunning).
+		Order("id DESC").First(&r).Error
+	return r, err
+}
+
+func (g *GORMWorkflowRunDAO) FindById(ctx context.Context, id int64) (WorkflowRun, error) {
+	var r WorkflowRun
+	err := g.db.WithContext(ctx).Where("id = ?", id).First(&r).Error
+	return r, err
+}
+
+func (g *GORMWorkflowRunDAO) FindNodes(ctx context.Context, runIds []int64) ([]WorkflowNodeRun, error) {
+	var res []WorkflowNodeRun
+	err := g.db.WithContext(ctx).Where("run_id IN ?", runIds).Order("id ASC").Find(&res).Error
+	return res, err
+}
+
+func (g *GORMWorkflowRunDAO) UpdateNode(ctx context.Context, n WorkflowNodeRun) error {
+	return g.db.WithContext(ctx).Model(&WorkflowNodeRun{}).Where("id = ?", n.Id).Updates(map[string]any{
+		"status":     n.Status,
+		"attempt":    n.Attempt,
+		"err_msg":    n.ErrMsg,
+		"start_time": n.StartTime,
+		"end_time":   n.EndTime,
+	}).Error
+}
+
+func (g *GORMWorkflowRunDAO) Finish(ctx context.Context, id int64, status uint8, endTime int64) error {
+	return g.db.WithContext(ctx).Model(&WorkflowRun{}).
+		Where("id = ? AND status = ?", id, WorkflowRunStatusRunning).Updates(map[string]any{
+		"status":   status,
+		"end_time": endTime,
+	}).Error
+}
+
+func (g *GORMWorkflowRunDAO) List(ctx context.Context, workflowId int64, offset, limit int) ([]WorkflowRun, error) {
+	var res []WorkflowRun
+	err := g.db.WithContext(ctx).Where("workflow_id = ?", workflowId).
+		Order("id DESC").Offset(offset).Limit(limit).Find(&res).Error
+	return res, err
+}
+
+func (g *GORMWorkflowRunDAO) Retry(ctx context.Context, id int64) error {
+	return g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&WorkflowRun{}).
+			Where("id = ? AND status = ?", id, WorkflowRunStatusFailed).Updates(map[string]any{
+			"status":   WorkflowRunStatusRunning,
+			"end_time": 0,
+		})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrWorkflowRunNotFound
+		}
+		return tx.Model(&WorkflowNodeRun{}).
+			Where("run_id = ? AND status IN ?", id, []uint8{WorkflowNodeStatusFailed, WorkflowNodeStatusSkipped}).
+			Updates(map[string]any{
+				"status":     WorkflowNodeStatusWaiting,
+				"attempt":    0,
+				"err_msg":    "",
+				"start_time": 0,
+				"end_time":   0,
+			}).Error
+	})
+}
+
+type WorkflowRun struct {
+	Id         int64 `gorm:"primaryKey,autoIncrement"`
+	WorkflowId int64 `gorm:"index:workflow_id_status"`
+	Status     uint8 `gorm:"index:workflow_id_status"`
+	StartTime  int64
+	EndTime    int64
+}
+
+type WorkflowNodeRun struct {
+	Id    int64 `gorm:"primaryKey,autoIncrement"`
+	RunId int64 `gorm:"uniqueIndex:run_id_job_id"`
+	JobId int64 `gorm:"uniqueIndex:run_id_job_id"`
+	// 创建执行的时候的上游，JSON 数组。工作流的定义后来改了也不影响这一次执行
+	Upstreams string `gorm:"type:varchar(1024)"`
+	Status    uint8
+	Attempt   int
+	ErrMsg    string `gorm:"type:varchar(1024)"`
+	StartTime int64
+	EndTime   int64
+}
+
+const (
+	WorkflowRunStatusUnknown uint8 = iota
+	WorkflowRunStatusRunning
+	WorkflowRunStatusSuccess
+	WorkflowRunStatusFailed
+)
+
+const (
+	WorkflowNodeStatusUnknown uint8 = iota
+	WorkflowNodeStatusWaiting
+	WorkflowNodeStatusRunning
+	WorkflowNodeStatusSuccess
+	WorkflowNodeStatusFailed
+	WorkflowNodeStatusSkipped
+)
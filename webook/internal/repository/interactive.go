@@ -6,6 +6,9 @@ import (
 	"go-basic/webook/internal/repository/cache"
 	"go-basic/webook/internal/repository/dao"
 	"go-basic/webook/pkg/logger"
+	"time"
+
+	"github.com/ecodeclub/ekit/slice"
 )
 
 type InteractiveRepository interface {
@@ -15,9 +18,16 @@ type InteractiveRepository interface {
 	DecrLike(ctx context.Context, biz string, id int64, uid int64) error
 	AddCollectionItem(ctx context.Context, biz string, id int64, cid int64, uid int64) error
 	Get(ctx context.Context, biz string, id int64) (domain.Interactive, error)
+	// GetByIds 批量查询计数，直接查数据库，没有计数的 id 不会返回
+	GetByIds(ctx context.Context, biz string, ids []int64) ([]domain.Interactive, error)
 	Liked(ctx context.Context, biz string, id int64, uid int64) (bool, error)
 	Collected(ctx context.Context, biz string, id int64, uid int64) (bool, error)
 	AddRecord(ctx context.Context, uid int64, aid int64) error
+	Likers(ctx context.Context, biz string, id int64, cursor domain.LikeCursor, limit int) ([]domain.UserLike, error)
+	LikedBy(ctx context.Context, uid int64, cursor int64, limit int) ([]domain.UserLike, error)
+	// ReconcileCnt 根据明细重新计算 minId 之后的一批点赞数和收藏数，dryRun 为 true 时只对比不修正。
+	// 返回有差异的记录，以及这一批最后一条记录的 id，没有更多数据时返回 0
+	ReconcileCnt(ctx context.Context, bizs []string, minId int64, limit int, dryRun bool) ([]domain.InteractiveCntDiff, int64, error)
 }
 
 type CachedInteractiveRepository struct {
@@ -112,6 +122,19 @@ func (c *CachedInteractiveRepository) Get(ctx context.Context, biz string, id in
 	return intr, nil
 }
 
+func (c *CachedInteractiveRepository) GetByIds(ctx context.Context, biz string, ids []int64) ([]domain.Interactive, error) {
+	intrs, err := c.dao.GetByIds(ctx, biz, ids)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map(intrs, func(idx int, src dao.Interactive) domain.Interactive {
+		intr := c.entityToDomain(src)
+		intr.Biz = src.Biz
+		intr.BizId = src.BizId
+		return intr
+	}), nil
+}
+
 func (c *CachedInteractiveRepository) Liked(ctx context.Context, biz string, id int64, uid int64) (bool, error) {
 	_, err := c.dao.GetLikeInfo(ctx, biz, id, uid)
 	switch err {
@@ -138,6 +161,100 @@ func (c *CachedInteractiveRepository) Collected(ctx context.Context, biz string,
 	}
 }
 
+func (c *CachedInteractiveRepository) Likers(ctx context.Context, biz string, id int64, cursor domain.LikeCursor, limit int) ([]domain.UserLike, error) {
+	likes, err := c.dao.ListLikesByBiz(ctx, biz, id, cursor.Utime.UnixMilli(), cursor.Id, limit)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map(likes, func(idx int, src dao.UserLikeBiz) domain.UserLike {
+		return c.likeToDomain(src)
+	}), nil
+}
+
+func (c *CachedInteractiveRepository) LikedBy(ctx context.Context, uid int64, cursor int64, limit int) ([]domain.UserLike, error) {
+	likes, err := c.dao.ListLikesByUid(ctx, uid, cursor, limit)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map(likes, func(idx int, src dao.UserLikeBiz) domain.UserLike {
+		return c.likeToDomain(src)
+	}), nil
+}
+
+func (c *CachedInteractiveRepository) ReconcileCnt(ctx context.Context, bizs []string, minId int64, limit int, dryRun bool) ([]domain.InteractiveCntDiff, int64, error) {
+	intrs, err := c.dao.ListByMinId(ctx, bizs, minId, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(intrs) == 0 {
+		return nil, 0, nil
+	}
+	// 一批数据里面可能有多种业务，按照业务分组去统计明细
+	groups := make(map[string][]int64)
+	for _, intr := range intrs {
+		groups[intr.Biz] = append(groups[intr.Biz], intr.BizId)
+	}
+	likeCnts := make(map[string]map[int64]int64, len(groups))
+	collectCnts := make(map[string]map[int64]int64, len(groups))
+	for biz, ids := range groups {
+		likeCnts[biz], err = c.dao.CountLikeByBizIds(ctx, biz, ids)
+		if err != nil {
+			return nil, 0, err
+		}
+		collectCnts[biz], err = c.dao.CountCollectByBizIds(ctx, biz, ids)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	var diffs []domain.InteractiveCntDiff
+	for _, intr := range intrs {
+		diff := domain.InteractiveCntDiff{
+			Biz:              intr.Biz,
+			BizId:            intr.BizId,
+			LikeCnt:          intr.LikeCnt,
+			CollectCnt:       intr.CollectCnt,
+			ExpectLikeCnt:    likeCnts[intr.Biz][intr.BizId],
+			ExpectCollectCnt: collectCnts[intr.Biz][intr.BizId],
+		}
+		if diff.LikeCnt == diff.ExpectLikeCnt && diff.CollectCnt == diff.ExpectCollectCnt {
+			continue
+		}
+		if !dryRun {
+			diff.Fixed, err = c.dao.UpdateCntIfUnchanged(ctx, intr, diff.ExpectLikeCnt, diff.ExpectCollectCnt)
+			if err != nil {
+				return diffs, 0, err
+			}
+			if diff.Fixed {
+				c.refreshCache(ctx, intr.Biz, intr.BizId)
+			}
+		}
+		diffs = append(diffs, diff)
+	}
+	return diffs, intrs[len(intrs)-1].Id, nil
+}
+
+// refreshCache 用数据库中修正后的数据覆盖缓存
+func (c *CachedInteractiveRepository) refreshCache(ctx context.Context, biz string, id int64) {
+	daoIntr, err := c.dao.Get(ctx, biz, id)
+	if err == nil {
+		err = c.cache.Set(ctx, biz, id, c.entityToDomain(daoIntr))
+	}
+	if err != nil {
+		c.l.Error("对账后刷新缓存失败", logger.Error(err), logger.String("biz", biz), logger.Int64("bizId", id))
+	}
+}
+
+func (c *CachedInteractiveRepository) likeToDomain(like dao.UserLikeBiz) domain.UserLike {
+	return domain.UserLike{
+		Id:    like.Id,
+		Biz:   like.Biz,
+		BizId: like.BizId,
+		Uid:   like.Uid,
+		Utime: time.UnixMilli(like.Utime),
+	}
+}
+
 func (c *CachedInteractiveRepository) entityToDomain(daoIntr dao.Interactive) domain.Interactive {
 	return domain.Interactive{
 		ReadCnt:    daoIntr.ReadCnt,
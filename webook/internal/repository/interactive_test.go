@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"go-basic/webook/internal/domain"
+	"go-basic/webook/internal/repository/cache"
+	cachemocks "go-basic/webook/internal/repository/cache/mocks"
+	"go-basic/webook/internal/repository/dao"
+	daomocks "go-basic/webook/internal/repository/dao/mocks"
+	"go-basic/webook/pkg/logger"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCachedInteractiveRepository_ReconcileCnt(t *testing.T) {
+	testCases := []struct {
+		name   string
+		mock   func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache)
+		dryRun bool
+
+		wantDiffs []domain.InteractiveCntDiff
+		wantNext  int64
+		wantErr   error
+	}{
+		{
+			name: "没有数据",
+			mock: func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache) {
+				d := daomocks.NewMockInteractiveDAO(ctrl)
+				d.EXPECT().ListByMinId(gomock.Any(), []string{"article"}, int64(0), 10).Return(nil, nil)
+				return d, cachemocks.NewMockInteractiveCache(ctrl)
+			},
+		},
+		{
+			name: "修正不一致的计数并刷新缓存",
+			mock: func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache) {
+				d := daomocks.NewMockInteractiveDAO(ctrl)
+				c := cachemocks.NewMockInteractiveCache(ctrl)
+				d.EXPECT().ListByMinId(gomock.Any(), []string{"article"}, int64(0), 10).Return([]dao.Interactive{
+					{Id: 1, Biz: "article", BizId: 11, LikeCnt: 3, CollectCnt: 1},
+					{Id: 2, Biz: "article", BizId: 12, LikeCnt: 5, CollectCnt: 2, ReadCnt: 9},
+				}, nil)
+				d.EXPECT().CountLikeByBizIds(gomock.Any(), "article", []int64{11, 12}).
+					Return(map[int64]int64{11: 3, 12: 4}, nil)
+				d.EXPECT().CountCollectByBizIds(gomock.Any(), "article", []int64{11, 12}).
+					Return(map[int64]int64{11: 1}, nil)
+				d.EXPECT().UpdateCntIfUnchanged(gomock.Any(),
+					dao.Interactive{Id: 2, Biz: "article", BizId: 12, LikeCnt: 5, CollectCnt: 2, ReadCnt: 9},
+					int64(4), int64(0)).Return(true, nil)
+				d.EXPECT().Get(gomock.Any(), "article", int64(12)).Return(dao.Interactive{
+					Id: 2, Biz: "article", BizId: 12, LikeCnt: 4, ReadCnt: 9,
+				}, nil)
+				c.EXPECT().Set(gomock.Any(), "article", int64(12), domain.Interactive{
+					LikeCnt: 4, ReadCnt: 9,
+				}).Return(nil)
+				return d, c
+			},
+			wantDiffs: []domain.InteractiveCntDiff{
+				{Biz: "article", BizId: 12, LikeCnt: 5, CollectCnt: 2, ExpectLikeCnt: 4, Fixed: true},
+			},
+			wantNext: 2,
+		},
+		{
+			name:   "试运行只报告不修正",
+			dryRun: true,
+			mock: func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache) {
+				d := daomocks.NewMockInteractiveDAO(ctrl)
+				d.EXPECT().ListByMinId(gomock.Any(), []string{"article"}, int64(0), 10).Return([]dao.Interactive{
+					{Id: 7, Biz: "article", BizId: 11, LikeCnt: 3},
+				}, nil)
+				d.EXPECT().CountLikeByBizIds(gomock.Any(), "article", []int64{11}).
+					Return(map[int64]int64{11: 2}, nil)
+				d.EXPECT().CountCollectByBizIds(gomock.Any(), "article", []int64{11}).
+					Return(map[int64]int64{}, nil)
+				return d, cachemocks.NewMockInteractiveCache(ctrl)
+			},
+			wantDiffs: []domain.InteractiveCntDiff{
+				{Biz: "article", BizId: 11, LikeCnt: 3, ExpectLikeCnt: 2},
+			},
+			wantNext: 7,
+		},
+		{
+			name: "统计明细失败",
+			mock: func(ctrl *gomock.Controller) (dao.InteractiveDAO, cache.InteractiveCache) {
+				d := daomocks.NewMockInteractiveDAO(ctrl)
+				d.EXPECT().ListByMinId(gomock.Any(), []string{"article"}, int64(0), 10).Return([]dao.Interactive{
+					{Id: 1, Biz: "article", BizId: 11, LikeCnt: 3},
+				}, nil)
+				d.EXPECT().CountLikeByBizIds(gomock.Any(), "article", []int64{11}).
+					Return(nil, errors.New("mock db error"))
+				return d, cachemocks.NewMockInteractiveCache(ctrl)
+			},
+			wantErr: errors.New("mock db error"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			d, c := tc.mock(ctrl)
+			repo := NewCachedInteractiveRepository(d, c, logger.NewNopLogger())
+			diffs, next, err := repo.ReconcileCnt(context.Background(), []string{"article"}, 0, 10, tc.dryRun)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantDiffs, diffs)
+			assert.Equal(t, tc.wantNext, next)
+		})
+	}
+}
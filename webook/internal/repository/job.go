@@ -7,46 +7,180 @@ import (
 	"time"
 )
 
+var (
+	ErrJobDuplicateName = dao.ErrJobDuplicateName
+	ErrJobNotFound      = dao.ErrJobNotFound
+	ErrJobLeaseLost     = dao.ErrJobLeaseLost
+)
+
 type JobRepository interface {
-	Preempt(ctx context.Context) (domain.Job, error)
+	// Preempt 租约超过 leaseTimeout 没有续上的任务也会被抢占，executors 不为空的时候只抢这些执行器的任务
+	Preempt(ctx context.Context, leaseTimeout time.Duration, executors []string) (domain.Job, error)
 	Release(ctx context.Context, id int64, version int) error
-	UpdateUtime(ctx context.Context, id int64) error
-	UpdateNextTime(ctx context.Context, id int64, next time.Time) error
+	UpdateUtime(ctx context.Context, id int64, version int) error
+	UpdateNextTime(ctx context.Context, id int64, version int, next time.Time) error
 	Stop(ctx context.Context, id int64) error
+	Cancelled(ctx context.Context, id int64, version int) (bool, error)
+
+	Create(ctx context.Context, j domain.Job) (int64, error)
+	Update(ctx context.Context, j domain.Job) error
+	Delete(ctx context.Context, id int64) error
+	FindById(ctx context.Context, id int64) (domain.Job, error)
+	List(ctx context.Context, offset, limit int) ([]domain.Job, error)
+	Resume(ctx context.Context, id int64, next time.Time) error
+	Trigger(ctx context.Context, id int64) error
+	Cancel(ctx context.Context, id int64) error
+	Retry(ctx context.Context, id int64, version int, next time.Time) error
+	MarkDead(ctx context.Context, id int64, version int) error
 }
 
 type PreemptCronJobRepository struct {
 	dao dao.JobDAO
 }
 
-func NewPreemptCronJobRepository(dao dao.JobDAO) *PreemptCronJobRepository {
+func NewPreemptCronJobRepository(dao dao.JobDAO) JobRepository {
 	return &PreemptCronJobRepository{
 		dao: dao,
 	}
 }
 
-func (p *PreemptCronJobRepository) UpdateUtime(ctx context.Context, id int64) error {
-	return p.dao.UpdateUtime(ctx, id)
+func (p *PreemptCronJobRepository) UpdateUtime(ctx context.Context, id int64, version int) error {
+	return p.dao.UpdateUtime(ctx, id, version)
 }
 
-func (p *PreemptCronJobRepository) UpdateNextTime(ctx context.Context, id int64, next time.Time) error {
-	return p.dao.UpdateNextTime(ctx, id, next)
+func (p *PreemptCronJobRepository) UpdateNextTime(ctx context.Context, id int64, version int, next time.Time) error {
+	return p.dao.UpdateNextTime(ctx, id, version, next)
 }
 
 func (p *PreemptCronJobRepository) Release(ctx context.Context, id int64, version int) error {
 	return p.dao.Release(ctx, id, version)
 }
 
-func (p *PreemptCronJobRepository) Preempt(ctx context.Context) (domain.Job, error) {
-	j, err := p.dao.Preempt(ctx)
+func (p *PreemptCronJobRepository) Stop(ctx context.Context, id int64) error {
+	return p.dao.Stop(ctx, id)
+}
+
+func (p *PreemptCronJobRepository) Cancelled(ctx context.Context, id int64, version int) (bool, error) {
+	return p.dao.Cancelled(ctx, id, version)
+}
+
+func (p *PreemptCronJobRepository) Preempt(ctx context.Context, leaseTimeout time.Duration,
+	executors []string) (domain.Job, error) {
+	j, err := p.dao.Preempt(ctx, leaseTimeout, executors)
+	if err != nil {
+		return domain.Job{}, err
+	}
+	res := p.toDomain(j)
+	// DAO 返回的是抢占之前的状态
+	res.Reclaimed = j.Status == dao.JobStatusRunning
+	res.Status = domain.JobStatusRunning
+	return res, nil
+}
+
+func (p *PreemptCronJobRepository) Create(ctx context.Context, j domain.Job) (int64, error) {
+	return p.dao.Insert(ctx, p.toEntity(j))
+}
+
+func (p *PreemptCronJobRepository) Update(ctx context.Context, j domain.Job) error {
+	return p.dao.Update(ctx, p.toEntity(j))
+}
+
+func (p *PreemptCronJobRepository) Delete(ctx context.Context, id int64) error {
+	return p.dao.Delete(ctx, id)
+}
+
+func (p *PreemptCronJobRepository) FindById(ctx context.Context, id int64) (domain.Job, error) {
+	j, err := p.dao.FindById(ctx, id)
 	if err != nil {
 		return domain.Job{}, err
 	}
+	return p.toDomain(j), nil
+}
+
+func (p *PreemptCronJobRepository) List(ctx context.Context, offset, limit int) ([]domain.Job, error) {
+	jobs, err := p.dao.List(ctx, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.Job, 0, len(jobs))
+	for _, j := range jobs {
+		res = append(res, p.toDomain(j))
+	}
+	return res, nil
+}
+
+func (p *PreemptCronJobRepository) Resume(ctx context.Context, id int64, next time.Time) error {
+	return p.dao.Resume(ctx, id, next)
+}
+
+func (p *PreemptCronJobRepository) Trigger(ctx context.Context, id int64) error {
+	return p.dao.Trigger(ctx, id)
+}
+
+func (p *PreemptCronJobRepository) Cancel(ctx context.Context, id int64) error {
+	return p.dao.Cancel(ctx, id)
+}
+
+func (p *PreemptCronJobRepository) Retry(ctx context.Context, id int64, version int, next time.Time) error {
+	return p.dao.Retry(ctx, id, version, next)
+}
+
+func (p *PreemptCronJobRepository) MarkDead(ctx context.Context, id int64, version int) error {
+	return p.dao.MarkDead(ctx, id, version)
+}
+
+func (p *PreemptCronJobRepository) toDomain(j dao.Job) domain.Job {
 	return domain.Job{
+		Id:         j.Id,
+		Name:       j.Name,
+		Cron:       j.Cron,
+		Timezone:   j.Timezone,
+		Misfire:    domain.MisfirePolicy(j.Misfire),
+		Executor:   j.Executor,
+		Cfg:        j.Cfg,
+		Priority:   j.Priority,
+		Group:      j.GroupName,
+		Version:    j.Version,
+		Status:     domain.JobStatus(j.Status),
+		Mode:       domain.JobMode(j.Mode),
+		ShardCount: j.ShardCount,
+		Retry: domain.RetryPolicy{
+			MaxAttempts: j.MaxAttempts,
+			Backoff:     domain.BackoffType(j.Backoff),
+			Interval:    time.Duration(j.RetryInterval) * time.Millisecond,
+			MaxInterval: time.Duration(j.MaxRetryInterval) * time.Millisecond,
+		},
+		Timeout:     time.Duration(j.Timeout) * time.Millisecond,
+		Attempt:     j.Attempt,
+		NextRunTime: time.UnixMilli(j.NextTime),
+		Ctime:       time.UnixMilli(j.Ctime),
+		Utime:       time.UnixMilli(j.Utime),
+	}
+}
+
+func (p *PreemptCronJobRepository) toEntity(j domain.Job) dao.Job {
+	return dao.Job{
 		Id:       j.Id,
-		Cfg:      j.Cfg,
-		Version:  j.Version,
 		Name:     j.Name,
+		Cron:     j.Cron,
+		Timezone: j.Timezone,
+		Misfire:  j.Misfire.ToUint8(),
 		Executor: j.Executor,
-	}, nil
+		Cfg:      j.Cfg,
+		Status:   int(j.Status.ToUint8()),
+
+		Priority:  j.Priority,
+		GroupName: j.Group,
+		NextTime:  j.NextRunTime.UnixMilli(),
+
+		Mode:       j.Mode.ToUint8(),
+		ShardCount: j.ShardCount,
+
+		MaxAttempts:      j.Retry.MaxAttempts,
+		Backoff:          j.Retry.Backoff.ToUint8(),
+		RetryInterval:    j.Retry.Interval.Milliseconds(),
+		MaxRetryInterval: j.Retry.MaxInterval.Milliseconds(),
+		Attempt:          j.Attempt,
+		Timeout:          j.Timeout.Milliseconds(),
+	}
 }
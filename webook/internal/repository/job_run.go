@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"go-basic/webook/internal/domain"
+	"go-basic/webook/internal/repository/dao"
+	"time"
+)
+
+type JobRunRepository interface {
+	Create(ctx context.Context, r domain.JobRun) (int64, error)
+	Finish(ctx context.Context, r domain.JobRun) error
+	Abandon(ctx context.Context, jobId int64, errMsg string, endTime time.Time) (int64, error)
+	List(ctx context.Context, jobId int64, status domain.JobRunStatus, offset, limit int) ([]domain.JobRun, error)
+	DeleteBefore(ctx context.Context, before time.Time, limit int) (int64, error)
+}
+
+type jobRunRepository struct {
+	dao dao.JobRunDAO
+}
+
+func NewJobRunRepository(dao dao.JobRunDAO) JobRunRepository {
+	return &jobRunRepository{
+		dao: dao,
+	}
+}
+
+func (j *jobRunRepository) Create(ctx context.Context, r domain.JobRun) (int64, error) {
+	return j.dao.Insert(ctx, dao.JobRun{
+		JobId:     r.JobId,
+		Status:    r.Status.ToUint8(),
+		Instance:  r.Instance,
+		Attempt:   r.Attempt,
+		StartTime: r.StartTime.UnixMilli(),
+	})
+}
+
+func (j *jobRunRepository) Finish(ctx context.Context, r domain.JobRun) error {
+	return j.dao.Finish(ctx, r.Id, r.Status.ToUint8(), r.ErrMsg, r.EndTime.UnixMilli())
+}
+
+func (j *jobRunRepository) Abandon(ctx context.Context, jobId int64, errMsg string, endTime time.Time) (int64, error) {
+	return j.dao.Abandon(ctx, jobId, errMsg, endTime.UnixMilli())
+}
+
+func (j *jobRunRepository) List(ctx context.Context, jobId int64, status domain.JobRunStatus,
+	offset, limit int) ([]domain.JobRun, error) {
+	runs, err := j.dao.List(ctx, jobId, status.ToUint8(), offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.JobRun, 0, len(runs))
+	for _, r := range runs {
+		res = append(res, j.toDomain(r))
+	}
+	return res, nil
+}
+
+func (j *jobRunRepository) DeleteBefore(ctx context.Context, before time.Time, limit int) (int64, error) {
+	return j.dao.DeleteBefore(ctx, before.UnixMilli(), limit)
+}
+
+func (j *jobRunRepository) toDomain(r dao.JobRun) domain.JobRun {
+	res := domain.JobRun{
+		Id:        r.Id,
+		JobId:     r.JobId,
+		Instance:  r.Instance,
+		Attempt:   r.Attempt,
+		Status:    domain.JobRunStatus(r.Status),
+		ErrMsg:    r.ErrMsg,
+		StartTime: time.UnixMilli(r.StartTime),
+	}
+	if r.EndTime > 0 {
+		res.EndTime = time.UnixMilli(r.EndTime)
+	}
+	return res
+}
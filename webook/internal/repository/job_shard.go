@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"go-basic/webook/internal/domain"
+	"go-basic/webook/internal/repository/dao"
+	"time"
+)
+
+type JobShardRepository interface {
+	Dispatch(ctx context.Context, jobId int64, shards []domain.JobShard) error
+	Preempt(ctx context.Context, instance string, leaseTimeout time.Duration) (domain.JobShard, error)
+	UpdateUtime(ctx context.Context, id int64, version int) error
+	Release(ctx context.Context, id int64, version int) error
+	Finish(ctx context.Context, s domain.JobShard) error
+	FindByRound(ctx context.Context, jobId int64, round int) ([]domain.JobShard, error)
+	Cancel(ctx context.Context, ids []int64) error
+}
+
+type jobShardRepository struct {
+	dao dao.JobShardDAO
+}
+
+func NewJobShardRepository(dao dao.JobShardDAO) JobShardRepository {
+	return &jobShardRepository{
+		dao: dao,
+	}
+}
+
+func (j *jobShardRepository) Dispatch(ctx context.Context, jobId int64, shards []domain.JobShard) error {
+	entities := make([]dao.JobShard, 0, len(shards))
+	for _, s := range shards {
+		entities = append(entities, dao.JobShard{
+			JobId:      s.JobId,
+			Round:      s.Round,
+			ShardIndex: s.Index,
+			Total:      s.Total,
+			Instance:   s.Instance,
+		})
+	}
+	return j.dao.Dispatch(ctx, jobId, entities)
+}
+
+func (j *jobShardRepository) Preempt(ctx context.Context, instance string, leaseTimeout time.Duration) (domain.JobShard, error) {
+	s, err := j.dao.Preempt(ctx, instance, leaseTimeout)
+	if err != nil {
+		return domain.JobShard{}, err
+	}
+	return j.toDomain(s), nil
+}
+
+func (j *jobShardRepository) UpdateUtime(ctx context.Context, id int64, version int) error {
+	return j.dao.UpdateUtime(ctx, id, version)
+}
+
+func (j *jobShardRepository) Release(ctx context.Context, id int64, version int) error {
+	return j.dao.Release(ctx, id, version)
+}
+
+func (j *jobShardRepository) Finish(ctx context.Context, s domain.JobShard) error {
+	return j.dao.Finish(ctx, s.Id, s.Version, s.Status.ToUint8(), s.ErrMsg)
+}
+
+func (j *jobShardRepository) FindByRound(ctx context.Context, jobId int64, round int) ([]domain.JobShard, error) {
+	shards, err := j.dao.FindByRound(ctx, jobId, round)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.JobShard, 0, len(shards))
+	for _, s := range shards {
+		res = append(res, j.toDomain(s))
+	}
+	return res, nil
+}
+
+func (j *jobShardRepository) Cancel(ctx context.Context, ids []int64) error {
+	return j.dao.Cancel(ctx, ids)
+}
+
+func (j *jobShardRepository) toDomain(s dao.JobShard) domain.JobShard {
+	return domain.JobShard{
+		Id:       s.Id,
+		JobId:    s.JobId,
+		Round:    s.Round,
+		Index:    s.ShardIndex,
+		Total:    s.Total,
+		Instance: s.Instance,
+		Status:   domain.JobShardStatus(s.Status),
+		Version:  s.Version,
+		ErrMsg:   s.ErrMsg,
+	}
+}
@@ -0,0 +1,137 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: job_shard.go
+
+// Package repomocks is a generated GoMock package.
+package repomocks
+
+import (
+	context "context"
+	domain "go-basic/webook/internal/domain"
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockJobShardRepository is a mock of JobShardRepository interface.
+type MockJobShardRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockJobShardRepositoryMockRecorder
+}
+
+// MockJobShardRepositoryMockRecorder is the mock recorder for MockJobShardRepository.
+type MockJobShardRepositoryMockRecorder struct {
+	mock *MockJobShardRepository
+}
+
+// NewMockJobShardRepository creates a new mock instance.
+func NewMockJobShardRepository(ctrl *gomock.Controller) *MockJobShardRepository {
+	mock := &MockJobShardRepository{ctrl: ctrl}
+	mock.recorder = &MockJobShardRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockJobShardRepository) EXPECT() *MockJobShardRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Cancel mocks base method.
+func (m *MockJobShardRepository) Cancel(ctx context.Context, ids []int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Cancel", ctx, ids)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Cancel indicates an expected call of Cancel.
+func (mr *MockJobShardRepositoryMockRecorder) Cancel(ctx, ids interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Cancel", reflect.TypeOf((*MockJobShardRepository)(nil).Cancel), ctx, ids)
+}
+
+// Dispatch mocks base method.
+func (m *MockJobShardRepository) Dispatch(ctx context.Context, jobId int64, shards []domain.JobShard) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Dispatch", ctx, jobId, shards)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Dispatch indicates an expected call of Dispatch.
+func (mr *MockJobShardRepositoryMockRecorder) Dispatch(ctx, jobId, shards interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Dispatch", reflect.TypeOf((*MockJobShardRepository)(nil).Dispatch), ctx, jobId, shards)
+}
+
+// FindByRound mocks base method.
+func (m *MockJobShardRepository) FindByRound(ctx context.Context, jobId int64, round int) ([]domain.JobShard, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByRound", ctx, jobId, round)
+	ret0, _ := ret[0].([]domain.JobShard)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByRound indicates an expected call of FindByRound.
+func (mr *MockJobShardRepositoryMockRecorder) FindByRound(ctx, jobId, round interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByRound", reflect.TypeOf((*MockJobShardRepository)(nil).FindByRound), ctx, jobId, round)
+}
+
+// Finish mocks base method.
+func (m *MockJobShardRepository) Finish(ctx context.Context, s domain.JobShard) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Finish", ctx, s)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Finish indicates an expected call of Finish.
+func (mr *MockJobShardRepositoryMockRecorder) Finish(ctx, s interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Finish", reflect.TypeOf((*MockJobShardRepository)(nil).Finish), ctx, s)
+}
+
+// Preempt mocks base method.
+func (m *MockJobShardRepository) Preempt(ctx context.Context, instance string, leaseTimeout time.Duration) (domain.JobShard, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Preempt", ctx, instance, leaseTimeout)
+	ret0, _ := ret[0].(domain.JobShard)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Preempt indicates an expected call of Preempt.
+func (mr *MockJobShardRepositoryMockRecorder) Preempt(ctx, instance, leaseTimeout interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Preempt", reflect.TypeOf((*MockJobShardRepository)(nil).Preempt), ctx, instance, leaseTimeout)
+}
+
+// Release mocks base method.
+func (m *MockJobShardRepository) Release(ctx context.Context, id int64, version int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Release", ctx, id, version)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Release indicates an expected call of Release.
+func (mr *MockJobShardRepositoryMockRecorder) Release(ctx, id, version interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Release", reflect.TypeOf((*MockJobShardRepository)(nil).Release), ctx, id, version)
+}
+
+// UpdateUtime mocks base method.
+func (m *MockJobShardRepository) UpdateUtime(ctx context.Context, id int64, version int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUtime", ctx, id, version)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateUtime indicates an expected call of UpdateUtime.
+func (mr *MockJobShardRepositoryMockRecorder) UpdateUtime(ctx, id, version interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUtime", reflect.TypeOf((*MockJobShardRepository)(nil).UpdateUtime), ctx, id, version)
+}
@@ -0,0 +1,123 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: sms.go
+
+// Package repomocks is a generated GoMock package.
+package repomocks
+
+import (
+	context "context"
+	domain "go-basic/webook/internal/domain"
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockSMSRepository is a mock of SMSRepository interface.
+type MockSMSRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockSMSRepositoryMockRecorder
+}
+
+// MockSMSRepositoryMockRecorder is the mock recorder for MockSMSRepository.
+type MockSMSRepositoryMockRecorder struct {
+	mock *MockSMSRepository
+}
+
+// NewMockSMSRepository creates a new mock instance.
+func NewMockSMSRepository(ctrl *gomock.Controller) *MockSMSRepository {
+	mock := &MockSMSRepository{ctrl: ctrl}
+	mock.recorder = &MockSMSRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSMSRepository) EXPECT() *MockSMSRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CountPending mocks base method.
+func (m *MockSMSRepository) CountPending(ctx context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountPending", ctx)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountPending indicates an expected call of CountPending.
+func (mr *MockSMSRepositoryMockRecorder) CountPending(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountPending", reflect.TypeOf((*MockSMSRepository)(nil).CountPending), ctx)
+}
+
+// MarkFailed mocks base method.
+func (m *MockSMSRepository) MarkFailed(ctx context.Context, sms domain.SMS) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkFailed", ctx, sms)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkFailed indicates an expected call of MarkFailed.
+func (mr *MockSMSRepositoryMockRecorder) MarkFailed(ctx, sms interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkFailed", reflect.TypeOf((*MockSMSRepository)(nil).MarkFailed), ctx, sms)
+}
+
+// MarkSuccess mocks base method.
+func (m *MockSMSRepository) MarkSuccess(ctx context.Context, sms domain.SMS) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkSuccess", ctx, sms)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkSuccess indicates an expected call of MarkSuccess.
+func (mr *MockSMSRepositoryMockRecorder) MarkSuccess(ctx, sms interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkSuccess", reflect.TypeOf((*MockSMSRepository)(nil).MarkSuccess), ctx, sms)
+}
+
+// Preempt mocks base method.
+func (m *MockSMSRepository) Preempt(ctx context.Context, leaseTimeout time.Duration) (domain.SMS, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Preempt", ctx, leaseTimeout)
+	ret0, _ := ret[0].(domain.SMS)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Preempt indicates an expected call of Preempt.
+func (mr *MockSMSRepositoryMockRecorder) Preempt(ctx, leaseTimeout interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Preempt", reflect.TypeOf((*MockSMSRepository)(nil).Preempt), ctx, leaseTimeout)
+}
+
+// Retry mocks base method.
+func (m *MockSMSRepository) Retry(ctx context.Context, sms domain.SMS, next time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Retry", ctx, sms, next)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Retry indicates an expected call of Retry.
+func (mr *MockSMSRepositoryMockRecorder) Retry(ctx, sms, next interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Retry", reflect.TypeOf((*MockSMSRepository)(nil).Retry), ctx, sms, next)
+}
+
+// Store mocks base method.
+func (m *MockSMSRepository) Store(ctx context.Context, tpl string, args, numbers []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Store", ctx, tpl, args, numbers)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Store indicates an expected call of Store.
+func (mr *MockSMSRepositoryMockRecorder) Store(ctx, tpl, args, numbers interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Store", reflect.TypeOf((*MockSMSRepository)(nil).Store), ctx, tpl, args, numbers)
+}
@@ -0,0 +1,80 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: sms_delivery.go
+
+// Package repomocks is a generated GoMock package.
+package repomocks
+
+import (
+	context "context"
+	domain "go-basic/webook/internal/domain"
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockSMSDeliveryRepository is a mock of SMSDeliveryRepository interface.
+type MockSMSDeliveryRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockSMSDeliveryRepositoryMockRecorder
+}
+
+// MockSMSDeliveryRepositoryMockRecorder is the mock recorder for MockSMSDeliveryRepository.
+type MockSMSDeliveryRepositoryMockRecorder struct {
+	mock *MockSMSDeliveryRepository
+}
+
+// NewMockSMSDeliveryRepository creates a new mock instance.
+func NewMockSMSDeliveryRepository(ctrl *gomock.Controller) *MockSMSDeliveryRepository {
+	mock := &MockSMSDeliveryRepository{ctrl: ctrl}
+	mock.recorder = &MockSMSDeliveryRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSMSDeliveryRepository) EXPECT() *MockSMSDeliveryRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockSMSDeliveryRepository) Create(ctx context.Context, ds []domain.SMSDelivery) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, ds)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockSMSDeliveryRepositoryMockRecorder) Create(ctx, ds interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockSMSDeliveryRepository)(nil).Create), ctx, ds)
+}
+
+// MarkReceipt mocks base method.
+func (m *MockSMSDeliveryRepository) MarkReceipt(ctx context.Context, d domain.SMSDelivery) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkReceipt", ctx, d)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkReceipt indicates an expected call of MarkReceipt.
+func (mr *MockSMSDeliveryRepositoryMockRecorder) MarkReceipt(ctx, d interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkReceipt", reflect.TypeOf((*MockSMSDeliveryRepository)(nil).MarkReceipt), ctx, d)
+}
+
+// Stats mocks base method.
+func (m *MockSMSDeliveryRepository) Stats(ctx context.Context, start, end time.Time) ([]domain.SMSDeliveryStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stats", ctx, start, end)
+	ret0, _ := ret[0].([]domain.SMSDeliveryStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Stats indicates an expected call of Stats.
+func (mr *MockSMSDeliveryRepositoryMockRecorder) Stats(ctx, start, end interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stats", reflect.TypeOf((*MockSMSDeliveryRepository)(nil).Stats), ctx, start, end)
+}
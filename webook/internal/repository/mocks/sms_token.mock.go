@@ -0,0 +1,109 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./webook/internal/repository/sms_token.go
+
+// Package repomocks is a generated GoMock package.
+package repomocks
+
+import (
+	context "context"
+	domain "go-basic/webook/internal/domain"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockSMSBizTokenRepository is a mock of SMSBizTokenRepository interface.
+type MockSMSBizTokenRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockSMSBizTokenRepositoryMockRecorder
+}
+
+// MockSMSBizTokenRepositoryMockRecorder is the mock recorder for MockSMSBizTokenRepository.
+type MockSMSBizTokenRepositoryMockRecorder struct {
+	mock *MockSMSBizTokenRepository
+}
+
+// NewMockSMSBizTokenRepository creates a new mock instance.
+func NewMockSMSBizTokenRepository(ctrl *gomock.Controller) *MockSMSBizTokenRepository {
+	mock := &MockSMSBizTokenRepository{ctrl: ctrl}
+	mock.recorder = &MockSMSBizTokenRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSMSBizTokenRepository) EXPECT() *MockSMSBizTokenRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockSMSBizTokenRepository) Create(ctx context.Context, t domain.SMSBizToken) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, t)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockSMSBizTokenRepositoryMockRecorder) Create(ctx, t interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockSMSBizTokenRepository)(nil).Create), ctx, t)
+}
+
+// FindById mocks base method.
+func (m *MockSMSBizTokenRepository) FindById(ctx context.Context, id int64) (domain.SMSBizToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindById", ctx, id)
+	ret0, _ := ret[0].(domain.SMSBizToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindById indicates an expected call of FindById.
+func (mr *MockSMSBizTokenRepositoryMockRecorder) FindById(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindById", reflect.TypeOf((*MockSMSBizTokenRepository)(nil).FindById), ctx, id)
+}
+
+// IncrQuota mocks base method.
+func (m *MockSMSBizTokenRepository) IncrQuota(ctx context.Context, tokenId int64, n, quota int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrQuota", ctx, tokenId, n, quota)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// IncrQuota indicates an expected call of IncrQuota.
+func (mr *MockSMSBizTokenRepositoryMockRecorder) IncrQuota(ctx, tokenId, n, quota interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrQuota", reflect.TypeOf((*MockSMSBizTokenRepository)(nil).IncrQuota), ctx, tokenId, n, quota)
+}
+
+// List mocks base method.
+func (m *MockSMSBizTokenRepository) List(ctx context.Context, offset, limit int) ([]domain.SMSBizToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, offset, limit)
+	ret0, _ := ret[0].([]domain.SMSBizToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockSMSBizTokenRepositoryMockRecorder) List(ctx, offset, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockSMSBizTokenRepository)(nil).List), ctx, offset, limit)
+}
+
+// Revoke mocks base method.
+func (m *MockSMSBizTokenRepository) Revoke(ctx context.Context, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Revoke", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Revoke indicates an expected call of Revoke.
+func (mr *MockSMSBizTokenRepositoryMockRecorder) Revoke(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Revoke", reflect.TypeOf((*MockSMSBizTokenRepository)(nil).Revoke), ctx, id)
+}
@@ -0,0 +1,138 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: workflow.go
+
+// Package repomocks is a generated GoMock package.
+package repomocks
+
+import (
+	context "context"
+	domain "go-basic/webook/internal/domain"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockWorkflowRunRepository is a mock of WorkflowRunRepository interface.
+type MockWorkflowRunRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockWorkflowRunRepositoryMockRecorder
+}
+
+// MockWorkflowRunRepositoryMockRecorder is the mock recorder for MockWorkflowRunRepository.
+type MockWorkflowRunRepositoryMockRecorder struct {
+	mock *MockWorkflowRunRepository
+}
+
+// NewMockWorkflowRunRepository creates a new mock instance.
+func NewMockWorkflowRunRepository(ctrl *gomock.Controller) *MockWorkflowRunRepository {
+	mock := &MockWorkflowRunRepository{ctrl: ctrl}
+	mock.recorder = &MockWorkflowRunRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWorkflowRunRepository) EXPECT() *MockWorkflowRunRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockWorkflowRunRepository) Create(ctx context.Context, r domain.WorkflowRun) (domain.WorkflowRun, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, r)
+	ret0, _ := ret[0].(domain.WorkflowRun)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockWorkflowRunRepositoryMockRecorder) Create(ctx, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockWorkflowRunRepository)(nil).Create), ctx, r)
+}
+
+// FindById mocks base method.
+func (m *MockWorkflowRunRepository) FindById(ctx context.Context, id int64) (domain.WorkflowRun, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindById", ctx, id)
+	ret0, _ := ret[0].(domain.WorkflowRun)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindById indicates an expected call of FindById.
+func (mr *MockWorkflowRunRepositoryMockRecorder) FindById(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindById", reflect.TypeOf((*MockWorkflowRunRepository)(nil).FindById), ctx, id)
+}
+
+// FindUnfinished mocks base method.
+func (m *MockWorkflowRunRepository) FindUnfinished(ctx context.Context, workflowId int64) (domain.WorkflowRun, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindUnfinished", ctx, workflowId)
+	ret0, _ := ret[0].(domain.WorkflowRun)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindUnfinished indicates an expected call of FindUnfinished.
+func (mr *MockWorkflowRunRepositoryMockRecorder) FindUnfinished(ctx, workflowId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindUnfinished", reflect.TypeOf((*MockWorkflowRunRepository)(nil).FindUnfinished), ctx, workflowId)
+}
+
+// Finish mocks base method.
+func (m *MockWorkflowRunRepository) Finish(ctx context.Context, r domain.WorkflowRun) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Finish", ctx, r)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Finish indicates an expected call of Finish.
+func (mr *MockWorkflowRunRepositoryMockRecorder) Finish(ctx, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Finish", reflect.TypeOf((*MockWorkflowRunRepository)(nil).Finish), ctx, r)
+}
+
+// List mocks base method.
+func (m *MockWorkflowRunRepository) List(ctx context.Context, workflowId int64, offset, limit int) ([]domain.WorkflowRun, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, workflowId, offset, limit)
+	ret0, _ := ret[0].([]domain.WorkflowRun)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockWorkflowRunRepositoryMockRecorder) List(ctx, workflowId, offset, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockWorkflowRunRepository)(nil).List), ctx, workflowId, offset, limit)
+}
+
+// Retry mocks base method.
+func (m *MockWorkflowRunRepository) Retry(ctx context.Context, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Retry", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Retry indicates an expected call of Retry.
+func (mr *MockWorkflowRunRepositoryMockRecorder) Retry(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Retry", reflect.TypeOf((*MockWorkflowRunRepository)(nil).Retry), ctx, id)
+}
+
+// UpdateNode mocks base method.
+func (m *MockWorkflowRunRepository) UpdateNode(ctx context.Context, n domain.WorkflowNodeRun) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateNode", ctx, n)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateNode indicates an expected call of UpdateNode.
+func (mr *MockWorkflowRunRepositoryMockRecorder) UpdateNode(ctx, n interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateNode", reflect.TypeOf((*MockWorkflowRunRepository)(nil).UpdateNode), ctx, n)
+}
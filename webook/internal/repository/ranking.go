@@ -7,37 +7,51 @@ import (
 )
 
 type RankingRepository interface {
-	ReplaceTopN(ctx context.Context, arts []domain.Article) error
-	GetTopN(ctx context.Context) ([]domain.Article, error)
+	ReplaceTopN(ctx context.Context, key domain.RankingKey, arts []domain.Article) error
+	GetTopN(ctx context.Context, key domain.RankingKey) ([]domain.Article, error)
+	// Categories 写过榜单的分类
+	Categories(ctx context.Context) ([]string, error)
 }
 
 type CacheRankingRepository struct {
 	redis cache.RankingCache
-	local cache.RankingLocalCache
+	local *cache.RankingLocalCache
 }
 
-func NewRankingRepository(redis cache.RankingCache, local cache.RankingLocalCache) RankingRepository {
+func NewRankingRepository(redis cache.RankingCache, local *cache.RankingLocalCache) RankingRepository {
 	return &CacheRankingRepository{
 		redis: redis,
 		local: local,
 	}
 }
 
-func (c *CacheRankingRepository) ReplaceTopN(ctx context.Context, arts []domain.Article) error {
-	_ = c.local.Set(ctx, arts)
-	return c.redis.Set(ctx, arts)
+func (c *CacheRankingRepository) ReplaceTopN(ctx context.Context, key domain.RankingKey, arts []domain.Article) error {
+	_ = c.local.Set(ctx, key, arts)
+	return c.redis.Set(ctx, key, arts)
 }
 
-func (c *CacheRankingRepository) GetTopN(ctx context.Context) ([]domain.Article, error) {
-	data, err := c.local.Get(ctx)
+func (c *CacheRankingRepository) Categories(ctx context.Context) ([]string, error) {
+	return c.redis.Categories(ctx)
+}
+
+func (c *CacheRankingRepository) GetTopN(ctx context.Context, key domain.RankingKey) ([]domain.Article, error) {
+	data, err := c.local.Get(ctx, key)
 	if err == nil {
 		return data, nil
 	}
-	data, err = c.redis.Get(ctx)
+	data, err = c.redis.Get(ctx, key)
 	if err == nil {
-		c.local.Set(ctx, data)
-	} else {
-		return c.local.ForceGet(ctx)
+		_ = c.local.Set(ctx, key, data)
+		return data, nil
+	}
+	// Redis 出问题了，用本地缓存兜底，哪怕已经过期了
+	res, er := c.local.ForceGet(ctx, key)
+	if er == nil && len(res) > 0 {
+		return res, nil
+	}
+	if err == cache.ErrKeyNotExist {
+		// 热榜还没有计算出来，返回空榜单
+		return []domain.Article{}, nil
 	}
-	return data, err
+	return nil, err
 }
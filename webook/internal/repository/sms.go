@@ -2,21 +2,32 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"go-basic/webook/internal/domain"
 	"go-basic/webook/internal/repository/dao"
-	"strings"
 	"time"
 )
 
+var (
+	ErrSMSNotFound  = dao.ErrSMSNotFound
+	ErrSMSLeaseLost = dao.ErrSMSLeaseLost
+)
+
 type SMSRepository interface {
 	Store(ctx context.Context, tpl string, args []string, numbers []string) error
+	Preempt(ctx context.Context, leaseTimeout time.Duration) (domain.SMS, error)
+	MarkSuccess(ctx context.Context, sms domain.SMS) error
+	// Retry 在 next 之后重新发送
+	Retry(ctx context.Context, sms domain.SMS, next time.Time) error
+	MarkFailed(ctx context.Context, sms domain.SMS) error
+	CountPending(ctx context.Context) (int64, error)
 }
 
 type SMSAysncReqRepository struct {
-	dao dao.SMSAysncReqDAO
+	dao dao.SMSDAO
 }
 
-func NewSMSAysncReqRepository(dao dao.SMSAysncReqDAO) SMSRepository {
+func NewSMSAysncReqRepository(dao dao.SMSDAO) SMSRepository {
 	return &SMSAysncReqRepository{
 		dao: dao,
 	}
@@ -28,43 +39,65 @@ func (repo *SMSAysncReqRepository) Store(ctx context.Context, tpl string, args [
 			Biz:     tpl,
 			Args:    args,
 			Numbers: numbers,
-			Status:  0,
+			Status:  domain.SMSStatusWaiting,
 			Ctime:   time.Now(),
 		},
 	))
 }
 
-func (repo *SMSAysncReqRepository) Find(ctx context.Context) ([]domain.SMS, error) {
-	reqs, err := repo.dao.FindByStatus(ctx, 0)
+func (repo *SMSAysncReqRepository) Preempt(ctx context.Context, leaseTimeout time.Duration) (domain.SMS, error) {
+	req, err := repo.dao.Preempt(ctx, leaseTimeout)
 	if err != nil {
-		return nil, err
-	}
-	res := make([]domain.SMS, 0, len(reqs))
-	for _, req := range reqs {
-		res = append(res, repo.entityToDomain(req))
+		return domain.SMS{}, err
 	}
-	return res, nil
+	return repo.entityToDomain(req), nil
+}
+
+func (repo *SMSAysncReqRepository) MarkSuccess(ctx context.Context, sms domain.SMS) error {
+	return repo.dao.MarkSuccess(ctx, sms.Id, sms.Version)
+}
+
+func (repo *SMSAysncReqRepository) Retry(ctx context.Context, sms domain.SMS, next time.Time) error {
+	return repo.dao.Retry(ctx, sms.Id, sms.Version, next, sms.ErrMsg)
+}
+
+func (repo *SMSAysncReqRepository) MarkFailed(ctx context.Context, sms domain.SMS) error {
+	return repo.dao.MarkFailed(ctx, sms.Id, sms.Version, sms.ErrMsg)
+}
+
+func (repo *SMSAysncReqRepository) CountPending(ctx context.Context) (int64, error) {
+	return repo.dao.CountPending(ctx)
 }
 
 func (repo *SMSAysncReqRepository) entityToDomain(req dao.SMSAysncReq) domain.SMS {
-	return domain.SMS{
+	res := domain.SMS{
 		Id:       req.Id,
 		Biz:      req.Biz,
-		Args:     []string{req.Args},
-		Numbers:  []string{req.Numbers},
-		Status:   req.Status,
+		Status:   domain.SMSStatus(req.Status),
 		RetryCnt: req.RetryCnt,
+		ErrMsg:   req.ErrMsg,
+		Version:  req.Version,
 		Ctime:    time.UnixMilli(req.Ctime),
 	}
+	// 写进去的时候就是合法的 JSON
+	_ = json.Unmarshal([]byte(req.Args), &res.Args)
+	_ = json.Unmarshal([]byte(req.Numbers), &res.Numbers)
+	return res
 }
 
 func (repo *SMSAysncReqRepository) domainToEntity(req domain.SMS) dao.SMSAysncReq {
+	// 参数里面可能有逗号，不能简单地拼接
+	args, _ := json.Marshal(req.Args)
+	numbers, _ := json.Marshal(req.Numbers)
 	return dao.SMSAysncReq{
 		Id:       req.Id,
-		Args:     strings.Join(req.Args, ","),
-		Numbers:  strings.Join(req.Numbers, ","),
-		Status:   req.Status,
+		Biz:      req.Biz,
+		Args:     string(args),
+		Numbers:  string(numbers),
+		Status:   req.Status.ToInt8(),
 		RetryCnt: req.RetryCnt,
+		ErrMsg:   req.ErrMsg,
+		Version:  req.Version,
 		Ctime:    req.Ctime.UnixMilli(),
 	}
 }
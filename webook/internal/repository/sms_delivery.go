@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"go-basic/webook/internal/domain"
+	"go-basic/webook/internal/repository/dao"
+	"time"
+)
+
+var ErrSMSDeliveryNotFound = dao.ErrSMSDeliveryNotFound
+
+type SMSDeliveryRepository interface {
+	Create(ctx context.Context, ds []domain.SMSDelivery) error
+	// MarkReceipt 按照 Provider、MessageId 和 Number 找到记录，更新回执里面的结果
+	MarkReceipt(ctx context.Context, d domain.SMSDelivery) error
+	Stats(ctx context.Context, start, end time.Time) ([]domain.SMSDeliveryStats, error)
+}
+
+type smsDeliveryRepository struct {
+	dao dao.SMSDeliveryDAO
+}
+
+func NewSMSDeliveryRepository(dao dao.SMSDeliveryDAO) SMSDeliveryRepository {
+	return &smsDeliveryRepository{
+		dao: dao,
+	}
+}
+
+func (r *smsDeliveryRepository) Create(ctx context.Context, ds []domain.SMSDelivery) error {
+	entities := make([]dao.SMSDelivery, 0, len(ds))
+	for _, d := range ds {
+		entities = append(entities, r.toEntity(d))
+	}
+	return r.dao.Insert(ctx, entities)
+}
+
+func (r *smsDeliveryRepository) MarkReceipt(ctx context.Context, d domain.SMSDelivery) error {
+	return r.dao.MarkReceipt(ctx, r.toEntity(d))
+}
+
+func (r *smsDeliveryRepository) Stats(ctx context.Context, start, end time.Time) ([]domain.SMSDeliveryStats, error) {
+	stats, err := r.dao.Stats(ctx, start.UnixMilli(), end.UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.SMSDeliveryStats, 0, len(stats))
+	for _, s := range stats {
+		res = append(res, domain.SMSDeliveryStats{
+			Provider:  s.Provider,
+			Biz:       s.Biz,
+			Total:     s.Total,
+			Delivered: s.Delivered,
+			Failed:    s.Failed,
+		})
+	}
+	return res, nil
+}
+
+func (r *smsDeliveryRepository) toEntity(d domain.SMSDelivery) dao.SMSDelivery {
+	var receiptTime int64
+	if !d.ReceiptTime.IsZero() {
+		receiptTime = d.ReceiptTime.UnixMilli()
+	}
+	return dao.SMSDelivery{
+		Id:          d.Id,
+		Provider:    d.Provider,
+		Biz:         d.Biz,
+		Number:      d.Number,
+		MessageId:   d.MessageId,
+		Status:      d.Status.ToUint8(),
+		ErrCode:     d.ErrCode,
+		ErrMsg:      d.ErrMsg,
+		ReceiptTime: receiptTime,
+	}
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"go-basic/webook/internal/domain"
+	"go-basic/webook/internal/repository/cache"
+	"go-basic/webook/internal/repository/dao"
+	"time"
+)
+
+var (
+	ErrSMSBizTokenNotFound = dao.ErrSMSBizTokenNotFound
+	ErrSMSQuotaExceeded    = cache.ErrSMSQuotaExceeded
+)
+
+type SMSBizTokenRepository interface {
+	Create(ctx context.Context, t domain.SMSBizToken) (int64, error)
+	FindById(ctx context.Context, id int64) (domain.SMSBizToken, error)
+	Revoke(ctx context.Context, id int64) error
+	List(ctx context.Context, offset, limit int) ([]domain.SMSBizToken, error)
+	// IncrQuota 令牌当天已经发送的数量加上 n，超过 quota 返回 ErrSMSQuotaExceeded。
+	// 每个令牌有自己的额度，同一个业务方的多个令牌分开计数
+	IncrQuota(ctx context.Context, tokenId int64, n, quota int) error
+}
+
+type smsBizTokenRepository struct {
+	dao   dao.SMSBizTokenDAO
+	quota cache.SMSQuotaCache
+}
+
+func NewSMSBizTokenRepository(dao dao.SMSBizTokenDAO, quota cache.SMSQuotaCache) SMSBizTokenRepository {
+	return &smsBizTokenRepository{
+		dao:   dao,
+		quota: quota,
+	}
+}
+
+func (r *smsBizTokenRepository) Create(ctx context.Context, t domain.SMSBizToken) (int64, error) {
+	return r.dao.Insert(ctx, r.toEntity(t))
+}
+
+func (r *smsBizTokenRepository) FindById(ctx context.Context, id int64) (domain.SMSBizToken, error) {
+	t, err := r.dao.FindById(ctx, id)
+	if err != nil {
+		return domain.SMSBizToken{}, err
+	}
+	return r.toDomain(t), nil
+}
+
+func (r *smsBizTokenRepository) Revoke(ctx context.Context, id int64) error {
+	return r.dao.Revoke(ctx, id)
+}
+
+func (r *smsBizTokenRepository) List(ctx context.Context, offset, limit int) ([]domain.SMSBizToken, error) {
+	ts, err := r.dao.List(ctx, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.SMSBizToken, 0, len(ts))
+	for _, t := range ts {
+		res = append(res, r.toDomain(t))
+	}
+	return res, nil
+}
+
+func (r *smsBizTokenRepository) IncrQuota(ctx context.Context, tokenId int64, n, quota int) error {
+	return r.quota.Incr(ctx, tokenId, time.Now(), n, quota)
+}
+
+func (r *smsBizTokenRepository) toDomain(t dao.SMSBizToken) domain.SMSBizToken {
+	return domain.SMSBizToken{
+		Id:         t.Id,
+		Biz:        t.Biz,
+		Tpl:        t.Tpl,
+		DailyQuota: t.DailyQuota,
+		Status:     domain.SMSBizTokenStatus(t.Status),
+		Ctime:      time.UnixMilli(t.Ctime),
+		Utime:      time.UnixMilli(t.Utime),
+	}
+}
+
+func (r *smsBizTokenRepository) toEntity(t domain.SMSBizToken) dao.SMSBizToken {
+	return dao.SMSBizToken{
+		Id:         t.Id,
+		Biz:        t.Biz,
+		Tpl:        t.Tpl,
+		DailyQuota: t.DailyQuota,
+		Status:     t.Status.ToUint8(),
+	}
+}
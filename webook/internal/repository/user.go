@@ -21,6 +21,8 @@ type UserRepository interface {
 	FindById(ctx context.Context, Id int64) (domain.User, error)
 	UpdateNonZeroFields(ctx context.Context, user domain.User) error
 	FindByWechat(ctx context.Context, openID string) (domain.User, error)
+	FindByIds(ctx context.Context, ids []int64) ([]domain.User, error)
+	UpdatePrivacy(ctx context.Context, id int64, hideLikes bool) error
 }
 
 type CacheUserRepository struct {
@@ -93,17 +95,43 @@ func (r *CacheUserRepository) FindByWechat(ctx context.Context, openID string) (
 	return r.entityToDomain(u), nil
 }
 
+func (r *CacheUserRepository) FindByIds(ctx context.Context, ids []int64) ([]domain.User, error) {
+	// 批量查询直接走数据库
+	users, err := r.dao.FindByIds(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.User, 0, len(users))
+	for _, u := range users {
+		res = append(res, r.entityToDomain(u))
+	}
+	return res, nil
+}
+
+func (r *CacheUserRepository) UpdatePrivacy(ctx context.Context, id int64, hideLikes bool) error {
+	err := r.dao.UpdatePrivacy(ctx, id, hideLikes)
+	if err != nil {
+		return err
+	}
+	// 删除缓存，下次查询时重新加载
+	return r.cache.Del(ctx, id)
+}
+
 func (r *CacheUserRepository) entityToDomain(u dao.User) domain.User {
 	return domain.User{
 		Id:       u.Id,
 		Email:    u.Email.String,
 		Phone:    u.Phone.String,
 		Password: u.Password,
+		Nickname: u.Nickname,
+		Birthday: u.Birthday,
+		Desc:     u.Desc,
 		WechatInfo: domain.WechatInfo{
 			OpenID:  u.WechatOpenID.String,
 			UnionID: u.WechatUnionID.String,
 		},
-		Ctime: time.UnixMilli(u.Ctime),
+		HideLikes: u.HideLikes,
+		Ctime:     time.UnixMilli(u.Ctime),
 	}
 }
 
@@ -119,6 +147,9 @@ func (r *CacheUserRepository) domainToEntity(u domain.User) dao.User {
 			Valid:  u.Phone != "",
 		},
 		Password: u.Password,
+		Nickname: u.Nickname,
+		Birthday: u.Birthday,
+		Desc:     u.Desc,
 		WechatOpenID: sql.NullString{
 			String: u.WechatInfo.OpenID,
 			Valid:  u.WechatInfo.OpenID != "",
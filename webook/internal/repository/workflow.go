@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"go-basic/webook/internal/domain"
+	"go-basic/webook/internal/repository/dao"
+	"time"
+)
+
+var ErrWorkflowRunNotFound = dao.ErrWorkflowRunNotFound
+
+type WorkflowRunRepository interface {
+	// Create 返回的执行带上了节点的 ID
+	Create(ctx context.Context, r domain.WorkflowRun) (domain.WorkflowRun, error)
+	FindUnfinished(ctx context.Context, workflowId int64) (domain.WorkflowRun, error)
+	FindById(ctx context.Context, id int64) (domain.WorkflowRun, error)
+	UpdateNode(ctx context.Context, n domain.WorkflowNodeRun) error
+	Finish(ctx context.Context, r domain.WorkflowRun) error
+	List(ctx context.Context, workflowId int64, offset, limit int) ([]domain.WorkflowRun, error)
+	Retry(ctx context.Context, id int64) error
+}
+
+type workflowRunRepository struct {
+	dao dao.WorkflowRunDAO
+}
+
+func NewWorkflowRunRepository(dao dao.WorkflowRunDAO) WorkflowRunRepository {
+	return &workflowRunRepository{
+		dao: dao,
+	}
+}
+
+func (w *workflowRunRepository) Create(ctx context.Context, r domain.WorkflowRun) (domain.WorkflowRun, error) {
+	nodes := make([]dao.WorkflowNodeRun, 0, len(r.Nodes))
+	for _, n := range r.Nodes {
+		nodes = append(nodes, w.nodeToEntity(n))
+	}
+	id, err := w.dao.Insert(ctx, dao.WorkflowRun{
+		WorkflowId: r.WorkflowId,
+		Status:     r.Status.ToUint8(),
+		StartTime:  r.StartTime.UnixMilli(),
+	}, nodes)
+	if err != nil {
+		return domain.WorkflowRun{}, err
+	}
+	// 插入之后节点才有 ID，重新查一遍
+	return w.FindById(ctx, id)
+}
+
+func (w *workflowRunRepository) FindUnfinished(ctx context.Context, workflowId int64) (domain.WorkflowRun, error) {
+	r, err := w.dao.FindUnfinished(ctx, workflowId)
+	if err != nil {
+		return domain.WorkflowRun{}, err
+	}
+	return w.withNodes(ctx, r)
+}
+
+func (w *workflowRunRepository) FindById(ctx context.Context, id int64) (domain.WorkflowRun, error) {
+	r, err := w.dao.FindById(ctx, id)
+	if err != nil {
+		return domain.WorkflowRun{}, err
+	}
+	return w.withNodes(ctx, r)
+}
+
+func (w *workflowRunRepository) withNodes(ctx context.Context, r dao.WorkflowRun) (domain.WorkflowRun, error) {
+	nodes, err := w.dao.FindNodes(ctx, []int64{r.Id})
+	if err != nil {
+		return domain.WorkflowRun{}, err
+	}
+	return w.toDomain(r, nodes), nil
+}
+
+func (w *workflowRunRepository) UpdateNode(ctx context.Context, n domain.WorkflowNodeRun) error {
+	return w.dao.UpdateNode(ctx, w.nodeToEntity(n))
+}
+
+func (w *workflowRunRepository) Finish(ctx context.Context, r domain.WorkflowRun) error {
+	return w.dao.Finish(ctx, r.Id, r.Status.ToUint8(), r.EndTime.UnixMilli())
+}
+
+func (w *workflowRunRepository) List(ctx context.Context, workflowId int64, offset, limit int) ([]domain.WorkflowRun, error) {
+	runs, err := w.dao.List(ctx, workflowId, offset, limit)
+	if err != nil || len(runs) == 0 {
+		return nil, err
+	}
+	ids := make([]int64, 0, len(runs))
+	for _, r := range runs {
+		ids = append(ids, r.Id)
+	}
+	// 一次把所有节点查出来
+	nodes, err := w.dao.FindNodes(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byRun := make(map[int64][]dao.WorkflowNodeRun, len(runs))
+	for _, n := range nodes {
+		byRun[n.RunId] = append(byRun[n.RunId], n)
+	}
+	res := make([]domain.WorkflowRun, 0, len(runs))
+	for _, r := range runs {
+		res = append(res, w.toDomain(r, byRun[r.Id]))
+	}
+	return res, nil
+}
+
+func (w *workflowRunRepository) Retry(ctx context.Context, id int64) error {
+	return w.dao.Retry(ctx, id)
+}
+
+func (w *workflowRunRepository) toDomain(r dao.WorkflowRun, nodes []dao.WorkflowNodeRun) domain.WorkflowRun {
+	res := domain.WorkflowRun{
+		Id:         r.Id,
+		WorkflowId: r.WorkflowId,
+		Status:     domain.WorkflowRunStatus(r.Status),
+		Nodes:      make([]domain.WorkflowNodeRun, 0, len(nodes)),
+		StartTime:  time.UnixMilli(r.StartTime),
+	}
+	if r.EndTime > 0 {
+		res.EndTime = time.UnixMilli(r.EndTime)
+	}
+	for _, n := range nodes {
+		res.Nodes = append(res.Nodes, w.nodeToDomain(n))
+	}
+	return res
+}
+
+func (w *workflowRunRepository) nodeToDomain(n dao.WorkflowNodeRun) domain.WorkflowNodeRun {
+	res := domain.WorkflowNodeRun{
+		Id:      n.Id,
+		RunId:   n.RunId,
+		JobId:   n.JobId,
+		Status:  domain.WorkflowNodeStatus(n.Status),
+		Attempt: n.Attempt,
+		ErrMsg:  n.ErrMsg,
+	}
+	// 写进去的时候就是合法的 JSON
+	_ = json.Unmarshal([]byte(n.Upstreams), &res.Upstreams)
+	if n.StartTime > 0 {
+		res.StartTime = time.UnixMilli(n.StartTime)
+	}
+	if n.EndTime > 0 {
+		res.EndTime = time.UnixMilli(n.EndTime)
+	}
+	return res
+}
+
+func (w *workflowRunRepository) nodeToEntity(n domain.WorkflowNodeRun) dao.WorkflowNodeRun {
+	upstreams, _ := json.Marshal(n.Upstreams)
+	res := dao.WorkflowNodeRun{
+		Id:        n.Id,
+		RunId:     n.RunId,
+		JobId:     n.JobId,
+		Upstreams: string(upstreams),
+		Status:    n.Status.ToUint8(),
+		Attempt:   n.Attempt,
+		ErrMsg:    n.ErrMsg,
+	}
+	if !n.StartTime.IsZero() {
+		res.StartTime = n.StartTime.UnixMilli()
+	}
+	if !n.EndTime.IsZero() {
+		res.EndTime = n.EndTime.UnixMilli()
+	}
+	return res
+}
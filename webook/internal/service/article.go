@@ -19,6 +19,8 @@ type ArticleService interface {
 	ListPub(ctx context.Context, offset, limit int, start time.Time) ([]domain.Article, error)
 	GetById(ctx context.Context, id int64) (domain.Article, error)
 	GetPublishedById(ctx context.Context, id, uid int64) (domain.Article, error)
+	// ListPubByIds 批量查询已发表的文章，已经撤回的文章不会返回
+	ListPubByIds(ctx context.Context, ids []int64) ([]domain.Article, error)
 }
 
 type articleService struct {
@@ -84,7 +86,7 @@ func NewArticleServiceV2(repo repository.ArticleRepository, l logger.Logger, pro
 		repo:     repo,
 		l:        l,
 		producer: producer,
-		ch:       make(chan readInfo, 10),
+		ch:       ch,
 	}
 }
 
@@ -92,14 +94,23 @@ func (a *articleService) ListPub(ctx context.Context, offset, limit int, start t
 	return a.repo.ListPub(ctx, offset, limit, start)
 }
 
+func (a *articleService) ListPubByIds(ctx context.Context, ids []int64) ([]domain.Article, error) {
+	if len(ids) == 0 {
+		return []domain.Article{}, nil
+	}
+	return a.repo.ListPubByIds(ctx, ids)
+}
+
 func (a *articleService) GetById(ctx context.Context, id int64) (domain.Article, error) {
 	return a.repo.GetById(ctx, id)
 }
 
 func (a *articleService) GetPublishedById(ctx context.Context, id, uid int64) (domain.Article, error) {
 	art, err := a.repo.GetPublishedById(ctx, id)
-	if err != nil {
+	if err == nil {
 		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
 			er := a.producer.ProduceReadEvent(ctx, events.ReadEvent{
 				// 即使消费者需要使用art中数据，让他去查询
 				Uid: uid,
@@ -110,13 +121,15 @@ func (a *articleService) GetPublishedById(ctx context.Context, id, uid int64) (d
 			}
 		}()
 
-		// 改批量
-		go func() {
-			a.ch <- readInfo{
-				uid: uid,
-				aid: id,
-			}
-		}()
+		// 改批量，只有 V2 才有 ch
+		if a.ch != nil {
+			go func() {
+				a.ch <- readInfo{
+					uid: uid,
+					aid: id,
+				}
+			}()
+		}
 	}
 	return art, err
 }
@@ -126,7 +139,11 @@ func (a *articleService) List(ctx context.Context, uid int64, offset, limit int)
 }
 
 func (a *articleService) Withdraw(ctx context.Context, art domain.Article) error {
-	return a.repo.SyncStatus(ctx, art.Id, art.Author.Id, domain.ArticleStatusPrivate)
+	err := a.repo.SyncStatus(ctx, art.Id, art.Author.Id, domain.ArticleStatusPrivate)
+	if err == nil {
+		a.producePublishEvent(art.Id, art.Author.Id, domain.ArticleStatusPrivate)
+	}
+	return err
 }
 
 func (a *articleService) Save(ctx context.Context, art domain.Article) (int64, error) {
@@ -142,7 +159,30 @@ func (a *articleService) Save(ctx context.Context, art domain.Article) (int64, e
 
 func (a *articleService) Publish(ctx context.Context, art domain.Article) (int64, error) {
 	art.Status = domain.ArticleStatusPublished
-	return a.repo.Sync(ctx, art)
+	id, err := a.repo.Sync(ctx, art)
+	if err == nil {
+		a.producePublishEvent(id, art.Author.Id, domain.ArticleStatusPublished)
+	}
+	return id, err
+}
+
+// producePublishEvent 发表和撤回的事件用来增量计算热榜，发送失败只影响热榜，不影响发表
+func (a *articleService) producePublishEvent(aid, uid int64, status domain.ArticleStatus) {
+	if a.producer == nil {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		er := a.producer.ProducePublishEvent(ctx, events.PublishEvent{
+			Aid:    aid,
+			Uid:    uid,
+			Status: status.ToUint8(),
+		})
+		if er != nil {
+			a.l.Error("发送发表事件失败", logger.Error(er), logger.Int64("art_id", aid))
+		}
+	}()
 }
 
 func (a *articleService) PublishV1(ctx context.Context, art domain.Article) (int64, error) {
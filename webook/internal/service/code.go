@@ -4,50 +4,76 @@ import (
 	"context"
 	"fmt"
 	"go-basic/webook/internal/repository"
+	"go-basic/webook/internal/service/email"
+	emailquota "go-basic/webook/internal/service/email/quota"
 	"go-basic/webook/internal/service/sms"
+	"go-basic/webook/internal/service/sms/quota"
 
 	"golang.org/x/exp/rand"
 )
 
-const codeTplId = "123456"
+// codeTpl 验证码短信在模板注册中心里面的名字，每个服务商的模板 ID 通过 sms.templates 配置
+const codeTpl = "code"
 
 var (
 	ErrCodeVerifyTooManyTimes = repository.ErrCodeVerifyTooManyTimes
 	ErrCodeSendTooMany        = repository.ErrCodeSendTooMany
+	// 短信配额，分别是手机号码、IP 和业务超过了配额
+	ErrCodePhoneQuotaExceeded = quota.ErrPhoneQuotaExceeded
+	ErrCodeIPQuotaExceeded    = quota.ErrIPQuotaExceeded
+	ErrCodeBizQuotaExceeded   = quota.ErrBizQuotaExceeded
+	// 邮件配额，分别是邮箱和 IP 超过了配额
+	ErrCodeEmailAddressQuotaExceeded = emailquota.ErrAddressQuotaExceeded
+	ErrCodeEmailIPQuotaExceeded      = emailquota.ErrIPQuotaExceeded
 )
 
+// CodeService target 是手机号码或者邮箱，由发送渠道决定
 type CodeService interface {
-	Send(ctx context.Context, biz string, phone string) error
-	Verify(ctx context.Context, biz string, phone string, code string) (bool, error)
+	Send(ctx context.Context, biz string, target string) error
+	Verify(ctx context.Context, biz string, target string, code string) (bool, error)
+}
+
+// EmailCodeService 通过邮件发送的验证码，和短信验证码分开存储
+type EmailCodeService interface {
+	CodeService
 }
 
 type codeService struct {
-	repo   repository.CodeRepository
-	smsSvc sms.Service
+	repo    repository.CodeRepository
+	channel CodeChannel
 }
 
+// NewCodeService 通过短信发送验证码
 func NewCodeService(repo repository.CodeRepository, smsSvc sms.Service) CodeService {
+	return NewChannelCodeService(repo, NewSMSCodeChannel(smsSvc))
+}
+
+// NewEmailCodeService repo 的 key 要和短信验证码区分开
+func NewEmailCodeService(repo repository.CodeRepository, emailSvc email.Service) EmailCodeService {
+	return NewChannelCodeService(repo, NewEmailCodeChannel(emailSvc))
+}
+
+func NewChannelCodeService(repo repository.CodeRepository, channel CodeChannel) CodeService {
 	return &codeService{
-		repo:   repo,
-		smsSvc: smsSvc,
+		repo:    repo,
+		channel: channel,
 	}
 }
 
-func (svc *codeService) Send(ctx context.Context, biz string, phone string) error {
+func (svc *codeService) Send(ctx context.Context, biz string, target string) error {
 	// 生成验证码
 	code := svc.generateCode()
 	// 存储验证码
-	err := svc.repo.Store(ctx, biz, phone, code)
+	err := svc.repo.Store(ctx, biz, target, code)
 	if err != nil {
 		return err
 	}
 	// 发送验证码
-	err = svc.smsSvc.Send(ctx, codeTplId, []string{code}, phone)
-	return err
+	return svc.channel.Send(ctx, biz, target, code)
 }
 
-func (svc *codeService) Verify(ctx context.Context, biz string, phone string, code string) (bool, error) {
-	return svc.repo.Verify(ctx, biz, phone, code)
+func (svc *codeService) Verify(ctx context.Context, biz string, target string, code string) (bool, error) {
+	return svc.repo.Verify(ctx, biz, target, code)
 }
 
 func (svc *codeService) generateCode() string {
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"go-basic/webook/internal/service/email"
+	"go-basic/webook/internal/service/sms"
+	htmltpl "html/template"
+	texttpl "text/template"
+)
+
+// CodeChannel 验证码的发送渠道，target 是手机号码或者邮箱
+type CodeChannel interface {
+	Send(ctx context.Context, biz, target, code string) error
+}
+
+type smsCodeChannel struct {
+	svc sms.Service
+}
+
+func NewSMSCodeChannel(svc sms.Service) CodeChannel {
+	return &smsCodeChannel{
+		svc: svc,
+	}
+}
+
+// Send 所有业务都用同一个短信模板
+func (c *smsCodeChannel) Send(ctx context.Context, biz, target, code string) error {
+	return c.svc.Send(ctx, codeTpl, []string{code}, target)
+}
+
+var (
+	//go:embed tpl/code_email.html
+	codeEmailHTML string
+	//go:embed tpl/code_email.txt
+	codeEmailText string
+
+	codeEmailHTMLTpl = htmltpl.Must(htmltpl.New("code_email").Parse(codeEmailHTML))
+	codeEmailTextTpl = texttpl.Must(texttpl.New("code_email").Parse(codeEmailText))
+)
+
+// codeEmailActions 业务在邮件里面的说法，没有列出来的用默认的
+var codeEmailActions = map[string]string{
+	"login":  "登录",
+	"signup": "注册",
+}
+
+type codeEmailData struct {
+	Action  string
+	Code    string
+	Minutes int
+}
+
+type emailCodeChannel struct {
+	svc email.Service
+}
+
+func NewEmailCodeChannel(svc email.Service) CodeChannel {
+	return &emailCodeChannel{
+		svc: svc,
+	}
+}
+
+func (c *emailCodeChannel) Send(ctx context.Context, biz, target, code string) error {
+	action, ok := codeEmailActions[biz]
+	if !ok {
+		action = "验证"
+	}
+	// 有效期和 set_code.lua 里面的过期时间保持一致
+	data := codeEmailData{Action: action, Code: code, Minutes: 10}
+	var html, text bytes.Buffer
+	if err := codeEmailHTMLTpl.Execute(&html, data); err != nil {
+		return err
+	}
+	if err := codeEmailTextTpl.Execute(&text, data); err != nil {
+		return err
+	}
+	return c.svc.Send(ctx, email.Message{
+		To:      []string{target},
+		Subject: "webook " + action + "验证码",
+		Text:    text.String(),
+		HTML:    html.String(),
+	})
+}
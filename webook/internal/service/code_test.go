@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"go-basic/webook/internal/repository"
+	repomocks "go-basic/webook/internal/repository/mocks"
+	"go-basic/webook/internal/service/email"
+	emailmocks "go-basic/webook/internal/service/email/mocks"
+	"go-basic/webook/internal/service/sms"
+	smsmocks "go-basic/webook/internal/service/sms/mocks"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodeService_Send(t *testing.T) {
+	testCases := []struct {
+		name   string
+		mock   func(ctrl *gomock.Controller) (repository.CodeRepository, CodeChannel)
+		biz    string
+		target string
+
+		wantErr error
+	}{
+		{
+			name: "短信验证码",
+			mock: func(ctrl *gomock.Controller) (repository.CodeRepository, CodeChannel) {
+				repo := repomocks.NewMockCodeRepository(ctrl)
+				svc := smsmocks.NewMockService(ctrl)
+				var code string
+				repo.EXPECT().Store(gomock.Any(), "login", "15212345678", gomock.Any()).
+					DoAndReturn(func(ctx context.Context, biz, phone, c string) error {
+						code = c
+						return nil
+					})
+				svc.EXPECT().Send(gomock.Any(), "code", gomock.Any(), "15212345678").
+					DoAndReturn(func(ctx context.Context, tpl string, args []string, numbers ...string) error {
+						// 发出去的和存下来的是同一个验证码
+						assert.Equal(t, []string{code}, args)
+						return nil
+					})
+				return repo, NewSMSCodeChannel(svc)
+			},
+			biz:    "login",
+			target: "15212345678",
+		},
+		{
+			name: "邮件验证码",
+			mock: func(ctrl *gomock.Controller) (repository.CodeRepository, CodeChannel) {
+				repo := repomocks.NewMockCodeRepository(ctrl)
+				svc := emailmocks.NewMockService(ctrl)
+				var code string
+				repo.EXPECT().Store(gomock.Any(), "signup", "123@qq.com", gomock.Any()).
+					DoAndReturn(func(ctx context.Context, biz, target, c string) error {
+						code = c
+						return nil
+					})
+				svc.EXPECT().Send(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, msg email.Message) error {
+						assert.Equal(t, []string{"123@qq.com"}, msg.To)
+						assert.Equal(t, "webook 注册验证码", msg.Subject)
+						assert.Contains(t, msg.Text, "你正在注册 webook，验证码是："+code)
+						assert.Contains(t, msg.HTML, ">"+code+"</p>")
+						return nil
+					})
+				return repo, NewEmailCodeChannel(svc)
+			},
+			biz:    "signup",
+			target: "123@qq.com",
+		},
+		{
+			name: "发送太频繁",
+			mock: func(ctrl *gomock.Controller) (repository.CodeRepository, CodeChannel) {
+				repo := repomocks.NewMockCodeRepository(ctrl)
+				repo.EXPECT().Store(gomock.Any(), "login", "123@qq.com", gomock.Any()).
+					Return(repository.ErrCodeSendTooMany)
+				return repo, NewEmailCodeChannel(emailmocks.NewMockService(ctrl))
+			},
+			biz:     "login",
+			target:  "123@qq.com",
+			wantErr: ErrCodeSendTooMany,
+		},
+		{
+			name: "短信发送失败",
+			mock: func(ctrl *gomock.Controller) (repository.CodeRepository, CodeChannel) {
+				repo := repomocks.NewMockCodeRepository(ctrl)
+				svc := smsmocks.NewMockService(ctrl)
+				repo.EXPECT().Store(gomock.Any(), "login", "15212345678", gomock.Any()).Return(nil)
+				svc.EXPECT().Send(gomock.Any(), "code", gomock.Any(), "15212345678").Return(sms.ErrRetryable)
+				return repo, NewSMSCodeChannel(svc)
+			},
+			biz:     "login",
+			target:  "15212345678",
+			wantErr: sms.ErrRetryable,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewChannelCodeService(tc.mock(ctrl))
+			err := svc.Send(context.Background(), tc.biz, tc.target)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"go-basic/webook/internal/service/email"
+)
+
+// Service 没有配置 SMTP 的时候用，只打印邮件内容
+type Service struct {
+}
+
+func NewService() *Service {
+	return &Service{}
+}
+
+func (s *Service) Send(ctx context.Context, msg email.Message) error {
+	fmt.Println(msg.To, msg.Subject, msg.Text)
+	return nil
+}
@@ -0,0 +1,79 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-basic/webook/internal/service/email"
+	"go-basic/webook/pkg/ratelimit"
+)
+
+var (
+	ErrAddressQuotaExceeded = errors.New("邮箱收到的邮件太多")
+	ErrIPQuotaExceeded      = errors.New("IP 发送的邮件太多")
+)
+
+type ipKey struct{}
+
+// WithIP 把请求方的 IP 放进 ctx 里面，只有带了 IP 的请求才会按照 IP 限制
+func WithIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, ipKey{}, ip)
+}
+
+func ipFromCtx(ctx context.Context) string {
+	ip, _ := ctx.Value(ipKey{}).(string)
+	return ip
+}
+
+// Service 按照 keys 返回的限流对象依次判断，任何一个超过配额都不会发送
+type Service struct {
+	svc     email.Service
+	limiter ratelimit.Limiter
+	keys    func(ctx context.Context, msg email.Message) []string
+	err     error
+}
+
+// NewAddressQuotaService 限制每个邮箱收到的邮件数量，时间窗口和数量由 limiter 决定
+func NewAddressQuotaService(svc email.Service, limiter ratelimit.Limiter) email.Service {
+	return &Service{
+		svc:     svc,
+		limiter: limiter,
+		keys: func(ctx context.Context, msg email.Message) []string {
+			keys := make([]string, 0, len(msg.To))
+			for _, to := range msg.To {
+				keys = append(keys, "email:quota:address:"+to)
+			}
+			return keys
+		},
+		err: ErrAddressQuotaExceeded,
+	}
+}
+
+// NewIPQuotaService 限制每个 IP 触发的邮件数量，ctx 里面没有 IP 的不限制
+func NewIPQuotaService(svc email.Service, limiter ratelimit.Limiter) email.Service {
+	return &Service{
+		svc:     svc,
+		limiter: limiter,
+		keys: func(ctx context.Context, msg email.Message) []string {
+			ip := ipFromCtx(ctx)
+			if ip == "" {
+				return nil
+			}
+			return []string{"email:quota:ip:" + ip}
+		},
+		err: ErrIPQuotaExceeded,
+	}
+}
+
+func (s *Service) Send(ctx context.Context, msg email.Message) error {
+	for _, key := range s.keys(ctx, msg) {
+		limited, err := s.limiter.Limit(ctx, key)
+		if err != nil {
+			return fmt.Errorf("判断邮件配额失败: %w", err)
+		}
+		if limited {
+			return s.err
+		}
+	}
+	return s.svc.Send(ctx, msg)
+}
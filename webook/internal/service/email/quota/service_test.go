@@ -0,0 +1,93 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-basic/webook/internal/service/email"
+	emailmocks "go-basic/webook/internal/service/email/mocks"
+	"go-basic/webook/pkg/ratelimit"
+	limitmocks "go-basic/webook/pkg/ratelimit/mocks"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestService_Send(t *testing.T) {
+	msg := email.Message{To: []string{"a@qq.com", "b@qq.com"}, Subject: "验证码", Text: "123456"}
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (email.Service, ratelimit.Limiter)
+		fn   func(svc email.Service, limiter ratelimit.Limiter) email.Service
+		ctx  context.Context
+
+		wantErr error
+	}{
+		{
+			name: "每个邮箱都没有超过配额",
+			mock: func(ctrl *gomock.Controller) (email.Service, ratelimit.Limiter) {
+				svc := emailmocks.NewMockService(ctrl)
+				limiter := limitmocks.NewMockLimiter(ctrl)
+				limiter.EXPECT().Limit(gomock.Any(), "email:quota:address:a@qq.com").Return(false, nil)
+				limiter.EXPECT().Limit(gomock.Any(), "email:quota:address:b@qq.com").Return(false, nil)
+				svc.EXPECT().Send(gomock.Any(), msg).Return(nil)
+				return svc, limiter
+			},
+			fn:  NewAddressQuotaService,
+			ctx: context.Background(),
+		},
+		{
+			name: "其中一个邮箱超过配额",
+			mock: func(ctrl *gomock.Controller) (email.Service, ratelimit.Limiter) {
+				limiter := limitmocks.NewMockLimiter(ctrl)
+				limiter.EXPECT().Limit(gomock.Any(), "email:quota:address:a@qq.com").Return(true, nil)
+				return emailmocks.NewMockService(ctrl), limiter
+			},
+			fn:      NewAddressQuotaService,
+			ctx:     context.Background(),
+			wantErr: ErrAddressQuotaExceeded,
+		},
+		{
+			name: "IP 超过配额",
+			mock: func(ctrl *gomock.Controller) (email.Service, ratelimit.Limiter) {
+				limiter := limitmocks.NewMockLimiter(ctrl)
+				limiter.EXPECT().Limit(gomock.Any(), "email:quota:ip:127.0.0.1").Return(true, nil)
+				return emailmocks.NewMockService(ctrl), limiter
+			},
+			fn:      NewIPQuotaService,
+			ctx:     WithIP(context.Background(), "127.0.0.1"),
+			wantErr: ErrIPQuotaExceeded,
+		},
+		{
+			name: "没有 IP 的不限制",
+			mock: func(ctrl *gomock.Controller) (email.Service, ratelimit.Limiter) {
+				svc := emailmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), msg).Return(nil)
+				return svc, limitmocks.NewMockLimiter(ctrl)
+			},
+			fn:  NewIPQuotaService,
+			ctx: context.Background(),
+		},
+		{
+			name: "判断配额失败",
+			mock: func(ctrl *gomock.Controller) (email.Service, ratelimit.Limiter) {
+				limiter := limitmocks.NewMockLimiter(ctrl)
+				limiter.EXPECT().Limit(gomock.Any(), "email:quota:ip:127.0.0.1").Return(false, errors.New("redis 错误"))
+				return emailmocks.NewMockService(ctrl), limiter
+			},
+			fn:      NewIPQuotaService,
+			ctx:     WithIP(context.Background(), "127.0.0.1"),
+			wantErr: fmt.Errorf("判断邮件配额失败: %w", errors.New("redis 错误")),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			s := tc.fn(tc.mock(ctrl))
+			err := s.Send(tc.ctx, msg)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
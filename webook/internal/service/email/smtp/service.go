@@ -0,0 +1,166 @@
+package smtp
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"go-basic/webook/internal/service/email"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+var errHeaderInjection = errors.New("邮件头不能包含换行")
+
+// defaultTimeout ctx 没有设置超时的时候，连接和整个会话都用这个超时时间，
+// 防止服务器不响应的时候一直卡住
+const defaultTimeout = time.Second * 30
+
+// Service 通过 SMTP 发送邮件。服务器支持 STARTTLS 的时候先升级成 TLS，
+// 配置了用户名的时候用 PLAIN 认证，net/smtp 不允许在明文连接上认证，除非是本机
+type Service struct {
+	addr     string
+	host     string
+	username string
+	password string
+	from     string
+	dialer   net.Dialer
+	timeout  time.Duration
+	now      func() time.Time
+}
+
+// NewService addr 是 host:port，from 是发件人地址
+func NewService(addr, username, password, from string) (*Service, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("SMTP 地址 %s 不合法: %w", addr, err)
+	}
+	return &Service{
+		addr:     addr,
+		host:     host,
+		username: username,
+		password: password,
+		from:     from,
+		dialer:   net.Dialer{Timeout: defaultTimeout},
+		timeout:  defaultTimeout,
+		now:      time.Now,
+	}, nil
+}
+
+func (s *Service) Send(ctx context.Context, msg email.Message) error {
+	if len(msg.To) == 0 {
+		return errors.New("没有收件人")
+	}
+	data, err := s.build(msg)
+	if err != nil {
+		return err
+	}
+	conn, err := s.dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	// net/smtp 不支持 ctx，用 deadline 控制整个会话的超时
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(s.timeout)
+	}
+	_ = conn.SetDeadline(deadline)
+	c, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err = c.StartTLS(&tls.Config{ServerName: s.host}); err != nil {
+			return err
+		}
+	}
+	if s.username != "" {
+		if err = c.Auth(smtp.PlainAuth("", s.username, s.password, s.host)); err != nil {
+			return err
+		}
+	}
+	if err = c.Mail(s.from); err != nil {
+		return err
+	}
+	for _, to := range msg.To {
+		if err = c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(data); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// build 生成 multipart/alternative 的邮件，正文用 quoted-printable 编码
+func (s *Service) build(msg email.Message) ([]byte, error) {
+	for _, h := range append([]string{s.from, msg.Subject}, msg.To...) {
+		if strings.ContainsAny(h, "\r\n") {
+			return nil, errHeaderInjection
+		}
+	}
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	parts := []struct {
+		contentType string
+		content     string
+	}{
+		{contentType: "text/plain", content: msg.Text},
+		{contentType: "text/html", content: msg.HTML},
+	}
+	for _, p := range parts {
+		if p.content == "" {
+			continue
+		}
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.contentType + "; charset=UTF-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qw := quotedprintable.NewWriter(pw)
+		if _, err = qw.Write([]byte(p.content)); err != nil {
+			return nil, err
+		}
+		if err = qw.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	headers := [][2]string{
+		{"From", s.from},
+		{"To", strings.Join(msg.To, ", ")},
+		{"Subject", mime.BEncoding.Encode("UTF-8", msg.Subject)},
+		{"Date", s.now().Format(time.RFC1123Z)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "multipart/alternative; boundary=" + mw.Boundary()},
+	}
+	for _, h := range headers {
+		buf.WriteString(h[0] + ": " + h[1] + "\r\n")
+	}
+	buf.WriteString("\r\n")
+	buf.Write(body.Bytes())
+	return buf.Bytes(), nil
+}
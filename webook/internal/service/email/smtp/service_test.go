@@ -0,0 +1,177 @@
+package smtp
+
+import (
+	"context"
+	"encoding/base64"
+	"go-basic/webook/internal/service/email"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// received 本地模拟的 SMTP 服务器收到的邮件
+type received struct {
+	auth string
+	from string
+	to   []string
+	data []byte
+}
+
+// newTestServer 只实现发送一封邮件需要的命令，收到的邮件写进 ch
+func newTestServer(t *testing.T, ch chan<- received) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = l.Close()
+	})
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		tc := textproto.NewConn(conn)
+		var r received
+		_ = tc.PrintfLine("220 localhost ESMTP")
+		for {
+			line, err := tc.ReadLine()
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+			switch cmd {
+			case "EHLO":
+				_ = tc.PrintfLine("250-localhost")
+				_ = tc.PrintfLine("250 AUTH PLAIN")
+			case "AUTH":
+				r.auth = strings.TrimPrefix(line, "AUTH PLAIN ")
+				_ = tc.PrintfLine("235 2.7.0 Authentication successful")
+			case "MAIL":
+				r.from = line
+				_ = tc.PrintfLine("250 OK")
+			case "RCPT":
+				r.to = append(r.to, line)
+				_ = tc.PrintfLine("250 OK")
+			case "DATA":
+				_ = tc.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+				r.data, err = tc.ReadDotBytes()
+				if err != nil {
+					return
+				}
+				_ = tc.PrintfLine("250 OK")
+			case "QUIT":
+				_ = tc.PrintfLine("221 Bye")
+				ch <- r
+				return
+			default:
+				_ = tc.PrintfLine("502 Command not implemented")
+			}
+		}
+	}()
+	return l.Addr().String()
+}
+
+func TestService_Send(t *testing.T) {
+	ch := make(chan received, 1)
+	addr := newTestServer(t, ch)
+	svc, err := NewService(addr, "webook", "secret", "noreply@webook.com")
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	err = svc.Send(ctx, email.Message{
+		To:      []string{"a@qq.com", "b@qq.com"},
+		Subject: "webook 登录验证码",
+		Text:    "验证码 123456",
+		HTML:    "<p>验证码 <b>123456</b></p>",
+	})
+	require.NoError(t, err)
+
+	var r received
+	select {
+	case r = <-ch:
+	case <-ctx.Done():
+		t.Fatal("SMTP 服务器没有收到邮件")
+	}
+	auth, err := base64.StdEncoding.DecodeString(r.auth)
+	require.NoError(t, err)
+	assert.Equal(t, "\x00webook\x00secret", string(auth))
+	assert.Equal(t, "MAIL FROM:<noreply@webook.com>", r.from)
+	assert.Equal(t, []string{"RCPT TO:<a@qq.com>", "RCPT TO:<b@qq.com>"}, r.to)
+
+	m, err := mail.ReadMessage(strings.NewReader(string(r.data)))
+	require.NoError(t, err)
+	subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "webook 登录验证码", subject)
+	assert.Equal(t, "a@qq.com, b@qq.com", m.Header.Get("To"))
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	parts := make(map[string]string)
+	mr := multipart.NewReader(m.Body, params["boundary"])
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		// NextPart 会自动解码 quoted-printable
+		content, err := io.ReadAll(p)
+		require.NoError(t, err)
+		parts[p.Header.Get("Content-Type")] = string(content)
+	}
+	assert.Equal(t, map[string]string{
+		"text/plain; charset=UTF-8": "验证码 123456",
+		"text/html; charset=UTF-8":  "<p>验证码 <b>123456</b></p>",
+	}, parts)
+}
+
+func TestService_SendHeaderInjection(t *testing.T) {
+	svc, err := NewService("127.0.0.1:25", "", "", "noreply@webook.com")
+	require.NoError(t, err)
+	err = svc.Send(context.Background(), email.Message{
+		To:      []string{"a@qq.com\r\nBcc: c@qq.com"},
+		Subject: "验证码",
+		Text:    "123456",
+	})
+	assert.Equal(t, errHeaderInjection, err)
+}
+
+func TestService_SendTimeout(t *testing.T) {
+	// 服务器接受连接之后什么都不返回
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = io.Copy(io.Discard, conn)
+	}()
+	svc, err := NewService(l.Addr().String(), "", "", "noreply@webook.com")
+	require.NoError(t, err)
+	svc.timeout = time.Millisecond * 100
+	// ctx 没有超时时间的时候用默认的超时时间
+	start := time.Now()
+	err = svc.Send(context.Background(), email.Message{
+		To:      []string{"a@qq.com"},
+		Subject: "验证码",
+		Text:    "123456",
+	})
+	var ne net.Error
+	require.ErrorAs(t, err, &ne)
+	assert.True(t, ne.Timeout())
+	assert.Less(t, time.Since(start), time.Second*5)
+}
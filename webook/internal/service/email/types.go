@@ -0,0 +1,15 @@
+package email
+
+import "context"
+
+// Message Text 和 HTML 至少要有一个，都有的时候由邮件客户端决定显示哪一个
+type Message struct {
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+type Service interface {
+	Send(ctx context.Context, msg Message) error
+}
@@ -2,8 +2,11 @@ package service
 
 import (
 	"context"
+	intrEvents "go-basic/webook/events/interactive"
 	"go-basic/webook/internal/domain"
 	"go-basic/webook/internal/repository"
+	"go-basic/webook/pkg/logger"
+	"time"
 
 	"golang.org/x/sync/errgroup"
 )
@@ -16,37 +19,122 @@ type InteractiveService interface {
 	Collect(ctx context.Context, biz string, bizId, cid, uid int64) error
 	Get(ctx context.Context, biz string, id int64, uid int64) (domain.Interactive, error)
 	GetByIds(ctx context.Context, biz string, ids []int64) (map[int64]domain.Interactive, error)
+	// Likers 点赞了某个资源的用户，按照点赞时间倒序，cursor 为零值表示第一页
+	Likers(ctx context.Context, biz string, id int64, cursor domain.LikeCursor, limit int) ([]domain.UserLike, error)
+	// LikedBy 用户点赞过的资源，包含所有业务
+	LikedBy(ctx context.Context, uid int64, cursor int64, limit int) ([]domain.UserLike, error)
 }
 
 type interactiveService struct {
-	repo repository.InteractiveRepository
+	repo     repository.InteractiveRepository
+	bizs     *InteractiveBizRegistry
+	producer intrEvents.Producer
+	l        logger.Logger
 }
 
-func NewInteractiveService(repo repository.InteractiveRepository) InteractiveService {
-	return &interactiveService{repo: repo}
+func NewInteractiveService(repo repository.InteractiveRepository, bizs *InteractiveBizRegistry,
+	producer intrEvents.Producer, l logger.Logger) InteractiveService {
+	return &interactiveService{repo: repo, bizs: bizs, producer: producer, l: l}
 }
 
 func (i *interactiveService) GetByIds(ctx context.Context, biz string, ids []int64) (map[int64]domain.Interactive, error) {
-	return map[int64]domain.Interactive{}, nil
+	if _, ok := i.bizs.Get(biz); !ok {
+		return nil, ErrUnknownBiz
+	}
+	if len(ids) == 0 {
+		return map[int64]domain.Interactive{}, nil
+	}
+	intrs, err := i.repo.GetByIds(ctx, biz, ids)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[int64]domain.Interactive, len(intrs))
+	for _, intr := range intrs {
+		res[intr.BizId] = intr
+	}
+	return res, nil
+}
+
+func (i *interactiveService) Likers(ctx context.Context, biz string, id int64, cursor domain.LikeCursor, limit int) ([]domain.UserLike, error) {
+	if _, ok := i.bizs.Get(biz); !ok {
+		return nil, ErrUnknownBiz
+	}
+	return i.repo.Likers(ctx, biz, id, cursor, limit)
+}
+
+func (i *interactiveService) LikedBy(ctx context.Context, uid int64, cursor int64, limit int) ([]domain.UserLike, error) {
+	return i.repo.LikedBy(ctx, uid, cursor, limit)
 }
 
 func (i *interactiveService) IncrReadCnt(ctx context.Context, biz string, bizId int64) error {
+	// 阅读是最热的路径，而且调用方刚刚查到了资源，所以不再查询 id 是否存在
+	err := i.bizs.CheckAction(biz, domain.InteractiveActionRead)
+	if err != nil {
+		return err
+	}
 	return i.repo.IncrReadCnt(ctx, biz, bizId)
 }
 
 func (i *interactiveService) Like(c context.Context, biz string, id int64, uid int64) error {
-	return i.repo.IncrLike(c, biz, id, uid)
+	err := i.bizs.Check(c, biz, id, domain.InteractiveActionLike)
+	if err != nil {
+		return err
+	}
+	err = i.repo.IncrLike(c, biz, id, uid)
+	if err == nil {
+		i.produceEvent(biz, id, uid, intrEvents.ActionLike)
+	}
+	return err
 }
 
 func (i *interactiveService) CancelLike(c context.Context, biz string, id int64, uid int64) error {
-	return i.repo.DecrLike(c, biz, id, uid)
+	// 资源被删除之后也允许取消点赞，所以不检查 id 是否存在
+	err := i.bizs.CheckAction(biz, domain.InteractiveActionLike)
+	if err != nil {
+		return err
+	}
+	err = i.repo.DecrLike(c, biz, id, uid)
+	if err == nil {
+		i.produceEvent(biz, id, uid, intrEvents.ActionCancelLike)
+	}
+	return err
 }
 
 func (i *interactiveService) Collect(ctx context.Context, biz string, bizId, cid, uid int64) error {
-	return i.repo.AddCollectionItem(ctx, biz, bizId, cid, uid)
+	err := i.bizs.Check(ctx, biz, bizId, domain.InteractiveActionCollect)
+	if err != nil {
+		return err
+	}
+	err = i.repo.AddCollectionItem(ctx, biz, bizId, cid, uid)
+	if err == nil {
+		i.produceEvent(biz, bizId, uid, intrEvents.ActionCollect)
+	}
+	return err
+}
+
+// produceEvent 互动事件用来增量计算热榜，发送失败不影响互动本身
+func (i *interactiveService) produceEvent(biz string, bizId, uid int64, action string) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		er := i.producer.ProduceInteractiveEvent(ctx, intrEvents.InteractiveEvent{
+			Biz:    biz,
+			BizId:  bizId,
+			Uid:    uid,
+			Action: action,
+		})
+		if er != nil {
+			i.l.Error("发送互动事件失败", logger.Error(er),
+				logger.String("biz", biz), logger.Int64("bizId", bizId), logger.String("action", action))
+		}
+	}()
 }
 
 func (i *interactiveService) Get(ctx context.Context, biz string, id int64, uid int64) (domain.Interactive, error) {
+	// 查询计数的时候调用方一般已经查过资源了，这里只检查业务是否注册
+	if _, ok := i.bizs.Get(biz); !ok {
+		return domain.Interactive{}, ErrUnknownBiz
+	}
 	var (
 		eg        errgroup.Group
 		intr      domain.Interactive
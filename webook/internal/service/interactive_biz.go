@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-basic/webook/internal/domain"
+	"time"
+)
+
+var (
+	ErrUnknownBiz              = errors.New("未注册的业务")
+	ErrBizNotFound             = errors.New("业务资源不存在")
+	ErrInteractiveNotSupported = errors.New("业务不支持该互动")
+)
+
+// 计数缓存默认的过期时间
+const defaultInteractiveCacheTTL = time.Minute * 15
+
+// BizExistsFunc 检查业务 id 是否存在
+type BizExistsFunc func(ctx context.Context, id int64) (bool, error)
+
+type registeredBiz struct {
+	domain.InteractiveBiz
+	exists BizExistsFunc
+}
+
+// InteractiveBizRegistry 接入互动服务的业务，新的业务只需要在这里注册，不需要复制一套接口
+// 只在启动的时候注册，之后只读，所以不需要加锁
+type InteractiveBizRegistry struct {
+	bizs map[string]registeredBiz
+}
+
+func NewInteractiveBizRegistry() *InteractiveBizRegistry {
+	return &InteractiveBizRegistry{
+		bizs: make(map[string]registeredBiz),
+	}
+}
+
+func (r *InteractiveBizRegistry) Register(biz domain.InteractiveBiz, exists BizExistsFunc) {
+	if _, ok := r.bizs[biz.Name]; ok {
+		panic(fmt.Sprintf("业务 %s 重复注册", biz.Name))
+	}
+	r.bizs[biz.Name] = registeredBiz{
+		InteractiveBiz: biz,
+		exists:         exists,
+	}
+}
+
+func (r *InteractiveBizRegistry) Get(biz string) (domain.InteractiveBiz, bool) {
+	b, ok := r.bizs[biz]
+	return b.InteractiveBiz, ok
+}
+
+// CheckAction 业务是否注册，以及是否支持 action，不查询 id 是否存在
+func (r *InteractiveBizRegistry) CheckAction(biz string, action domain.InteractiveAction) error {
+	b, ok := r.bizs[biz]
+	if !ok {
+		return ErrUnknownBiz
+	}
+	if !b.Allow(action) {
+		return ErrInteractiveNotSupported
+	}
+	return nil
+}
+
+// Check 业务是否注册，是否支持 action，以及 id 是否存在
+func (r *InteractiveBizRegistry) Check(ctx context.Context, biz string, id int64, action domain.InteractiveAction) error {
+	err := r.CheckAction(biz, action)
+	if err != nil {
+		return err
+	}
+	b := r.bizs[biz]
+	if b.exists == nil {
+		return nil
+	}
+	ok, err := b.exists(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrBizNotFound
+	}
+	return nil
+}
+
+// CacheTTL 业务计数缓存的过期时间
+func (r *InteractiveBizRegistry) CacheTTL(biz string) time.Duration {
+	b, ok := r.bizs[biz]
+	if !ok || b.CacheTTL <= 0 {
+		return defaultInteractiveCacheTTL
+	}
+	return b.CacheTTL
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"go-basic/webook/internal/domain"
+	"go-basic/webook/internal/repository"
+	"go-basic/webook/pkg/logger"
+)
+
+// InteractiveReconcileService 计数对账，点赞数和收藏数与明细表不是在同一条语句里更新的，时间长了会有偏差
+type InteractiveReconcileService interface {
+	// Reconcile 对账点赞数和收藏数，bizs 为空表示所有业务，dryRun 为 true 时只报告差异不修正。
+	// 每一批的差异交给 fn 处理，不会把整张表的差异都留在内存里
+	Reconcile(ctx context.Context, bizs []string, dryRun bool, fn func(diffs []domain.InteractiveCntDiff)) error
+}
+
+type interactiveReconcileService struct {
+	repo      repository.InteractiveRepository
+	batchSize int
+	l         logger.Logger
+}
+
+func NewInteractiveReconcileService(repo repository.InteractiveRepository, l logger.Logger) InteractiveReconcileService {
+	return &interactiveReconcileService{
+		repo:      repo,
+		batchSize: 100,
+		l:         l,
+	}
+}
+
+func (s *interactiveReconcileService) Reconcile(ctx context.Context, bizs []string, dryRun bool, fn func(diffs []domain.InteractiveCntDiff)) error {
+	var minId int64
+	for {
+		// 分批处理，避免一次性扫描整张表
+		diffs, next, err := s.repo.ReconcileCnt(ctx, bizs, minId, s.batchSize, dryRun)
+		for _, diff := range diffs {
+			s.l.Warn("计数与明细不一致",
+				logger.String("biz", diff.Biz),
+				logger.Int64("bizId", diff.BizId),
+				logger.Int64("like_cnt", diff.LikeCnt),
+				logger.Int64("expect_like_cnt", diff.ExpectLikeCnt),
+				logger.Int64("collect_cnt", diff.CollectCnt),
+				logger.Int64("expect_collect_cnt", diff.ExpectCollectCnt),
+				logger.Field{Key: "fixed", Value: diff.Fixed})
+		}
+		if len(diffs) > 0 {
+			fn(diffs)
+		}
+		if err != nil {
+			return err
+		}
+		if next == 0 {
+			return nil
+		}
+		minId = next
+	}
+}
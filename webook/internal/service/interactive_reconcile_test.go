@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"go-basic/webook/internal/domain"
+	"go-basic/webook/internal/repository"
+	repomocks "go-basic/webook/internal/repository/mocks"
+	"go-basic/webook/pkg/logger"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_interactiveReconcileService_Reconcile(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.InteractiveRepository
+
+		wantBatches [][]domain.InteractiveCntDiff
+		wantErr     error
+	}{
+		{
+			name: "分批回调",
+			mock: func(ctrl *gomock.Controller) repository.InteractiveRepository {
+				repo := repomocks.NewMockInteractiveRepository(ctrl)
+				repo.EXPECT().ReconcileCnt(gomock.Any(), []string{"article"}, int64(0), 100, false).
+					Return([]domain.InteractiveCntDiff{{Biz: "article", BizId: 1, Fixed: true}}, int64(100), nil)
+				// 这一批没有差异，不回调
+				repo.EXPECT().ReconcileCnt(gomock.Any(), []string{"article"}, int64(100), 100, false).
+					Return(nil, int64(200), nil)
+				repo.EXPECT().ReconcileCnt(gomock.Any(), []string{"article"}, int64(200), 100, false).
+					Return([]domain.InteractiveCntDiff{{Biz: "article", BizId: 201}}, int64(0), nil)
+				return repo
+			},
+			wantBatches: [][]domain.InteractiveCntDiff{
+				{{Biz: "article", BizId: 1, Fixed: true}},
+				{{Biz: "article", BizId: 201}},
+			},
+		},
+		{
+			name: "中途出错",
+			mock: func(ctrl *gomock.Controller) repository.InteractiveRepository {
+				repo := repomocks.NewMockInteractiveRepository(ctrl)
+				repo.EXPECT().ReconcileCnt(gomock.Any(), []string{"article"}, int64(0), 100, false).
+					Return([]domain.InteractiveCntDiff{{Biz: "article", BizId: 1, Fixed: true}}, int64(0), errors.New("db错误"))
+				return repo
+			},
+			wantBatches: [][]domain.InteractiveCntDiff{
+				{{Biz: "article", BizId: 1, Fixed: true}},
+			},
+			wantErr: errors.New("db错误"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewInteractiveReconcileService(tc.mock(ctrl), logger.NewNopLogger())
+			var batches [][]domain.InteractiveCntDiff
+			err := svc.Reconcile(context.Background(), []string{"article"}, false, func(diffs []domain.InteractiveCntDiff) {
+				batches = append(batches, diffs)
+			})
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantBatches, batches)
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"errors"
+	intrEvents "go-basic/webook/events/interactive"
+	"go-basic/webook/internal/domain"
+	"go-basic/webook/internal/repository"
+	repomocks "go-basic/webook/internal/repository/mocks"
+	"go-basic/webook/pkg/logger"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_interactiveService_Like(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.InteractiveRepository
+		biz  string
+		id   int64
+
+		wantErr error
+	}{
+		{
+			name: "点赞成功",
+			mock: func(ctrl *gomock.Controller) repository.InteractiveRepository {
+				repo := repomocks.NewMockInteractiveRepository(ctrl)
+				repo.EXPECT().IncrLike(gomock.Any(), "article", int64(1), int64(123)).Return(nil)
+				return repo
+			},
+			biz: "article",
+			id:  1,
+		},
+		{
+			name: "未注册的业务",
+			mock: func(ctrl *gomock.Controller) repository.InteractiveRepository {
+				return repomocks.NewMockInteractiveRepository(ctrl)
+			},
+			biz:     "unknown",
+			id:      1,
+			wantErr: ErrUnknownBiz,
+		},
+		{
+			name: "业务不支持点赞",
+			mock: func(ctrl *gomock.Controller) repository.InteractiveRepository {
+				return repomocks.NewMockInteractiveRepository(ctrl)
+			},
+			biz:     "readonly",
+			id:      1,
+			wantErr: ErrInteractiveNotSupported,
+		},
+		{
+			name: "资源不存在",
+			mock: func(ctrl *gomock.Controller) repository.InteractiveRepository {
+				return repomocks.NewMockInteractiveRepository(ctrl)
+			},
+			biz:     "article",
+			id:      2,
+			wantErr: ErrBizNotFound,
+		},
+		{
+			name: "检查资源是否存在失败",
+			mock: func(ctrl *gomock.Controller) repository.InteractiveRepository {
+				return repomocks.NewMockInteractiveRepository(ctrl)
+			},
+			biz:     "article",
+			id:      3,
+			wantErr: errors.New("mock db 错误"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewInteractiveService(tc.mock(ctrl), newTestBizRegistry(), nopInteractiveProducer{}, logger.NewNopLogger())
+			err := svc.Like(context.Background(), tc.biz, tc.id, 123)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func Test_interactiveService_IncrReadCnt(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.InteractiveRepository
+		biz  string
+		id   int64
+
+		wantErr error
+	}{
+		{
+			name: "不查询资源是否存在",
+			mock: func(ctrl *gomock.Controller) repository.InteractiveRepository {
+				repo := repomocks.NewMockInteractiveRepository(ctrl)
+				repo.EXPECT().IncrReadCnt(gomock.Any(), "article", int64(3)).Return(nil)
+				return repo
+			},
+			biz: "article",
+			id:  3,
+		},
+		{
+			name: "未注册的业务",
+			mock: func(ctrl *gomock.Controller) repository.InteractiveRepository {
+				return repomocks.NewMockInteractiveRepository(ctrl)
+			},
+			biz:     "unknown",
+			id:      1,
+			wantErr: ErrUnknownBiz,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewInteractiveService(tc.mock(ctrl), newTestBizRegistry(), nopInteractiveProducer{}, logger.NewNopLogger())
+			err := svc.IncrReadCnt(context.Background(), tc.biz, tc.id)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func TestInteractiveBizRegistry_CacheTTL(t *testing.T) {
+	registry := newTestBizRegistry()
+	assert.Equal(t, time.Minute, registry.CacheTTL("article"))
+	// 没有配置或者未注册的业务使用默认值
+	assert.Equal(t, defaultInteractiveCacheTTL, registry.CacheTTL("readonly"))
+	assert.Equal(t, defaultInteractiveCacheTTL, registry.CacheTTL("unknown"))
+}
+
+// newTestBizRegistry article 只有 1 存在，查询 3 会出错；readonly 只能阅读
+func newTestBizRegistry() *InteractiveBizRegistry {
+	registry := NewInteractiveBizRegistry()
+	registry.Register(domain.InteractiveBiz{
+		Name: "article",
+		Actions: []domain.InteractiveAction{
+			domain.InteractiveActionRead,
+			domain.InteractiveActionLike,
+			domain.InteractiveActionCollect,
+		},
+		CacheTTL: time.Minute,
+	}, func(ctx context.Context, id int64) (bool, error) {
+		if id == 3 {
+			return false, errors.New("mock db 错误")
+		}
+		return id == 1, nil
+	})
+	registry.Register(domain.InteractiveBiz{
+		Name:    "readonly",
+		Actions: []domain.InteractiveAction{domain.InteractiveActionRead},
+	}, nil)
+	return registry
+}
+
+type nopInteractiveProducer struct{}
+
+func (nopInteractiveProducer) ProduceInteractiveEvent(ctx context.Context, evt intrEvents.InteractiveEvent) error {
+	return nil
+}
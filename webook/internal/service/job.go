@@ -2,61 +2,384 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"go-basic/webook/internal/domain"
 	"go-basic/webook/internal/repository"
 	"go-basic/webook/pkg/logger"
+	"sync"
 	"time"
 )
 
+var (
+	ErrJobDuplicateName = repository.ErrJobDuplicateName
+	ErrJobNotFound      = repository.ErrJobNotFound
+	ErrJobLeaseLost     = repository.ErrJobLeaseLost
+	ErrInvalidCron      = errors.New("cron 表达式有误")
+	ErrJobRunning       = errors.New("任务正在执行")
+	ErrJobPaused        = errors.New("任务已暂停")
+	ErrJobNotPaused     = errors.New("任务没有暂停")
+	ErrJobDead          = errors.New("任务已经死掉，需要先恢复")
+	ErrInvalidRetry     = errors.New("重试策略有误")
+	ErrInvalidShard     = errors.New("执行方式或者分片数量有误")
+	ErrInvalidWorkflow  = errors.New("工作流定义有误")
+	ErrInvalidTimeout   = errors.New("超时时间有误")
+	ErrJobNotRunning    = errors.New("任务没有在执行")
+	ErrInvalidTimezone  = errors.New("时区有误")
+	ErrInvalidMisfire   = errors.New("错过执行时间的处理方式有误")
+)
+
+// 一次执行最多拆成多少片
+const maxJobShardCount = 1024
+
+//go:generate mockgen -source=job.go -package=svcmocks -destination=mocks/job.mock.go JobService
 type JobService interface {
-	// 抢占任务
-	Preempt(ctx context.Context) (domain.Job, error)
-	refresh(id int64)
+	// Preempt 抢占任务，executors 不为空的时候只抢这些执行器的任务
+	Preempt(ctx context.Context, executors []string) (domain.Job, error)
 	ResetNextTime(ctx context.Context, job domain.Job) error
+	// Fail 执行失败之后，还可以重试就安排重试，否则标记为死掉，返回任务是否死掉了。
+	// fatal 表示不可重试的错误，没有配置重试的任务按照 cron 继续调度
+	Fail(ctx context.Context, job domain.Job, fatal bool) (bool, error)
+
+	// 下面是管理接口，所有修改都直接落库，调度器下一次抢占的时候就能看到
+	Create(ctx context.Context, j domain.Job) (int64, error)
+	Update(ctx context.Context, j domain.Job) error
+	Delete(ctx context.Context, id int64) error
+	Pause(ctx context.Context, id int64) error
+	// Resume 恢复暂停或者死掉的任务
+	Resume(ctx context.Context, id int64) error
+	// Trigger 立刻执行一次，之后按照 cron 表达式继续调度
+	Trigger(ctx context.Context, id int64) error
+	// Cancel 取消正在执行的这一次，持有任务的实例最多过 cancelCheckInterval 就会停下来
+	Cancel(ctx context.Context, id int64) error
+	FindById(ctx context.Context, id int64) (domain.Job, error)
+	List(ctx context.Context, offset, limit int) ([]domain.Job, error)
 }
 
 type CronJobService struct {
 	repo            repository.JobRepository
 	refreshInterval time.Duration
-	l               logger.Logger
+	// 超过这个时间没有续约，就认为持有任务的实例已经崩溃了，别的实例可以重新抢占
+	leaseTimeout time.Duration
+	// 执行期间隔多久检查一次有没有被取消
+	cancelCheckInterval time.Duration
+	l                   logger.Logger
+}
+
+func NewCronJobService(repo repository.JobRepository, l logger.Logger) JobService {
+	return &CronJobService{
+		repo:            repo,
+		refreshInterval: time.Minute,
+		// 容忍连续两次续约失败
+		leaseTimeout:        time.Minute * 3,
+		cancelCheckInterval: time.Second * 5,
+		l:                   l,
+	}
 }
 
-func (p *CronJobService) Preempt(ctx context.Context) (domain.Job, error) {
-	j, err := p.repo.Preempt(ctx)
+func (p *CronJobService) Preempt(ctx context.Context, executors []string) (domain.Job, error) {
+	j, err := p.repo.Preempt(ctx, p.leaseTimeout, executors)
+	if err != nil {
+		return domain.Job{}, err
+	}
+	if j.Reclaimed {
+		p.l.Warn("抢占了租约过期的任务", logger.Int64("job_id", j.Id), logger.Int64("attempt", int64(j.Attempt)))
+	}
 
 	// 续约
-	ticker := time.NewTicker(p.refreshInterval)
-	go func() {
-		for range ticker.C {
-			p.refresh(j.Id)
-		}
-	}()
+	version := j.Version
+	lost, stop := keepLease(p.refreshInterval, func() bool {
+		return p.refresh(j.Id, version)
+	})
+	j.LeaseLost = lost
+	cancelled, stopWatch := keepLease(p.cancelCheckInterval, func() bool {
+		return p.cancelled(j.Id, version)
+	})
+	j.Cancelled = cancelled
 
 	// 抢占之后，考虑释放资源
-	version := j.Version
 	j.CancelFunc = func() error {
-		ticker.Stop()
+		stop()
+		stopWatch()
 		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 		defer cancel()
 		return p.repo.Release(ctx, j.Id, version)
 	}
-	return j, err
+	return j, nil
+}
+
+// keepLease 在后台定时续约，refresh 返回 true 表示租约已经丢了，这时候关闭返回的 channel。
+// 检查有没有被取消也是一样的模式。返回的 stop 可以重复调用
+func keepLease(interval time.Duration, refresh func() bool) (<-chan struct{}, func()) {
+	done := make(chan struct{})
+	lost := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			if refresh() {
+				// 租约已经丢了，后面也不用再续了
+				close(lost)
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return lost, func() {
+		once.Do(func() {
+			close(done)
+		})
+	}
 }
 
-func (p *CronJobService) refresh(id int64) {
+// refresh 续约，返回租约是否已经丢了
+func (p *CronJobService) refresh(id int64, version int) bool {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
 	// 续约，更新一下时间就可以
-	err := p.repo.UpdateUtime(ctx, id)
+	err := p.repo.UpdateUtime(ctx, id, version)
+	if err == repository.ErrJobLeaseLost {
+		p.l.Warn("任务已经被别的实例抢占", logger.Int64("job_id", id))
+		return true
+	}
 	if err != nil {
+		// 偶尔失败一次没关系，租约还没有过期
 		p.l.Error("续约失败", logger.Error(err), logger.Int64("job_id", id))
 	}
+	return false
+}
+
+// cancelled 查询失败就当作没有取消，下一次再查
+func (p *CronJobService) cancelled(id int64, version int) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	ok, err := p.repo.Cancelled(ctx, id, version)
+	if err != nil {
+		p.l.Error("查询任务是否被取消失败", logger.Error(err), logger.Int64("job_id", id))
+		return false
+	}
+	return ok
 }
 
 func (p *CronJobService) ResetNextTime(ctx context.Context, job domain.Job) error {
-	next := job.NextTime()
+	// 执行期间任务可能被修改或者删除了，按照最新的定义计算
+	latest, err := p.repo.FindById(ctx, job.Id)
+	if err == repository.ErrJobNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	after := time.Now()
+	if latest.Misfire == domain.MisfireFireAll && job.Attempt <= 1 {
+		// 从这一次计划的时间开始算，错过的执行会一次接一次地补上
+		after = job.NextRunTime
+	}
+	next, err := latest.NextTime(after)
+	if err != nil {
+		// 保存的时候校验过，只有直接改了数据库才会走到这里
+		p.l.Error("任务的 cron 表达式或者时区有误，暂停调度", logger.Error(err), logger.Int64("job_id", job.Id))
+		return p.repo.Stop(ctx, job.Id)
+	}
 	if next.IsZero() {
 		return p.repo.Stop(ctx, job.Id)
 	}
-	return p.repo.UpdateNextTime(ctx, job.Id, next)
+	return p.repo.UpdateNextTime(ctx, job.Id, job.Version, next)
+}
+
+func (p *CronJobService) Fail(ctx context.Context, job domain.Job, fatal bool) (bool, error) {
+	if !job.Retry.Enabled() {
+		return false, p.ResetNextTime(ctx, job)
+	}
+	if fatal || job.Attempt >= job.Retry.MaxAttempts {
+		return true, p.repo.MarkDead(ctx, job.Id, job.Version)
+	}
+	// 重试也是写在数据库里面，哪个实例抢到都可以执行
+	next := time.Now().Add(job.Retry.NextInterval(job.Attempt))
+	return false, p.repo.Retry(ctx, job.Id, job.Version, next)
+}
+
+func (p *CronJobService) Create(ctx context.Context, j domain.Job) (int64, error) {
+	if !j.Retry.Valid() {
+		return 0, ErrInvalidRetry
+	}
+	if j.Timeout < 0 {
+		return 0, ErrInvalidTimeout
+	}
+	j, err := p.checkMode(j)
+	if err != nil {
+		return 0, err
+	}
+	err = p.checkWorkflow(ctx, j)
+	if err != nil {
+		return 0, err
+	}
+	next, err := p.firstRunTime(j)
+	if err != nil {
+		return 0, err
+	}
+	j.Status = domain.JobStatusWaiting
+	j.NextRunTime = next
+	return p.repo.Create(ctx, j)
+}
+
+func (p *CronJobService) Update(ctx context.Context, j domain.Job) error {
+	if !j.Retry.Valid() {
+		return ErrInvalidRetry
+	}
+	if j.Timeout < 0 {
+		return ErrInvalidTimeout
+	}
+	j, err := p.checkMode(j)
+	if err != nil {
+		return err
+	}
+	err = p.checkWorkflow(ctx, j)
+	if err != nil {
+		return err
+	}
+	next, err := p.firstRunTime(j)
+	if err != nil {
+		return err
+	}
+	j.NextRunTime = next
+	return p.repo.Update(ctx, j)
+}
+
+// checkMode 只有分片模式需要 ShardCount，其它模式统一清零
+func (p *CronJobService) checkMode(j domain.Job) (domain.Job, error) {
+	switch j.Mode {
+	case domain.JobModeSharded:
+		if j.ShardCount <= 0 || j.ShardCount > maxJobShardCount {
+			return j, ErrInvalidShard
+		}
+	case domain.JobModeNormal, domain.JobModeBroadcast:
+		j.ShardCount = 0
+	default:
+		return j, ErrInvalidShard
+	}
+	return j, nil
+}
+
+// checkWorkflow 工作流的节点必须是已经存在的普通任务，不能嵌套工作流。
+// 节点由工作流直接执行，必须是暂停状态，否则自己的 cron 到点了会被抢占再执行一次
+func (p *CronJobService) checkWorkflow(ctx context.Context, j domain.Job) error {
+	if j.Executor != domain.WorkflowExecutor {
+		return nil
+	}
+	if j.Mode != domain.JobModeNormal {
+		return ErrInvalidShard
+	}
+	wf, err := domain.ParseWorkflow(j.Cfg)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidWorkflow, err)
+	}
+	for _, n := range wf.Nodes {
+		node, err := p.repo.FindById(ctx, n.JobId)
+		if err == repository.ErrJobNotFound {
+			return fmt.Errorf("%w: 任务 %d 不存在", ErrInvalidWorkflow, n.JobId)
+		}
+		if err != nil {
+			return err
+		}
+		if node.Executor == domain.WorkflowExecutor {
+			return fmt.Errorf("%w: 任务 %d 是工作流", ErrInvalidWorkflow, n.JobId)
+		}
+		if node.Status != domain.JobStatusPaused {
+			return fmt.Errorf("%w: 任务 %d 没有暂停，会按照自己的 cron 重复执行", ErrInvalidWorkflow, n.JobId)
+		}
+	}
+	return nil
+}
+
+func (p *CronJobService) Delete(ctx context.Context, id int64) error {
+	return p.repo.Delete(ctx, id)
+}
+
+func (p *CronJobService) Pause(ctx context.Context, id int64) error {
+	// 正在执行的任务这一次会执行完，释放的时候不会覆盖暂停的状态
+	_, err := p.repo.FindById(ctx, id)
+	if err != nil {
+		return err
+	}
+	return p.repo.Stop(ctx, id)
+}
+
+func (p *CronJobService) Resume(ctx context.Context, id int64) error {
+	j, err := p.repo.FindById(ctx, id)
+	if err != nil {
+		return err
+	}
+	if j.Status != domain.JobStatusPaused && j.Status != domain.JobStatusDead {
+		return ErrJobNotPaused
+	}
+	next, err := p.firstRunTime(j)
+	if err != nil {
+		return err
+	}
+	return p.repo.Resume(ctx, id, next)
+}
+
+func (p *CronJobService) Trigger(ctx context.Context, id int64) error {
+	j, err := p.repo.FindById(ctx, id)
+	if err != nil {
+		return err
+	}
+	switch j.Status {
+	case domain.JobStatusRunning:
+		return ErrJobRunning
+	case domain.JobStatusPaused:
+		return ErrJobPaused
+	case domain.JobStatusDead:
+		return ErrJobDead
+	}
+	return p.repo.Trigger(ctx, id)
+}
+
+func (p *CronJobService) Cancel(ctx context.Context, id int64) error {
+	j, err := p.repo.FindById(ctx, id)
+	if err != nil {
+		return err
+	}
+	if j.Status != domain.JobStatusRunning {
+		return ErrJobNotRunning
+	}
+	err = p.repo.Cancel(ctx, id)
+	if err == repository.ErrJobNotFound {
+		// 查询之后刚好执行完了
+		return ErrJobNotRunning
+	}
+	return err
+}
+
+func (p *CronJobService) FindById(ctx context.Context, id int64) (domain.Job, error) {
+	return p.repo.FindById(ctx, id)
+}
+
+func (p *CronJobService) List(ctx context.Context, offset, limit int) ([]domain.Job, error) {
+	return p.repo.List(ctx, offset, limit)
+}
+
+// firstRunTime 校验 cron 表达式和时区，并且计算从现在开始的下一次执行时间
+func (p *CronJobService) firstRunTime(j domain.Job) (time.Time, error) {
+	if !j.Misfire.Valid() {
+		return time.Time{}, ErrInvalidMisfire
+	}
+	if _, err := domain.ParseCron(j.Cron); err != nil {
+		return time.Time{}, ErrInvalidCron
+	}
+	next, err := j.NextTime(time.Now())
+	if err != nil {
+		return time.Time{}, ErrInvalidTimezone
+	}
+	if next.IsZero() {
+		// 例如 2 月 30 号，永远不会执行
+		return time.Time{}, ErrInvalidCron
+	}
+	return next, nil
 }
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"go-basic/webook/internal/domain"
+	"go-basic/webook/internal/repository"
+	"go-basic/webook/pkg/stringx"
+	"time"
+)
+
+// 和数据库的字段长度保持一致
+const maxJobRunErrMsgLen = 1024
+
+//go:generate mockgen -source=job_run.go -package=svcmocks -destination=mocks/job_run.mock.go JobRunService
+type JobRunService interface {
+	// Start 开始执行任务之前记录一条执行中的记录
+	Start(ctx context.Context, j domain.Job, instance string, attempt int) (domain.JobRun, error)
+	// Finish execErr 为 nil 表示执行成功
+	Finish(ctx context.Context, run domain.JobRun, execErr error) error
+	// Abandon 任务从崩溃的实例手里收回来之后，把之前还在执行的记录标记为放弃，返回放弃的条数
+	Abandon(ctx context.Context, jobId int64) (int64, error)
+	List(ctx context.Context, jobId int64, status domain.JobRunStatus, offset, limit int) ([]domain.JobRun, error)
+	// Cleanup 删除 before 之前开始的执行记录，返回删除的条数
+	Cleanup(ctx context.Context, before time.Time) (int64, error)
+}
+
+type jobRunService struct {
+	repo repository.JobRunRepository
+	// 清理的时候每一批删除多少条
+	batchSize int
+}
+
+func NewJobRunService(repo repository.JobRunRepository) JobRunService {
+	return &jobRunService{
+		repo:      repo,
+		batchSize: 1000,
+	}
+}
+
+func (s *jobRunService) Start(ctx context.Context, j domain.Job, instance string, attempt int) (domain.JobRun, error) {
+	run := domain.JobRun{
+		JobId:     j.Id,
+		Instance:  instance,
+		Attempt:   attempt,
+		Status:    domain.JobRunStatusRunning,
+		StartTime: time.Now(),
+	}
+	id, err := s.repo.Create(ctx, run)
+	run.Id = id
+	return run, err
+}
+
+func (s *jobRunService) Finish(ctx context.Context, run domain.JobRun, execErr error) error {
+	run.EndTime = time.Now()
+	run.Status = domain.JobRunStatusSuccess
+	if execErr != nil {
+		run.Status = domain.JobRunStatusFailed
+		run.ErrMsg = stringx.Truncate(execErr.Error(), maxJobRunErrMsgLen)
+	}
+	return s.repo.Finish(ctx, run)
+}
+
+func (s *jobRunService) Abandon(ctx context.Context, jobId int64) (int64, error) {
+	return s.repo.Abandon(ctx, jobId, "持有任务的实例租约过期，任务已经被收回", time.Now())
+}
+
+func (s *jobRunService) List(ctx context.Context, jobId int64, status domain.JobRunStatus,
+	offset, limit int) ([]domain.JobRun, error) {
+	return s.repo.List(ctx, jobId, status, offset, limit)
+}
+
+func (s *jobRunService) Cleanup(ctx context.Context, before time.Time) (int64, error) {
+	var total int64
+	for {
+		cnt, err := s.repo.DeleteBefore(ctx, before, s.batchSize)
+		total += cnt
+		if err != nil {
+			return total, err
+		}
+		if cnt < int64(s.batchSize) {
+			return total, nil
+		}
+		if ctx.Err() != nil {
+			return total, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"go-basic/webook/internal/domain"
+	repomocks "go-basic/webook/internal/repository/mocks"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobRunService_Finish(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := repomocks.NewMockJobRunRepository(ctrl)
+	svc := NewJobRunService(repo)
+	start := time.Now().Add(-time.Second)
+
+	repo.EXPECT().Finish(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, r domain.JobRun) error {
+		assert.Equal(t, domain.JobRunStatusSuccess, r.Status)
+		assert.Empty(t, r.ErrMsg)
+		assert.True(t, r.EndTime.After(start))
+		return nil
+	})
+	err := svc.Finish(context.Background(), domain.JobRun{Id: 1, StartTime: start}, nil)
+	assert.NoError(t, err)
+
+	// 错误信息太长的时候截断，并且不能截断半个汉字
+	repo.EXPECT().Finish(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, r domain.JobRun) error {
+		assert.Equal(t, domain.JobRunStatusFailed, r.Status)
+		assert.LessOrEqual(t, len(r.ErrMsg), maxJobRunErrMsgLen)
+		assert.True(t, utf8.ValidString(r.ErrMsg))
+		return nil
+	})
+	err = svc.Finish(context.Background(), domain.JobRun{Id: 2, StartTime: start},
+		errors.New("a"+strings.Repeat("超时", 1000)))
+	assert.NoError(t, err)
+}
+
+func TestJobRunService_Cleanup(t *testing.T) {
+	before := time.Now().Add(-time.Hour)
+	testCases := []struct {
+		name    string
+		deleted []int64
+		err     error
+
+		wantCnt int64
+		wantErr error
+	}{
+		{
+			name:    "分批删除直到删完",
+			deleted: []int64{2, 2, 1},
+			wantCnt: 5,
+		},
+		{
+			name:    "刚好删完",
+			deleted: []int64{2, 0},
+			wantCnt: 2,
+		},
+		{
+			name:    "删除失败",
+			deleted: []int64{2, 0},
+			err:     errors.New("mock db 错误"),
+			wantCnt: 2,
+			wantErr: errors.New("mock db 错误"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			repo := repomocks.NewMockJobRunRepository(ctrl)
+			for i, cnt := range tc.deleted {
+				var err error
+				if i == len(tc.deleted)-1 {
+					err = tc.err
+				}
+				repo.EXPECT().DeleteBefore(gomock.Any(), before, 2).Return(cnt, err)
+			}
+			svc := &jobRunService{repo: repo, batchSize: 2}
+			cnt, err := svc.Cleanup(context.Background(), before)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantCnt, cnt)
+		})
+	}
+}
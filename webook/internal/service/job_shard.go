@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"go-basic/webook/internal/domain"
+	"go-basic/webook/internal/repository"
+	"go-basic/webook/pkg/logger"
+	"go-basic/webook/pkg/stringx"
+	"time"
+)
+
+var ErrNoLiveInstance = errors.New("没有存活的实例可以执行广播任务")
+
+//go:generate mockgen -source=job_shard.go -package=svcmocks -destination=mocks/job_shard.mock.go JobShardService
+type JobShardService interface {
+	// Dispatch 开始新一轮执行，Round 就是抢占任务之后的版本号。
+	// 分片任务按照 ShardCount 拆分，广播任务给 instances 里面的每个实例一片
+	Dispatch(ctx context.Context, j domain.Job, instances []string) error
+	// Preempt 抢占一个分片，返回的 Job 是分片所属的任务，带上了分片信息
+	Preempt(ctx context.Context, instance string) (domain.Job, error)
+	// Finish 记录分片的执行结果，execErr 为 nil 表示执行成功
+	Finish(ctx context.Context, j domain.Job, execErr error) error
+	// Progress 某一轮所有分片的状态
+	Progress(ctx context.Context, jobId int64, round int) ([]domain.JobShard, error)
+	Cancel(ctx context.Context, ids []int64) error
+}
+
+type jobShardService struct {
+	repo    repository.JobShardRepository
+	jobRepo repository.JobRepository
+	// 续约和租约过期的时间和任务保持一致
+	refreshInterval time.Duration
+	leaseTimeout    time.Duration
+	l               logger.Logger
+}
+
+func NewJobShardService(repo repository.JobShardRepository, jobRepo repository.JobRepository,
+	l logger.Logger) JobShardService {
+	return &jobShardService{
+		repo:            repo,
+		jobRepo:         jobRepo,
+		refreshInterval: time.Minute,
+		leaseTimeout:    time.Minute * 3,
+		l:               l,
+	}
+}
+
+func (s *jobShardService) Dispatch(ctx context.Context, j domain.Job, instances []string) error {
+	var shards []domain.JobShard
+	switch j.Mode {
+	case domain.JobModeSharded:
+		shards = make([]domain.JobShard, 0, j.ShardCount)
+		for i := 0; i < j.ShardCount; i++ {
+			shards = append(shards, domain.JobShard{JobId: j.Id, Round: j.Version, Index: i, Total: j.ShardCount})
+		}
+	case domain.JobModeBroadcast:
+		if len(instances) == 0 {
+			return ErrNoLiveInstance
+		}
+		shards = make([]domain.JobShard, 0, len(instances))
+		for i, instance := range instances {
+			shards = append(shards, domain.JobShard{JobId: j.Id, Round: j.Version, Index: i,
+				Total: len(instances), Instance: instance})
+		}
+	default:
+		return ErrInvalidShard
+	}
+	return s.repo.Dispatch(ctx, j.Id, shards)
+}
+
+func (s *jobShardService) Preempt(ctx context.Context, instance string) (domain.Job, error) {
+	shard, err := s.repo.Preempt(ctx, instance, s.leaseTimeout)
+	if err != nil {
+		return domain.Job{}, err
+	}
+	j, err := s.jobRepo.FindById(ctx, shard.JobId)
+	if err == repository.ErrJobNotFound {
+		// 任务已经删掉了，分片也不用执行了
+		er := s.repo.Cancel(ctx, []int64{shard.Id})
+		if er != nil {
+			s.l.Error("取消分片失败", logger.Error(er), logger.Int64("shard_id", shard.Id))
+		}
+		return domain.Job{}, err
+	}
+	if err != nil {
+		er := s.repo.Release(ctx, shard.Id, shard.Version)
+		if er != nil {
+			s.l.Error("释放分片失败", logger.Error(er), logger.Int64("shard_id", shard.Id))
+		}
+		return domain.Job{}, err
+	}
+	// 执行器看到的版本号是这一轮的版本号，和整个任务执行的时候保持一致
+	j.Version = shard.Round
+	j.Shard = shard
+
+	lost, stop := keepLease(s.refreshInterval, func() bool {
+		return s.refresh(shard.Id, shard.Version)
+	})
+	j.LeaseLost = lost
+	j.CancelFunc = func() error {
+		stop()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		// 已经执行完的分片释放不了，会返回 ErrJobLeaseLost
+		return s.repo.Release(ctx, shard.Id, shard.Version)
+	}
+	return j, nil
+}
+
+func (s *jobShardService) refresh(id int64, version int) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := s.repo.UpdateUtime(ctx, id, version)
+	if err == repository.ErrJobLeaseLost {
+		s.l.Warn("分片已经被别的实例抢占或者取消", logger.Int64("shard_id", id))
+		return true
+	}
+	if err != nil {
+		s.l.Error("分片续约失败", logger.Error(err), logger.Int64("shard_id", id))
+	}
+	return false
+}
+
+func (s *jobShardService) Finish(ctx context.Context, j domain.Job, execErr error) error {
+	shard := j.Shard
+	shard.Status = domain.JobShardStatusSuccess
+	if execErr != nil {
+		shard.Status = domain.JobShardStatusFailed
+		shard.ErrMsg = stringx.Truncate(execErr.Error(), maxJobRunErrMsgLen)
+	}
+	return s.repo.Finish(ctx, shard)
+}
+
+func (s *jobShardService) Progress(ctx context.Context, jobId int64, round int) ([]domain.JobShard, error) {
+	return s.repo.FindByRound(ctx, jobId, round)
+}
+
+func (s *jobShardService) Cancel(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return s.repo.Cancel(ctx, ids)
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"go-basic/webook/internal/domain"
+	"go-basic/webook/internal/repository"
+	repomocks "go-basic/webook/internal/repository/mocks"
+	"go-basic/webook/pkg/logger"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobShardService_Dispatch(t *testing.T) {
+	testCases := []struct {
+		name      string
+		mock      func(ctrl *gomock.Controller) repository.JobShardRepository
+		job       domain.Job
+		instances []string
+
+		wantErr error
+	}{
+		{
+			name: "按照分片数量拆分",
+			mock: func(ctrl *gomock.Controller) repository.JobShardRepository {
+				repo := repomocks.NewMockJobShardRepository(ctrl)
+				repo.EXPECT().Dispatch(gomock.Any(), int64(1), []domain.JobShard{
+					{JobId: 1, Round: 3, Index: 0, Total: 3},
+					{JobId: 1, Round: 3, Index: 1, Total: 3},
+					{JobId: 1, Round: 3, Index: 2, Total: 3},
+				}).Return(nil)
+				return repo
+			},
+			job: domain.Job{Id: 1, Version: 3, Mode: domain.JobModeSharded, ShardCount: 3},
+		},
+		{
+			name: "广播给每个实例",
+			mock: func(ctrl *gomock.Controller) repository.JobShardRepository {
+				repo := repomocks.NewMockJobShardRepository(ctrl)
+				repo.EXPECT().Dispatch(gomock.Any(), int64(1), []domain.JobShard{
+					{JobId: 1, Round: 3, Index: 0, Total: 2, Instance: "a"},
+					{JobId: 1, Round: 3, Index: 1, Total: 2, Instance: "b"},
+				}).Return(nil)
+				return repo
+			},
+			job:       domain.Job{Id: 1, Version: 3, Mode: domain.JobModeBroadcast},
+			instances: []string{"a", "b"},
+		},
+		{
+			name: "没有存活的实例",
+			mock: func(ctrl *gomock.Controller) repository.JobShardRepository {
+				return repomocks.NewMockJobShardRepository(ctrl)
+			},
+			job:     domain.Job{Id: 1, Version: 3, Mode: domain.JobModeBroadcast},
+			wantErr: ErrNoLiveInstance,
+		},
+		{
+			name: "普通任务不需要分派",
+			mock: func(ctrl *gomock.Controller) repository.JobShardRepository {
+				return repomocks.NewMockJobShardRepository(ctrl)
+			},
+			job:     domain.Job{Id: 1, Version: 3},
+			wantErr: ErrInvalidShard,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewJobShardService(tc.mock(ctrl), repomocks.NewMockJobRepository(ctrl), logger.NewNopLogger())
+			err := svc.Dispatch(context.Background(), tc.job, tc.instances)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func TestJobShardService_Preempt(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := repomocks.NewMockJobShardRepository(ctrl)
+	jobRepo := repomocks.NewMockJobRepository(ctrl)
+	svc := NewJobShardService(repo, jobRepo, logger.NewNopLogger())
+
+	shard := domain.JobShard{Id: 10, JobId: 1, Round: 3, Index: 1, Total: 2, Version: 2}
+	repo.EXPECT().Preempt(gomock.Any(), "a", gomock.Any()).Return(shard, nil)
+	jobRepo.EXPECT().FindById(gomock.Any(), int64(1)).
+		Return(domain.Job{Id: 1, Name: "reconcile", Version: 4, Mode: domain.JobModeSharded}, nil)
+	repo.EXPECT().Release(gomock.Any(), int64(10), 2).Return(nil)
+	j, err := svc.Preempt(context.Background(), "a")
+	assert.NoError(t, err)
+	// 版本号用的是分派时候的版本号
+	assert.Equal(t, 3, j.Version)
+	assert.Equal(t, shard, j.Shard)
+	assert.NoError(t, j.CancelFunc())
+
+	// 任务已经删掉了，分片直接取消
+	repo.EXPECT().Preempt(gomock.Any(), "a", gomock.Any()).Return(shard, nil)
+	jobRepo.EXPECT().FindById(gomock.Any(), int64(1)).Return(domain.Job{}, repository.ErrJobNotFound)
+	repo.EXPECT().Cancel(gomock.Any(), []int64{10}).Return(nil)
+	_, err = svc.Preempt(context.Background(), "a")
+	assert.Equal(t, ErrJobNotFound, err)
+}
@@ -0,0 +1,564 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"go-basic/webook/internal/domain"
+	"go-basic/webook/internal/repository"
+	repomocks "go-basic/webook/internal/repository/mocks"
+	"go-basic/webook/pkg/logger"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCronJobService_Create(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.JobRepository
+		job  domain.Job
+
+		wantId  int64
+		wantErr error
+	}{
+		{
+			name: "创建成功",
+			mock: func(ctrl *gomock.Controller) repository.JobRepository {
+				repo := repomocks.NewMockJobRepository(ctrl)
+				repo.EXPECT().Create(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, j domain.Job) (int64, error) {
+						assert.Equal(t, domain.JobStatusWaiting, j.Status)
+						// 每分钟执行一次，下一次执行时间在一分钟以内
+						assert.True(t, j.NextRunTime.After(time.Now()))
+						assert.True(t, j.NextRunTime.Before(time.Now().Add(time.Minute+time.Second)))
+						return 1, nil
+					})
+				return repo
+			},
+			job:    domain.Job{Name: "ranking", Cron: "0 * * * * ?", Executor: "local"},
+			wantId: 1,
+		},
+		{
+			name: "cron 表达式有误",
+			mock: func(ctrl *gomock.Controller) repository.JobRepository {
+				return repomocks.NewMockJobRepository(ctrl)
+			},
+			job:     domain.Job{Name: "ranking", Cron: "every minute", Executor: "local"},
+			wantErr: ErrInvalidCron,
+		},
+		{
+			name: "永远不会执行的 cron 表达式",
+			mock: func(ctrl *gomock.Controller) repository.JobRepository {
+				return repomocks.NewMockJobRepository(ctrl)
+			},
+			job:     domain.Job{Name: "ranking", Cron: "0 0 0 30 2 ?", Executor: "local"},
+			wantErr: ErrInvalidCron,
+		},
+		{
+			name: "按照任务的时区计算",
+			mock: func(ctrl *gomock.Controller) repository.JobRepository {
+				repo := repomocks.NewMockJobRepository(ctrl)
+				repo.EXPECT().Create(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, j domain.Job) (int64, error) {
+						loc, err := time.LoadLocation("Asia/Shanghai")
+						require.NoError(t, err)
+						assert.Equal(t, 9, j.NextRunTime.In(loc).Hour())
+						return 1, nil
+					})
+				return repo
+			},
+			job:    domain.Job{Name: "report", Cron: "0 0 9 * * ?", Timezone: "Asia/Shanghai", Executor: "http"},
+			wantId: 1,
+		},
+		{
+			name: "时区有误",
+			mock: func(ctrl *gomock.Controller) repository.JobRepository {
+				return repomocks.NewMockJobRepository(ctrl)
+			},
+			job:     domain.Job{Name: "report", Cron: "0 0 9 * * ?", Timezone: "Mars/Olympus", Executor: "http"},
+			wantErr: ErrInvalidTimezone,
+		},
+		{
+			name: "错过执行时间的处理方式有误",
+			mock: func(ctrl *gomock.Controller) repository.JobRepository {
+				return repomocks.NewMockJobRepository(ctrl)
+			},
+			job:     domain.Job{Name: "report", Cron: "0 0 9 * * ?", Misfire: domain.MisfirePolicy(9), Executor: "http"},
+			wantErr: ErrInvalidMisfire,
+		},
+		{
+			name: "分片模式没有分片数量",
+			mock: func(ctrl *gomock.Controller) repository.JobRepository {
+				return repomocks.NewMockJobRepository(ctrl)
+			},
+			job:     domain.Job{Name: "reconcile", Cron: "@every 1h", Executor: "http", Mode: domain.JobModeSharded},
+			wantErr: ErrInvalidShard,
+		},
+		{
+			name: "任务名称冲突",
+			mock: func(ctrl *gomock.Controller) repository.JobRepository {
+				repo := repomocks.NewMockJobRepository(ctrl)
+				repo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(int64(0), repository.ErrJobDuplicateName)
+				return repo
+			},
+			job:     domain.Job{Name: "ranking", Cron: "@every 1m", Executor: "local"},
+			wantErr: ErrJobDuplicateName,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewCronJobService(tc.mock(ctrl), logger.NewNopLogger())
+			id, err := svc.Create(context.Background(), tc.job)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantId, id)
+		})
+	}
+}
+
+func TestCronJobService_CheckWorkflow(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.JobRepository
+		cfg  string
+
+		wantErr error
+	}{
+		{
+			name: "合法的工作流",
+			mock: func(ctrl *gomock.Controller) repository.JobRepository {
+				repo := repomocks.NewMockJobRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).Return(domain.Job{Id: 1, Executor: "local", Status: domain.JobStatusPaused}, nil)
+				repo.EXPECT().FindById(gomock.Any(), int64(2)).
+					Return(domain.Job{Id: 2, Executor: "http", Status: domain.JobStatusPaused}, nil)
+				return repo
+			},
+			cfg: `{"nodes":[{"jobId":1},{"jobId":2,"upstreams":[1]}]}`,
+		},
+		{
+			name: "有环",
+			mock: func(ctrl *gomock.Controller) repository.JobRepository {
+				return repomocks.NewMockJobRepository(ctrl)
+			},
+			cfg:     `{"nodes":[{"jobId":1,"upstreams":[2]},{"jobId":2,"upstreams":[1]}]}`,
+			wantErr: ErrInvalidWorkflow,
+		},
+		{
+			name: "上游不在工作流里面",
+			mock: func(ctrl *gomock.Controller) repository.JobRepository {
+				return repomocks.NewMockJobRepository(ctrl)
+			},
+			cfg:     `{"nodes":[{"jobId":1,"upstreams":[3]}]}`,
+			wantErr: ErrInvalidWorkflow,
+		},
+		{
+			name: "节点重复",
+			mock: func(ctrl *gomock.Controller) repository.JobRepository {
+				return repomocks.NewMockJobRepository(ctrl)
+			},
+			cfg:     `{"nodes":[{"jobId":1},{"jobId":1}]}`,
+			wantErr: ErrInvalidWorkflow,
+		},
+		{
+			name: "节点任务不存在",
+			mock: func(ctrl *gomock.Controller) repository.JobRepository {
+				repo := repomocks.NewMockJobRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).Return(domain.Job{}, repository.ErrJobNotFound)
+				return repo
+			},
+			cfg:     `{"nodes":[{"jobId":1}]}`,
+			wantErr: ErrInvalidWorkflow,
+		},
+		{
+			name: "嵌套工作流",
+			mock: func(ctrl *gomock.Controller) repository.JobRepository {
+				repo := repomocks.NewMockJobRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).
+					Return(domain.Job{Id: 1, Executor: domain.WorkflowExecutor}, nil)
+				return repo
+			},
+			cfg:     `{"nodes":[{"jobId":1}]}`,
+			wantErr: ErrInvalidWorkflow,
+		},
+		{
+			name: "节点任务还有自己的调度",
+			mock: func(ctrl *gomock.Controller) repository.JobRepository {
+				repo := repomocks.NewMockJobRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).
+					Return(domain.Job{Id: 1, Executor: "local", Status: domain.JobStatusWaiting}, nil)
+				return repo
+			},
+			cfg:     `{"nodes":[{"jobId":1}]}`,
+			wantErr: ErrInvalidWorkflow,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewCronJobService(tc.mock(ctrl), logger.NewNopLogger())
+			err := svc.(*CronJobService).checkWorkflow(context.Background(), domain.Job{Executor: domain.WorkflowExecutor, Cfg: tc.cfg})
+			assert.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestCronJobService_Trigger(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.JobRepository
+
+		wantErr error
+	}{
+		{
+			name: "立刻执行",
+			mock: func(ctrl *gomock.Controller) repository.JobRepository {
+				repo := repomocks.NewMockJobRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).
+					Return(domain.Job{Id: 1, Status: domain.JobStatusWaiting}, nil)
+				repo.EXPECT().Trigger(gomock.Any(), int64(1)).Return(nil)
+				return repo
+			},
+		},
+		{
+			name: "任务正在执行",
+			mock: func(ctrl *gomock.Controller) repository.JobRepository {
+				repo := repomocks.NewMockJobRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).
+					Return(domain.Job{Id: 1, Status: domain.JobStatusRunning}, nil)
+				return repo
+			},
+			wantErr: ErrJobRunning,
+		},
+		{
+			name: "任务已暂停",
+			mock: func(ctrl *gomock.Controller) repository.JobRepository {
+				repo := repomocks.NewMockJobRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).
+					Return(domain.Job{Id: 1, Status: domain.JobStatusPaused}, nil)
+				return repo
+			},
+			wantErr: ErrJobPaused,
+		},
+		{
+			name: "任务不存在",
+			mock: func(ctrl *gomock.Controller) repository.JobRepository {
+				repo := repomocks.NewMockJobRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).
+					Return(domain.Job{}, repository.ErrJobNotFound)
+				return repo
+			},
+			wantErr: ErrJobNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewCronJobService(tc.mock(ctrl), logger.NewNopLogger())
+			err := svc.Trigger(context.Background(), 1)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func TestCronJobService_Cancel(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.JobRepository
+
+		wantErr error
+	}{
+		{
+			name: "取消成功",
+			mock: func(ctrl *gomock.Controller) repository.JobRepository {
+				repo := repomocks.NewMockJobRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).
+					Return(domain.Job{Id: 1, Status: domain.JobStatusRunning}, nil)
+				repo.EXPECT().Cancel(gomock.Any(), int64(1)).Return(nil)
+				return repo
+			},
+		},
+		{
+			name: "任务没有在执行",
+			mock: func(ctrl *gomock.Controller) repository.JobRepository {
+				repo := repomocks.NewMockJobRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).
+					Return(domain.Job{Id: 1, Status: domain.JobStatusWaiting}, nil)
+				return repo
+			},
+			wantErr: ErrJobNotRunning,
+		},
+		{
+			name: "取消之前刚好执行完",
+			mock: func(ctrl *gomock.Controller) repository.JobRepository {
+				repo := repomocks.NewMockJobRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).
+					Return(domain.Job{Id: 1, Status: domain.JobStatusRunning}, nil)
+				repo.EXPECT().Cancel(gomock.Any(), int64(1)).Return(repository.ErrJobNotFound)
+				return repo
+			},
+			wantErr: ErrJobNotRunning,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewCronJobService(tc.mock(ctrl), logger.NewNopLogger())
+			err := svc.Cancel(context.Background(), 1)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func TestCronJobService_Resume(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.JobRepository
+
+		wantErr error
+	}{
+		{
+			name: "恢复调度",
+			mock: func(ctrl *gomock.Controller) repository.JobRepository {
+				repo := repomocks.NewMockJobRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).
+					Return(domain.Job{Id: 1, Cron: "@every 1h", Status: domain.JobStatusPaused}, nil)
+				repo.EXPECT().Resume(gomock.Any(), int64(1), gomock.Any()).Return(nil)
+				return repo
+			},
+		},
+		{
+			name: "恢复死掉的任务",
+			mock: func(ctrl *gomock.Controller) repository.JobRepository {
+				repo := repomocks.NewMockJobRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).
+					Return(domain.Job{Id: 1, Cron: "@every 1h", Status: domain.JobStatusDead}, nil)
+				repo.EXPECT().Resume(gomock.Any(), int64(1), gomock.Any()).Return(nil)
+				return repo
+			},
+		},
+		{
+			name: "任务没有暂停",
+			mock: func(ctrl *gomock.Controller) repository.JobRepository {
+				repo := repomocks.NewMockJobRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).
+					Return(domain.Job{Id: 1, Cron: "@every 1h", Status: domain.JobStatusWaiting}, nil)
+				return repo
+			},
+			wantErr: ErrJobNotPaused,
+		},
+		{
+			name: "恢复失败",
+			mock: func(ctrl *gomock.Controller) repository.JobRepository {
+				repo := repomocks.NewMockJobRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).
+					Return(domain.Job{Id: 1, Cron: "@every 1h", Status: domain.JobStatusPaused}, nil)
+				repo.EXPECT().Resume(gomock.Any(), int64(1), gomock.Any()).Return(errors.New("mock db 错误"))
+				return repo
+			},
+			wantErr: errors.New("mock db 错误"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewCronJobService(tc.mock(ctrl), logger.NewNopLogger())
+			err := svc.Resume(context.Background(), 1)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func TestCronJobService_Fail(t *testing.T) {
+	retry := domain.RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     domain.BackoffExponential,
+		Interval:    time.Second * 10,
+		MaxInterval: time.Minute,
+	}
+	testCases := []struct {
+		name  string
+		mock  func(ctrl *gomock.Controller) repository.JobRepository
+		job   domain.Job
+		fatal bool
+
+		wantDead bool
+		wantErr  error
+	}{
+		{
+			name: "安排重试",
+			mock: func(ctrl *gomock.Controller) repository.JobRepository {
+				repo := repomocks.NewMockJobRepository(ctrl)
+				repo.EXPECT().Retry(gomock.Any(), int64(1), 5, gomock.Any()).
+					DoAndReturn(func(ctx context.Context, id int64, version int, next time.Time) error {
+						// 第二次失败，隔 20 秒重试
+						assert.WithinDuration(t, time.Now().Add(time.Second*20), next, time.Second)
+						return nil
+					})
+				return repo
+			},
+			job: domain.Job{Id: 1, Version: 5, Attempt: 2, Retry: retry},
+		},
+		{
+			name: "次数用完",
+			mock: func(ctrl *gomock.Controller) repository.JobRepository {
+				repo := repomocks.NewMockJobRepository(ctrl)
+				repo.EXPECT().MarkDead(gomock.Any(), int64(1), 5).Return(nil)
+				return repo
+			},
+			job:      domain.Job{Id: 1, Version: 5, Attempt: 3, Retry: retry},
+			wantDead: true,
+		},
+		{
+			name: "不可重试的错误",
+			mock: func(ctrl *gomock.Controller) repository.JobRepository {
+				repo := repomocks.NewMockJobRepository(ctrl)
+				repo.EXPECT().MarkDead(gomock.Any(), int64(1), 5).Return(nil)
+				return repo
+			},
+			job:      domain.Job{Id: 1, Version: 5, Attempt: 1, Retry: retry},
+			fatal:    true,
+			wantDead: true,
+		},
+		{
+			name: "没有配置重试，按照 cron 继续调度",
+			mock: func(ctrl *gomock.Controller) repository.JobRepository {
+				repo := repomocks.NewMockJobRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).
+					Return(domain.Job{Id: 1, Cron: "@every 1h", Status: domain.JobStatusRunning}, nil)
+				repo.EXPECT().UpdateNextTime(gomock.Any(), int64(1), 5, gomock.Any()).Return(nil)
+				return repo
+			},
+			job:   domain.Job{Id: 1, Version: 5, Attempt: 1},
+			fatal: true,
+		},
+		{
+			name: "重试失败",
+			mock: func(ctrl *gomock.Controller) repository.JobRepository {
+				repo := repomocks.NewMockJobRepository(ctrl)
+				repo.EXPECT().Retry(gomock.Any(), int64(1), 5, gomock.Any()).Return(errors.New("mock db 错误"))
+				return repo
+			},
+			job:     domain.Job{Id: 1, Version: 5, Attempt: 1, Retry: retry},
+			wantErr: errors.New("mock db 错误"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewCronJobService(tc.mock(ctrl), logger.NewNopLogger())
+			dead, err := svc.Fail(context.Background(), tc.job, tc.fatal)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantDead, dead)
+		})
+	}
+}
+
+func TestCronJobService_ResetNextTime(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := repomocks.NewMockJobRepository(ctrl)
+	// 执行期间 cron 被改成了每小时一次，按照新的表达式计算
+	repo.EXPECT().FindById(gomock.Any(), int64(1)).
+		Return(domain.Job{Id: 1, Cron: "@every 1h"}, nil)
+	repo.EXPECT().UpdateNextTime(gomock.Any(), int64(1), 3, gomock.Any()).
+		DoAndReturn(func(ctx context.Context, id int64, version int, next time.Time) error {
+			assert.True(t, next.After(time.Now().Add(time.Minute*59)))
+			return nil
+		})
+	svc := NewCronJobService(repo, logger.NewNopLogger())
+	err := svc.ResetNextTime(context.Background(), domain.Job{Id: 1, Version: 3, Cron: "@every 1m"})
+	assert.NoError(t, err)
+
+	// 执行期间被删掉了
+	repo.EXPECT().FindById(gomock.Any(), int64(2)).Return(domain.Job{}, repository.ErrJobNotFound)
+	err = svc.ResetNextTime(context.Background(), domain.Job{Id: 2, Cron: "@every 1m"})
+	assert.NoError(t, err)
+
+	// 错过的每一次都要补，从计划的时间开始算，下一次还是在过去
+	missed := time.Now().Add(-time.Hour * 3)
+	repo.EXPECT().FindById(gomock.Any(), int64(3)).
+		Return(domain.Job{Id: 3, Cron: "@every 1h", Misfire: domain.MisfireFireAll}, nil)
+	repo.EXPECT().UpdateNextTime(gomock.Any(), int64(3), 3, gomock.Any()).
+		DoAndReturn(func(ctx context.Context, id int64, version int, next time.Time) error {
+			assert.True(t, next.Before(time.Now().Add(-time.Hour)))
+			return nil
+		})
+	err = svc.ResetNextTime(context.Background(), domain.Job{Id: 3, Version: 3, Attempt: 1, NextRunTime: missed})
+	assert.NoError(t, err)
+
+	// 直接改了数据库，表达式不合法，暂停调度而不是在零值的时候执行
+	repo.EXPECT().FindById(gomock.Any(), int64(4)).Return(domain.Job{Id: 4, Cron: "every minute"}, nil)
+	repo.EXPECT().Stop(gomock.Any(), int64(4)).Return(nil)
+	err = svc.ResetNextTime(context.Background(), domain.Job{Id: 4, Version: 3})
+	assert.NoError(t, err)
+}
+
+func TestCronJobService_Preempt(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := repomocks.NewMockJobRepository(ctrl)
+	repo.EXPECT().Preempt(gomock.Any(), time.Minute*3, []string{"local"}).
+		Return(domain.Job{Id: 1, Version: 3, Status: domain.JobStatusRunning, Reclaimed: true}, nil)
+	// 第一次续约成功，第二次发现已经被别人抢走了
+	repo.EXPECT().UpdateUtime(gomock.Any(), int64(1), 3).Return(nil)
+	repo.EXPECT().UpdateUtime(gomock.Any(), int64(1), 3).Return(repository.ErrJobLeaseLost)
+	repo.EXPECT().Release(gomock.Any(), int64(1), 3).Return(repository.ErrJobLeaseLost).Times(2)
+
+	svc := NewCronJobService(repo, logger.NewNopLogger()).(*CronJobService)
+	svc.refreshInterval = time.Millisecond * 10
+	j, err := svc.Preempt(context.Background(), []string{"local"})
+	require.NoError(t, err)
+	assert.True(t, j.Reclaimed)
+	select {
+	case <-j.LeaseLost:
+	case <-time.After(time.Second):
+		t.Fatal("没有通知租约丢失")
+	}
+	err = j.CancelFunc()
+	assert.Equal(t, ErrJobLeaseLost, err)
+	// 重复调用不会 panic
+	err = j.CancelFunc()
+	assert.Equal(t, ErrJobLeaseLost, err)
+}
+
+func TestCronJobService_PreemptCancelled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := repomocks.NewMockJobRepository(ctrl)
+	repo.EXPECT().Preempt(gomock.Any(), time.Minute*3, []string{"local"}).
+		Return(domain.Job{Id: 1, Version: 3, Status: domain.JobStatusRunning}, nil)
+	// 第二次检查的时候管理接口已经取消了
+	gomock.InOrder(
+		repo.EXPECT().Cancelled(gomock.Any(), int64(1), 3).Return(false, nil),
+		repo.EXPECT().Cancelled(gomock.Any(), int64(1), 3).Return(true, nil),
+	)
+	repo.EXPECT().Release(gomock.Any(), int64(1), 3).Return(nil)
+
+	svc := NewCronJobService(repo, logger.NewNopLogger()).(*CronJobService)
+	svc.refreshInterval = time.Hour
+	svc.cancelCheckInterval = time.Millisecond * 10
+	j, err := svc.Preempt(context.Background(), []string{"local"})
+	require.NoError(t, err)
+	select {
+	case <-j.Cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("没有通知任务被取消")
+	}
+	err = j.CancelFunc()
+	assert.NoError(t, err)
+}
@@ -120,3 +120,33 @@ func (mr *MockInteractiveServiceMockRecorder) Like(c, biz, id, uid interface{})
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Like", reflect.TypeOf((*MockInteractiveService)(nil).Like), c, biz, id, uid)
 }
+
+// LikedBy mocks base method.
+func (m *MockInteractiveService) LikedBy(ctx context.Context, uid, cursor int64, limit int) ([]domain.UserLike, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LikedBy", ctx, uid, cursor, limit)
+	ret0, _ := ret[0].([]domain.UserLike)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LikedBy indicates an expected call of LikedBy.
+func (mr *MockInteractiveServiceMockRecorder) LikedBy(ctx, uid, cursor, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LikedBy", reflect.TypeOf((*MockInteractiveService)(nil).LikedBy), ctx, uid, cursor, limit)
+}
+
+// Likers mocks base method.
+func (m *MockInteractiveService) Likers(ctx context.Context, biz string, id int64, cursor domain.LikeCursor, limit int) ([]domain.UserLike, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Likers", ctx, biz, id, cursor, limit)
+	ret0, _ := ret[0].([]domain.UserLike)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Likers indicates an expected call of Likers.
+func (mr *MockInteractiveServiceMockRecorder) Likers(ctx, biz, id, cursor, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Likers", reflect.TypeOf((*MockInteractiveService)(nil).Likers), ctx, biz, id, cursor, limit)
+}
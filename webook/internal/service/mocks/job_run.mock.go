@@ -0,0 +1,111 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: job_run.go
+
+// Package svcmocks is a generated GoMock package.
+package svcmocks
+
+import (
+	context "context"
+	domain "go-basic/webook/internal/domain"
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockJobRunService is a mock of JobRunService interface.
+type MockJobRunService struct {
+	ctrl     *gomock.Controller
+	recorder *MockJobRunServiceMockRecorder
+}
+
+// MockJobRunServiceMockRecorder is the mock recorder for MockJobRunService.
+type MockJobRunServiceMockRecorder struct {
+	mock *MockJobRunService
+}
+
+// NewMockJobRunService creates a new mock instance.
+func NewMockJobRunService(ctrl *gomock.Controller) *MockJobRunService {
+	mock := &MockJobRunService{ctrl: ctrl}
+	mock.recorder = &MockJobRunServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockJobRunService) EXPECT() *MockJobRunServiceMockRecorder {
+	return m.recorder
+}
+
+// Abandon mocks base method.
+func (m *MockJobRunService) Abandon(ctx context.Context, jobId int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Abandon", ctx, jobId)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Abandon indicates an expected call of Abandon.
+func (mr *MockJobRunServiceMockRecorder) Abandon(ctx, jobId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Abandon", reflect.TypeOf((*MockJobRunService)(nil).Abandon), ctx, jobId)
+}
+
+// Cleanup mocks base method.
+func (m *MockJobRunService) Cleanup(ctx context.Context, before time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Cleanup", ctx, before)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Cleanup indicates an expected call of Cleanup.
+func (mr *MockJobRunServiceMockRecorder) Cleanup(ctx, before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Cleanup", reflect.TypeOf((*MockJobRunService)(nil).Cleanup), ctx, before)
+}
+
+// Finish mocks base method.
+func (m *MockJobRunService) Finish(ctx context.Context, run domain.JobRun, execErr error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Finish", ctx, run, execErr)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Finish indicates an expected call of Finish.
+func (mr *MockJobRunServiceMockRecorder) Finish(ctx, run, execErr interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Finish", reflect.TypeOf((*MockJobRunService)(nil).Finish), ctx, run, execErr)
+}
+
+// List mocks base method.
+func (m *MockJobRunService) List(ctx context.Context, jobId int64, status domain.JobRunStatus, offset, limit int) ([]domain.JobRun, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, jobId, status, offset, limit)
+	ret0, _ := ret[0].([]domain.JobRun)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockJobRunServiceMockRecorder) List(ctx, jobId, status, offset, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockJobRunService)(nil).List), ctx, jobId, status, offset, limit)
+}
+
+// Start mocks base method.
+func (m *MockJobRunService) Start(ctx context.Context, j domain.Job, instance string, attempt int) (domain.JobRun, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Start", ctx, j, instance, attempt)
+	ret0, _ := ret[0].(domain.JobRun)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Start indicates an expected call of Start.
+func (mr *MockJobRunServiceMockRecorder) Start(ctx, j, instance, attempt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Start", reflect.TypeOf((*MockJobRunService)(nil).Start), ctx, j, instance, attempt)
+}
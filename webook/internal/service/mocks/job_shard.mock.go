@@ -0,0 +1,108 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: job_shard.go
+
+// Package svcmocks is a generated GoMock package.
+package svcmocks
+
+import (
+	context "context"
+	domain "go-basic/webook/internal/domain"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockJobShardService is a mock of JobShardService interface.
+type MockJobShardService struct {
+	ctrl     *gomock.Controller
+	recorder *MockJobShardServiceMockRecorder
+}
+
+// MockJobShardServiceMockRecorder is the mock recorder for MockJobShardService.
+type MockJobShardServiceMockRecorder struct {
+	mock *MockJobShardService
+}
+
+// NewMockJobShardService creates a new mock instance.
+func NewMockJobShardService(ctrl *gomock.Controller) *MockJobShardService {
+	mock := &MockJobShardService{ctrl: ctrl}
+	mock.recorder = &MockJobShardServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockJobShardService) EXPECT() *MockJobShardServiceMockRecorder {
+	return m.recorder
+}
+
+// Cancel mocks base method.
+func (m *MockJobShardService) Cancel(ctx context.Context, ids []int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Cancel", ctx, ids)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Cancel indicates an expected call of Cancel.
+func (mr *MockJobShardServiceMockRecorder) Cancel(ctx, ids interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Cancel", reflect.TypeOf((*MockJobShardService)(nil).Cancel), ctx, ids)
+}
+
+// Dispatch mocks base method.
+func (m *MockJobShardService) Dispatch(ctx context.Context, j domain.Job, instances []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Dispatch", ctx, j, instances)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Dispatch indicates an expected call of Dispatch.
+func (mr *MockJobShardServiceMockRecorder) Dispatch(ctx, j, instances interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Dispatch", reflect.TypeOf((*MockJobShardService)(nil).Dispatch), ctx, j, instances)
+}
+
+// Finish mocks base method.
+func (m *MockJobShardService) Finish(ctx context.Context, j domain.Job, execErr error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Finish", ctx, j, execErr)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Finish indicates an expected call of Finish.
+func (mr *MockJobShardServiceMockRecorder) Finish(ctx, j, execErr interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Finish", reflect.TypeOf((*MockJobShardService)(nil).Finish), ctx, j, execErr)
+}
+
+// Preempt mocks base method.
+func (m *MockJobShardService) Preempt(ctx context.Context, instance string) (domain.Job, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Preempt", ctx, instance)
+	ret0, _ := ret[0].(domain.Job)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Preempt indicates an expected call of Preempt.
+func (mr *MockJobShardServiceMockRecorder) Preempt(ctx, instance interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Preempt", reflect.TypeOf((*MockJobShardService)(nil).Preempt), ctx, instance)
+}
+
+// Progress mocks base method.
+func (m *MockJobShardService) Progress(ctx context.Context, jobId int64, round int) ([]domain.JobShard, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Progress", ctx, jobId, round)
+	ret0, _ := ret[0].([]domain.JobShard)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Progress indicates an expected call of Progress.
+func (mr *MockJobShardServiceMockRecorder) Progress(ctx, jobId, round interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Progress", reflect.TypeOf((*MockJobShardService)(nil).Progress), ctx, jobId, round)
+}
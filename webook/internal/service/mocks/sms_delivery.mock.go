@@ -0,0 +1,67 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: sms_delivery.go
+
+// Package svcmocks is a generated GoMock package.
+package svcmocks
+
+import (
+	context "context"
+	domain "go-basic/webook/internal/domain"
+	sms "go-basic/webook/internal/service/sms"
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockSMSDeliveryService is a mock of SMSDeliveryService interface.
+type MockSMSDeliveryService struct {
+	ctrl     *gomock.Controller
+	recorder *MockSMSDeliveryServiceMockRecorder
+}
+
+// MockSMSDeliveryServiceMockRecorder is the mock recorder for MockSMSDeliveryService.
+type MockSMSDeliveryServiceMockRecorder struct {
+	mock *MockSMSDeliveryService
+}
+
+// NewMockSMSDeliveryService creates a new mock instance.
+func NewMockSMSDeliveryService(ctrl *gomock.Controller) *MockSMSDeliveryService {
+	mock := &MockSMSDeliveryService{ctrl: ctrl}
+	mock.recorder = &MockSMSDeliveryServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSMSDeliveryService) EXPECT() *MockSMSDeliveryServiceMockRecorder {
+	return m.recorder
+}
+
+// Receive mocks base method.
+func (m *MockSMSDeliveryService) Receive(ctx context.Context, provider string, receipts []sms.Receipt) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Receive", ctx, provider, receipts)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Receive indicates an expected call of Receive.
+func (mr *MockSMSDeliveryServiceMockRecorder) Receive(ctx, provider, receipts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Receive", reflect.TypeOf((*MockSMSDeliveryService)(nil).Receive), ctx, provider, receipts)
+}
+
+// Stats mocks base method.
+func (m *MockSMSDeliveryService) Stats(ctx context.Context, start, end time.Time) ([]domain.SMSDeliveryStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stats", ctx, start, end)
+	ret0, _ := ret[0].([]domain.SMSDeliveryStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Stats indicates an expected call of Stats.
+func (mr *MockSMSDeliveryServiceMockRecorder) Stats(ctx, start, end interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stats", reflect.TypeOf((*MockSMSDeliveryService)(nil).Stats), ctx, start, end)
+}
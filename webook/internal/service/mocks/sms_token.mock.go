@@ -0,0 +1,110 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: sms_token.go
+
+// Package svcmocks is a generated GoMock package.
+package svcmocks
+
+import (
+	context "context"
+	domain "go-basic/webook/internal/domain"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockSMSBizTokenService is a mock of SMSBizTokenService interface.
+type MockSMSBizTokenService struct {
+	ctrl     *gomock.Controller
+	recorder *MockSMSBizTokenServiceMockRecorder
+}
+
+// MockSMSBizTokenServiceMockRecorder is the mock recorder for MockSMSBizTokenService.
+type MockSMSBizTokenServiceMockRecorder struct {
+	mock *MockSMSBizTokenService
+}
+
+// NewMockSMSBizTokenService creates a new mock instance.
+func NewMockSMSBizTokenService(ctrl *gomock.Controller) *MockSMSBizTokenService {
+	mock := &MockSMSBizTokenService{ctrl: ctrl}
+	mock.recorder = &MockSMSBizTokenServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSMSBizTokenService) EXPECT() *MockSMSBizTokenServiceMockRecorder {
+	return m.recorder
+}
+
+// Check mocks base method.
+func (m *MockSMSBizTokenService) Check(ctx context.Context, token string) (domain.SMSBizToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Check", ctx, token)
+	ret0, _ := ret[0].(domain.SMSBizToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Check indicates an expected call of Check.
+func (mr *MockSMSBizTokenServiceMockRecorder) Check(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Check", reflect.TypeOf((*MockSMSBizTokenService)(nil).Check), ctx, token)
+}
+
+// Consume mocks base method.
+func (m *MockSMSBizTokenService) Consume(ctx context.Context, t domain.SMSBizToken, n int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Consume", ctx, t, n)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Consume indicates an expected call of Consume.
+func (mr *MockSMSBizTokenServiceMockRecorder) Consume(ctx, t, n interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Consume", reflect.TypeOf((*MockSMSBizTokenService)(nil).Consume), ctx, t, n)
+}
+
+// Issue mocks base method.
+func (m *MockSMSBizTokenService) Issue(ctx context.Context, t domain.SMSBizToken) (domain.SMSBizToken, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Issue", ctx, t)
+	ret0, _ := ret[0].(domain.SMSBizToken)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Issue indicates an expected call of Issue.
+func (mr *MockSMSBizTokenServiceMockRecorder) Issue(ctx, t interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Issue", reflect.TypeOf((*MockSMSBizTokenService)(nil).Issue), ctx, t)
+}
+
+// List mocks base method.
+func (m *MockSMSBizTokenService) List(ctx context.Context, offset, limit int) ([]domain.SMSBizToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, offset, limit)
+	ret0, _ := ret[0].([]domain.SMSBizToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockSMSBizTokenServiceMockRecorder) List(ctx, offset, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockSMSBizTokenService)(nil).List), ctx, offset, limit)
+}
+
+// Revoke mocks base method.
+func (m *MockSMSBizTokenService) Revoke(ctx context.Context, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Revoke", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Revoke indicates an expected call of Revoke.
+func (mr *MockSMSBizTokenServiceMockRecorder) Revoke(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Revoke", reflect.TypeOf((*MockSMSBizTokenService)(nil).Revoke), ctx, id)
+}
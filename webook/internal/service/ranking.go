@@ -4,16 +4,18 @@ import (
 	"context"
 	"go-basic/webook/internal/domain"
 	"go-basic/webook/internal/repository"
-	"math"
 	"time"
 
 	"github.com/ecodeclub/ekit/queue"
 	"github.com/ecodeclub/ekit/slice"
 )
 
+//go:generate mockgen -source=ranking.go -package=svcmocks -destination=mocks/ranking.mock.go RankingService
 type RankingService interface {
+	// TopN 计算热榜并存起来
 	TopN(ctx context.Context) error
-	topN(ctx context.Context) ([]domain.Article, error)
+	// GetTopN 查询计算好的热榜
+	GetTopN(ctx context.Context, key domain.RankingKey) ([]domain.Article, error)
 }
 
 type BatchRankingService struct {
@@ -22,49 +24,58 @@ type BatchRankingService struct {
 	intrSvc   InteractiveService
 	batchSize int
 	n         int
-	scoreFunc func(t time.Time, likeCnt int64) float64
+	scorer    RankingScorer
 }
 
-func NewBatchRankingService(artSvc ArticleService, intrSvc InteractiveService) RankingService {
+func NewBatchRankingService(artSvc ArticleService, intrSvc InteractiveService,
+	repo repository.RankingRepository, scorer RankingScorer) RankingService {
 	return &BatchRankingService{
+		repo:      repo,
 		artSvc:    artSvc,
 		intrSvc:   intrSvc,
 		batchSize: 100,
 		n:         100,
-		scoreFunc: func(t time.Time, likeCnt int64) float64 {
-			sec := time.Since(t).Seconds()
-			return float64(likeCnt-1) / math.Pow(float64(sec+2), 1.5)
-		},
+		scorer:    scorer,
 	}
 }
 
+func (s *BatchRankingService) GetTopN(ctx context.Context, key domain.RankingKey) ([]domain.Article, error) {
+	return s.repo.GetTopN(ctx, key)
+}
+
 func (s *BatchRankingService) TopN(ctx context.Context) error {
-	arts, err := s.topN(ctx)
+	lists, err := s.topN(ctx)
 	if err != nil {
 		return err
 	}
-	// 存起来
-	return s.repo.ReplaceTopN(ctx, arts)
+	// 计算期间分布式锁丢了，别的节点会接着算，不能再写入结果
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	// 存起来，一个榜单失败不影响其它榜单
+	var lastErr error
+	for key, arts := range lists {
+		err = s.repo.ReplaceTopN(ctx, key, arts)
+		if err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+type rankingArticle struct {
+	art   domain.Article
+	score float64
 }
 
-func (s *BatchRankingService) topN(ctx context.Context) ([]domain.Article, error) {
+// topN 只扫描一遍数据，同时计算所有分类和所有时间窗口的榜单
+func (s *BatchRankingService) topN(ctx context.Context) (map[domain.RankingKey][]domain.Article, error) {
 	now := time.Now()
+	maxWindow := domain.RankingWindows[len(domain.RankingWindows)-1].Duration()
+	queues := make(map[domain.RankingKey]*queue.ConcurrentPriorityQueue[rankingArticle])
 	// 先获取一批数据
 	offset := 0
-	type Score struct {
-		art   domain.Article
-		score float64
-	}
-	topN := queue.NewConcurrentPriorityQueue[Score](s.n, func(i, j Score) int {
-		if i.score > j.score {
-			return 1
-		} else if i.score < j.score {
-			return -1
-		}
-		return 0
-	})
 	for {
-		// 先获取一批数据
 		arts, err := s.artSvc.ListPub(ctx, offset, s.batchSize, now)
 		if err != nil {
 			return nil, err
@@ -77,46 +88,95 @@ func (s *BatchRankingService) topN(ctx context.Context) ([]domain.Article, error
 		if err != nil {
 			return nil, err
 		}
-		// 合并计算score
-		// 排序
 		for _, art := range arts {
 			intr, ok := intrs[art.Id]
 			if !ok {
 				// 没有点赞数据, 跳过
 				continue
 			}
-			score := s.scoreFunc(art.Utime, intr.LikeCnt)
-			// 考虑这个score是否在前一百名
-			err = topN.Enqueue(Score{
+			item := rankingArticle{
 				art:   art,
-				score: score,
-			})
-			if err == queue.ErrOutOfCapacity {
-				val, _ := topN.Peek()
-				if score > val.score {
-					topN.Dequeue()
-					topN.Enqueue(Score{
-						art:   art,
-						score: score,
-					})
+				score: s.scorer.Score(now, art, intr),
+			}
+			// 按照发表时间划分时间窗口，编辑老文章不会让它回到最近的榜单
+			age := now.Sub(art.Ctime)
+			for _, w := range domain.RankingWindows {
+				if age > w.Duration() {
+					continue
+				}
+				s.enqueue(queues, domain.RankingKey{Window: w}, item)
+				if art.Category != "" {
+					s.enqueue(queues, domain.RankingKey{Category: art.Category, Window: w}, item)
 				}
 			}
 		}
-		// 一批已经处理完了，看看是否还有下一批，当数据不足或者数据太旧时，停止
-		if len(arts) < s.batchSize || now.Sub(arts[0].Utime) > 7*24*time.Hour {
+		// 一批已经处理完了，看看是否还有下一批，当数据不足或者超出了最长的时间窗口时，停止
+		if len(arts) < s.batchSize || now.Sub(arts[len(arts)-1].Ctime) > maxWindow {
 			break
 		}
-		// 更新offset
 		offset = offset + len(arts)
 	}
-	// 得出结果
-	res := make([]domain.Article, s.n)
-	for i := s.n - 1; i >= 0; i-- {
-		val, err := topN.Dequeue()
-		if err != nil {
-			break
+	res := make(map[domain.RankingKey][]domain.Article, len(queues))
+	for key, q := range queues {
+		// 文章不够 n 篇的时候不能留下空的文章
+		arts := make([]domain.Article, q.Len())
+		for i := len(arts) - 1; i >= 0; i-- {
+			val, err := q.Dequeue()
+			if err != nil {
+				break
+			}
+			arts[i] = val.art
+		}
+		res[key] = arts
+	}
+	// 没有数据的榜单也要显式写空榜单，否则缓存里面会一直是旧数据。
+	// 写过的分类记录在缓存里面，重启或者换了节点计算也不会漏掉
+	categories, err := s.repo.Categories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// 这一次才出现的分类，在部分时间窗口里面也可能没有文章
+	for key := range res {
+		if key.Category != "" {
+			categories = append(categories, key.Category)
+		}
+	}
+	for _, w := range domain.RankingWindows {
+		s.fillEmpty(res, domain.RankingKey{Window: w})
+		for _, category := range categories {
+			s.fillEmpty(res, domain.RankingKey{Category: category, Window: w})
 		}
-		res[i] = val.art
 	}
 	return res, nil
 }
+
+func (s *BatchRankingService) fillEmpty(res map[domain.RankingKey][]domain.Article, key domain.RankingKey) {
+	if _, ok := res[key]; !ok {
+		res[key] = []domain.Article{}
+	}
+}
+
+// enqueue 每个榜单只保留分数最高的 n 篇文章
+func (s *BatchRankingService) enqueue(queues map[domain.RankingKey]*queue.ConcurrentPriorityQueue[rankingArticle],
+	key domain.RankingKey, item rankingArticle) {
+	q, ok := queues[key]
+	if !ok {
+		q = queue.NewConcurrentPriorityQueue[rankingArticle](s.n, func(i, j rankingArticle) int {
+			if i.score > j.score {
+				return 1
+			} else if i.score < j.score {
+				return -1
+			}
+			return 0
+		})
+		queues[key] = q
+	}
+	err := q.Enqueue(item)
+	if err == queue.ErrOutOfCapacity {
+		val, _ := q.Peek()
+		if item.score > val.score {
+			_, _ = q.Dequeue()
+			_ = q.Enqueue(item)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"errors"
+	"go-basic/webook/internal/domain"
+	"math"
+	"time"
+)
+
+var ErrUnknownRankingAlgorithm = errors.New("未知的热榜算法")
+
+// RankingScorer 热榜的打分算法，分数越高越靠前
+type RankingScorer interface {
+	Score(now time.Time, art domain.Article, intr domain.Interactive) float64
+}
+
+// RankingScoreFunc 让普通函数也可以作为打分算法
+type RankingScoreFunc func(now time.Time, art domain.Article, intr domain.Interactive) float64
+
+func (f RankingScoreFunc) Score(now time.Time, art domain.Article, intr domain.Interactive) float64 {
+	return f(now, art, intr)
+}
+
+// NewRankingScorer 根据名字创建打分算法，名字一般来自配置
+func NewRankingScorer(algorithm string) (RankingScorer, error) {
+	switch algorithm {
+	case "", "gravity":
+		return GravityScorer{Gravity: 1.5}, nil
+	case "hackernews":
+		return HackerNewsScorer{Gravity: 1.8}, nil
+	case "reddit":
+		return RedditHotScorer{}, nil
+	case "wilson":
+		return WilsonScorer{Z: 1.96, CollectWeight: 2}, nil
+	default:
+		return nil, ErrUnknownRankingAlgorithm
+	}
+}
+
+// GravityScorer 最早使用的算法，点赞数随着时间按秒衰减
+type GravityScorer struct {
+	Gravity float64
+}
+
+func (g GravityScorer) Score(now time.Time, art domain.Article, intr domain.Interactive) float64 {
+	sec := now.Sub(art.Ctime).Seconds()
+	return float64(intr.LikeCnt-1) / math.Pow(sec+2, g.Gravity)
+}
+
+// HackerNewsScorer (P-1) / (T+2)^G，T 是发表之后经过的小时数
+type HackerNewsScorer struct {
+	Gravity float64
+}
+
+func (h HackerNewsScorer) Score(now time.Time, art domain.Article, intr domain.Interactive) float64 {
+	hours := now.Sub(art.Ctime).Hours()
+	return float64(intr.LikeCnt-1) / math.Pow(hours+2, h.Gravity)
+}
+
+// redditEpoch Reddit 算法里面的起始时间，只影响分数的绝对值，不影响排序
+const redditEpoch = 1134028003
+
+// RedditHotScorer 点赞数取对数，发表时间越晚分数越高，每 12.5 小时相当于点赞数增加 10 倍。
+// 我们没有踩，所以点赞数就是净得票数
+type RedditHotScorer struct{}
+
+func (r RedditHotScorer) Score(now time.Time, art domain.Article, intr domain.Interactive) float64 {
+	order := math.Log10(math.Max(float64(intr.LikeCnt), 1))
+	seconds := float64(art.Ctime.Unix() - redditEpoch)
+	return order + seconds/45000
+}
+
+// WilsonScorer 威尔逊区间的下界，把阅读看作一次投票，点赞和收藏看作好评。
+// 阅读数少的文章置信区间宽，下界低，不会因为偶然几个点赞就冲上热榜
+type WilsonScorer struct {
+	// 置信水平对应的 z 值，1.96 对应 95%
+	Z float64
+	// 一次收藏相当于多少次点赞
+	CollectWeight float64
+}
+
+func (w WilsonScorer) Score(now time.Time, art domain.Article, intr domain.Interactive) float64 {
+	positive := float64(intr.LikeCnt) + w.CollectWeight*float64(intr.CollectCnt)
+	// 阅读数是异步统计的，可能比点赞数还少
+	n := math.Max(float64(intr.ReadCnt), positive)
+	if n == 0 {
+		return 0
+	}
+	p := positive / n
+	z2 := w.Z * w.Z
+	return (p + z2/(2*n) - w.Z*math.Sqrt((p*(1-p)+z2/(4*n))/n)) / (1 + z2/n)
+}
@@ -0,0 +1,248 @@
+package service
+
+import (
+	"context"
+	"go-basic/webook/internal/domain"
+	"go-basic/webook/internal/repository"
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ecodeclub/ekit/slice"
+)
+
+// StreamingRankingService 根据互动事件增量计算热榜，不需要扫描所有已发表的文章。
+// 只保留有限的候选文章，分数按照半衰期衰减，定时调用 TopN 生成快照。
+// 衰减的分数没有时间窗口的概念，所以只生成默认的全站榜单
+type StreamingRankingService interface {
+	RankingService
+	// OnInteractive delta 为负数表示撤销，例如取消点赞
+	OnInteractive(aid int64, action domain.InteractiveAction, delta int64, at time.Time)
+	OnPublish(aid int64, at time.Time)
+	OnWithdraw(aid int64)
+	// Warmup 根据数据库里面最近发表的文章和它们的互动数重建候选集，完成之前 TopN 不会覆盖热榜
+	Warmup(ctx context.Context) error
+}
+
+type rankingCandidate struct {
+	score float64
+	// 分数衰减到了哪个时刻
+	utime time.Time
+}
+
+type streamingRankingService struct {
+	artSvc  ArticleService
+	intrSvc InteractiveService
+	repo    repository.RankingRepository
+	// 预热完成之前候选集是不完整的
+	ready atomic.Bool
+
+	mu         sync.Mutex
+	candidates map[int64]*rankingCandidate
+	// 候选集的上限，超过之后淘汰分数最低的
+	capacity int
+	n        int
+	// 经过一个半衰期，分数减半
+	halfLife time.Duration
+	weights  map[domain.InteractiveAction]float64
+	// 预热的时候只看这么久之内发表的文章
+	warmupWindow time.Duration
+	batchSize    int
+}
+
+func NewStreamingRankingService(artSvc ArticleService, intrSvc InteractiveService, repo repository.RankingRepository,
+	capacity int, halfLife time.Duration, warmupWindow time.Duration) StreamingRankingService {
+	return &streamingRankingService{
+		artSvc:     artSvc,
+		intrSvc:    intrSvc,
+		repo:       repo,
+		candidates: make(map[int64]*rankingCandidate, capacity),
+		capacity:   capacity,
+		n:          100,
+		halfLife:   halfLife,
+		weights: map[domain.InteractiveAction]float64{
+			domain.InteractiveActionRead:    1,
+			domain.InteractiveActionLike:    5,
+			domain.InteractiveActionCollect: 10,
+		},
+		warmupWindow: warmupWindow,
+		batchSize:    100,
+	}
+}
+
+func (s *streamingRankingService) OnInteractive(aid int64, action domain.InteractiveAction, delta int64, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.candidates[aid]
+	if !ok {
+		c = &rankingCandidate{utime: at}
+		s.candidates[aid] = c
+	}
+	incr := s.weights[action] * float64(delta)
+	if at.After(c.utime) {
+		c.score = s.decay(c.score, at.Sub(c.utime))
+		c.utime = at
+	} else {
+		// 乱序到达的事件，按照它发生的时间衰减到 utime
+		incr = s.decay(incr, c.utime.Sub(at))
+	}
+	c.score += incr
+	s.evictIfFull(at)
+}
+
+func (s *streamingRankingService) OnPublish(aid int64, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// 新发表的文章先进入候选集，否则冷启动的时候热榜是空的
+	if _, ok := s.candidates[aid]; !ok {
+		s.candidates[aid] = &rankingCandidate{utime: at}
+		s.evictIfFull(at)
+	}
+}
+
+func (s *streamingRankingService) OnWithdraw(aid int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.candidates, aid)
+}
+
+func (s *streamingRankingService) Warmup(ctx context.Context) error {
+	now := time.Now()
+	offset := 0
+	for {
+		arts, err := s.artSvc.ListPub(ctx, offset, s.batchSize, now)
+		if err != nil {
+			return err
+		}
+		ids := slice.Map[domain.Article, int64](arts, func(idx int, src domain.Article) int64 {
+			return src.Id
+		})
+		intrs, err := s.intrSvc.GetByIds(ctx, "article", ids)
+		if err != nil {
+			return err
+		}
+		s.mu.Lock()
+		for _, art := range arts {
+			intr := intrs[art.Id]
+			// 不知道每一次互动发生的时间，当成都发生在发表的时候，按照文章的年龄衰减
+			score := s.weights[domain.InteractiveActionRead]*float64(intr.ReadCnt) +
+				s.weights[domain.InteractiveActionLike]*float64(intr.LikeCnt) +
+				s.weights[domain.InteractiveActionCollect]*float64(intr.CollectCnt)
+			s.seed(art.Id, s.decay(score, now.Sub(art.Ctime)), now)
+		}
+		s.evictIfFull(now)
+		s.mu.Unlock()
+		if len(arts) < s.batchSize || now.Sub(arts[len(arts)-1].Ctime) > s.warmupWindow {
+			break
+		}
+		offset = offset + len(arts)
+	}
+	s.ready.Store(true)
+	return nil
+}
+
+// seed 预热的分数加到已有的候选上，预热期间收到的事件不会被覆盖，调用者需要持有锁
+func (s *streamingRankingService) seed(aid int64, score float64, now time.Time) {
+	c, ok := s.candidates[aid]
+	if !ok {
+		s.candidates[aid] = &rankingCandidate{score: score, utime: now}
+		return
+	}
+	c.score = s.decay(c.score, now.Sub(c.utime)) + score
+	c.utime = now
+}
+
+func (s *streamingRankingService) TopN(ctx context.Context) error {
+	if !s.ready.Load() {
+		// 还没有预热完，候选集不完整，不要覆盖别的节点算好的热榜
+		return nil
+	}
+	ids := s.topIds(time.Now(), s.n*2)
+	if len(ids) == 0 {
+		return nil
+	}
+	// 候选集里面可能有已经撤回的文章，所以多取一些，只保留已发表的
+	arts, err := s.artSvc.ListPubByIds(ctx, ids)
+	if err != nil {
+		return err
+	}
+	artMap := make(map[int64]domain.Article, len(arts))
+	for _, art := range arts {
+		artMap[art.Id] = art
+	}
+	res := make([]domain.Article, 0, s.n)
+	for _, id := range ids {
+		art, ok := artMap[id]
+		if !ok {
+			continue
+		}
+		res = append(res, art)
+		if len(res) == s.n {
+			break
+		}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return s.repo.ReplaceTopN(ctx, domain.DefaultRankingKey, res)
+}
+
+func (s *streamingRankingService) GetTopN(ctx context.Context, key domain.RankingKey) ([]domain.Article, error) {
+	return s.repo.GetTopN(ctx, key)
+}
+
+// topIds 分数最高的 limit 篇文章，按照分数从高到低排列
+func (s *streamingRankingService) topIds(now time.Time, limit int) []int64 {
+	s.mu.Lock()
+	scores := s.scores(now)
+	s.mu.Unlock()
+	sort.Slice(scores, func(i, j int) bool {
+		return scores[i].score > scores[j].score
+	})
+	if len(scores) > limit {
+		scores = scores[:limit]
+	}
+	ids := make([]int64, 0, len(scores))
+	for _, sc := range scores {
+		ids = append(ids, sc.aid)
+	}
+	return ids
+}
+
+type rankingScore struct {
+	aid   int64
+	score float64
+}
+
+// scores 所有候选文章衰减到 now 的分数，调用者需要持有锁
+func (s *streamingRankingService) scores(now time.Time) []rankingScore {
+	res := make([]rankingScore, 0, len(s.candidates))
+	for aid, c := range s.candidates {
+		score := c.score
+		if now.After(c.utime) {
+			score = s.decay(score, now.Sub(c.utime))
+		}
+		res = append(res, rankingScore{aid: aid, score: score})
+	}
+	return res
+}
+
+// evictIfFull 候选集超过上限 10% 之后，一次性淘汰到上限，避免每个事件都排序
+func (s *streamingRankingService) evictIfFull(now time.Time) {
+	if len(s.candidates) <= s.capacity+s.capacity/10 {
+		return
+	}
+	scores := s.scores(now)
+	sort.Slice(scores, func(i, j int) bool {
+		return scores[i].score < scores[j].score
+	})
+	for _, sc := range scores[:len(scores)-s.capacity] {
+		delete(s.candidates, sc.aid)
+	}
+}
+
+func (s *streamingRankingService) decay(score float64, elapsed time.Duration) float64 {
+	return score * math.Exp2(-elapsed.Seconds()/s.halfLife.Seconds())
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"go-basic/webook/internal/domain"
+	repomocks "go-basic/webook/internal/repository/mocks"
+	svcmocks "go-basic/webook/internal/service/mocks"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamingRankingService_OnInteractive(t *testing.T) {
+	now := time.Now()
+	svc := NewStreamingRankingService(nil, nil, nil, 10, time.Hour, time.Hour).(*streamingRankingService)
+
+	// 一个小时之前的点赞衰减了一半
+	svc.OnInteractive(1, domain.InteractiveActionLike, 1, now.Add(-time.Hour))
+	svc.OnInteractive(2, domain.InteractiveActionLike, 1, now)
+	// 取消点赞
+	svc.OnInteractive(3, domain.InteractiveActionLike, 1, now)
+	svc.OnInteractive(3, domain.InteractiveActionLike, -1, now)
+	// 乱序到达的事件按照发生的时间衰减
+	svc.OnInteractive(4, domain.InteractiveActionCollect, 1, now)
+	svc.OnInteractive(4, domain.InteractiveActionRead, 1, now.Add(-time.Hour))
+
+	scores := make(map[int64]float64)
+	for _, sc := range svc.scores(now) {
+		scores[sc.aid] = sc.score
+	}
+	assert.InDelta(t, 2.5, scores[1], 0.0001)
+	assert.InDelta(t, 5, scores[2], 0.0001)
+	assert.InDelta(t, 0, scores[3], 0.0001)
+	assert.InDelta(t, 10.5, scores[4], 0.0001)
+	assert.Equal(t, []int64{4, 2, 1, 3}, svc.topIds(now, 10))
+
+	svc.OnWithdraw(4)
+	assert.Equal(t, []int64{2, 1}, svc.topIds(now, 2))
+}
+
+func TestStreamingRankingService_Evict(t *testing.T) {
+	now := time.Now()
+	svc := NewStreamingRankingService(nil, nil, nil, 10, time.Hour, time.Hour).(*streamingRankingService)
+	for i := int64(1); i <= 11; i++ {
+		svc.OnInteractive(i, domain.InteractiveActionLike, i, now)
+	}
+	// 没有超过上限的 10%，不淘汰
+	assert.Len(t, svc.candidates, 11)
+	svc.OnPublish(12, now)
+	// 淘汰分数最低的，新发表的文章分数为 0
+	assert.Len(t, svc.candidates, 10)
+	for _, aid := range []int64{1, 12} {
+		_, ok := svc.candidates[aid]
+		assert.False(t, ok)
+	}
+}
+
+func TestStreamingRankingService_TopN(t *testing.T) {
+	now := time.Now()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	artSvc := svcmocks.NewMockArticleService(ctrl)
+	intrSvc := svcmocks.NewMockInteractiveService(ctrl)
+	repo := repomocks.NewMockRankingRepository(ctrl)
+	svc := NewStreamingRankingService(artSvc, intrSvc, repo, 10, time.Hour, time.Hour).(*streamingRankingService)
+	svc.n = 2
+
+	svc.OnInteractive(1, domain.InteractiveActionLike, 3, now)
+	svc.OnInteractive(2, domain.InteractiveActionLike, 2, now)
+	svc.OnInteractive(3, domain.InteractiveActionLike, 1, now)
+	// 还没有预热，不覆盖已有的热榜
+	require.NoError(t, svc.TopN(context.Background()))
+
+	// 预热的时候加载重启之前就有互动的文章
+	artSvc.EXPECT().ListPub(gomock.Any(), 0, 100, gomock.Any()).Return([]domain.Article{
+		{Id: 5, Ctime: now},
+	}, nil)
+	intrSvc.EXPECT().GetByIds(gomock.Any(), "article", []int64{5}).Return(map[int64]domain.Interactive{
+		5: {LikeCnt: 10},
+	}, nil)
+	require.NoError(t, svc.Warmup(context.Background()))
+
+	// 2 已经撤回了
+	artSvc.EXPECT().ListPubByIds(gomock.Any(), []int64{5, 1, 2, 3}).Return([]domain.Article{
+		{Id: 1, Title: "1"}, {Id: 3, Title: "3"}, {Id: 5, Title: "5"},
+	}, nil)
+	repo.EXPECT().ReplaceTopN(gomock.Any(), domain.DefaultRankingKey, []domain.Article{
+		{Id: 5, Title: "5"}, {Id: 1, Title: "1"},
+	}).Return(nil)
+	require.NoError(t, svc.TopN(context.Background()))
+}
@@ -6,10 +6,12 @@ import (
 	"testing"
 	"time"
 
+	repomocks "go-basic/webook/internal/repository/mocks"
 	svcmocks "go-basic/webook/internal/service/mocks"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestBatchRankingService_topN(t *testing.T) {
@@ -17,9 +19,16 @@ func TestBatchRankingService_topN(t *testing.T) {
 	testCases := []struct {
 		name string
 		mock func(ctrl *gomock.Controller) (ArticleService, InteractiveService)
+		// 为空的时候直接使用点赞数作为分数
+		scorer RankingScorer
 
-		wantErr  error
+		wantErr error
+		// 所有文章都是刚发表的，每个时间窗口的全站榜单都一样
 		wantArts []domain.Article
+		// 不为空的时候检查所有的榜单
+		wantLists map[domain.RankingKey][]domain.Article
+		// 缓存里面记录的写过榜单的分类
+		categories []string
 	}{
 		{
 			name: "计算成功",
@@ -46,6 +55,120 @@ func TestBatchRankingService_topN(t *testing.T) {
 				{Id: 1, Utime: now, Ctime: now},
 			},
 		},
+		{
+			name: "文章不够 n 篇",
+			mock: func(ctrl *gomock.Controller) (ArticleService, InteractiveService) {
+				artSvc := svcmocks.NewMockArticleService(ctrl)
+				artSvc.EXPECT().ListPub(gomock.Any(), 0, 3, gomock.Any()).Return([]domain.Article{
+					{Id: 1, Utime: now, Ctime: now},
+					{Id: 2, Utime: now, Ctime: now},
+				}, nil)
+				intrSvc := svcmocks.NewMockInteractiveService(ctrl)
+				intrSvc.EXPECT().GetByIds(gomock.Any(), "article", []int64{1, 2}).Return(map[int64]domain.Interactive{
+					1: {BizId: 1, LikeCnt: 1},
+					2: {BizId: 2, LikeCnt: 2}}, nil)
+				return artSvc, intrSvc
+			},
+			wantArts: []domain.Article{
+				{Id: 2, Utime: now, Ctime: now},
+				{Id: 1, Utime: now, Ctime: now},
+			},
+		},
+		{
+			name: "威尔逊区间，样本太少的文章排在后面",
+			mock: func(ctrl *gomock.Controller) (ArticleService, InteractiveService) {
+				artSvc := svcmocks.NewMockArticleService(ctrl)
+				artSvc.EXPECT().ListPub(gomock.Any(), 0, 3, gomock.Any()).Return([]domain.Article{
+					{Id: 1, Utime: now, Ctime: now},
+					{Id: 2, Utime: now, Ctime: now},
+				}, nil)
+				intrSvc := svcmocks.NewMockInteractiveService(ctrl)
+				intrSvc.EXPECT().GetByIds(gomock.Any(), "article", []int64{1, 2}).Return(map[int64]domain.Interactive{
+					1: {BizId: 1, ReadCnt: 2, LikeCnt: 2},
+					2: {BizId: 2, ReadCnt: 1000, LikeCnt: 300, CollectCnt: 100}}, nil)
+				return artSvc, intrSvc
+			},
+			scorer: WilsonScorer{Z: 1.96, CollectWeight: 2},
+			wantArts: []domain.Article{
+				{Id: 2, Utime: now, Ctime: now},
+				{Id: 1, Utime: now, Ctime: now},
+			},
+		},
+		{
+			name: "一次扫描计算所有分类和时间窗口",
+			mock: func(ctrl *gomock.Controller) (ArticleService, InteractiveService) {
+				artSvc := svcmocks.NewMockArticleService(ctrl)
+				artSvc.EXPECT().ListPub(gomock.Any(), 0, 3, gomock.Any()).Return([]domain.Article{
+					{Id: 1, Category: "go", Ctime: now.Add(-time.Hour)},
+					{Id: 2, Category: "java", Ctime: now.Add(-time.Hour * 48)},
+					// 编辑过的老文章，仍然按照发表时间划分窗口
+					{Id: 3, Category: "go", Ctime: now.Add(-time.Hour * 24 * 10), Utime: now},
+				}, nil)
+				artSvc.EXPECT().ListPub(gomock.Any(), 3, 3, gomock.Any()).Return([]domain.Article{
+					// 超出了最长的时间窗口
+					{Id: 4, Ctime: now.Add(-time.Hour * 24 * 31)},
+				}, nil)
+				intrSvc := svcmocks.NewMockInteractiveService(ctrl)
+				intrSvc.EXPECT().GetByIds(gomock.Any(), "article", []int64{1, 2, 3}).Return(map[int64]domain.Interactive{
+					1: {BizId: 1, LikeCnt: 1},
+					2: {BizId: 2, LikeCnt: 2},
+					3: {BizId: 3, LikeCnt: 3}}, nil)
+				intrSvc.EXPECT().GetByIds(gomock.Any(), "article", []int64{4}).Return(map[int64]domain.Interactive{
+					4: {BizId: 4, LikeCnt: 4}}, nil)
+				return artSvc, intrSvc
+			},
+			wantLists: map[domain.RankingKey][]domain.Article{
+				{Window: domain.RankingWindowDay}: {
+					{Id: 1, Category: "go", Ctime: now.Add(-time.Hour)},
+				},
+				{Window: domain.RankingWindowWeek}: {
+					{Id: 2, Category: "java", Ctime: now.Add(-time.Hour * 48)},
+					{Id: 1, Category: "go", Ctime: now.Add(-time.Hour)},
+				},
+				{Window: domain.RankingWindowMonth}: {
+					{Id: 3, Category: "go", Ctime: now.Add(-time.Hour * 24 * 10), Utime: now},
+					{Id: 2, Category: "java", Ctime: now.Add(-time.Hour * 48)},
+					{Id: 1, Category: "go", Ctime: now.Add(-time.Hour)},
+				},
+				{Category: "go", Window: domain.RankingWindowDay}: {
+					{Id: 1, Category: "go", Ctime: now.Add(-time.Hour)},
+				},
+				{Category: "go", Window: domain.RankingWindowWeek}: {
+					{Id: 1, Category: "go", Ctime: now.Add(-time.Hour)},
+				},
+				{Category: "go", Window: domain.RankingWindowMonth}: {
+					{Id: 3, Category: "go", Ctime: now.Add(-time.Hour * 24 * 10), Utime: now},
+					{Id: 1, Category: "go", Ctime: now.Add(-time.Hour)},
+				},
+				// 分类在这个窗口里面没有文章，写空榜单
+				{Category: "java", Window: domain.RankingWindowDay}: {},
+				{Category: "java", Window: domain.RankingWindowWeek}: {
+					{Id: 2, Category: "java", Ctime: now.Add(-time.Hour * 48)},
+				},
+				{Category: "java", Window: domain.RankingWindowMonth}: {
+					{Id: 2, Category: "java", Ctime: now.Add(-time.Hour * 48)},
+				},
+			},
+		},
+		{
+			name: "分类已经没有文章",
+			mock: func(ctrl *gomock.Controller) (ArticleService, InteractiveService) {
+				artSvc := svcmocks.NewMockArticleService(ctrl)
+				artSvc.EXPECT().ListPub(gomock.Any(), 0, 3, gomock.Any()).Return([]domain.Article{}, nil)
+				intrSvc := svcmocks.NewMockInteractiveService(ctrl)
+				intrSvc.EXPECT().GetByIds(gomock.Any(), "article", []int64{}).Return(map[int64]domain.Interactive{}, nil)
+				return artSvc, intrSvc
+			},
+			categories: []string{"go"},
+			wantLists: map[domain.RankingKey][]domain.Article{
+				{Window: domain.RankingWindowDay}:                   {},
+				{Window: domain.RankingWindowWeek}:                  {},
+				{Window: domain.RankingWindowMonth}:                 {},
+				{Category: "go", Window: domain.RankingWindowDay}:   {},
+				{Category: "go", Window: domain.RankingWindowWeek}:  {},
+				{Category: "go", Window: domain.RankingWindowMonth}: {},
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -53,18 +176,53 @@ func TestBatchRankingService_topN(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			defer ctrl.Finish()
 			artSvc, intrSvc := tc.mock(ctrl)
+			scorer := tc.scorer
+			if scorer == nil {
+				scorer = RankingScoreFunc(func(now time.Time, art domain.Article, intr domain.Interactive) float64 {
+					return float64(intr.LikeCnt)
+				})
+			}
+			repo := repomocks.NewMockRankingRepository(ctrl)
+			repo.EXPECT().Categories(gomock.Any()).Return(tc.categories, nil).AnyTimes()
 			svc := &BatchRankingService{
+				repo:      repo,
 				intrSvc:   intrSvc,
 				artSvc:    artSvc,
 				batchSize: 3,
 				n:         3,
-				scoreFunc: func(utime time.Time, likeCnt int64) float64 {
-					return float64(likeCnt)
-				},
+				scorer:    scorer,
 			}
-			arts, err := svc.topN(context.Background())
+			lists, err := svc.topN(context.Background())
 			assert.Equal(t, tc.wantErr, err)
-			assert.Equal(t, tc.wantArts, arts)
+			if tc.wantLists != nil {
+				assert.Equal(t, tc.wantLists, lists)
+				return
+			}
+			for _, w := range domain.RankingWindows {
+				assert.Equal(t, tc.wantArts, lists[domain.RankingKey{Window: w}])
+			}
+		})
+	}
+}
+
+func TestNewRankingScorer(t *testing.T) {
+	now := time.Now()
+	fresh := domain.Article{Ctime: now.Add(-time.Hour)}
+	old := domain.Article{Ctime: now.Add(-time.Hour * 48)}
+	intr := domain.Interactive{ReadCnt: 100, LikeCnt: 10, CollectCnt: 2}
+	more := domain.Interactive{ReadCnt: 100, LikeCnt: 50, CollectCnt: 2}
+	for _, name := range []string{"gravity", "hackernews", "reddit", "wilson"} {
+		t.Run(name, func(t *testing.T) {
+			scorer, err := NewRankingScorer(name)
+			require.NoError(t, err)
+			// 点赞越多分数越高
+			assert.Greater(t, scorer.Score(now, fresh, more), scorer.Score(now, fresh, intr))
+			if name != "wilson" {
+				// 除了威尔逊区间，其它算法的分数都会随着时间衰减
+				assert.Greater(t, scorer.Score(now, fresh, intr), scorer.Score(now, old, intr))
+			}
 		})
 	}
+	_, err := NewRankingScorer("unknown")
+	assert.Equal(t, ErrUnknownRankingAlgorithm, err)
 }
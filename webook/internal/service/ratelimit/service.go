@@ -7,7 +7,8 @@ import (
 	"go-basic/webook/pkg/ratelimit"
 )
 
-var errLimited = fmt.Errorf("短信服务限流")
+// errLimited 我们自己的限流，异步任务会在一段时间之后重试
+var errLimited = fmt.Errorf("%w: 短信服务限流", sms.ErrRateLimited)
 
 type RatelimitSMSService struct {
 	svc     sms.Service
@@ -0,0 +1,65 @@
+package aliyun
+
+import (
+	"crypto/hmac"
+	"encoding/json"
+	"fmt"
+	"go-basic/webook/internal/service/sms"
+	"net/http"
+	"time"
+)
+
+// receiptTimeLayout 阿里云回执里面的时间是北京时间
+const receiptTimeLayout = "2006-01-02 15:04:05"
+
+var beijing = time.FixedZone("CST", 8*3600)
+
+// receipt 阿里云 HTTP 批量推送的短信回执，一次推送一个数组
+type receipt struct {
+	PhoneNumber string `json:"phone_number"`
+	ReportTime  string `json:"report_time"`
+	Success     bool   `json:"success"`
+	ErrCode     string `json:"err_code"`
+	ErrMsg      string `json:"err_msg"`
+	BizId       string `json:"biz_id"`
+}
+
+// ReceiptParser 阿里云推送回执的时候不带签名，
+// 所以在控制台配置的回执地址上带一个只有我们和阿里云知道的 token，推送过来的时候比较 token
+type ReceiptParser struct {
+	token string
+}
+
+func NewReceiptParser(token string) *ReceiptParser {
+	return &ReceiptParser{
+		token: token,
+	}
+}
+
+func (p *ReceiptParser) Parse(req *http.Request, body []byte) ([]sms.Receipt, error) {
+	token := req.URL.Query().Get("token")
+	if p.token == "" || !hmac.Equal([]byte(token), []byte(p.token)) {
+		return nil, fmt.Errorf("%w: token 不对", sms.ErrInvalidReceipt)
+	}
+	var rs []receipt
+	err := json.Unmarshal(body, &rs)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", sms.ErrInvalidReceipt, err)
+	}
+	res := make([]sms.Receipt, 0, len(rs))
+	for _, r := range rs {
+		t, err := time.ParseInLocation(receiptTimeLayout, r.ReportTime, beijing)
+		if err != nil {
+			return nil, fmt.Errorf("%w: 回执时间 %s 不合法", sms.ErrInvalidReceipt, r.ReportTime)
+		}
+		res = append(res, sms.Receipt{
+			MessageId: r.BizId,
+			Number:    r.PhoneNumber,
+			Delivered: r.Success,
+			ErrCode:   r.ErrCode,
+			ErrMsg:    r.ErrMsg,
+			Time:      t,
+		})
+	}
+	return res, nil
+}
@@ -0,0 +1,64 @@
+package aliyun
+
+import (
+	"go-basic/webook/internal/service/sms"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReceiptParser_Parse(t *testing.T) {
+	body := `[
+{"phone_number":"152","send_time":"2024-01-02 15:04:00","report_time":"2024-01-02 15:04:05","success":true,"err_code":"DELIVERED","err_msg":"用户接收成功","sms_size":"1","biz_id":"biz_1","out_id":""},
+{"phone_number":"153","send_time":"2024-01-02 15:04:00","report_time":"2024-01-02 15:04:06","success":false,"err_code":"MK:0001","err_msg":"空号","sms_size":"1","biz_id":"biz_1","out_id":""}]`
+	testCases := []struct {
+		name  string
+		token string
+		body  string
+
+		want    []sms.Receipt
+		wantErr error
+	}{
+		{
+			name:  "token 正确",
+			token: "receipt-token",
+			body:  body,
+			want: []sms.Receipt{
+				{
+					MessageId: "biz_1", Number: "152", Delivered: true, ErrCode: "DELIVERED", ErrMsg: "用户接收成功",
+					Time: time.Date(2024, 1, 2, 15, 4, 5, 0, beijing),
+				},
+				{
+					MessageId: "biz_1", Number: "153", ErrCode: "MK:0001", ErrMsg: "空号",
+					Time: time.Date(2024, 1, 2, 15, 4, 6, 0, beijing),
+				},
+			},
+		},
+		{
+			name:    "token 不对",
+			token:   "wrong",
+			body:    body,
+			wantErr: sms.ErrInvalidReceipt,
+		},
+		{
+			name:    "格式不对",
+			token:   "receipt-token",
+			body:    `{"phone_number":"152"}`,
+			wantErr: sms.ErrInvalidReceipt,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/sms/receipts?provider=aliyun&token="+tc.token,
+				strings.NewReader(tc.body))
+			require.NoError(t, err)
+			res, err := NewReceiptParser("receipt-token").Parse(req, []byte(tc.body))
+			assert.ErrorIs(t, err, tc.wantErr)
+			assert.Equal(t, tc.want, res)
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package aliyun
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go-basic/webook/internal/service/sms"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultEndpoint 阿里云短信服务的公网地址
+const DefaultEndpoint = "https://dysmsapi.aliyuncs.com"
+
+// codes 阿里云的错误码，没有列出来的按照未知错误处理，会重试
+var codes = map[string]error{
+	"isp.SYSTEM_ERROR":   sms.ErrRetryable,
+	"ServiceUnavailable": sms.ErrRetryable,
+
+	"isv.BUSINESS_LIMIT_CONTROL": sms.ErrRateLimited,
+	"Throttling.User":            sms.ErrRateLimited,
+
+	"isv.MOBILE_NUMBER_ILLEGAL":   sms.ErrInvalidNumber,
+	"isv.MOBILE_COUNT_OVER_LIMIT": sms.ErrInvalidNumber,
+
+	"isv.TEMPLATE_MISSING_PARAMETERS": sms.ErrInvalidArgs,
+	"isv.INVALID_PARAMETERS":          sms.ErrInvalidArgs,
+	"isv.INVALID_JSON_PARAM":          sms.ErrInvalidArgs,
+	"isv.PARAM_LENGTH_LIMIT":          sms.ErrInvalidArgs,
+	"isv.SMS_TEMPLATE_ILLEGAL":        sms.ErrInvalidArgs,
+	"isv.SMS_SIGNATURE_ILLEGAL":       sms.ErrInvalidArgs,
+
+	"isv.BLACK_KEY_CONTROL_LIMIT": sms.ErrBlocked,
+
+	"isv.ACCOUNT_NOT_EXISTS":      sms.ErrAuthFailed,
+	"isv.DENY_IP_RANGE":           sms.ErrAuthFailed,
+	"isv.ACCOUNT_ABNORMAL":        sms.ErrAuthFailed,
+	"isv.OUT_OF_SERVICE":          sms.ErrAuthFailed,
+	"isv.AMOUNT_NOT_ENOUGH":       sms.ErrAuthFailed,
+	"InvalidAccessKeyId.NotFound": sms.ErrAuthFailed,
+	"SignatureDoesNotMatch":       sms.ErrAuthFailed,
+}
+
+// Template 阿里云的模板参数是按照名字传的，Params 是 args 依次对应的参数名
+type Template struct {
+	Code   string   `yaml:"code"`
+	Params []string `yaml:"params"`
+}
+
+// Service 直接调用阿里云 SendSms 的 RPC 接口，签名算法见阿里云的 RPC 风格签名文档
+type Service struct {
+	client          *http.Client
+	endpoint        string
+	accessKeyId     string
+	accessKeySecret string
+	signName        string
+	// biz 到阿里云模板的映射
+	templates map[string]Template
+	now       func() time.Time
+}
+
+func NewService(client *http.Client, endpoint, accessKeyId, accessKeySecret, signName string,
+	templates map[string]Template) *Service {
+	return &Service{
+		client:          client,
+		endpoint:        strings.TrimSuffix(endpoint, "/"),
+		accessKeyId:     accessKeyId,
+		accessKeySecret: accessKeySecret,
+		signName:        signName,
+		templates:       templates,
+		now:             time.Now,
+	}
+}
+
+type sendSmsResponse struct {
+	Code      string `json:"Code"`
+	Message   string `json:"Message"`
+	BizId     string `json:"BizId"`
+	RequestId string `json:"RequestId"`
+}
+
+func (s *Service) Send(ctx context.Context, biz string, args []string, numbers ...string) error {
+	tpl, ok := s.templates[biz]
+	if !ok {
+		return fmt.Errorf("%w: 没有配置模板 %s", sms.ErrInvalidArgs, biz)
+	}
+	if len(args) != len(tpl.Params) {
+		return fmt.Errorf("%w: 模板 %s 需要 %d 个参数，传入了 %d 个", sms.ErrInvalidArgs, biz, len(tpl.Params), len(args))
+	}
+	tplParams := make(map[string]string, len(args))
+	for i, name := range tpl.Params {
+		tplParams[name] = args[i]
+	}
+	tplParam, err := json.Marshal(tplParams)
+	if err != nil {
+		return err
+	}
+	params := map[string]string{
+		"Action":        "SendSms",
+		"Version":       "2017-05-25",
+		"RegionId":      "cn-hangzhou",
+		"PhoneNumbers":  strings.Join(numbers, ","),
+		"SignName":      s.signName,
+		"TemplateCode":  tpl.Code,
+		"TemplateParam": string(tplParam),
+	}
+	resp, err := s.call(ctx, params)
+	if err != nil {
+		return err
+	}
+	if resp.Code == "OK" {
+		// 阿里云一次请求只返回一个 BizId，回执里面用 BizId 和号码区分
+		sms.SetMessageId(ctx, resp.BizId, numbers...)
+		return nil
+	}
+	if e, ok := codes[resp.Code]; ok {
+		return fmt.Errorf("%w: %s %s", e, resp.Code, resp.Message)
+	}
+	return fmt.Errorf("发送短信失败: %s %s", resp.Code, resp.Message)
+}
+
+func (s *Service) call(ctx context.Context, params map[string]string) (sendSmsResponse, error) {
+	var resp sendSmsResponse
+	params["AccessKeyId"] = s.accessKeyId
+	params["Format"] = "JSON"
+	params["SignatureMethod"] = "HMAC-SHA1"
+	params["SignatureVersion"] = "1.0"
+	params["SignatureNonce"] = nonce()
+	params["Timestamp"] = s.now().UTC().Format("2006-01-02T15:04:05Z")
+	query := canonicalize(params)
+	query = query + "&Signature=" + percentEncode(sign(s.accessKeySecret, http.MethodGet, query))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.endpoint+"/?"+query, nil)
+	if err != nil {
+		return resp, err
+	}
+	httpResp, err := s.client.Do(req)
+	if err != nil {
+		return resp, err
+	}
+	defer httpResp.Body.Close()
+	data, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return resp, err
+	}
+	// 参数错误之类的 4xx 也会返回错误码，解析不出来的时候才看状态码
+	err = json.Unmarshal(data, &resp)
+	if err == nil && resp.Code != "" {
+		return resp, nil
+	}
+	if httpResp.StatusCode >= http.StatusInternalServerError {
+		return resp, fmt.Errorf("%w: HTTP %d", sms.ErrRetryable, httpResp.StatusCode)
+	}
+	if httpResp.StatusCode == http.StatusTooManyRequests {
+		return resp, fmt.Errorf("%w: HTTP %d", sms.ErrRateLimited, httpResp.StatusCode)
+	}
+	return resp, fmt.Errorf("阿里云短信服务响应无法解析: HTTP %d, %s", httpResp.StatusCode, data)
+}
+
+// sign 按照阿里云 RPC 风格计算签名，query 是排好序并且编码过的参数
+func sign(secret, method, query string) string {
+	stringToSign := method + "&" + percentEncode("/") + "&" + percentEncode(query)
+	mac := hmac.New(sha1.New, []byte(secret+"&"))
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// canonicalize 按照参数名排序之后拼接
+func canonicalize(params map[string]string) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, percentEncode(k)+"="+percentEncode(params[k]))
+	}
+	return strings.Join(pairs, "&")
+}
+
+// percentEncode 阿里云要求的 RFC 3986 编码，和 url.QueryEscape 有几个字符不一样
+func percentEncode(s string) string {
+	res := url.QueryEscape(s)
+	res = strings.ReplaceAll(res, "+", "%20")
+	res = strings.ReplaceAll(res, "*", "%2A")
+	res = strings.ReplaceAll(res, "%7E", "~")
+	return res
+}
+
+func nonce() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
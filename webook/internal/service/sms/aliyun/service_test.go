@@ -0,0 +1,152 @@
+package aliyun
+
+import (
+	"context"
+	"encoding/json"
+	"go-basic/webook/internal/service/sms"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestServer 模拟阿里云，校验签名之后按照 handle 返回
+func newTestServer(handle func(w http.ResponseWriter, params url.Values)) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		signature := params.Get("Signature")
+		params.Del("Signature")
+		flat := make(map[string]string, len(params))
+		for k := range params {
+			flat[k] = params.Get(k)
+		}
+		if sign("secret", http.MethodGet, canonicalize(flat)) != signature {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"Code":"SignatureDoesNotMatch","Message":"Specified signature is not matched"}`))
+			return
+		}
+		handle(w, params)
+	}))
+}
+
+func TestService_Send(t *testing.T) {
+	templates := map[string]Template{
+		"login": {Code: "SMS_123", Params: []string{"code"}},
+	}
+	testCases := []struct {
+		name    string
+		handle  func(w http.ResponseWriter, params url.Values)
+		secret  string
+		biz     string
+		args    []string
+		wantErr error
+		// 没有对应的类型，只比较错误信息
+		wantErrMsg string
+	}{
+		{
+			name: "发送成功",
+			handle: func(w http.ResponseWriter, params url.Values) {
+				assert.Equal(t, "SendSms", params.Get("Action"))
+				assert.Equal(t, "SMS_123", params.Get("TemplateCode"))
+				assert.Equal(t, "webook", params.Get("SignName"))
+				assert.Equal(t, "152,153", params.Get("PhoneNumbers"))
+				assert.Equal(t, "ak", params.Get("AccessKeyId"))
+				assert.Equal(t, "2023-11-14T22:13:20Z", params.Get("Timestamp"))
+				var tplParam map[string]string
+				require.NoError(t, json.Unmarshal([]byte(params.Get("TemplateParam")), &tplParam))
+				assert.Equal(t, map[string]string{"code": "12 34*~"}, tplParam)
+				_, _ = w.Write([]byte(`{"Code":"OK","Message":"OK","BizId":"1","RequestId":"2"}`))
+			},
+			secret: "secret",
+			biz:    "login",
+			args:   []string{"12 34*~"},
+		},
+		{
+			name:    "签名错误",
+			secret:  "wrong",
+			biz:     "login",
+			args:    []string{"1234"},
+			wantErr: sms.ErrAuthFailed,
+		},
+		{
+			name: "限流",
+			handle: func(w http.ResponseWriter, params url.Values) {
+				_, _ = w.Write([]byte(`{"Code":"isv.BUSINESS_LIMIT_CONTROL","Message":"触发分钟级流控"}`))
+			},
+			secret:  "secret",
+			biz:     "login",
+			args:    []string{"1234"},
+			wantErr: sms.ErrRateLimited,
+		},
+		{
+			name: "号码不合法",
+			handle: func(w http.ResponseWriter, params url.Values) {
+				_, _ = w.Write([]byte(`{"Code":"isv.MOBILE_NUMBER_ILLEGAL","Message":"非法手机号"}`))
+			},
+			secret:  "secret",
+			biz:     "login",
+			args:    []string{"1234"},
+			wantErr: sms.ErrInvalidNumber,
+		},
+		{
+			name: "服务端错误",
+			handle: func(w http.ResponseWriter, params url.Values) {
+				w.WriteHeader(http.StatusBadGateway)
+			},
+			secret:  "secret",
+			biz:     "login",
+			args:    []string{"1234"},
+			wantErr: sms.ErrRetryable,
+		},
+		{
+			name: "未知错误码",
+			handle: func(w http.ResponseWriter, params url.Values) {
+				_, _ = w.Write([]byte(`{"Code":"isv.UNKNOWN","Message":"未知"}`))
+			},
+			secret:     "secret",
+			biz:        "login",
+			args:       []string{"1234"},
+			wantErrMsg: "发送短信失败: isv.UNKNOWN 未知",
+		},
+		{
+			name:    "没有配置模板",
+			secret:  "secret",
+			biz:     "unknown",
+			args:    []string{"1234"},
+			wantErr: sms.ErrInvalidArgs,
+		},
+		{
+			name:    "参数个数不对",
+			secret:  "secret",
+			biz:     "login",
+			args:    []string{"1234", "5678"},
+			wantErr: sms.ErrInvalidArgs,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newTestServer(func(w http.ResponseWriter, params url.Values) {
+				if tc.handle == nil {
+					t.Error("不应该发出请求")
+					return
+				}
+				tc.handle(w, params)
+			})
+			defer server.Close()
+			svc := NewService(server.Client(), server.URL, "ak", tc.secret, "webook", templates)
+			svc.now = func() time.Time {
+				return time.Unix(1_700_000_000, 0)
+			}
+			err := svc.Send(context.Background(), tc.biz, tc.args, "152", "153")
+			if tc.wantErrMsg != "" {
+				assert.EqualError(t, err, tc.wantErrMsg)
+				return
+			}
+			assert.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+}
@@ -5,103 +5,153 @@ import (
 	"errors"
 	"go-basic/webook/internal/repository"
 	"go-basic/webook/internal/service/sms"
-	"net"
-	"strings"
-	"syscall"
+	"go-basic/webook/pkg/logger"
+	"go-basic/webook/pkg/stringx"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
 )
 
+// 和数据库字段的长度保持一致
+const maxErrMsgLen = 1024
+
+// SMSService 同步发送失败并且可以重试的短信保存到数据库，由异步任务重试。
+// 多个实例通过乐观锁抢占，同一条短信同一时刻只会有一个实例在发送
 type SMSService struct {
 	svc  sms.Service
-	repo repository.SMSAysncReqRepository
+	repo repository.SMSRepository
+	l    logger.Logger
+
+	// 最多尝试几次，包括第一次异步发送
+	maxRetry         int32
+	retryInterval    time.Duration
+	maxRetryInterval time.Duration
+	// 没有可以发送的短信的时候等多久再抢占
+	idleInterval time.Duration
+	// 发送中的短信超过这个时间没有结果，认为实例已经崩溃，别的实例可以重新抢占
+	leaseTimeout time.Duration
+	sendTimeout  time.Duration
+	// 多久统计一次积压的短信
+	reportInterval time.Duration
+	pending        prometheus.Gauge
 }
 
-func NewSMSService(svc sms.Service) *SMSService {
+func NewSMSService(svc sms.Service, repo repository.SMSRepository, l logger.Logger) *SMSService {
+	pending := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "webook",
+		Subsystem: "sms",
+		Name:      "async_pending",
+		Help:      "等待异步重试的短信数量",
+	})
+	prometheus.MustRegister(pending)
 	return &SMSService{
-		svc: svc,
+		svc:              svc,
+		repo:             repo,
+		l:                l,
+		maxRetry:         5,
+		retryInterval:    time.Second * 10,
+		maxRetryInterval: time.Minute * 10,
+		idleInterval:     time.Second,
+		leaseTimeout:     time.Minute,
+		sendTimeout:      time.Second * 10,
+		reportInterval:   time.Second * 10,
+		pending:          pending,
 	}
 }
 
+// StartAysnc 启动异步重试，ctx 取消之后退出。发送到一半退出的短信等租约过期之后会被重新抢占
 func (s *SMSService) StartAysnc(ctx context.Context) {
-	go func() {
-		lists, err := s.repo.Find(ctx)
-		if err != nil {
-			return
-		}
-		for _, req := range lists {
-			_ = s.svc.Send(ctx, req.Biz, req.Args, req.Numbers...)
-		}
-	}()
+	go s.loop(ctx)
+	go s.report(ctx)
 }
 
-func (s *SMSService) Send(ctx context.Context, tpl string, args []string, numbers ...string) error {
-	// 正常路径
-	err := s.svc.Send(ctx, tpl, args, numbers...)
-	// 异常路径
-	if err != nil {
-		if s.shouldRetryAsync(err) {
-			return s.repo.Store(ctx, tpl, args, numbers)
+func (s *SMSService) loop(ctx context.Context) {
+	for ctx.Err() == nil {
+		err := s.retryOne(ctx)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, repository.ErrSMSNotFound) {
+			s.l.Error("异步重试短信失败", logger.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(s.idleInterval):
 		}
-		return err
 	}
-	return nil
 }
 
-func (s *SMSService) shouldRetryAsync(err error) bool {
-	if s.isCommonTemporaryError(err) {
-		return true
+// retryOne 抢占一条短信并且发送，没有可以发送的短信返回 ErrSMSNotFound
+func (s *SMSService) retryOne(ctx context.Context) error {
+	pctx, cancel := context.WithTimeout(ctx, time.Second)
+	req, err := s.repo.Preempt(pctx, s.leaseTimeout)
+	cancel()
+	if err != nil {
+		return err
 	}
+	sctx, cancel := context.WithTimeout(ctx, s.sendTimeout)
+	err = s.svc.Send(sctx, req.Biz, req.Args, req.Numbers...)
+	cancel()
 
-	errMsg := err.Error()
-	// 服务端错误
-	if strings.Contains(strings.ToLower(errMsg), "service unavailable") ||
-		strings.Contains(strings.ToLower(errMsg), "server busy") ||
-		strings.Contains(strings.ToLower(errMsg), "try again later") {
-		return true
+	// 退出的时候也要把结果写回去，不然要等租约过期
+	uctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	switch {
+	case err == nil:
+		err = s.repo.MarkSuccess(uctx, req)
+	case !sms.Retryable(err) || req.RetryCnt >= s.maxRetry:
+		s.l.Warn("短信重试失败，不再重试", logger.Error(err),
+			logger.Int64("id", req.Id), logger.Int64("retry_cnt", int64(req.RetryCnt)))
+		req.ErrMsg = stringx.Truncate(err.Error(), maxErrMsgLen)
+		err = s.repo.MarkFailed(uctx, req)
+	default:
+		req.ErrMsg = stringx.Truncate(err.Error(), maxErrMsgLen)
+		err = s.repo.Retry(uctx, req, time.Now().Add(s.nextInterval(req.RetryCnt)))
 	}
-
-	// 限流错误
-	if strings.Contains(strings.ToLower(errMsg), "rate limit") ||
-		strings.Contains(strings.ToLower(errMsg), "too many requests") ||
-		strings.Contains(strings.ToLower(errMsg), "throttl") ||
-		strings.Contains(strings.ToLower(errMsg), "limit exceed") {
-		return true
+	if errors.Is(err, repository.ErrSMSLeaseLost) {
+		// 发送时间太长租约过期，已经被别的实例抢走了，以那边的结果为准
+		s.l.Warn("短信租约已经失效", logger.Int64("id", req.Id))
+		return nil
 	}
+	return err
+}
 
-	// 网络错误
-	if strings.Contains(strings.ToLower(errMsg), "connection") ||
-		strings.Contains(strings.ToLower(errMsg), "network") ||
-		strings.Contains(strings.ToLower(errMsg), "timeout") {
-		return true
+// nextInterval 第 n 次失败之后的等待时间，指数退避
+func (s *SMSService) nextInterval(n int32) time.Duration {
+	res := s.retryInterval
+	for i := int32(1); i < n && res < s.maxRetryInterval; i++ {
+		res *= 2
 	}
+	return min(res, s.maxRetryInterval)
+}
 
-	// 不需要重试的错误类型
-	if strings.Contains(strings.ToLower(errMsg), "invalid parameter") ||
-		strings.Contains(strings.ToLower(errMsg), "unauthorized") ||
-		strings.Contains(strings.ToLower(errMsg), "authentication failed") ||
-		strings.Contains(strings.ToLower(errMsg), "sensitive word") ||
-		strings.Contains(strings.ToLower(errMsg), "black list") ||
-		strings.Contains(strings.ToLower(errMsg), "daily limit") ||
-		strings.Contains(strings.ToLower(errMsg), "content illegal") {
-		return false
+func (s *SMSService) report(ctx context.Context) {
+	ticker := time.NewTicker(s.reportInterval)
+	defer ticker.Stop()
+	for {
+		cctx, cancel := context.WithTimeout(ctx, time.Second)
+		cnt, err := s.repo.CountPending(cctx)
+		cancel()
+		if err == nil {
+			s.pending.Set(float64(cnt))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
 	}
-
-	return true
 }
 
-func (s *SMSService) isCommonTemporaryError(err error) bool {
-	// 上下文超时
-	if errors.Is(err, context.DeadlineExceeded) {
-		return true
-	}
-	// 网络错误
-	var netErr net.Error
-	if errors.As(err, &netErr) && netErr.Timeout() {
-		return true
-	}
-	// 连接错误
-	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ETIMEDOUT) {
-		return true
+func (s *SMSService) Send(ctx context.Context, tpl string, args []string, numbers ...string) error {
+	// 正常路径
+	err := s.svc.Send(ctx, tpl, args, numbers...)
+	// 异常路径
+	if err != nil {
+		if sms.Retryable(err) {
+			return s.repo.Store(ctx, tpl, args, numbers)
+		}
+		return err
 	}
-
-	return false
+	return nil
 }
@@ -0,0 +1,161 @@
+package async
+
+import (
+	"context"
+	"fmt"
+	"go-basic/webook/internal/domain"
+	"go-basic/webook/internal/repository"
+	repomocks "go-basic/webook/internal/repository/mocks"
+	"go-basic/webook/internal/service/sms"
+	smsmocks "go-basic/webook/internal/service/sms/mocks"
+	"go-basic/webook/pkg/logger"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSMSService_retryOne(t *testing.T) {
+	errBusy := fmt.Errorf("%w: isp.SYSTEM_ERROR", sms.ErrRetryable)
+	req := domain.SMS{
+		Id:       1,
+		Biz:      "123",
+		Args:     []string{"a,b", "c"},
+		Numbers:  []string{"152"},
+		Status:   domain.SMSStatusSending,
+		RetryCnt: 2,
+		Version:  3,
+	}
+	testCases := []struct {
+		name    string
+		mock    func(ctrl *gomock.Controller) (sms.Service, repository.SMSRepository)
+		wantErr error
+	}{
+		{
+			name: "发送成功",
+			mock: func(ctrl *gomock.Controller) (sms.Service, repository.SMSRepository) {
+				svc := smsmocks.NewMockService(ctrl)
+				repo := repomocks.NewMockSMSRepository(ctrl)
+				repo.EXPECT().Preempt(gomock.Any(), time.Minute).Return(req, nil)
+				svc.EXPECT().Send(gomock.Any(), "123", []string{"a,b", "c"}, "152").Return(nil)
+				repo.EXPECT().MarkSuccess(gomock.Any(), req).Return(nil)
+				return svc, repo
+			},
+		},
+		{
+			name: "没有可以发送的短信",
+			mock: func(ctrl *gomock.Controller) (sms.Service, repository.SMSRepository) {
+				svc := smsmocks.NewMockService(ctrl)
+				repo := repomocks.NewMockSMSRepository(ctrl)
+				repo.EXPECT().Preempt(gomock.Any(), time.Minute).Return(domain.SMS{}, repository.ErrSMSNotFound)
+				return svc, repo
+			},
+			wantErr: repository.ErrSMSNotFound,
+		},
+		{
+			name: "临时错误，退避之后重试",
+			mock: func(ctrl *gomock.Controller) (sms.Service, repository.SMSRepository) {
+				svc := smsmocks.NewMockService(ctrl)
+				repo := repomocks.NewMockSMSRepository(ctrl)
+				repo.EXPECT().Preempt(gomock.Any(), time.Minute).Return(req, nil)
+				svc.EXPECT().Send(gomock.Any(), "123", []string{"a,b", "c"}, "152").
+					Return(errBusy)
+				retry := req
+				retry.ErrMsg = errBusy.Error()
+				repo.EXPECT().Retry(gomock.Any(), retry, gomock.Any()).
+					DoAndReturn(func(ctx context.Context, sms domain.SMS, next time.Time) error {
+						// 第二次失败，等待 20s
+						assert.WithinDuration(t, time.Now().Add(time.Second*20), next, time.Second)
+						return nil
+					})
+				return svc, repo
+			},
+		},
+		{
+			name: "不可重试的错误",
+			mock: func(ctrl *gomock.Controller) (sms.Service, repository.SMSRepository) {
+				svc := smsmocks.NewMockService(ctrl)
+				repo := repomocks.NewMockSMSRepository(ctrl)
+				repo.EXPECT().Preempt(gomock.Any(), time.Minute).Return(req, nil)
+				svc.EXPECT().Send(gomock.Any(), "123", []string{"a,b", "c"}, "152").
+					Return(fmt.Errorf("%w: isv.MOBILE_NUMBER_ILLEGAL", sms.ErrInvalidNumber))
+				failed := req
+				failed.ErrMsg = "手机号码不合法: isv.MOBILE_NUMBER_ILLEGAL"
+				repo.EXPECT().MarkFailed(gomock.Any(), failed).Return(nil)
+				return svc, repo
+			},
+		},
+		{
+			name: "没有翻译过的错误也重试",
+			mock: func(ctrl *gomock.Controller) (sms.Service, repository.SMSRepository) {
+				svc := smsmocks.NewMockService(ctrl)
+				repo := repomocks.NewMockSMSRepository(ctrl)
+				repo.EXPECT().Preempt(gomock.Any(), time.Minute).Return(req, nil)
+				svc.EXPECT().Send(gomock.Any(), "123", []string{"a,b", "c"}, "152").
+					Return(context.DeadlineExceeded)
+				retry := req
+				retry.ErrMsg = context.DeadlineExceeded.Error()
+				repo.EXPECT().Retry(gomock.Any(), retry, gomock.Any()).Return(nil)
+				return svc, repo
+			},
+		},
+		{
+			name: "超过重试次数",
+			mock: func(ctrl *gomock.Controller) (sms.Service, repository.SMSRepository) {
+				svc := smsmocks.NewMockService(ctrl)
+				repo := repomocks.NewMockSMSRepository(ctrl)
+				last := req
+				last.RetryCnt = 5
+				repo.EXPECT().Preempt(gomock.Any(), time.Minute).Return(last, nil)
+				svc.EXPECT().Send(gomock.Any(), "123", []string{"a,b", "c"}, "152").
+					Return(errBusy)
+				last.ErrMsg = errBusy.Error()
+				repo.EXPECT().MarkFailed(gomock.Any(), last).Return(nil)
+				return svc, repo
+			},
+		},
+		{
+			name: "租约已经被别的实例抢走",
+			mock: func(ctrl *gomock.Controller) (sms.Service, repository.SMSRepository) {
+				svc := smsmocks.NewMockService(ctrl)
+				repo := repomocks.NewMockSMSRepository(ctrl)
+				repo.EXPECT().Preempt(gomock.Any(), time.Minute).Return(req, nil)
+				svc.EXPECT().Send(gomock.Any(), "123", []string{"a,b", "c"}, "152").Return(nil)
+				repo.EXPECT().MarkSuccess(gomock.Any(), req).Return(repository.ErrSMSLeaseLost)
+				return svc, repo
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc, repo := tc.mock(ctrl)
+			s := &SMSService{
+				svc:              svc,
+				repo:             repo,
+				l:                logger.NewNopLogger(),
+				maxRetry:         5,
+				retryInterval:    time.Second * 10,
+				maxRetryInterval: time.Minute * 10,
+				leaseTimeout:     time.Minute,
+				sendTimeout:      time.Second,
+			}
+			err := s.retryOne(context.Background())
+			assert.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestSMSService_nextInterval(t *testing.T) {
+	s := &SMSService{
+		retryInterval:    time.Second * 10,
+		maxRetryInterval: time.Minute,
+	}
+	assert.Equal(t, time.Second*10, s.nextInterval(1))
+	assert.Equal(t, time.Second*20, s.nextInterval(2))
+	assert.Equal(t, time.Second*40, s.nextInterval(3))
+	assert.Equal(t, time.Minute, s.nextInterval(4))
+	assert.Equal(t, time.Minute, s.nextInterval(100))
+}
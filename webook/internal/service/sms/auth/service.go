@@ -2,36 +2,41 @@ package auth
 
 import (
 	"context"
-	"errors"
+	"go-basic/webook/internal/service"
 	"go-basic/webook/internal/service/sms"
-
-	"github.com/golang-jwt/jwt/v5"
+	"go-basic/webook/internal/service/sms/template"
 )
 
+// SMSService 给内部业务方用，biz 是管理后台签发的令牌，
+// 令牌决定了能用哪个模板，这个令牌每天能发多少条
 type SMSService struct {
-	svc sms.Service
-	key string
+	svc      sms.Service
+	tokens   service.SMSBizTokenService
+	registry *template.Registry
+}
+
+func NewSMSService(svc sms.Service, tokens service.SMSBizTokenService, registry *template.Registry) sms.Service {
+	return &SMSService{
+		svc:      svc,
+		tokens:   tokens,
+		registry: registry,
+	}
 }
 
-// Send发送，其中 biz 是线下申请的一个代表业务方的 token
 func (s *SMSService) Send(ctx context.Context, biz string, args []string, numbers ...string) error {
-	// 在这做权限校验
-	var tc Claims
-	// 如果这里能解析成功，说明就是对应的业务方
-	token, err := jwt.ParseWithClaims(biz, &tc, func(token *jwt.Token) (interface{}, error) {
-		return s.key, nil
-	})
+	t, err := s.tokens.Check(ctx, biz)
+	if err != nil {
+		return err
+	}
+	// 参数不合法的请求不扣额度
+	err = s.registry.Validate(t.Tpl, args)
 	if err != nil {
 		return err
 	}
-	if !token.Valid {
-		return errors.New("token 不合法")
+	// 先扣额度再发送，发送失败的不退回
+	err = s.tokens.Consume(ctx, t, len(numbers))
+	if err != nil {
+		return err
 	}
-
-	return s.svc.Send(ctx, tc.Tpl, args, numbers...)
-}
-
-type Claims struct {
-	jwt.RegisteredClaims
-	Tpl string
+	return s.svc.Send(ctx, t.Tpl, args, numbers...)
 }
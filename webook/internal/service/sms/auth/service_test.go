@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"context"
+	"go-basic/webook/internal/domain"
+	"go-basic/webook/internal/service"
+	svcmocks "go-basic/webook/internal/service/mocks"
+	"go-basic/webook/internal/service/sms"
+	smsmocks "go-basic/webook/internal/service/sms/mocks"
+	"go-basic/webook/internal/service/sms/template"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSMSService_Send(t *testing.T) {
+	tk := domain.SMSBizToken{Id: 1, Biz: "order", Tpl: "code", DailyQuota: 100}
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (sms.Service, service.SMSBizTokenService)
+
+		wantErr error
+	}{
+		{
+			name: "发送成功",
+			mock: func(ctrl *gomock.Controller) (sms.Service, service.SMSBizTokenService) {
+				svc := smsmocks.NewMockService(ctrl)
+				tokens := svcmocks.NewMockSMSBizTokenService(ctrl)
+				tokens.EXPECT().Check(gomock.Any(), "token").Return(tk, nil)
+				tokens.EXPECT().Consume(gomock.Any(), tk, 2).Return(nil)
+				// 用令牌里面的模板发送
+				svc.EXPECT().Send(gomock.Any(), "code", []string{"123456"}, "152", "153").Return(nil)
+				return svc, tokens
+			},
+		},
+		{
+			name: "令牌被吊销",
+			mock: func(ctrl *gomock.Controller) (sms.Service, service.SMSBizTokenService) {
+				tokens := svcmocks.NewMockSMSBizTokenService(ctrl)
+				tokens.EXPECT().Check(gomock.Any(), "token").Return(domain.SMSBizToken{}, service.ErrSMSBizTokenRevoked)
+				return smsmocks.NewMockService(ctrl), tokens
+			},
+			wantErr: service.ErrSMSBizTokenRevoked,
+		},
+		{
+			name: "参数不合法，不扣额度",
+			mock: func(ctrl *gomock.Controller) (sms.Service, service.SMSBizTokenService) {
+				tokens := svcmocks.NewMockSMSBizTokenService(ctrl)
+				tokens.EXPECT().Check(gomock.Any(), "token").Return(domain.SMSBizToken{Id: 2, Biz: "order", Tpl: "notice"}, nil)
+				return smsmocks.NewMockService(ctrl), tokens
+			},
+			wantErr: sms.ErrInvalidArgs,
+		},
+		{
+			name: "额度用完",
+			mock: func(ctrl *gomock.Controller) (sms.Service, service.SMSBizTokenService) {
+				tokens := svcmocks.NewMockSMSBizTokenService(ctrl)
+				tokens.EXPECT().Check(gomock.Any(), "token").Return(tk, nil)
+				tokens.EXPECT().Consume(gomock.Any(), tk, 2).Return(service.ErrSMSQuotaExceeded)
+				return smsmocks.NewMockService(ctrl), tokens
+			},
+			wantErr: service.ErrSMSQuotaExceeded,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			registry, err := template.NewRegistry([]template.Template{
+				{
+					Biz:       "code",
+					Params:    []template.Param{{Name: "code", Pattern: `^\d{6}$`}},
+					Providers: map[string]string{"memory": "123456"},
+				},
+				{
+					Biz:       "notice",
+					Params:    []template.Param{{Name: "title"}, {Name: "time"}},
+					Providers: map[string]string{"memory": "654321"},
+				},
+			}, []string{"memory"})
+			require.NoError(t, err)
+			svc, tokens := tc.mock(ctrl)
+			s := NewSMSService(svc, tokens, registry)
+			err = s.Send(context.Background(), "token", []string{"123456"}, "152", "153")
+			assert.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+}
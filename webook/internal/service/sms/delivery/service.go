@@ -0,0 +1,55 @@
+package delivery
+
+import (
+	"context"
+	"go-basic/webook/internal/domain"
+	"go-basic/webook/internal/repository"
+	"go-basic/webook/internal/service/sms"
+	"go-basic/webook/pkg/logger"
+)
+
+// SMSService 包装一个服务商，发送成功之后给每个号码保存一条投递记录，
+// 收到服务商的回执之后再更新成送达或者失败
+type SMSService struct {
+	svc      sms.Service
+	repo     repository.SMSDeliveryRepository
+	provider string
+	l        logger.Logger
+}
+
+func NewSMSService(svc sms.Service, repo repository.SMSDeliveryRepository, provider string,
+	l logger.Logger) sms.Service {
+	return &SMSService{
+		svc:      svc,
+		repo:     repo,
+		provider: provider,
+		l:        l,
+	}
+}
+
+func (s *SMSService) Send(ctx context.Context, biz string, args []string, numbers ...string) error {
+	sendCtx, ids := sms.WithMessageIds(ctx)
+	err := s.svc.Send(sendCtx, biz, args, numbers...)
+	if err != nil {
+		return err
+	}
+	ds := make([]domain.SMSDelivery, 0, len(numbers))
+	for _, number := range numbers {
+		ds = append(ds, domain.SMSDelivery{
+			Provider:  s.provider,
+			Biz:       biz,
+			Number:    number,
+			MessageId: ids[number],
+			Status:    domain.SMSDeliveryStatusAccepted,
+		})
+	}
+	// 短信已经发出去了，记录失败只影响统计，不能让调用方重试
+	err = s.repo.Create(ctx, ds)
+	if err != nil {
+		s.l.Error("保存短信投递记录失败",
+			logger.String("provider", s.provider),
+			logger.String("biz", biz),
+			logger.Error(err))
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"go-basic/webook/internal/domain"
+	"go-basic/webook/internal/repository"
+	repomocks "go-basic/webook/internal/repository/mocks"
+	"go-basic/webook/internal/service/sms"
+	smsmocks "go-basic/webook/internal/service/sms/mocks"
+	"go-basic/webook/pkg/logger"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSMSService_Send(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (sms.Service, repository.SMSDeliveryRepository)
+
+		wantErr error
+	}{
+		{
+			name: "发送成功，保存服务商返回的消息 ID",
+			mock: func(ctrl *gomock.Controller) (sms.Service, repository.SMSDeliveryRepository) {
+				svc := smsmocks.NewMockService(ctrl)
+				repo := repomocks.NewMockSMSDeliveryRepository(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "code", []string{"123456"}, "152", "153").
+					DoAndReturn(func(ctx context.Context, biz string, args []string, numbers ...string) error {
+						sms.SetMessageId(ctx, "biz_1", numbers...)
+						return nil
+					})
+				repo.EXPECT().Create(gomock.Any(), []domain.SMSDelivery{
+					{Provider: "aliyun", Biz: "code", Number: "152", MessageId: "biz_1", Status: domain.SMSDeliveryStatusAccepted},
+					{Provider: "aliyun", Biz: "code", Number: "153", MessageId: "biz_1", Status: domain.SMSDeliveryStatusAccepted},
+				}).Return(nil)
+				return svc, repo
+			},
+		},
+		{
+			name: "发送失败不保存",
+			mock: func(ctrl *gomock.Controller) (sms.Service, repository.SMSDeliveryRepository) {
+				svc := smsmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "code", []string{"123456"}, "152", "153").Return(sms.ErrRetryable)
+				return svc, repomocks.NewMockSMSDeliveryRepository(ctrl)
+			},
+			wantErr: sms.ErrRetryable,
+		},
+		{
+			name: "保存失败不影响发送结果",
+			mock: func(ctrl *gomock.Controller) (sms.Service, repository.SMSDeliveryRepository) {
+				svc := smsmocks.NewMockService(ctrl)
+				repo := repomocks.NewMockSMSDeliveryRepository(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "code", []string{"123456"}, "152", "153").Return(nil)
+				repo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(errors.New("db 错误"))
+				return svc, repo
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc, repo := tc.mock(ctrl)
+			s := NewSMSService(svc, repo, "aliyun", &logger.NopLogger{})
+			err := s.Send(context.Background(), "code", []string{"123456"}, "152", "153")
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
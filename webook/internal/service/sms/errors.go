@@ -0,0 +1,38 @@
+package sms
+
+import "errors"
+
+// 各个服务商把自己的错误码翻译成下面这些错误，装饰器只根据这些错误做判断，不看错误信息。
+// 服务商返回的时候用 fmt.Errorf("%w: ...", ErrXXX) 带上原始的错误码
+var (
+	// ErrRetryable 服务商暂时不可用，过一会儿重试或者换一个服务商都可以
+	ErrRetryable = errors.New("短信服务暂时不可用")
+	// ErrRateLimited 触发了服务商或者我们自己的限流，过一会儿重试
+	ErrRateLimited = errors.New("短信发送被限流")
+	// ErrInvalidNumber 手机号码不合法，换服务商或者重试都没用
+	ErrInvalidNumber = errors.New("手机号码不合法")
+	// ErrInvalidArgs 模板不存在或者参数不对，是调用方的问题
+	ErrInvalidArgs = errors.New("短信模板或者参数不合法")
+	// ErrBlocked 内容包含敏感词或者号码在黑名单里面
+	ErrBlocked = errors.New("短信内容或者号码被拦截")
+	// ErrAuthFailed 密钥错误、账号欠费之类的问题，这个服务商暂时不能用，但是可以换别的服务商
+	ErrAuthFailed = errors.New("短信服务鉴权失败")
+)
+
+// IsCallerError 请求本身有问题，换服务商或者重试都会得到同样的结果
+func IsCallerError(err error) bool {
+	return errors.Is(err, ErrInvalidNumber) ||
+		errors.Is(err, ErrInvalidArgs) ||
+		errors.Is(err, ErrBlocked)
+}
+
+// Retryable 过一段时间重试有可能成功。没有翻译过的错误也算可以重试，宁可多发一次也不要丢掉
+func Retryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if IsCallerError(err) || errors.Is(err, ErrAuthFailed) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,35 @@
+package sms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryable(t *testing.T) {
+	testCases := []struct {
+		name      string
+		err       error
+		retryable bool
+		callerErr bool
+	}{
+		{name: "服务商不可用", err: fmt.Errorf("%w: isp.SYSTEM_ERROR", ErrRetryable), retryable: true},
+		{name: "限流", err: fmt.Errorf("%w: isv.BUSINESS_LIMIT_CONTROL", ErrRateLimited), retryable: true},
+		{name: "超时", err: context.DeadlineExceeded, retryable: true},
+		{name: "没有翻译过的错误", err: errors.New("unknown"), retryable: true},
+		{name: "号码不合法", err: fmt.Errorf("%w: 152", ErrInvalidNumber), callerErr: true},
+		{name: "参数不对", err: ErrInvalidArgs, callerErr: true},
+		{name: "被拦截", err: ErrBlocked, callerErr: true},
+		{name: "鉴权失败", err: ErrAuthFailed},
+		{name: "成功"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.retryable, Retryable(tc.err))
+			assert.Equal(t, tc.callerErr, IsCallerError(tc.err))
+		})
+	}
+}
@@ -0,0 +1,267 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-basic/webook/internal/service/sms"
+	"go-basic/webook/pkg/logger"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrNoAvailableProvider 所有服务商都在熔断，过一会儿可以重试
+var ErrNoAvailableProvider = fmt.Errorf("%w: 没有可用的短信服务", sms.ErrRetryable)
+
+// Provider 一个短信服务商
+type Provider struct {
+	Name string
+	Svc  sms.Service
+	// 基础权重，都健康的时候按照这个比例分配流量
+	Weight int
+}
+
+type breakerState int8
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerClosed:
+		return "closed"
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half_open"
+	default:
+		return "unknown"
+	}
+}
+
+// HealthRoutingSMSService 统计每个服务商最近一段时间的错误率和耗时，按照健康程度加权分配流量。
+// 错误率或者平均耗时超过阈值就熔断，熔断一段时间之后放少量探测请求，探测都成功了才恢复
+type HealthRoutingSMSService struct {
+	providers []*provider
+	l         logger.Logger
+
+	// 窗口里面至少有这么多请求才判断是否熔断，避免请求太少的时候误判
+	minRequests int64
+	// 错误率超过这个值熔断
+	errRateThreshold float64
+	// 平均耗时超过这个值熔断，也用来计算健康分
+	slowThreshold time.Duration
+	// 熔断多久之后进入半开
+	openTimeout time.Duration
+	// 半开的时候放多少个探测请求，全部成功才恢复
+	halfOpenProbes int
+	now            func() time.Time
+}
+
+type provider struct {
+	Provider
+	window *slidingWindow
+
+	mu        sync.Mutex
+	state     breakerState
+	openUntil time.Time
+	// 半开状态下已经放出去的探测请求和其中成功的数量
+	probes    int
+	probeSucc int
+}
+
+func NewHealthRoutingSMSService(providers []Provider, window time.Duration, l logger.Logger) *HealthRoutingSMSService {
+	res := &HealthRoutingSMSService{
+		providers:        make([]*provider, 0, len(providers)),
+		l:                l,
+		minRequests:      10,
+		errRateThreshold: 0.5,
+		slowThreshold:    time.Second * 3,
+		openTimeout:      time.Second * 30,
+		halfOpenProbes:   3,
+		now:              time.Now,
+	}
+	for _, p := range providers {
+		res.providers = append(res.providers, &provider{
+			Provider: p,
+			window:   newSlidingWindow(window, 10),
+		})
+	}
+	return res
+}
+
+// Send 先按照健康分加权随机挑一个服务商，失败了按照健康分从高到低换别的服务商
+func (h *HealthRoutingSMSService) Send(ctx context.Context, tpl string, args []string, numbers ...string) error {
+	candidates := h.candidates()
+	if len(candidates) == 0 {
+		return ErrNoAvailableProvider
+	}
+	var err error
+	for _, p := range candidates {
+		if !h.allow(p) {
+			// 半开状态的探测名额被别的请求用完了
+			continue
+		}
+		start := h.now()
+		err = p.Svc.Send(ctx, tpl, args, numbers...)
+		if errors.Is(err, context.Canceled) {
+			// 调用方放弃了，不算服务商的问题，探测名额也还回去
+			h.giveBack(p)
+			return err
+		}
+		// 号码不对之类的问题不是服务商的错，换服务商也没用
+		callerErr := sms.IsCallerError(err)
+		h.record(p, err == nil || callerErr, h.now().Sub(start))
+		if err == nil || callerErr {
+			return err
+		}
+		h.l.Warn("短信服务商发送失败", logger.Error(err), logger.String("provider", p.Name))
+		if ctx.Err() != nil {
+			return err
+		}
+	}
+	if err == nil {
+		return ErrNoAvailableProvider
+	}
+	return err
+}
+
+type scoredProvider struct {
+	p     *provider
+	score float64
+}
+
+// candidates 第一个是加权随机选中的，后面的按照健康分从高到低排列，熔断中的服务商不参与
+func (h *HealthRoutingSMSService) candidates() []*provider {
+	now := h.now()
+	scored := make([]scoredProvider, 0, len(h.providers))
+	var total float64
+	for _, p := range h.providers {
+		if h.state(p, now) == breakerOpen {
+			continue
+		}
+		s := h.score(p, now)
+		scored = append(scored, scoredProvider{p: p, score: s})
+		total += s
+	}
+	if len(scored) == 0 {
+		return nil
+	}
+	sort.SliceStable(scored, func(i, j int) bool {
+		return scored[i].score > scored[j].score
+	})
+	first := 0
+	target := rand.Float64() * total
+	for i, s := range scored {
+		target -= s.score
+		if target < 0 {
+			first = i
+			break
+		}
+	}
+	res := make([]*provider, 0, len(scored))
+	res = append(res, scored[first].p)
+	for i, s := range scored {
+		if i != first {
+			res = append(res, s.p)
+		}
+	}
+	return res
+}
+
+// score 权重乘以成功率，再按照平均耗时打折；半开的服务商只给一点流量用来探测
+func (h *HealthRoutingSMSService) score(p *provider, now time.Time) float64 {
+	weight := float64(max(p.Weight, 1))
+	stats := p.window.stats(now)
+	res := weight * (1 - stats.errRate()) / (1 + float64(stats.avgLatency())/float64(h.slowThreshold))
+	if h.state(p, now) == breakerHalfOpen {
+		res = res / 10
+	}
+	// 不能是 0，不然永远选不中，也就没有机会恢复
+	return max(res, weight/100)
+}
+
+// state 熔断时间到了自动进入半开
+func (h *HealthRoutingSMSService) state(p *provider, now time.Time) breakerState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.state == breakerOpen && !now.Before(p.openUntil) {
+		h.transit(p, breakerHalfOpen, now)
+	}
+	return p.state
+}
+
+// allow 半开的时候只放 halfOpenProbes 个请求
+func (h *HealthRoutingSMSService) allow(p *provider) bool {
+	now := h.now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.state == breakerOpen && !now.Before(p.openUntil) {
+		h.transit(p, breakerHalfOpen, now)
+	}
+	switch p.state {
+	case breakerClosed:
+		return true
+	case breakerHalfOpen:
+		if p.probes >= h.halfOpenProbes {
+			return false
+		}
+		p.probes++
+		return true
+	default:
+		return false
+	}
+}
+
+func (h *HealthRoutingSMSService) giveBack(p *provider) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.state == breakerHalfOpen && p.probes > 0 {
+		p.probes--
+	}
+}
+
+func (h *HealthRoutingSMSService) record(p *provider, ok bool, latency time.Duration) {
+	now := h.now()
+	p.window.add(now, !ok, latency)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch p.state {
+	case breakerHalfOpen:
+		if !ok {
+			h.transit(p, breakerOpen, now)
+			return
+		}
+		p.probeSucc++
+		if p.probeSucc >= h.halfOpenProbes {
+			// 熔断之前的统计数据不再有参考意义
+			p.window.reset()
+			h.transit(p, breakerClosed, now)
+		}
+	case breakerClosed:
+		stats := p.window.stats(now)
+		if stats.total < h.minRequests {
+			return
+		}
+		if stats.errRate() >= h.errRateThreshold || stats.avgLatency() >= h.slowThreshold {
+			h.transit(p, breakerOpen, now)
+		}
+	}
+}
+
+// transit 调用方持有 p.mu
+func (h *HealthRoutingSMSService) transit(p *provider, state breakerState, now time.Time) {
+	h.l.Warn("短信服务商熔断状态变化", logger.String("provider", p.Name),
+		logger.String("from", p.state.String()), logger.String("to", state.String()))
+	p.state = state
+	p.probes = 0
+	p.probeSucc = 0
+	if state == breakerOpen {
+		p.openUntil = now.Add(h.openTimeout)
+	}
+}
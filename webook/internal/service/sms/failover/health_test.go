@@ -0,0 +1,180 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-basic/webook/internal/service/sms"
+	smsmocks "go-basic/webook/internal/service/sms/mocks"
+	"go-basic/webook/pkg/logger"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestHealthRouting(now *time.Time, svcs ...sms.Service) *HealthRoutingSMSService {
+	providers := make([]Provider, 0, len(svcs))
+	for i, svc := range svcs {
+		providers = append(providers, Provider{Name: string(rune('a' + i)), Svc: svc, Weight: 100})
+	}
+	res := NewHealthRoutingSMSService(providers, time.Minute, logger.NewNopLogger())
+	res.minRequests = 4
+	res.halfOpenProbes = 2
+	res.now = func() time.Time {
+		return *now
+	}
+	return res
+}
+
+func TestHealthRoutingSMSService_Breaker(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	now := time.UnixMilli(1_700_000_000_000)
+	bad := smsmocks.NewMockService(ctrl)
+	good := smsmocks.NewMockService(ctrl)
+	h := newTestHealthRouting(&now, bad, good)
+
+	// 失败率高的服务商分到的流量很少，这里直接用它发送直到熔断
+	bad.EXPECT().Send(gomock.Any(), "tpl", gomock.Any(), "152").Return(errors.New("server busy")).Times(4)
+	for i := 0; i < 4; i++ {
+		assert.True(t, h.allow(h.providers[0]))
+		start := h.now()
+		err := h.providers[0].Svc.Send(context.Background(), "tpl", nil, "152")
+		h.record(h.providers[0], err == nil, h.now().Sub(start))
+	}
+	assert.Equal(t, breakerOpen, h.providers[0].state)
+
+	// 熔断期间不会再选到 bad
+	good.EXPECT().Send(gomock.Any(), "tpl", gomock.Any(), "152").Return(nil).Times(10)
+	for i := 0; i < 10; i++ {
+		require.NoError(t, h.Send(context.Background(), "tpl", nil, "152"))
+		cands := h.candidates()
+		assert.Len(t, cands, 1)
+		assert.Equal(t, "b", cands[0].Name)
+	}
+
+	// 熔断时间到了进入半开，探测失败重新熔断
+	now = now.Add(h.openTimeout)
+	assert.Equal(t, breakerHalfOpen, h.state(h.providers[0], now))
+	assert.True(t, h.allow(h.providers[0]))
+	h.record(h.providers[0], false, time.Millisecond)
+	assert.Equal(t, breakerOpen, h.providers[0].state)
+
+	// 半开的时候只放 halfOpenProbes 个请求，都成功了才恢复
+	now = now.Add(h.openTimeout)
+	assert.True(t, h.allow(h.providers[0]))
+	assert.True(t, h.allow(h.providers[0]))
+	assert.False(t, h.allow(h.providers[0]))
+	h.record(h.providers[0], true, time.Millisecond)
+	assert.Equal(t, breakerHalfOpen, h.providers[0].state)
+	h.record(h.providers[0], true, time.Millisecond)
+	assert.Equal(t, breakerClosed, h.providers[0].state)
+	assert.Equal(t, int64(0), h.providers[0].window.stats(now).total)
+}
+
+func TestHealthRoutingSMSService_Slow(t *testing.T) {
+	now := time.UnixMilli(1_700_000_000_000)
+	h := newTestHealthRouting(&now, nil)
+	p := h.providers[0]
+	for i := 0; i < 3; i++ {
+		h.record(p, true, h.slowThreshold*2)
+	}
+	// 请求数不够，不判断
+	assert.Equal(t, breakerClosed, p.state)
+	h.record(p, true, h.slowThreshold*2)
+	assert.Equal(t, breakerOpen, p.state)
+}
+
+func TestHealthRoutingSMSService_Send(t *testing.T) {
+	errBusy := fmt.Errorf("%w: server busy", sms.ErrRetryable)
+	testCases := []struct {
+		name    string
+		mock    func(ctrl *gomock.Controller) (sms.Service, sms.Service)
+		open    bool
+		wantErr error
+		// 两个服务商的统计里面都没有失败
+		wantHealthy bool
+	}{
+		{
+			name: "全部失败",
+			mock: func(ctrl *gomock.Controller) (sms.Service, sms.Service) {
+				a := smsmocks.NewMockService(ctrl)
+				b := smsmocks.NewMockService(ctrl)
+				a.EXPECT().Send(gomock.Any(), "tpl", gomock.Any(), "152").Return(errBusy)
+				b.EXPECT().Send(gomock.Any(), "tpl", gomock.Any(), "152").Return(errBusy)
+				return a, b
+			},
+			wantErr: errBusy,
+		},
+		{
+			name: "号码不合法，不换服务商",
+			mock: func(ctrl *gomock.Controller) (sms.Service, sms.Service) {
+				a := smsmocks.NewMockService(ctrl)
+				b := smsmocks.NewMockService(ctrl)
+				a.EXPECT().Send(gomock.Any(), "tpl", gomock.Any(), "152").Return(sms.ErrInvalidNumber).MaxTimes(1)
+				b.EXPECT().Send(gomock.Any(), "tpl", gomock.Any(), "152").Return(sms.ErrInvalidNumber).MaxTimes(1)
+				return a, b
+			},
+			wantErr:     sms.ErrInvalidNumber,
+			wantHealthy: true,
+		},
+		{
+			name: "调用方取消，不换服务商",
+			mock: func(ctrl *gomock.Controller) (sms.Service, sms.Service) {
+				a := smsmocks.NewMockService(ctrl)
+				b := smsmocks.NewMockService(ctrl)
+				a.EXPECT().Send(gomock.Any(), "tpl", gomock.Any(), "152").Return(context.Canceled).MaxTimes(1)
+				b.EXPECT().Send(gomock.Any(), "tpl", gomock.Any(), "152").Return(context.Canceled).MaxTimes(1)
+				return a, b
+			},
+			wantErr: context.Canceled,
+		},
+		{
+			name: "全部熔断",
+			mock: func(ctrl *gomock.Controller) (sms.Service, sms.Service) {
+				return smsmocks.NewMockService(ctrl), smsmocks.NewMockService(ctrl)
+			},
+			open:    true,
+			wantErr: ErrNoAvailableProvider,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			now := time.UnixMilli(1_700_000_000_000)
+			a, b := tc.mock(ctrl)
+			h := newTestHealthRouting(&now, a, b)
+			if tc.open {
+				for _, p := range h.providers {
+					p.state = breakerOpen
+					p.openUntil = now.Add(time.Minute)
+				}
+			}
+			err := h.Send(context.Background(), "tpl", nil, "152")
+			assert.ErrorIs(t, err, tc.wantErr)
+			if tc.wantHealthy {
+				for _, p := range h.providers {
+					assert.Equal(t, int64(0), p.window.stats(now).failed)
+				}
+			}
+		})
+	}
+}
+
+func TestHealthRoutingSMSService_score(t *testing.T) {
+	now := time.UnixMilli(1_700_000_000_000)
+	h := newTestHealthRouting(&now, nil, nil)
+	healthy, sick := h.providers[0], h.providers[1]
+	for i := 0; i < 3; i++ {
+		h.record(healthy, true, time.Millisecond*100)
+		h.record(sick, i == 0, time.Second)
+	}
+	assert.Greater(t, h.score(healthy, now), h.score(sick, now)*3)
+	// 统计数据过了窗口就不算了
+	now = now.Add(time.Minute * 2)
+	assert.Equal(t, h.score(healthy, now), h.score(sick, now))
+}
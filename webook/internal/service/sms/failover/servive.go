@@ -19,17 +19,20 @@ func NewFailoverSMSService(svcs ...sms.Service) sms.Service {
 	}
 }
 
-func (f FailoverSMSService) Send(ctx context.Context, tpl string, args []string, numbers ...string) error {
+func (f *FailoverSMSService) Send(ctx context.Context, tpl string, args []string, numbers ...string) error {
 	// 取下一个节点作为起始节点
 	idx := atomic.AddUint64(&f.idx, 1)
 	length := uint64(len(f.svcs))
 	for i := idx; i < idx+length; i++ {
 		svc := f.svcs[int(i%length)]
 		err := svc.Send(ctx, tpl, args, numbers...)
-		switch err {
-		case nil:
+		switch {
+		case err == nil:
 			return nil
-		case context.DeadlineExceeded, context.Canceled:
+		case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+			return err
+		case sms.IsCallerError(err):
+			// 请求本身有问题，换服务商也没用
 			return err
 		default:
 			// 输出日志
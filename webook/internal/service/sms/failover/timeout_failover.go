@@ -2,6 +2,7 @@ package failover
 
 import (
 	"context"
+	"errors"
 	"go-basic/webook/internal/service/sms"
 	"sync/atomic"
 )
@@ -16,9 +17,11 @@ type TimeoutFailoverSMSService struct {
 	threshold int32
 }
 
-func NewTimeoutFailoverSMSService() sms.Service {
-
-	return &TimeoutFailoverSMSService{}
+func NewTimeoutFailoverSMSService(svcs []sms.Service, threshold int32) sms.Service {
+	return &TimeoutFailoverSMSService{
+		svcs:      svcs,
+		threshold: threshold,
+	}
 }
 
 func (t *TimeoutFailoverSMSService) Send(ctx context.Context, tpl string, args []string, numbers ...string) error {
@@ -38,16 +41,15 @@ func (t *TimeoutFailoverSMSService) Send(ctx context.Context, tpl string, args [
 
 	svc := t.svcs[idx]
 	err := svc.Send(ctx, tpl, args, numbers...)
-	switch err {
-	case context.DeadlineExceeded:
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, sms.ErrRetryable):
 		// 超时了，计数器加一
 		atomic.AddInt32(&t.cnt, 1)
-	case nil:
+	case err == nil:
 		// 成功了，计数器清零
 		atomic.StoreInt32(&t.cnt, 0)
 	default:
 		// 其他错误，不做处理
-		return err
 	}
-	return nil
+	return err
 }
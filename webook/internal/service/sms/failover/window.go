@@ -0,0 +1,87 @@
+package failover
+
+import (
+	"sync"
+	"time"
+)
+
+// slidingWindow 把窗口分成若干个桶统计请求数、失败数和耗时，过期的桶直接丢弃
+type slidingWindow struct {
+	mu      sync.Mutex
+	buckets []windowBucket
+	// 每个桶覆盖的时间
+	width time.Duration
+}
+
+type windowBucket struct {
+	// 桶的序号，时间除以桶宽度，用来判断桶是不是已经过期
+	seq     int64
+	total   int64
+	failed  int64
+	latency time.Duration
+}
+
+type windowStats struct {
+	total   int64
+	failed  int64
+	latency time.Duration
+}
+
+func (s windowStats) errRate() float64 {
+	if s.total == 0 {
+		return 0
+	}
+	return float64(s.failed) / float64(s.total)
+}
+
+func (s windowStats) avgLatency() time.Duration {
+	if s.total == 0 {
+		return 0
+	}
+	return s.latency / time.Duration(s.total)
+}
+
+func newSlidingWindow(size time.Duration, cnt int) *slidingWindow {
+	return &slidingWindow{
+		buckets: make([]windowBucket, cnt),
+		width:   size / time.Duration(cnt),
+	}
+}
+
+func (w *slidingWindow) add(now time.Time, failed bool, latency time.Duration) {
+	seq := now.UnixNano() / int64(w.width)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	b := &w.buckets[seq%int64(len(w.buckets))]
+	if b.seq != seq {
+		*b = windowBucket{seq: seq}
+	}
+	b.total++
+	if failed {
+		b.failed++
+	}
+	b.latency += latency
+}
+
+func (w *slidingWindow) stats(now time.Time) windowStats {
+	seq := now.UnixNano() / int64(w.width)
+	oldest := seq - int64(len(w.buckets)) + 1
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var res windowStats
+	for _, b := range w.buckets {
+		if b.seq < oldest || b.seq > seq {
+			continue
+		}
+		res.total += b.total
+		res.failed += b.failed
+		res.latency += b.latency
+	}
+	return res
+}
+
+func (w *slidingWindow) reset() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	clear(w.buckets)
+}
@@ -0,0 +1,82 @@
+package gateway
+
+import (
+	"crypto/hmac"
+	"encoding/json"
+	"fmt"
+	"go-basic/webook/internal/service/sms"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// receiptMaxSkew 回执时间戳和本地时间最多差这么多，防止重放很久以前的回执
+const receiptMaxSkew = time.Minute * 5
+
+// ReceiptRequest 网关推送回执的请求体，签名方法和发送短信一样
+type ReceiptRequest struct {
+	Receipts []Receipt `json:"receipts"`
+}
+
+// Receipt Status 是 delivered 或者 failed，Time 是 Unix 秒
+type Receipt struct {
+	MessageId string `json:"message_id"`
+	Number    string `json:"number"`
+	Status    string `json:"status"`
+	ErrCode   string `json:"err_code"`
+	ErrMsg    string `json:"err_msg"`
+	Time      int64  `json:"time"`
+}
+
+type ReceiptParser struct {
+	appKey string
+	secret []byte
+	now    func() time.Time
+}
+
+func NewReceiptParser(appKey string, secret []byte) *ReceiptParser {
+	return &ReceiptParser{
+		appKey: appKey,
+		secret: secret,
+		now:    time.Now,
+	}
+}
+
+func (p *ReceiptParser) Parse(req *http.Request, body []byte) ([]sms.Receipt, error) {
+	if req.Header.Get(AppKeyHeader) != p.appKey {
+		return nil, fmt.Errorf("%w: app key 不对", sms.ErrInvalidReceipt)
+	}
+	ts := req.Header.Get(TimestampHeader)
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: 时间戳不合法", sms.ErrInvalidReceipt)
+	}
+	skew := p.now().Sub(time.Unix(sec, 0))
+	if skew > receiptMaxSkew || skew < -receiptMaxSkew {
+		return nil, fmt.Errorf("%w: 时间戳过期", sms.ErrInvalidReceipt)
+	}
+	sign := Sign(p.secret, ts, req.Header.Get(NonceHeader), body)
+	if !hmac.Equal([]byte(sign), []byte(req.Header.Get(SignatureHeader))) {
+		return nil, fmt.Errorf("%w: 签名不对", sms.ErrInvalidReceipt)
+	}
+	var r ReceiptRequest
+	err = json.Unmarshal(body, &r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", sms.ErrInvalidReceipt, err)
+	}
+	res := make([]sms.Receipt, 0, len(r.Receipts))
+	for _, rc := range r.Receipts {
+		if rc.Status != "delivered" && rc.Status != "failed" {
+			return nil, fmt.Errorf("%w: 回执状态 %s 不合法", sms.ErrInvalidReceipt, rc.Status)
+		}
+		res = append(res, sms.Receipt{
+			MessageId: rc.MessageId,
+			Number:    rc.Number,
+			Delivered: rc.Status == "delivered",
+			ErrCode:   rc.ErrCode,
+			ErrMsg:    rc.ErrMsg,
+			Time:      time.Unix(rc.Time, 0),
+		})
+	}
+	return res, nil
+}
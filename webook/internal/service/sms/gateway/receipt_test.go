@@ -0,0 +1,92 @@
+package gateway
+
+import (
+	"bytes"
+	"go-basic/webook/internal/service/sms"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReceiptParser_Parse(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"receipts":[
+{"message_id":"m1","number":"152","status":"delivered","time":1700000000},
+{"message_id":"m1","number":"153","status":"failed","err_code":"UNDELIV","err_msg":"空号","time":1700000001}]}`)
+	testCases := []struct {
+		name   string
+		appKey string
+		ts     time.Time
+		secret []byte
+		body   []byte
+
+		want    []sms.Receipt
+		wantErr error
+	}{
+		{
+			name:   "签名正确",
+			appKey: "app",
+			ts:     now,
+			secret: testSecret,
+			body:   body,
+			want: []sms.Receipt{
+				{MessageId: "m1", Number: "152", Delivered: true, Time: time.Unix(1700000000, 0)},
+				{MessageId: "m1", Number: "153", ErrCode: "UNDELIV", ErrMsg: "空号", Time: time.Unix(1700000001, 0)},
+			},
+		},
+		{
+			name:    "签名不对",
+			appKey:  "app",
+			ts:      now,
+			secret:  []byte("wrong"),
+			body:    body,
+			wantErr: sms.ErrInvalidReceipt,
+		},
+		{
+			name:    "app key 不对",
+			appKey:  "other",
+			ts:      now,
+			secret:  testSecret,
+			body:    body,
+			wantErr: sms.ErrInvalidReceipt,
+		},
+		{
+			name:    "时间戳过期",
+			appKey:  "app",
+			ts:      now.Add(-time.Hour),
+			secret:  testSecret,
+			body:    body,
+			wantErr: sms.ErrInvalidReceipt,
+		},
+		{
+			name:    "状态不合法",
+			appKey:  "app",
+			ts:      now,
+			secret:  testSecret,
+			body:    []byte(`{"receipts":[{"message_id":"m1","number":"152","status":"sending"}]}`),
+			wantErr: sms.ErrInvalidReceipt,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := NewReceiptParser("app", testSecret)
+			p.now = func() time.Time {
+				return now
+			}
+			req, err := http.NewRequest(http.MethodPost, "/sms/receipts?provider=gateway", bytes.NewReader(tc.body))
+			require.NoError(t, err)
+			ts := strconv.FormatInt(tc.ts.Unix(), 10)
+			req.Header.Set(AppKeyHeader, tc.appKey)
+			req.Header.Set(TimestampHeader, ts)
+			req.Header.Set(NonceHeader, "nonce")
+			req.Header.Set(SignatureHeader, Sign(tc.secret, ts, "nonce", tc.body))
+			res, err := p.Parse(req, tc.body)
+			assert.ErrorIs(t, err, tc.wantErr)
+			assert.Equal(t, tc.want, res)
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go-basic/webook/internal/service/sms"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	AppKeyHeader    = "X-Sms-App-Key"
+	TimestampHeader = "X-Sms-Timestamp"
+	NonceHeader     = "X-Sms-Nonce"
+	// SignatureHeader hex(HMAC-SHA256(secret, timestamp + "\n" + nonce + "\n" + body))
+	SignatureHeader = "X-Sms-Signature"
+)
+
+// errorKinds 配置里面错误码可以翻译成的类型
+var errorKinds = map[string]error{
+	"unavailable":    sms.ErrRetryable,
+	"rate_limit":     sms.ErrRateLimited,
+	"invalid_number": sms.ErrInvalidNumber,
+	"invalid":        sms.ErrInvalidArgs,
+	"blocked":        sms.ErrBlocked,
+	"unauthorized":   sms.ErrAuthFailed,
+}
+
+// Request 发给网关的请求体
+type Request struct {
+	TemplateId string   `json:"template_id"`
+	Numbers    []string `json:"numbers"`
+	Params     []string `json:"params"`
+}
+
+// Response 网关的响应体，Code 等于配置的成功码才算成功
+type Response struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	MessageId string `json:"message_id"`
+}
+
+// Service 对接只提供 HTTP 接口的短信网关。请求用 HMAC-SHA256 签名，
+// biz 按照配置映射成网关的模板 ID，网关的错误码按照配置翻译成 sms 包里面的错误
+type Service struct {
+	client      *http.Client
+	url         string
+	appKey      string
+	secret      []byte
+	templates   map[string]string
+	successCode string
+	codes       map[string]error
+	now         func() time.Time
+}
+
+// NewService errorCodes 是网关错误码到错误类型的映射，类型见 errorKinds
+func NewService(client *http.Client, url, appKey string, secret []byte, templates map[string]string,
+	successCode string, errorCodes map[string]string) (*Service, error) {
+	codes := make(map[string]error, len(errorCodes))
+	for code, kind := range errorCodes {
+		err, ok := errorKinds[kind]
+		if !ok {
+			return nil, fmt.Errorf("错误码 %s 的类型 %s 不合法", code, kind)
+		}
+		codes[code] = err
+	}
+	return &Service{
+		client:      client,
+		url:         url,
+		appKey:      appKey,
+		secret:      secret,
+		templates:   templates,
+		successCode: successCode,
+		codes:       codes,
+		now:         time.Now,
+	}, nil
+}
+
+func (s *Service) Send(ctx context.Context, biz string, args []string, numbers ...string) error {
+	// 没有配置映射的时候 biz 就是网关的模板 ID，例如已经经过模板注册中心转换
+	tplId := biz
+	if s.templates != nil {
+		var ok bool
+		tplId, ok = s.templates[biz]
+		if !ok {
+			return fmt.Errorf("%w: 没有配置模板 %s", sms.ErrInvalidArgs, biz)
+		}
+	}
+	body, err := json.Marshal(Request{
+		TemplateId: tplId,
+		Numbers:    numbers,
+		Params:     args,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	ts := strconv.FormatInt(s.now().Unix(), 10)
+	n := nonce()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(AppKeyHeader, s.appKey)
+	req.Header.Set(TimestampHeader, ts)
+	req.Header.Set(NonceHeader, n)
+	req.Header.Set(SignatureHeader, Sign(s.secret, ts, n, body))
+	httpResp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+	data, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return err
+	}
+	var resp Response
+	if json.Unmarshal(data, &resp) != nil || resp.Code == "" {
+		return s.statusErr(httpResp.StatusCode, data)
+	}
+	if resp.Code == s.successCode {
+		sms.SetMessageId(ctx, resp.MessageId, numbers...)
+		return nil
+	}
+	if e, ok := s.codes[resp.Code]; ok {
+		return fmt.Errorf("%w: %s %s", e, resp.Code, resp.Message)
+	}
+	return fmt.Errorf("发送短信失败: %s %s", resp.Code, resp.Message)
+}
+
+// statusErr 响应体里面没有错误码的时候按照状态码判断
+func (s *Service) statusErr(status int, data []byte) error {
+	switch {
+	case status == http.StatusTooManyRequests:
+		return fmt.Errorf("%w: HTTP %d", sms.ErrRateLimited, status)
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return fmt.Errorf("%w: HTTP %d", sms.ErrAuthFailed, status)
+	case status >= http.StatusInternalServerError:
+		return fmt.Errorf("%w: HTTP %d", sms.ErrRetryable, status)
+	default:
+		return fmt.Errorf("短信网关响应无法解析: HTTP %d, %s", status, data)
+	}
+}
+
+// Sign 网关用同样的方法计算签名，和 X-Sms-Signature 比较。网关推送回执的时候也用这个方法签名
+func Sign(secret []byte, timestamp, nonce string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("\n"))
+	mac.Write([]byte(nonce))
+	mac.Write([]byte("\n"))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func nonce() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,127 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"go-basic/webook/internal/service/sms"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testSecret = []byte("gateway-secret")
+
+// newTestServer 模拟短信网关，校验签名之后按照 handle 返回
+func newTestServer(handle func(w http.ResponseWriter, req Request)) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		sig := Sign(testSecret, r.Header.Get(TimestampHeader), r.Header.Get(NonceHeader), body)
+		if r.Header.Get(AppKeyHeader) != "app" || sig != r.Header.Get(SignatureHeader) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var req Request
+		_ = json.Unmarshal(body, &req)
+		handle(w, req)
+	}))
+}
+
+func TestService_Send(t *testing.T) {
+	testCases := []struct {
+		name       string
+		handle     func(w http.ResponseWriter, req Request)
+		secret     []byte
+		biz        string
+		wantErr    error
+		wantErrMsg string
+	}{
+		{
+			name: "发送成功",
+			handle: func(w http.ResponseWriter, req Request) {
+				assert.Equal(t, Request{
+					TemplateId: "tpl_login",
+					Numbers:    []string{"152"},
+					Params:     []string{"1234"},
+				}, req)
+				_, _ = w.Write([]byte(`{"code":"0","message":"ok","message_id":"m1"}`))
+			},
+			secret: testSecret,
+			biz:    "login",
+		},
+		{
+			name:    "签名错误",
+			secret:  []byte("wrong"),
+			biz:     "login",
+			wantErr: sms.ErrAuthFailed,
+		},
+		{
+			name: "错误码翻译",
+			handle: func(w http.ResponseWriter, req Request) {
+				_, _ = w.Write([]byte(`{"code":"1001","message":"too fast"}`))
+			},
+			secret:  testSecret,
+			biz:     "login",
+			wantErr: sms.ErrRateLimited,
+		},
+		{
+			name: "没有配置的错误码",
+			handle: func(w http.ResponseWriter, req Request) {
+				_, _ = w.Write([]byte(`{"code":"9999","message":"unknown"}`))
+			},
+			secret:     testSecret,
+			biz:        "login",
+			wantErrMsg: "发送短信失败: 9999 unknown",
+		},
+		{
+			name: "网关不可用",
+			handle: func(w http.ResponseWriter, req Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			},
+			secret:  testSecret,
+			biz:     "login",
+			wantErr: sms.ErrRetryable,
+		},
+		{
+			name:    "没有配置模板",
+			secret:  testSecret,
+			biz:     "unknown",
+			wantErr: sms.ErrInvalidArgs,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newTestServer(func(w http.ResponseWriter, req Request) {
+				if tc.handle == nil {
+					t.Error("不应该发出请求")
+					return
+				}
+				tc.handle(w, req)
+			})
+			defer server.Close()
+			svc, err := NewService(server.Client(), server.URL, "app", tc.secret,
+				map[string]string{"login": "tpl_login"}, "0",
+				map[string]string{"1001": "rate_limit", "1002": "invalid"})
+			require.NoError(t, err)
+			svc.now = func() time.Time {
+				return time.Unix(1_700_000_000, 0)
+			}
+			err = svc.Send(context.Background(), tc.biz, []string{"1234"}, "152")
+			if tc.wantErrMsg != "" {
+				assert.EqualError(t, err, tc.wantErrMsg)
+				return
+			}
+			assert.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestNewService(t *testing.T) {
+	_, err := NewService(http.DefaultClient, "http://localhost", "app", testSecret, nil, "0",
+		map[string]string{"1001": "unknown"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,93 @@
+package quota
+
+import (
+	"context"
+	"fmt"
+	"go-basic/webook/internal/service/sms"
+	"go-basic/webook/pkg/ratelimit"
+)
+
+// 每一层配额都有自己的错误，调用方可以告诉用户触发了哪个限制。
+// 都包装了 sms.ErrRateLimited，不关心是哪一层的调用方当成限流处理就可以
+var (
+	ErrPhoneQuotaExceeded = fmt.Errorf("%w: 手机号码发送的短信太多", sms.ErrRateLimited)
+	ErrIPQuotaExceeded    = fmt.Errorf("%w: IP 发送的短信太多", sms.ErrRateLimited)
+	ErrBizQuotaExceeded   = fmt.Errorf("%w: 业务发送的短信太多", sms.ErrRateLimited)
+)
+
+type ipKey struct{}
+
+// WithIP 把请求方的 IP 放进 ctx 里面，只有带了 IP 的请求才会按照 IP 限制
+func WithIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, ipKey{}, ip)
+}
+
+func ipFromCtx(ctx context.Context) string {
+	ip, _ := ctx.Value(ipKey{}).(string)
+	return ip
+}
+
+// SMSService 按照 keys 返回的限流对象依次判断，任何一个超过配额都不会发送
+type SMSService struct {
+	svc     sms.Service
+	limiter ratelimit.Limiter
+	keys    func(ctx context.Context, biz string, numbers []string) []string
+	err     error
+}
+
+// NewPhoneQuotaSMSService 限制每个手机号码收到的短信数量，时间窗口和数量由 limiter 决定
+func NewPhoneQuotaSMSService(svc sms.Service, limiter ratelimit.Limiter) sms.Service {
+	return &SMSService{
+		svc:     svc,
+		limiter: limiter,
+		keys: func(ctx context.Context, biz string, numbers []string) []string {
+			keys := make([]string, 0, len(numbers))
+			for _, number := range numbers {
+				keys = append(keys, "sms:quota:phone:"+number)
+			}
+			return keys
+		},
+		err: ErrPhoneQuotaExceeded,
+	}
+}
+
+// NewIPQuotaSMSService 限制每个 IP 触发的短信数量，ctx 里面没有 IP 的不限制
+func NewIPQuotaSMSService(svc sms.Service, limiter ratelimit.Limiter) sms.Service {
+	return &SMSService{
+		svc:     svc,
+		limiter: limiter,
+		keys: func(ctx context.Context, biz string, numbers []string) []string {
+			ip := ipFromCtx(ctx)
+			if ip == "" {
+				return nil
+			}
+			return []string{"sms:quota:ip:" + ip}
+		},
+		err: ErrIPQuotaExceeded,
+	}
+}
+
+// NewBizQuotaSMSService 限制每个业务发送的短信数量
+func NewBizQuotaSMSService(svc sms.Service, limiter ratelimit.Limiter) sms.Service {
+	return &SMSService{
+		svc:     svc,
+		limiter: limiter,
+		keys: func(ctx context.Context, biz string, numbers []string) []string {
+			return []string{"sms:quota:biz:" + biz}
+		},
+		err: ErrBizQuotaExceeded,
+	}
+}
+
+func (s *SMSService) Send(ctx context.Context, biz string, args []string, numbers ...string) error {
+	for _, key := range s.keys(ctx, biz, numbers) {
+		limited, err := s.limiter.Limit(ctx, key)
+		if err != nil {
+			return fmt.Errorf("判断短信配额失败: %w", err)
+		}
+		if limited {
+			return s.err
+		}
+	}
+	return s.svc.Send(ctx, biz, args, numbers...)
+}
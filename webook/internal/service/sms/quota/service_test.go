@@ -0,0 +1,108 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-basic/webook/internal/service/sms"
+	smsmocks "go-basic/webook/internal/service/sms/mocks"
+	"go-basic/webook/pkg/ratelimit"
+	limitmocks "go-basic/webook/pkg/ratelimit/mocks"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSMSService_Send(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (sms.Service, ratelimit.Limiter)
+		fn   func(svc sms.Service, limiter ratelimit.Limiter) sms.Service
+		ctx  context.Context
+
+		wantErr error
+	}{
+		{
+			name: "每个手机号码都没有超过配额",
+			mock: func(ctrl *gomock.Controller) (sms.Service, ratelimit.Limiter) {
+				svc := smsmocks.NewMockService(ctrl)
+				limiter := limitmocks.NewMockLimiter(ctrl)
+				limiter.EXPECT().Limit(gomock.Any(), "sms:quota:phone:152").Return(false, nil)
+				limiter.EXPECT().Limit(gomock.Any(), "sms:quota:phone:153").Return(false, nil)
+				svc.EXPECT().Send(gomock.Any(), "code", []string{"123456"}, "152", "153").Return(nil)
+				return svc, limiter
+			},
+			fn:  NewPhoneQuotaSMSService,
+			ctx: context.Background(),
+		},
+		{
+			name: "其中一个手机号码超过配额",
+			mock: func(ctrl *gomock.Controller) (sms.Service, ratelimit.Limiter) {
+				limiter := limitmocks.NewMockLimiter(ctrl)
+				limiter.EXPECT().Limit(gomock.Any(), "sms:quota:phone:152").Return(false, nil)
+				limiter.EXPECT().Limit(gomock.Any(), "sms:quota:phone:153").Return(true, nil)
+				return smsmocks.NewMockService(ctrl), limiter
+			},
+			fn:      NewPhoneQuotaSMSService,
+			ctx:     context.Background(),
+			wantErr: ErrPhoneQuotaExceeded,
+		},
+		{
+			name: "IP 超过配额",
+			mock: func(ctrl *gomock.Controller) (sms.Service, ratelimit.Limiter) {
+				limiter := limitmocks.NewMockLimiter(ctrl)
+				limiter.EXPECT().Limit(gomock.Any(), "sms:quota:ip:127.0.0.1").Return(true, nil)
+				return smsmocks.NewMockService(ctrl), limiter
+			},
+			fn:      NewIPQuotaSMSService,
+			ctx:     WithIP(context.Background(), "127.0.0.1"),
+			wantErr: ErrIPQuotaExceeded,
+		},
+		{
+			name: "没有 IP 的不限制",
+			mock: func(ctrl *gomock.Controller) (sms.Service, ratelimit.Limiter) {
+				svc := smsmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "code", []string{"123456"}, "152", "153").Return(nil)
+				return svc, limitmocks.NewMockLimiter(ctrl)
+			},
+			fn:  NewIPQuotaSMSService,
+			ctx: context.Background(),
+		},
+		{
+			name: "业务超过配额",
+			mock: func(ctrl *gomock.Controller) (sms.Service, ratelimit.Limiter) {
+				limiter := limitmocks.NewMockLimiter(ctrl)
+				limiter.EXPECT().Limit(gomock.Any(), "sms:quota:biz:code").Return(true, nil)
+				return smsmocks.NewMockService(ctrl), limiter
+			},
+			fn:      NewBizQuotaSMSService,
+			ctx:     context.Background(),
+			wantErr: ErrBizQuotaExceeded,
+		},
+		{
+			name: "判断配额失败",
+			mock: func(ctrl *gomock.Controller) (sms.Service, ratelimit.Limiter) {
+				limiter := limitmocks.NewMockLimiter(ctrl)
+				limiter.EXPECT().Limit(gomock.Any(), "sms:quota:biz:code").Return(false, errors.New("redis 错误"))
+				return smsmocks.NewMockService(ctrl), limiter
+			},
+			fn:      NewBizQuotaSMSService,
+			ctx:     context.Background(),
+			wantErr: fmt.Errorf("判断短信配额失败: %w", errors.New("redis 错误")),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			s := tc.fn(tc.mock(ctrl))
+			err := s.Send(tc.ctx, "code", []string{"123456"}, "152", "153")
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+	// 不关心是哪一层的调用方当成限流处理
+	for _, err := range []error{ErrPhoneQuotaExceeded, ErrIPQuotaExceeded, ErrBizQuotaExceeded} {
+		assert.ErrorIs(t, err, sms.ErrRateLimited)
+	}
+}
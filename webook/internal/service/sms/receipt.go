@@ -0,0 +1,48 @@
+package sms
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// ErrInvalidReceipt 回执的签名不对，或者格式解析不了
+var ErrInvalidReceipt = errors.New("短信回执不合法")
+
+// Receipt 服务商推送过来的回执，一个号码一条
+type Receipt struct {
+	MessageId string
+	Number    string
+	// Delivered 为 false 的时候 ErrCode 和 ErrMsg 是服务商给的失败原因
+	Delivered bool
+	ErrCode   string
+	ErrMsg    string
+	Time      time.Time
+}
+
+// ReceiptParser 每个服务商推送回执的格式和签名方式都不一样，
+// 校验签名之后把回执翻译成 Receipt，校验不通过返回 ErrInvalidReceipt
+type ReceiptParser interface {
+	Parse(req *http.Request, body []byte) ([]Receipt, error)
+}
+
+type messageIdsKey struct{}
+
+// WithMessageIds 返回的 map 会收集这一次发送里面服务商返回的消息 ID，号码到消息 ID。
+// Send 的签名里面没有消息 ID，需要的装饰器用这个方法从服务商那里拿
+func WithMessageIds(ctx context.Context) (context.Context, map[string]string) {
+	ids := make(map[string]string)
+	return context.WithValue(ctx, messageIdsKey{}, ids), ids
+}
+
+// SetMessageId 服务商发送成功之后调用，没有人收集的时候什么也不做
+func SetMessageId(ctx context.Context, messageId string, numbers ...string) {
+	ids, ok := ctx.Value(messageIdsKey{}).(map[string]string)
+	if !ok {
+		return
+	}
+	for _, number := range numbers {
+		ids[number] = messageId
+	}
+}
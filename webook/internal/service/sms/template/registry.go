@@ -0,0 +1,115 @@
+package template
+
+import (
+	"fmt"
+	"go-basic/webook/internal/service/sms"
+	"regexp"
+)
+
+// Param 模板参数，Pattern 为空的时候不校验格式
+type Param struct {
+	Name    string `yaml:"name"`
+	Pattern string `yaml:"pattern"`
+}
+
+// Template 一个业务用的短信模板。Biz 是业务里面使用的名字，
+// Providers 是服务商名字到这个服务商那边审核通过的模板 ID
+type Template struct {
+	Biz       string            `yaml:"biz"`
+	Params    []Param           `yaml:"params"`
+	Providers map[string]string `yaml:"providers"`
+}
+
+type compiledTemplate struct {
+	Template
+	patterns []*regexp.Regexp
+}
+
+// Registry 启动之后不会再修改，可以并发使用
+type Registry struct {
+	tpls map[string]compiledTemplate
+}
+
+// NewRegistry 每个模板都要在所有服务商那里配置，不然换服务商的时候会发不出去
+func NewRegistry(tpls []Template, providers []string) (*Registry, error) {
+	res := &Registry{
+		tpls: make(map[string]compiledTemplate, len(tpls)),
+	}
+	for _, tpl := range tpls {
+		if _, ok := res.tpls[tpl.Biz]; ok {
+			return nil, fmt.Errorf("短信模板 %s 重复", tpl.Biz)
+		}
+		for _, p := range providers {
+			if tpl.Providers[p] == "" {
+				return nil, fmt.Errorf("短信模板 %s 没有配置服务商 %s 的模板 ID", tpl.Biz, p)
+			}
+		}
+		c := compiledTemplate{
+			Template: tpl,
+			patterns: make([]*regexp.Regexp, len(tpl.Params)),
+		}
+		for i, p := range tpl.Params {
+			if p.Pattern == "" {
+				continue
+			}
+			reg, err := regexp.Compile(p.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("短信模板 %s 参数 %s 的格式有误: %w", tpl.Biz, p.Name, err)
+			}
+			c.patterns[i] = reg
+		}
+		res.tpls[tpl.Biz] = c
+	}
+	return res, nil
+}
+
+// Exist 模板是否存在
+func (r *Registry) Exist(biz string) bool {
+	_, ok := r.tpls[biz]
+	return ok
+}
+
+// Validate 参数个数和格式都要和模板一致
+func (r *Registry) Validate(biz string, args []string) error {
+	tpl, ok := r.tpls[biz]
+	if !ok {
+		return fmt.Errorf("%w: 短信模板 %s 不存在", sms.ErrInvalidArgs, biz)
+	}
+	if len(args) != len(tpl.Params) {
+		return fmt.Errorf("%w: 短信模板 %s 需要 %d 个参数，传入了 %d 个",
+			sms.ErrInvalidArgs, biz, len(tpl.Params), len(args))
+	}
+	for i, reg := range tpl.patterns {
+		if reg != nil && !reg.MatchString(args[i]) {
+			return fmt.Errorf("%w: 短信模板 %s 的参数 %s 格式不对",
+				sms.ErrInvalidArgs, biz, tpl.Params[i].Name)
+		}
+	}
+	return nil
+}
+
+// ProviderTemplate 业务模板在服务商那边的模板 ID
+func (r *Registry) ProviderTemplate(biz, provider string) (string, error) {
+	tpl, ok := r.tpls[biz]
+	if !ok {
+		return "", fmt.Errorf("%w: 短信模板 %s 不存在", sms.ErrInvalidArgs, biz)
+	}
+	id, ok := tpl.Providers[provider]
+	if !ok {
+		return "", fmt.Errorf("%w: 短信模板 %s 没有配置服务商 %s", sms.ErrInvalidArgs, biz, provider)
+	}
+	return id, nil
+}
+
+// ParamNames 服务商模板 ID 到参数名，给按照名字传参数的服务商用，例如阿里云
+func (r *Registry) ParamNames(provider string) map[string][]string {
+	res := make(map[string][]string, len(r.tpls))
+	for _, tpl := range r.tpls {
+		names := make([]string, 0, len(tpl.Params))
+		for _, p := range tpl.Params {
+			names = append(names, p.Name)
+		}
+		res[tpl.Providers[provider]] = names
+	}
+	return res
+}
@@ -0,0 +1,108 @@
+package template
+
+import (
+	"context"
+	"go-basic/webook/internal/service/sms"
+	smsmocks "go-basic/webook/internal/service/sms/mocks"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testTemplates = []Template{
+	{
+		Biz:       "code",
+		Params:    []Param{{Name: "code", Pattern: `^\d{6}$`}},
+		Providers: map[string]string{"aliyun": "SMS_1", "tencent": "1001"},
+	},
+	{
+		Biz:       "notice",
+		Params:    []Param{{Name: "name"}, {Name: "time", Pattern: `^\d{2}:\d{2}$`}},
+		Providers: map[string]string{"aliyun": "SMS_2", "tencent": "1002"},
+	},
+}
+
+func TestNewRegistry(t *testing.T) {
+	testCases := []struct {
+		name      string
+		tpls      []Template
+		providers []string
+		wantErr   bool
+	}{
+		{
+			name:      "合法",
+			tpls:      testTemplates,
+			providers: []string{"aliyun", "tencent"},
+		},
+		{
+			name:      "缺少服务商的模板",
+			tpls:      testTemplates,
+			providers: []string{"aliyun", "gateway"},
+			wantErr:   true,
+		},
+		{
+			name:    "重复",
+			tpls:    []Template{testTemplates[0], testTemplates[0]},
+			wantErr: true,
+		},
+		{
+			name: "格式不合法",
+			tpls: []Template{
+				{Biz: "bad", Params: []Param{{Name: "code", Pattern: `(`}}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewRegistry(tc.tpls, tc.providers)
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestRegistry_Validate(t *testing.T) {
+	r, err := NewRegistry(testTemplates, []string{"aliyun", "tencent"})
+	require.NoError(t, err)
+	testCases := []struct {
+		name    string
+		biz     string
+		args    []string
+		wantErr error
+	}{
+		{name: "合法", biz: "code", args: []string{"012345"}},
+		{name: "不校验格式的参数", biz: "notice", args: []string{"任意内容", "09:30"}},
+		{name: "模板不存在", biz: "unknown", args: []string{"012345"}, wantErr: sms.ErrInvalidArgs},
+		{name: "参数个数不对", biz: "code", args: []string{"012345", "1"}, wantErr: sms.ErrInvalidArgs},
+		{name: "参数格式不对", biz: "code", args: []string{"12345a"}, wantErr: sms.ErrInvalidArgs},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.ErrorIs(t, r.Validate(tc.biz, tc.args), tc.wantErr)
+		})
+	}
+}
+
+func TestRegistry_ParamNames(t *testing.T) {
+	r, err := NewRegistry(testTemplates, []string{"aliyun", "tencent"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"SMS_1": {"code"},
+		"SMS_2": {"name", "time"},
+	}, r.ParamNames("aliyun"))
+}
+
+func TestProviderSMSService_Send(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	r, err := NewRegistry(testTemplates, []string{"aliyun", "tencent"})
+	require.NoError(t, err)
+	svc := smsmocks.NewMockService(ctrl)
+	svc.EXPECT().Send(gomock.Any(), "1001", []string{"012345"}, "152").Return(nil)
+	s := NewValidateSMSService(NewProviderSMSService(svc, r, "tencent"), r)
+	assert.NoError(t, s.Send(context.Background(), "code", []string{"012345"}, "152"))
+	// 参数不对的不会发给服务商
+	assert.ErrorIs(t, s.Send(context.Background(), "code", []string{"1"}, "152"), sms.ErrInvalidArgs)
+}
@@ -0,0 +1,50 @@
+package template
+
+import (
+	"context"
+	"go-basic/webook/internal/service/sms"
+)
+
+// ValidateSMSService 发送之前按照模板校验参数，不合法的请求不会到服务商那里
+type ValidateSMSService struct {
+	svc      sms.Service
+	registry *Registry
+}
+
+func NewValidateSMSService(svc sms.Service, registry *Registry) sms.Service {
+	return &ValidateSMSService{
+		svc:      svc,
+		registry: registry,
+	}
+}
+
+func (s *ValidateSMSService) Send(ctx context.Context, biz string, args []string, numbers ...string) error {
+	err := s.registry.Validate(biz, args)
+	if err != nil {
+		return err
+	}
+	return s.svc.Send(ctx, biz, args, numbers...)
+}
+
+// ProviderSMSService 包装一个服务商，把业务模板换成这个服务商的模板 ID
+type ProviderSMSService struct {
+	svc      sms.Service
+	registry *Registry
+	provider string
+}
+
+func NewProviderSMSService(svc sms.Service, registry *Registry, provider string) sms.Service {
+	return &ProviderSMSService{
+		svc:      svc,
+		registry: registry,
+		provider: provider,
+	}
+}
+
+func (s *ProviderSMSService) Send(ctx context.Context, biz string, args []string, numbers ...string) error {
+	tplId, err := s.registry.ProviderTemplate(biz, s.provider)
+	if err != nil {
+		return err
+	}
+	return s.svc.Send(ctx, tplId, args, numbers...)
+}
@@ -2,20 +2,55 @@ package tencent
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"go-basic/webook/internal/service/sms"
 
 	"github.com/ecodeclub/ekit"
 	"github.com/ecodeclub/ekit/slice"
-	sms "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/sms/v20210111"
+	tcerr "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/errors"
+	tcsms "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/sms/v20210111"
 )
 
+// codes 腾讯云的错误码，没有列出来的按照未知错误处理，会重试
+var codes = map[string]error{
+	"InternalError.Timeout":         sms.ErrRetryable,
+	"InternalError.SendAndRecvFail": sms.ErrRetryable,
+	"InternalError.OtherError":      sms.ErrRetryable,
+
+	"LimitExceeded.PhoneNumberThirtySecondLimit":     sms.ErrRateLimited,
+	"LimitExceeded.PhoneNumberOneHourLimit":          sms.ErrRateLimited,
+	"LimitExceeded.PhoneNumberDailyLimit":            sms.ErrRateLimited,
+	"LimitExceeded.PhoneNumberSameContentDailyLimit": sms.ErrRateLimited,
+	"LimitExceeded.DeliveryFrequencyLimit":           sms.ErrRateLimited,
+	"LimitExceeded.DailyLimit":                       sms.ErrRateLimited,
+
+	"InvalidParameterValue.IncorrectPhoneNumber":                 sms.ErrInvalidNumber,
+	"FailedOperation.ContainDomesticAndInternationalPhoneNumber": sms.ErrInvalidNumber,
+	"UnsupportedOperation.UnsupportedRegion":                     sms.ErrInvalidNumber,
+
+	"InvalidParameterValue.TemplateParameterFormatError": sms.ErrInvalidArgs,
+	"InvalidParameterValue.TemplateParameterLengthLimit": sms.ErrInvalidArgs,
+	"FailedOperation.TemplateIncorrectOrUnapproved":      sms.ErrInvalidArgs,
+	"FailedOperation.SignatureIncorrectOrUnapproved":     sms.ErrInvalidArgs,
+
+	"FailedOperation.PhoneNumberInBlacklist":      sms.ErrBlocked,
+	"FailedOperation.ContainSensitiveWord":        sms.ErrBlocked,
+	"FailedOperation.MarketingSendTimeConstraint": sms.ErrBlocked,
+
+	"FailedOperation.InsufficientBalanceInSmsPackage": sms.ErrAuthFailed,
+	"AuthFailure.SecretIdNotFound":                    sms.ErrAuthFailed,
+	"AuthFailure.SignatureFailure":                    sms.ErrAuthFailed,
+	"UnauthorizedOperation.SmsSdkAppIdVerifyFail":     sms.ErrAuthFailed,
+}
+
 type Service struct {
 	appId    *string
 	signName *string
-	client   *sms.Client
+	client   *tcsms.Client
 }
 
-func NewService(client *sms.Client, appId, signName string) *Service {
+func NewService(client *tcsms.Client, appId, signName string) *Service {
 	return &Service{
 		appId:    ekit.ToPtr[string](appId),
 		signName: ekit.ToPtr[string](signName),
@@ -24,7 +59,7 @@ func NewService(client *sms.Client, appId, signName string) *Service {
 }
 
 func (s *Service) Send(ctx context.Context, biz string, args []string, numbers ...string) error {
-	req := sms.NewSendSmsRequest()
+	req := tcsms.NewSendSmsRequest()
 	req.SmsSdkAppId = s.appId
 	req.SignName = s.signName
 	req.TemplateId = ekit.ToPtr[string](biz)
@@ -32,16 +67,39 @@ func (s *Service) Send(ctx context.Context, biz string, args []string, numbers .
 	req.TemplateParamSet = s.toStringPtrSlice(args)
 	resp, err := s.client.SendSms(req)
 	if err != nil {
+		var sdkErr *tcerr.TencentCloudSDKError
+		if errors.As(err, &sdkErr) {
+			return translate(sdkErr.GetCode(), sdkErr.GetMessage())
+		}
 		return err
 	}
-	for _, status := range resp.Response.SendStatusSet {
-		if status.Code == nil || *(status.Code) == "OK" {
-			return fmt.Errorf("发送短信失败: %s， %s", *status.Code, *status.Message)
+	for i, status := range resp.Response.SendStatusSet {
+		if status.Code != nil && *(status.Code) == "Ok" {
+			// 返回的号码带了国家码，和传进来的不一样，按照顺序对应
+			if status.SerialNo != nil && i < len(numbers) {
+				sms.SetMessageId(ctx, *status.SerialNo, numbers[i])
+			}
+			continue
+		}
+		var code, msg string
+		if status.Code != nil {
+			code = *status.Code
+		}
+		if status.Message != nil {
+			msg = *status.Message
 		}
+		return translate(code, msg)
 	}
 	return nil
 }
 
+func translate(code, msg string) error {
+	if e, ok := codes[code]; ok {
+		return fmt.Errorf("%w: %s %s", e, code, msg)
+	}
+	return fmt.Errorf("发送短信失败: %s， %s", code, msg)
+}
+
 func (s *Service) toStringPtrSlice(src []string) []*string {
 	return slice.Map[string, *string](src, func(idx int, src string) *string {
 		return &src
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"go-basic/webook/internal/domain"
+	"go-basic/webook/internal/repository"
+	"go-basic/webook/internal/service/sms"
+	"time"
+)
+
+var ErrInvalidSMSDeliveryRange = errors.New("统计的时间范围不合法")
+
+//go:generate mockgen -source=sms_delivery.go -package=svcmocks -destination=mocks/sms_delivery.mock.go SMSDeliveryService
+type SMSDeliveryService interface {
+	// Receive 处理一个服务商推送过来的回执，找不到记录的回执直接忽略
+	Receive(ctx context.Context, provider string, receipts []sms.Receipt) error
+	// Stats 按照服务商和模板统计 [start, end) 之间发送的短信的送达情况
+	Stats(ctx context.Context, start, end time.Time) ([]domain.SMSDeliveryStats, error)
+}
+
+type smsDeliveryService struct {
+	repo repository.SMSDeliveryRepository
+}
+
+func NewSMSDeliveryService(repo repository.SMSDeliveryRepository) SMSDeliveryService {
+	return &smsDeliveryService{
+		repo: repo,
+	}
+}
+
+func (s *smsDeliveryService) Receive(ctx context.Context, provider string, receipts []sms.Receipt) error {
+	for _, r := range receipts {
+		status := domain.SMSDeliveryStatusFailed
+		if r.Delivered {
+			status = domain.SMSDeliveryStatusDelivered
+		}
+		err := s.repo.MarkReceipt(ctx, domain.SMSDelivery{
+			Provider:    provider,
+			MessageId:   r.MessageId,
+			Number:      r.Number,
+			Status:      status,
+			ErrCode:     r.ErrCode,
+			ErrMsg:      r.ErrMsg,
+			ReceiptTime: r.Time,
+		})
+		// 服务商会重复推送同一条回执，也可能推送不是我们发的短信的回执
+		if err != nil && !errors.Is(err, repository.ErrSMSDeliveryNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *smsDeliveryService) Stats(ctx context.Context, start, end time.Time) ([]domain.SMSDeliveryStats, error) {
+	if !start.Before(end) {
+		return nil, ErrInvalidSMSDeliveryRange
+	}
+	return s.repo.Stats(ctx, start, end)
+}
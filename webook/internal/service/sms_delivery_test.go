@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"go-basic/webook/internal/domain"
+	"go-basic/webook/internal/repository"
+	repomocks "go-basic/webook/internal/repository/mocks"
+	"go-basic/webook/internal/service/sms"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSMSDeliveryService_Receive(t *testing.T) {
+	now := time.Now()
+	receipts := []sms.Receipt{
+		{MessageId: "m1", Number: "152", Delivered: true, Time: now},
+		{MessageId: "m1", Number: "153", ErrCode: "UNDELIV", ErrMsg: "空号", Time: now},
+	}
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.SMSDeliveryRepository
+
+		wantErr error
+	}{
+		{
+			name: "更新送达和失败",
+			mock: func(ctrl *gomock.Controller) repository.SMSDeliveryRepository {
+				repo := repomocks.NewMockSMSDeliveryRepository(ctrl)
+				repo.EXPECT().MarkReceipt(gomock.Any(), domain.SMSDelivery{
+					Provider: "gateway", MessageId: "m1", Number: "152",
+					Status: domain.SMSDeliveryStatusDelivered, ReceiptTime: now,
+				}).Return(nil)
+				repo.EXPECT().MarkReceipt(gomock.Any(), domain.SMSDelivery{
+					Provider: "gateway", MessageId: "m1", Number: "153",
+					Status: domain.SMSDeliveryStatusFailed, ErrCode: "UNDELIV", ErrMsg: "空号", ReceiptTime: now,
+				}).Return(nil)
+				return repo
+			},
+		},
+		{
+			name: "重复推送的回执直接忽略",
+			mock: func(ctrl *gomock.Controller) repository.SMSDeliveryRepository {
+				repo := repomocks.NewMockSMSDeliveryRepository(ctrl)
+				repo.EXPECT().MarkReceipt(gomock.Any(), gomock.Any()).
+					Return(repository.ErrSMSDeliveryNotFound).Times(2)
+				return repo
+			},
+		},
+		{
+			name: "数据库错误",
+			mock: func(ctrl *gomock.Controller) repository.SMSDeliveryRepository {
+				repo := repomocks.NewMockSMSDeliveryRepository(ctrl)
+				repo.EXPECT().MarkReceipt(gomock.Any(), gomock.Any()).Return(errors.New("db 错误"))
+				return repo
+			},
+			wantErr: errors.New("db 错误"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewSMSDeliveryService(tc.mock(ctrl))
+			err := svc.Receive(context.Background(), "gateway", receipts)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func TestSMSDeliveryService_Stats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := repomocks.NewMockSMSDeliveryRepository(ctrl)
+	svc := NewSMSDeliveryService(repo)
+	now := time.Now()
+	_, err := svc.Stats(context.Background(), now, now)
+	assert.Equal(t, ErrInvalidSMSDeliveryRange, err)
+
+	stats := []domain.SMSDeliveryStats{{Provider: "aliyun", Biz: "code", Total: 4, Delivered: 3, Failed: 1}}
+	repo.EXPECT().Stats(gomock.Any(), now.Add(-time.Hour), now).Return(stats, nil)
+	res, err := svc.Stats(context.Background(), now.Add(-time.Hour), now)
+	assert.NoError(t, err)
+	assert.Equal(t, stats, res)
+}
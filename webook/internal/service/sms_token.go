@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"go-basic/webook/internal/domain"
+	"go-basic/webook/internal/repository"
+	"go-basic/webook/internal/service/sms/template"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrSMSBizTokenNotFound = repository.ErrSMSBizTokenNotFound
+	ErrSMSBizTokenInvalid  = errors.New("短信令牌不合法")
+	ErrSMSBizTokenRevoked  = errors.New("短信令牌已经被吊销")
+	ErrSMSQuotaExceeded    = repository.ErrSMSQuotaExceeded
+	ErrSMSTemplateNotFound = errors.New("短信模板不存在")
+	ErrInvalidSMSQuota     = errors.New("短信额度不合法")
+)
+
+//go:generate mockgen -source=sms_token.go -package=svcmocks -destination=mocks/sms_token.mock.go SMSBizTokenService
+type SMSBizTokenService interface {
+	// Issue 返回签发的令牌，业务方发送短信的时候把令牌作为 biz 传进来
+	Issue(ctx context.Context, t domain.SMSBizToken) (domain.SMSBizToken, string, error)
+	// Revoke 吊销之后这个令牌马上不能再使用
+	Revoke(ctx context.Context, id int64) error
+	List(ctx context.Context, offset, limit int) ([]domain.SMSBizToken, error)
+	// Check 校验签名并且确认令牌没有被吊销
+	Check(ctx context.Context, token string) (domain.SMSBizToken, error)
+	// Consume 扣减令牌当天的额度，n 是这次发送的短信数量
+	Consume(ctx context.Context, t domain.SMSBizToken, n int) error
+}
+
+// SMSBizClaims ID 是令牌记录的 ID，Subject 是业务方
+type SMSBizClaims struct {
+	jwt.RegisteredClaims
+	Tpl string
+}
+
+type smsBizTokenService struct {
+	repo     repository.SMSBizTokenRepository
+	registry *template.Registry
+	key      []byte
+}
+
+func NewSMSBizTokenService(repo repository.SMSBizTokenRepository, registry *template.Registry,
+	key []byte) SMSBizTokenService {
+	return &smsBizTokenService{
+		repo:     repo,
+		registry: registry,
+		key:      key,
+	}
+}
+
+func (s *smsBizTokenService) Issue(ctx context.Context, t domain.SMSBizToken) (domain.SMSBizToken, string, error) {
+	if !s.registry.Exist(t.Tpl) {
+		return domain.SMSBizToken{}, "", ErrSMSTemplateNotFound
+	}
+	if t.DailyQuota < 0 {
+		return domain.SMSBizToken{}, "", ErrInvalidSMSQuota
+	}
+	now := time.Now()
+	t.Status = domain.SMSBizTokenStatusActive
+	t.Ctime = now
+	t.Utime = now
+	id, err := s.repo.Create(ctx, t)
+	if err != nil {
+		return domain.SMSBizToken{}, "", err
+	}
+	t.Id = id
+	// 令牌不过期，不用的时候吊销
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, SMSBizClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:      strconv.FormatInt(id, 10),
+			Subject: t.Biz,
+		},
+		Tpl: t.Tpl,
+	}).SignedString(s.key)
+	return t, token, err
+}
+
+func (s *smsBizTokenService) Revoke(ctx context.Context, id int64) error {
+	return s.repo.Revoke(ctx, id)
+}
+
+func (s *smsBizTokenService) List(ctx context.Context, offset, limit int) ([]domain.SMSBizToken, error) {
+	return s.repo.List(ctx, offset, limit)
+}
+
+func (s *smsBizTokenService) Check(ctx context.Context, token string) (domain.SMSBizToken, error) {
+	var claims SMSBizClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(token *jwt.Token) (interface{}, error) {
+		return s.key, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return domain.SMSBizToken{}, ErrSMSBizTokenInvalid
+	}
+	id, err := strconv.ParseInt(claims.ID, 10, 64)
+	if err != nil {
+		return domain.SMSBizToken{}, ErrSMSBizTokenInvalid
+	}
+	t, err := s.repo.FindById(ctx, id)
+	if err == repository.ErrSMSBizTokenNotFound {
+		return domain.SMSBizToken{}, ErrSMSBizTokenInvalid
+	}
+	if err != nil {
+		return domain.SMSBizToken{}, err
+	}
+	if t.Status != domain.SMSBizTokenStatusActive {
+		return domain.SMSBizToken{}, ErrSMSBizTokenRevoked
+	}
+	return t, nil
+}
+
+func (s *smsBizTokenService) Consume(ctx context.Context, t domain.SMSBizToken, n int) error {
+	if t.DailyQuota == 0 {
+		return nil
+	}
+	return s.repo.IncrQuota(ctx, t.Id, n, t.DailyQuota)
+}
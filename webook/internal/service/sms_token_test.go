@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"go-basic/webook/internal/domain"
+	"go-basic/webook/internal/repository"
+	repomocks "go-basic/webook/internal/repository/mocks"
+	"go-basic/webook/internal/service/sms/template"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSMSTemplateRegistry(t *testing.T) *template.Registry {
+	r, err := template.NewRegistry([]template.Template{
+		{
+			Biz:       "code",
+			Params:    []template.Param{{Name: "code", Pattern: `^\d{6}$`}},
+			Providers: map[string]string{"memory": "123456"},
+		},
+	}, []string{"memory"})
+	require.NoError(t, err)
+	return r
+}
+
+func TestSMSBizTokenService_Issue(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.SMSBizTokenRepository
+		t    domain.SMSBizToken
+
+		wantId  int64
+		wantErr error
+	}{
+		{
+			name: "签发成功",
+			mock: func(ctrl *gomock.Controller) repository.SMSBizTokenRepository {
+				repo := repomocks.NewMockSMSBizTokenRepository(ctrl)
+				repo.EXPECT().Create(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, tk domain.SMSBizToken) (int64, error) {
+						assert.Equal(t, domain.SMSBizTokenStatusActive, tk.Status)
+						return 1, nil
+					})
+				return repo
+			},
+			t:      domain.SMSBizToken{Biz: "order", Tpl: "code", DailyQuota: 100},
+			wantId: 1,
+		},
+		{
+			name: "模板不存在",
+			mock: func(ctrl *gomock.Controller) repository.SMSBizTokenRepository {
+				return repomocks.NewMockSMSBizTokenRepository(ctrl)
+			},
+			t:       domain.SMSBizToken{Biz: "order", Tpl: "notice"},
+			wantErr: ErrSMSTemplateNotFound,
+		},
+		{
+			name: "额度小于 0",
+			mock: func(ctrl *gomock.Controller) repository.SMSBizTokenRepository {
+				return repomocks.NewMockSMSBizTokenRepository(ctrl)
+			},
+			t:       domain.SMSBizToken{Biz: "order", Tpl: "code", DailyQuota: -1},
+			wantErr: ErrInvalidSMSQuota,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewSMSBizTokenService(tc.mock(ctrl), newTestSMSTemplateRegistry(t), []byte("key"))
+			res, token, err := svc.Issue(context.Background(), tc.t)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantId, res.Id)
+			assert.NotEmpty(t, token)
+		})
+	}
+}
+
+func TestSMSBizTokenService_Check(t *testing.T) {
+	active := domain.SMSBizToken{Id: 1, Biz: "order", Tpl: "code", Status: domain.SMSBizTokenStatusActive}
+	testCases := []struct {
+		name  string
+		mock  func(ctrl *gomock.Controller) repository.SMSBizTokenRepository
+		token func(svc SMSBizTokenService) string
+
+		wantToken domain.SMSBizToken
+		wantErr   error
+	}{
+		{
+			name: "令牌有效",
+			mock: func(ctrl *gomock.Controller) repository.SMSBizTokenRepository {
+				repo := repomocks.NewMockSMSBizTokenRepository(ctrl)
+				repo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(int64(1), nil)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).Return(active, nil)
+				return repo
+			},
+			token:     issueTestSMSBizToken,
+			wantToken: active,
+		},
+		{
+			name: "令牌已经被吊销",
+			mock: func(ctrl *gomock.Controller) repository.SMSBizTokenRepository {
+				repo := repomocks.NewMockSMSBizTokenRepository(ctrl)
+				repo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(int64(1), nil)
+				revoked := active
+				revoked.Status = domain.SMSBizTokenStatusRevoked
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).Return(revoked, nil)
+				return repo
+			},
+			token:   issueTestSMSBizToken,
+			wantErr: ErrSMSBizTokenRevoked,
+		},
+		{
+			name: "令牌记录不存在",
+			mock: func(ctrl *gomock.Controller) repository.SMSBizTokenRepository {
+				repo := repomocks.NewMockSMSBizTokenRepository(ctrl)
+				repo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(int64(1), nil)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).
+					Return(domain.SMSBizToken{}, repository.ErrSMSBizTokenNotFound)
+				return repo
+			},
+			token:   issueTestSMSBizToken,
+			wantErr: ErrSMSBizTokenInvalid,
+		},
+		{
+			name: "签名不对",
+			mock: func(ctrl *gomock.Controller) repository.SMSBizTokenRepository {
+				return repomocks.NewMockSMSBizTokenRepository(ctrl)
+			},
+			token: func(svc SMSBizTokenService) string {
+				token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, SMSBizClaims{
+					RegisteredClaims: jwt.RegisteredClaims{ID: "1", Subject: "order"},
+					Tpl:              "code",
+				}).SignedString([]byte("other"))
+				return token
+			},
+			wantErr: ErrSMSBizTokenInvalid,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewSMSBizTokenService(tc.mock(ctrl), newTestSMSTemplateRegistry(t), []byte("key"))
+			res, err := svc.Check(context.Background(), tc.token(svc))
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantToken, res)
+		})
+	}
+}
+
+func issueTestSMSBizToken(svc SMSBizTokenService) string {
+	_, token, _ := svc.Issue(context.Background(), domain.SMSBizToken{Biz: "order", Tpl: "code"})
+	return token
+}
+
+func TestSMSBizTokenService_Consume(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.SMSBizTokenRepository
+		t    domain.SMSBizToken
+
+		wantErr error
+	}{
+		{
+			name: "不限额度",
+			mock: func(ctrl *gomock.Controller) repository.SMSBizTokenRepository {
+				return repomocks.NewMockSMSBizTokenRepository(ctrl)
+			},
+			t: domain.SMSBizToken{Biz: "order"},
+		},
+		{
+			name: "额度用完",
+			mock: func(ctrl *gomock.Controller) repository.SMSBizTokenRepository {
+				repo := repomocks.NewMockSMSBizTokenRepository(ctrl)
+				repo.EXPECT().IncrQuota(gomock.Any(), int64(1), 2, 100).Return(repository.ErrSMSQuotaExceeded)
+				return repo
+			},
+			t:       domain.SMSBizToken{Id: 1, Biz: "order", DailyQuota: 100},
+			wantErr: ErrSMSQuotaExceeded,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewSMSBizTokenService(tc.mock(ctrl), newTestSMSTemplateRegistry(t), []byte("key"))
+			assert.Equal(t, tc.wantErr, svc.Consume(context.Background(), tc.t, 2))
+		})
+	}
+}
@@ -22,7 +22,12 @@ type UserService interface {
 	UpdateNonSensitiveInfo(ctx context.Context, user domain.User) error
 	Profile(ctx context.Context, id int64) (domain.User, error)
 	FindOrCreate(ctx context.Context, phone string) (domain.User, error)
+	// FindOrCreateByEmail 邮箱验证码登录的时候用，没有注册过的邮箱直接创建用户，不设置密码
+	FindOrCreateByEmail(ctx context.Context, email string) (domain.User, error)
 	FindOrCreateByWechat(ctx context.Context, info domain.WechatInfo) (domain.User, error)
+	// ProfileByIds 批量查询用户信息，key 是用户 id
+	ProfileByIds(ctx context.Context, ids []int64) (map[int64]domain.User, error)
+	UpdatePrivacy(ctx context.Context, id int64, hideLikes bool) error
 }
 
 type userService struct {
@@ -74,6 +79,25 @@ func (svc *userService) Profile(ctx context.Context, id int64) (domain.User, err
 	return svc.repo.FindById(ctx, id)
 }
 
+func (svc *userService) ProfileByIds(ctx context.Context, ids []int64) (map[int64]domain.User, error) {
+	if len(ids) == 0 {
+		return map[int64]domain.User{}, nil
+	}
+	users, err := svc.repo.FindByIds(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[int64]domain.User, len(users))
+	for _, u := range users {
+		res[u.Id] = u
+	}
+	return res, nil
+}
+
+func (svc *userService) UpdatePrivacy(ctx context.Context, id int64, hideLikes bool) error {
+	return svc.repo.UpdatePrivacy(ctx, id, hideLikes)
+}
+
 func (svc *userService) FindOrCreate(ctx context.Context, phone string) (domain.User, error) {
 	// 先找到用户
 	u, err := svc.repo.FindByPhone(ctx, phone)
@@ -92,6 +116,22 @@ func (svc *userService) FindOrCreate(ctx context.Context, phone string) (domain.
 	return svc.repo.FindByPhone(ctx, phone)
 }
 
+func (svc *userService) FindOrCreateByEmail(ctx context.Context, email string) (domain.User, error) {
+	u, err := svc.repo.FindByEmail(ctx, email)
+	if err != repository.ErrUserNotFound {
+		return u, err
+	}
+	svc.l.Info("用户未注册", logger.String("email", email))
+	err = svc.repo.Create(ctx, domain.User{
+		Email: email,
+	})
+	// 并发登录的时候另一个请求已经创建了
+	if err != nil && err != repository.ErrUserDuplicateEmail {
+		return u, err
+	}
+	return svc.repo.FindByEmail(ctx, email)
+}
+
 func (svc *userService) FindOrCreateByWechat(ctx context.Context, info domain.WechatInfo) (domain.User, error) {
 	// 先找到用户
 	u, err := svc.repo.FindByWechat(ctx, info.OpenID)
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"go-basic/webook/internal/domain"
+	"go-basic/webook/internal/repository"
+	"go-basic/webook/pkg/stringx"
+	"time"
+)
+
+var ErrWorkflowRunNotFound = repository.ErrWorkflowRunNotFound
+
+//go:generate mockgen -source=workflow.go -package=svcmocks -destination=mocks/workflow.mock.go WorkflowService
+type WorkflowService interface {
+	// Start 恢复这个工作流还没有结束的执行，例如上一个实例执行到一半崩溃了；没有的话按照 wf 创建一个新的
+	Start(ctx context.Context, workflowId int64, wf domain.Workflow) (domain.WorkflowRun, error)
+	UpdateNode(ctx context.Context, n domain.WorkflowNodeRun) error
+	// Finish 所有节点都成功才算成功
+	Finish(ctx context.Context, r domain.WorkflowRun) (domain.WorkflowRun, error)
+	List(ctx context.Context, workflowId int64, offset, limit int) ([]domain.WorkflowRun, error)
+	// Retry 重新执行失败的节点和被跳过的下游，成功的节点不会再执行
+	Retry(ctx context.Context, runId int64) error
+}
+
+type workflowService struct {
+	repo   repository.WorkflowRunRepository
+	jobSvc JobService
+}
+
+func NewWorkflowService(repo repository.WorkflowRunRepository, jobSvc JobService) WorkflowService {
+	return &workflowService{
+		repo:   repo,
+		jobSvc: jobSvc,
+	}
+}
+
+func (s *workflowService) Start(ctx context.Context, workflowId int64, wf domain.Workflow) (domain.WorkflowRun, error) {
+	run, err := s.repo.FindUnfinished(ctx, workflowId)
+	if err == nil {
+		return run, nil
+	}
+	if err != repository.ErrWorkflowRunNotFound {
+		return domain.WorkflowRun{}, err
+	}
+	run = domain.WorkflowRun{
+		WorkflowId: workflowId,
+		Status:     domain.WorkflowRunStatusRunning,
+		Nodes:      make([]domain.WorkflowNodeRun, 0, len(wf.Nodes)),
+		StartTime:  time.Now(),
+	}
+	for _, n := range wf.Nodes {
+		run.Nodes = append(run.Nodes, domain.WorkflowNodeRun{
+			JobId:     n.JobId,
+			Upstreams: n.Upstreams,
+			Status:    domain.WorkflowNodeStatusWaiting,
+		})
+	}
+	return s.repo.Create(ctx, run)
+}
+
+func (s *workflowService) UpdateNode(ctx context.Context, n domain.WorkflowNodeRun) error {
+	n.ErrMsg = stringx.Truncate(n.ErrMsg, maxJobRunErrMsgLen)
+	return s.repo.UpdateNode(ctx, n)
+}
+
+func (s *workflowService) Finish(ctx context.Context, r domain.WorkflowRun) (domain.WorkflowRun, error) {
+	r.Status = domain.WorkflowRunStatusSuccess
+	for _, n := range r.Nodes {
+		if n.Status != domain.WorkflowNodeStatusSuccess {
+			r.Status = domain.WorkflowRunStatusFailed
+			break
+		}
+	}
+	r.EndTime = time.Now()
+	return r, s.repo.Finish(ctx, r)
+}
+
+func (s *workflowService) List(ctx context.Context, workflowId int64, offset, limit int) ([]domain.WorkflowRun, error) {
+	return s.repo.List(ctx, workflowId, offset, limit)
+}
+
+func (s *workflowService) Retry(ctx context.Context, runId int64) error {
+	run, err := s.repo.FindById(ctx, runId)
+	if err != nil {
+		return err
+	}
+	err = s.repo.Retry(ctx, runId)
+	if err != nil {
+		return err
+	}
+	err = s.jobSvc.Trigger(ctx, run.WorkflowId)
+	if err == ErrJobRunning {
+		// 正在执行的那一次结束之后，下一次调度会接着执行这一次
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"go-basic/webook/internal/domain"
+	"go-basic/webook/internal/repository"
+	repomocks "go-basic/webook/internal/repository/mocks"
+	"go-basic/webook/pkg/logger"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkflowService_Retry(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (repository.WorkflowRunRepository, repository.JobRepository)
+
+		wantErr error
+	}{
+		{
+			name: "重新执行并且立刻触发",
+			mock: func(ctrl *gomock.Controller) (repository.WorkflowRunRepository, repository.JobRepository) {
+				repo := repomocks.NewMockWorkflowRunRepository(ctrl)
+				jobRepo := repomocks.NewMockJobRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(2)).
+					Return(domain.WorkflowRun{Id: 2, WorkflowId: 1, Status: domain.WorkflowRunStatusFailed}, nil)
+				repo.EXPECT().Retry(gomock.Any(), int64(2)).Return(nil)
+				jobRepo.EXPECT().FindById(gomock.Any(), int64(1)).
+					Return(domain.Job{Id: 1, Status: domain.JobStatusWaiting}, nil)
+				jobRepo.EXPECT().Trigger(gomock.Any(), int64(1)).Return(nil)
+				return repo, jobRepo
+			},
+		},
+		{
+			name: "工作流正在执行，等下一次调度",
+			mock: func(ctrl *gomock.Controller) (repository.WorkflowRunRepository, repository.JobRepository) {
+				repo := repomocks.NewMockWorkflowRunRepository(ctrl)
+				jobRepo := repomocks.NewMockJobRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(2)).
+					Return(domain.WorkflowRun{Id: 2, WorkflowId: 1, Status: domain.WorkflowRunStatusFailed}, nil)
+				repo.EXPECT().Retry(gomock.Any(), int64(2)).Return(nil)
+				jobRepo.EXPECT().FindById(gomock.Any(), int64(1)).
+					Return(domain.Job{Id: 1, Status: domain.JobStatusRunning}, nil)
+				return repo, jobRepo
+			},
+		},
+		{
+			name: "执行没有失败",
+			mock: func(ctrl *gomock.Controller) (repository.WorkflowRunRepository, repository.JobRepository) {
+				repo := repomocks.NewMockWorkflowRunRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(2)).
+					Return(domain.WorkflowRun{Id: 2, WorkflowId: 1, Status: domain.WorkflowRunStatusSuccess}, nil)
+				repo.EXPECT().Retry(gomock.Any(), int64(2)).Return(repository.ErrWorkflowRunNotFound)
+				return repo, repomocks.NewMockJobRepository(ctrl)
+			},
+			wantErr: ErrWorkflowRunNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			repo, jobRepo := tc.mock(ctrl)
+			svc := NewWorkflowService(repo, NewCronJobService(jobRepo, logger.NewNopLogger()))
+			err := svc.Retry(context.Background(), 2)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
@@ -51,10 +51,7 @@ func (h *ArticleHandler) RegisterRoutes(server *gin.Engine) {
 func (h *ArticleHandler) Collect(ctx *gin.Context, req CollectReq, uc ijwt.UserClaims) (ginx.Result, error) {
 	err := h.intrSvc.Collect(ctx, h.biz, req.Id, req.Cid, uc.Uid)
 	if err != nil {
-		return ginx.Result{
-			Code: 5,
-			Msg:  "系统错误",
-		}, err
+		return interactiveErrResult(err)
 	}
 	return ginx.Result{
 		Msg: "OK",
@@ -70,10 +67,7 @@ func (h *ArticleHandler) Like(ctx *gin.Context, req LikeReq, uc ijwt.UserClaims)
 		err = h.intrSvc.CancelLike(ctx, h.biz, req.Id, uc.Uid)
 	}
 	if err != nil {
-		return ginx.Result{
-			Code: 5,
-			Msg:  "系统错误",
-		}, err
+		return interactiveErrResult(err)
 	}
 	return ginx.Result{
 		Msg: "OK",
@@ -128,6 +122,7 @@ func (h *ArticleHandler) PubDetail(ctx *gin.Context, uc ijwt.UserClaims) (ginx.R
 			Title:      art.Title,
 			Status:     art.Status.ToUint8(),
 			Author:     art.Author.Name,
+			Category:   art.Category,
 			Content:    art.Content,
 			Ctime:      art.Ctime.Format(time.DateTime),
 			Utime:      art.Utime.Format(time.DateTime),
@@ -165,12 +160,13 @@ func (h *ArticleHandler) Detail(ctx *gin.Context, uc ijwt.UserClaims) (ginx.Resu
 	}
 	return ginx.Result{
 		Data: ArticleVO{
-			Id:      art.Id,
-			Title:   art.Title,
-			Status:  art.Status.ToUint8(),
-			Content: art.Content,
-			Ctime:   art.Ctime.Format(time.DateTime),
-			Utime:   art.Utime.Format(time.DateTime),
+			Id:       art.Id,
+			Title:    art.Title,
+			Status:   art.Status.ToUint8(),
+			Content:  art.Content,
+			Category: art.Category,
+			Ctime:    art.Ctime.Format(time.DateTime),
+			Utime:    art.Utime.Format(time.DateTime),
 		},
 	}, nil
 }
@@ -85,7 +85,7 @@ func TestArticleHandler_Publish(t *testing.T) {
 					Uid: 123,
 				})
 			})
-			h := NewArticleHandler(tc.mock(ctrl), &logger.NopLogger{})
+			h := NewArticleHandler(tc.mock(ctrl), &logger.NopLogger{}, nil)
 			h.RegisterRoutes(server)
 
 			req, err := http.NewRequest(http.MethodPost, "/articles/publish", bytes.NewBuffer([]byte(tc.reqBody)))
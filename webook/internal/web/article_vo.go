@@ -9,6 +9,7 @@ type ArticleVO struct {
 	Abstract string
 	Content  string
 	Author   string
+	Category string
 	Status   uint8
 	// 准确的计数
 	ReadCnt    int64
@@ -31,9 +32,10 @@ type DetailReq struct {
 }
 
 type ArticleReq struct {
-	Id      int64  `json:"id"`
-	Title   string `json:"title"`
-	Content string `json:"content"`
+	Id       int64  `json:"id"`
+	Title    string `json:"title"`
+	Content  string `json:"content"`
+	Category string `json:"category"`
 }
 
 // 点赞和取消点赞一个请求
@@ -50,9 +52,10 @@ type CollectReq struct {
 
 func (req ArticleReq) toDomain(uid int64) domain.Article {
 	return domain.Article{
-		Id:      req.Id,
-		Title:   req.Title,
-		Content: req.Content,
+		Id:       req.Id,
+		Title:    req.Title,
+		Content:  req.Content,
+		Category: req.Category,
 		Author: domain.Author{
 			Id: uid,
 		},
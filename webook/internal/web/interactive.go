@@ -0,0 +1,283 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"go-basic/webook/internal/domain"
+	"go-basic/webook/internal/service"
+	ijwt "go-basic/webook/internal/web/jwt"
+	"go-basic/webook/pkg/ginx"
+	"go-basic/webook/pkg/logger"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ecodeclub/ekit/slice"
+	"github.com/gin-gonic/gin"
+)
+
+var _ handler = (*InteractiveHandler)(nil)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// InteractiveHandler 和具体业务无关的互动接口，例如谁点赞了，点赞了什么
+type InteractiveHandler struct {
+	intrSvc service.InteractiveService
+	userSvc service.UserService
+	artSvc  service.ArticleService
+	l       logger.Logger
+}
+
+func NewInteractiveHandler(intrSvc service.InteractiveService, userSvc service.UserService,
+	artSvc service.ArticleService, l logger.Logger) *InteractiveHandler {
+	return &InteractiveHandler{
+		intrSvc: intrSvc,
+		userSvc: userSvc,
+		artSvc:  artSvc,
+		l:       l,
+	}
+}
+
+func (h *InteractiveHandler) RegisterRoutes(server *gin.Engine) {
+	g := server.Group("/interactive")
+	g.POST("/likers", ginx.WrapBodyAndToken[LikersReq, ijwt.UserClaims](h.Likers))
+	g.POST("/liked", ginx.WrapBodyAndToken[LikedReq, ijwt.UserClaims](h.Liked))
+	// 通用的点赞和收藏接口，业务注册之后就可以直接使用
+	g.POST("/like", ginx.WrapBodyAndToken[InteractiveLikeReq, ijwt.UserClaims](h.Like))
+	g.POST("/collect", ginx.WrapBodyAndToken[InteractiveCollectReq, ijwt.UserClaims](h.Collect))
+}
+
+// Like 点赞和取消点赞都是一个接口，通过参数区分
+func (h *InteractiveHandler) Like(ctx *gin.Context, req InteractiveLikeReq, uc ijwt.UserClaims) (ginx.Result, error) {
+	if req.Biz == "" || req.BizId <= 0 {
+		return ginx.Result{
+			Code: 4,
+			Msg:  "输入有误",
+		}, nil
+	}
+	var err error
+	if req.Like {
+		err = h.intrSvc.Like(ctx, req.Biz, req.BizId, uc.Uid)
+	} else {
+		err = h.intrSvc.CancelLike(ctx, req.Biz, req.BizId, uc.Uid)
+	}
+	if err != nil {
+		return interactiveErrResult(err)
+	}
+	return ginx.Result{
+		Msg: "OK",
+	}, nil
+}
+
+func (h *InteractiveHandler) Collect(ctx *gin.Context, req InteractiveCollectReq, uc ijwt.UserClaims) (ginx.Result, error) {
+	if req.Biz == "" || req.BizId <= 0 {
+		return ginx.Result{
+			Code: 4,
+			Msg:  "输入有误",
+		}, nil
+	}
+	err := h.intrSvc.Collect(ctx, req.Biz, req.BizId, req.Cid, uc.Uid)
+	if err != nil {
+		return interactiveErrResult(err)
+	}
+	return ginx.Result{
+		Msg: "OK",
+	}, nil
+}
+
+// Likers 谁点赞了这个资源，不公开点赞记录的用户会显示为匿名用户
+func (h *InteractiveHandler) Likers(ctx *gin.Context, req LikersReq, uc ijwt.UserClaims) (ginx.Result, error) {
+	if req.Biz == "" || req.BizId <= 0 {
+		return ginx.Result{
+			Code: 4,
+			Msg:  "输入有误",
+		}, nil
+	}
+	cursor, err := h.parseLikeCursor(req.Cursor)
+	if err != nil {
+		return ginx.Result{
+			Code: 4,
+			Msg:  "输入有误",
+		}, nil
+	}
+	limit := h.pageLimit(req.Limit)
+	likes, err := h.intrSvc.Likers(ctx, req.Biz, req.BizId, cursor, limit)
+	if err != nil {
+		return interactiveErrResult(err)
+	}
+	uids := slice.Map(likes, func(idx int, src domain.UserLike) int64 {
+		return src.Uid
+	})
+	users, err := h.userSvc.ProfileByIds(ctx, uids)
+	if err != nil {
+		return ginx.Result{
+			Code: 5,
+			Msg:  "系统错误",
+		}, err
+	}
+	likers := slice.Map(likes, func(idx int, src domain.UserLike) LikerVO {
+		u := users[src.Uid]
+		// 自己的点赞记录总是可见的
+		if u.HideLikes && src.Uid != uc.Uid {
+			return LikerVO{
+				Nickname: "匿名用户",
+				LikedAt:  src.Utime.Format(time.DateTime),
+			}
+		}
+		return LikerVO{
+			Uid:      src.Uid,
+			Nickname: u.Nickname,
+			LikedAt:  src.Utime.Format(time.DateTime),
+		}
+	})
+	return ginx.Result{
+		Data: LikersVO{
+			Likers: likers,
+			Cursor: h.nextLikeCursor(likes, limit),
+		},
+	}, nil
+}
+
+// parseLikeCursor 游标是 毫秒时间戳_id，空字符串表示第一页
+func (h *InteractiveHandler) parseLikeCursor(cursor string) (domain.LikeCursor, error) {
+	if cursor == "" {
+		return domain.LikeCursor{}, nil
+	}
+	utime, id, ok := strings.Cut(cursor, "_")
+	if !ok {
+		return domain.LikeCursor{}, errors.New("游标格式不对")
+	}
+	ms, err := strconv.ParseInt(utime, 10, 64)
+	if err != nil {
+		return domain.LikeCursor{}, err
+	}
+	res := domain.LikeCursor{Utime: time.UnixMilli(ms)}
+	res.Id, err = strconv.ParseInt(id, 10, 64)
+	return res, err
+}
+
+// nextLikeCursor 下一页的游标，为空表示没有更多数据了
+func (h *InteractiveHandler) nextLikeCursor(likes []domain.UserLike, limit int) string {
+	if len(likes) < limit {
+		return ""
+	}
+	last := likes[len(likes)-1]
+	return fmt.Sprintf("%d_%d", last.Utime.UnixMilli(), last.Id)
+}
+
+// Liked 用户点赞过的资源，Uid 为 0 表示查询自己
+func (h *InteractiveHandler) Liked(ctx *gin.Context, req LikedReq, uc ijwt.UserClaims) (ginx.Result, error) {
+	uid := req.Uid
+	if uid == 0 {
+		uid = uc.Uid
+	}
+	if uid != uc.Uid {
+		u, err := h.userSvc.Profile(ctx, uid)
+		if err != nil {
+			return ginx.Result{
+				Code: 5,
+				Msg:  "系统错误",
+			}, err
+		}
+		if u.HideLikes {
+			return ginx.Result{
+				Code: 4,
+				Msg:  "对方没有公开点赞记录",
+			}, nil
+		}
+	}
+
+	limit := h.pageLimit(req.Limit)
+	likes, err := h.intrSvc.LikedBy(ctx, uid, req.Cursor, limit)
+	if err != nil {
+		return ginx.Result{
+			Code: 5,
+			Msg:  "系统错误",
+		}, err
+	}
+	// 目前只有文章需要补充摘要信息
+	var artIds []int64
+	for _, like := range likes {
+		if like.Biz == "article" {
+			artIds = append(artIds, like.BizId)
+		}
+	}
+	arts, err := h.artSvc.ListPubByIds(ctx, artIds)
+	if err != nil {
+		return ginx.Result{
+			Code: 5,
+			Msg:  "系统错误",
+		}, err
+	}
+	artMap := make(map[int64]domain.Article, len(arts))
+	for _, art := range arts {
+		artMap[art.Id] = art
+	}
+	items := slice.Map(likes, func(idx int, src domain.UserLike) LikedItemVO {
+		item := LikedItemVO{
+			Biz:     src.Biz,
+			BizId:   src.BizId,
+			LikedAt: src.Utime.Format(time.DateTime),
+		}
+		if art, ok := artMap[src.BizId]; ok && src.Biz == "article" {
+			item.Article = &ArticleVO{
+				Id:       art.Id,
+				Title:    art.Title,
+				Abstract: art.Abstract(),
+				Category: art.Category,
+				Status:   art.Status.ToUint8(),
+				Ctime:    art.Ctime.Format(time.DateTime),
+				Utime:    art.Utime.Format(time.DateTime),
+			}
+		}
+		return item
+	})
+	return ginx.Result{
+		Data: LikedVO{
+			Items:  items,
+			Cursor: h.nextCursor(likes, limit),
+		},
+	}, nil
+}
+
+func (h *InteractiveHandler) pageLimit(limit int) int {
+	if limit <= 0 {
+		return defaultPageLimit
+	}
+	if limit > maxPageLimit {
+		return maxPageLimit
+	}
+	return limit
+}
+
+// nextCursor 下一页的游标，为 0 表示没有更多数据了
+func (h *InteractiveHandler) nextCursor(likes []domain.UserLike, limit int) int64 {
+	if len(likes) < limit {
+		return 0
+	}
+	return likes[len(likes)-1].Id
+}
+
+// interactiveErrResult 业务未注册、不支持该互动或者资源不存在都是前端输入的问题
+func interactiveErrResult(err error) (ginx.Result, error) {
+	switch {
+	case errors.Is(err, service.ErrUnknownBiz), errors.Is(err, service.ErrInteractiveNotSupported):
+		return ginx.Result{
+			Code: 4,
+			Msg:  "输入有误",
+		}, nil
+	case errors.Is(err, service.ErrBizNotFound):
+		return ginx.Result{
+			Code: 4,
+			Msg:  "资源不存在",
+		}, nil
+	default:
+		return ginx.Result{
+			Code: 5,
+			Msg:  "系统错误",
+		}, err
+	}
+}
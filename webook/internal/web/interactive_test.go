@@ -0,0 +1,206 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go-basic/webook/internal/domain"
+	"go-basic/webook/internal/service"
+	svcmocks "go-basic/webook/internal/service/mocks"
+	ijwt "go-basic/webook/internal/web/jwt"
+	"go-basic/webook/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInteractiveHandler_Likers(t *testing.T) {
+	now := time.UnixMilli(time.Now().UnixMilli())
+	testCases := []struct {
+		name     string
+		mock     func(ctrl *gomock.Controller) (service.InteractiveService, service.UserService)
+		reqBody  string
+		wantBody Result
+	}{
+		{
+			name: "隐藏点赞的用户显示为匿名",
+			mock: func(ctrl *gomock.Controller) (service.InteractiveService, service.UserService) {
+				intrSvc := svcmocks.NewMockInteractiveService(ctrl)
+				intrSvc.EXPECT().Likers(gomock.Any(), "article", int64(1), domain.LikeCursor{}, 2).
+					Return([]domain.UserLike{
+						{Id: 9, Uid: 11, Utime: now},
+						{Id: 8, Uid: 12, Utime: now},
+					}, nil)
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().ProfileByIds(gomock.Any(), []int64{11, 12}).
+					Return(map[int64]domain.User{
+						11: {Id: 11, Nickname: "Tom"},
+						12: {Id: 12, Nickname: "Jerry", HideLikes: true},
+					}, nil)
+				return intrSvc, userSvc
+			},
+			reqBody: `{"biz": "article", "bizId": 1, "limit": 2}`,
+			wantBody: Result{
+				Data: map[string]any{
+					"Likers": []any{
+						map[string]any{"Uid": float64(11), "Nickname": "Tom", "LikedAt": now.Format(time.DateTime)},
+						map[string]any{"Uid": float64(0), "Nickname": "匿名用户", "LikedAt": now.Format(time.DateTime)},
+					},
+					"Cursor": fmt.Sprintf("%d_8", now.UnixMilli()),
+				},
+			},
+		},
+		{
+			name: "最后一页",
+			mock: func(ctrl *gomock.Controller) (service.InteractiveService, service.UserService) {
+				intrSvc := svcmocks.NewMockInteractiveService(ctrl)
+				intrSvc.EXPECT().Likers(gomock.Any(), "article", int64(1), domain.LikeCursor{Utime: now, Id: 8}, 2).
+					Return([]domain.UserLike{
+						{Id: 3, Uid: 123, Utime: now},
+					}, nil)
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().ProfileByIds(gomock.Any(), []int64{123}).
+					Return(map[int64]domain.User{
+						// 自己总是可以看到自己
+						123: {Id: 123, Nickname: "me", HideLikes: true},
+					}, nil)
+				return intrSvc, userSvc
+			},
+			reqBody: fmt.Sprintf(`{"biz": "article", "bizId": 1, "cursor": "%d_8", "limit": 2}`, now.UnixMilli()),
+			wantBody: Result{
+				Data: map[string]any{
+					"Likers": []any{
+						map[string]any{"Uid": float64(123), "Nickname": "me", "LikedAt": now.Format(time.DateTime)},
+					},
+					"Cursor": "",
+				},
+			},
+		},
+		{
+			name: "参数错误",
+			mock: func(ctrl *gomock.Controller) (service.InteractiveService, service.UserService) {
+				return svcmocks.NewMockInteractiveService(ctrl), svcmocks.NewMockUserService(ctrl)
+			},
+			reqBody:  `{"biz": "article"}`,
+			wantBody: Result{Code: 4, Msg: "输入有误"},
+		},
+		{
+			name: "游标格式不对",
+			mock: func(ctrl *gomock.Controller) (service.InteractiveService, service.UserService) {
+				return svcmocks.NewMockInteractiveService(ctrl), svcmocks.NewMockUserService(ctrl)
+			},
+			reqBody:  `{"biz": "article", "bizId": 1, "cursor": "8"}`,
+			wantBody: Result{Code: 4, Msg: "输入有误"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			intrSvc, userSvc := tc.mock(ctrl)
+			server := newInteractiveTestServer(intrSvc, userSvc, svcmocks.NewMockArticleService(ctrl))
+			webRes := doInteractiveRequest(t, server, "/interactive/likers", tc.reqBody)
+			assert.Equal(t, tc.wantBody, webRes)
+		})
+	}
+}
+
+func TestInteractiveHandler_Liked(t *testing.T) {
+	now := time.UnixMilli(time.Now().UnixMilli())
+	testCases := []struct {
+		name     string
+		mock     func(ctrl *gomock.Controller) (service.InteractiveService, service.UserService, service.ArticleService)
+		reqBody  string
+		wantBody Result
+	}{
+		{
+			name: "查询自己的点赞",
+			mock: func(ctrl *gomock.Controller) (service.InteractiveService, service.UserService, service.ArticleService) {
+				intrSvc := svcmocks.NewMockInteractiveService(ctrl)
+				intrSvc.EXPECT().LikedBy(gomock.Any(), int64(123), int64(0), 20).
+					Return([]domain.UserLike{
+						{Id: 5, Biz: "article", BizId: 1, Uid: 123, Utime: now},
+						{Id: 4, Biz: "comment", BizId: 2, Uid: 123, Utime: now},
+					}, nil)
+				artSvc := svcmocks.NewMockArticleService(ctrl)
+				artSvc.EXPECT().ListPubByIds(gomock.Any(), []int64{1}).
+					Return([]domain.Article{
+						{Id: 1, Title: "标题", Content: "内容", Status: domain.ArticleStatusPublished, Ctime: now, Utime: now},
+					}, nil)
+				return intrSvc, svcmocks.NewMockUserService(ctrl), artSvc
+			},
+			reqBody: `{}`,
+			wantBody: Result{
+				Data: map[string]any{
+					"Items": []any{
+						map[string]any{
+							"Biz": "article", "BizId": float64(1), "LikedAt": now.Format(time.DateTime),
+							"Article": map[string]any{
+								"Id": float64(1), "Title": "标题", "Abstract": "内容", "Content": "", "Author": "", "Category": "",
+								"Status": float64(2), "ReadCnt": float64(0), "LikeCnt": float64(0), "CollectCnt": float64(0),
+								"Liked": false, "Collected": false,
+								"Ctime": now.Format(time.DateTime), "Utime": now.Format(time.DateTime),
+							},
+						},
+						map[string]any{"Biz": "comment", "BizId": float64(2), "LikedAt": now.Format(time.DateTime)},
+					},
+					"Cursor": float64(0),
+				},
+			},
+		},
+		{
+			name: "对方没有公开点赞记录",
+			mock: func(ctrl *gomock.Controller) (service.InteractiveService, service.UserService, service.ArticleService) {
+				userSvc := svcmocks.NewMockUserService(ctrl)
+				userSvc.EXPECT().Profile(gomock.Any(), int64(456)).
+					Return(domain.User{Id: 456, HideLikes: true}, nil)
+				return svcmocks.NewMockInteractiveService(ctrl), userSvc, svcmocks.NewMockArticleService(ctrl)
+			},
+			reqBody:  `{"uid": 456}`,
+			wantBody: Result{Code: 4, Msg: "对方没有公开点赞记录"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			server := newInteractiveTestServer(tc.mock(ctrl))
+			webRes := doInteractiveRequest(t, server, "/interactive/liked", tc.reqBody)
+			assert.Equal(t, tc.wantBody, webRes)
+		})
+	}
+}
+
+func newInteractiveTestServer(intrSvc service.InteractiveService, userSvc service.UserService,
+	artSvc service.ArticleService) *gin.Engine {
+	server := gin.Default()
+	server.Use(func(ctx *gin.Context) {
+		ctx.Set("claims", ijwt.UserClaims{
+			Uid: 123,
+		})
+	})
+	h := NewInteractiveHandler(intrSvc, userSvc, artSvc, &logger.NopLogger{})
+	h.RegisterRoutes(server)
+	return server
+}
+
+func doInteractiveRequest(t *testing.T, server *gin.Engine, path string, body string) Result {
+	req, err := http.NewRequest(http.MethodPost, path, bytes.NewBuffer([]byte(body)))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	server.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code)
+	var webRes Result
+	err = json.NewDecoder(resp.Body).Decode(&webRes)
+	require.NoError(t, err)
+	return webRes
+}
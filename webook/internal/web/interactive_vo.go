@@ -0,0 +1,57 @@
+package web
+
+// cursor 是上一页原样返回的游标，为空表示第一页
+type LikersReq struct {
+	Biz    string `json:"biz"`
+	BizId  int64  `json:"bizId"`
+	Cursor string `json:"cursor"`
+	Limit  int    `json:"limit"`
+}
+
+// uid 为 0 表示查询自己
+type LikedReq struct {
+	Uid    int64 `json:"uid"`
+	Cursor int64 `json:"cursor"`
+	Limit  int   `json:"limit"`
+}
+
+type InteractiveLikeReq struct {
+	Biz   string `json:"biz"`
+	BizId int64  `json:"bizId"`
+	// true 是点赞，false 是取消点赞
+	Like bool `json:"like"`
+}
+
+type InteractiveCollectReq struct {
+	Biz   string `json:"biz"`
+	BizId int64  `json:"bizId"`
+	// 收藏夹的 ID
+	Cid int64 `json:"cid"`
+}
+
+type LikerVO struct {
+	// 匿名用户为 0
+	Uid      int64
+	Nickname string
+	LikedAt  string
+}
+
+// LikersVO 按照点赞时间倒序
+type LikersVO struct {
+	Likers []LikerVO
+	// 下一页的游标，为空表示没有更多数据
+	Cursor string
+}
+
+type LikedItemVO struct {
+	Biz     string
+	BizId   int64
+	LikedAt string
+	// 文章的摘要信息，文章已经撤回时为空
+	Article *ArticleVO `json:",omitempty"`
+}
+
+type LikedVO struct {
+	Items  []LikedItemVO
+	Cursor int64
+}
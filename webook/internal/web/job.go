@@ -0,0 +1,369 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"go-basic/webook/internal/domain"
+	"go-basic/webook/internal/service"
+	"go-basic/webook/pkg/ginx"
+	"go-basic/webook/pkg/logger"
+	"time"
+
+	"github.com/ecodeclub/ekit/slice"
+	"github.com/gin-gonic/gin"
+)
+
+var _ handler = (*JobHandler)(nil)
+
+// JobHandler 管理 MySQL 调度器里面的任务，修改之后正在运行的调度器不需要重启
+type JobHandler struct {
+	svc    service.JobService
+	runSvc service.JobRunService
+	wfSvc  service.WorkflowService
+	l      logger.Logger
+}
+
+func NewJobHandler(svc service.JobService, runSvc service.JobRunService, wfSvc service.WorkflowService,
+	l logger.Logger) *JobHandler {
+	return &JobHandler{
+		svc:    svc,
+		runSvc: runSvc,
+		wfSvc:  wfSvc,
+		l:      l,
+	}
+}
+
+// RegisterRoutes /jobs 下面都是管理接口，由管理员中间件统一校验权限
+func (h *JobHandler) RegisterRoutes(server *gin.Engine) {
+	g := server.Group("/jobs")
+	g.POST("/create", ginx.WrapBody[JobReq](h.Create))
+	g.POST("/update", ginx.WrapBody[JobReq](h.Update))
+	g.POST("/delete", ginx.WrapBody[JobIdReq](h.Delete))
+	g.POST("/pause", ginx.WrapBody[JobIdReq](h.Pause))
+	g.POST("/resume", ginx.WrapBody[JobIdReq](h.Resume))
+	g.POST("/trigger", ginx.WrapBody[JobIdReq](h.Trigger))
+	g.POST("/cancel", ginx.WrapBody[JobIdReq](h.Cancel))
+	g.POST("/list", ginx.WrapBody[ListReq](h.List))
+	g.POST("/runs", ginx.WrapBody[JobRunListReq](h.Runs))
+	// 工作流也是任务，创建、触发和普通任务一样，这里只有执行记录相关的接口
+	g.POST("/workflow/runs", ginx.WrapBody[WorkflowRunListReq](h.WorkflowRuns))
+	g.POST("/workflow/retry", ginx.WrapBody[WorkflowRetryReq](h.WorkflowRetry))
+}
+
+func (h *JobHandler) Create(ctx *gin.Context, req JobReq) (ginx.Result, error) {
+	if req.Name == "" || req.Executor == "" {
+		return ginx.Result{
+			Code: 4,
+			Msg:  "任务名称和执行器不能为空",
+		}, nil
+	}
+	j, err := req.toDomain()
+	if err != nil {
+		return ginx.Result{
+			Code: 4,
+			Msg:  err.Error(),
+		}, nil
+	}
+	id, err := h.svc.Create(ctx, j)
+	if err != nil {
+		return jobErrResult(err)
+	}
+	return ginx.Result{
+		Data: id,
+	}, nil
+}
+
+func (h *JobHandler) Update(ctx *gin.Context, req JobReq) (ginx.Result, error) {
+	if req.Id <= 0 || req.Name == "" || req.Executor == "" {
+		return ginx.Result{
+			Code: 4,
+			Msg:  "输入有误",
+		}, nil
+	}
+	j, err := req.toDomain()
+	if err != nil {
+		return ginx.Result{
+			Code: 4,
+			Msg:  err.Error(),
+		}, nil
+	}
+	err = h.svc.Update(ctx, j)
+	if err != nil {
+		return jobErrResult(err)
+	}
+	return ginx.Result{
+		Msg: "OK",
+	}, nil
+}
+
+func (h *JobHandler) Delete(ctx *gin.Context, req JobIdReq) (ginx.Result, error) {
+	return h.byId(ctx, req, h.svc.Delete)
+}
+
+func (h *JobHandler) Pause(ctx *gin.Context, req JobIdReq) (ginx.Result, error) {
+	return h.byId(ctx, req, h.svc.Pause)
+}
+
+func (h *JobHandler) Resume(ctx *gin.Context, req JobIdReq) (ginx.Result, error) {
+	return h.byId(ctx, req, h.svc.Resume)
+}
+
+func (h *JobHandler) Trigger(ctx *gin.Context, req JobIdReq) (ginx.Result, error) {
+	return h.byId(ctx, req, h.svc.Trigger)
+}
+
+// Cancel 取消正在执行的这一次，之后按照 cron 继续调度
+func (h *JobHandler) Cancel(ctx *gin.Context, req JobIdReq) (ginx.Result, error) {
+	return h.byId(ctx, req, h.svc.Cancel)
+}
+
+func (h *JobHandler) List(ctx *gin.Context, req ListReq) (ginx.Result, error) {
+	if req.Limit <= 0 || req.Limit > 100 {
+		req.Limit = 100
+	}
+	jobs, err := h.svc.List(ctx, req.Offset, req.Limit)
+	if err != nil {
+		return ginx.Result{
+			Code: 5,
+			Msg:  "系统错误",
+		}, err
+	}
+	return ginx.Result{
+		Data: slice.Map(jobs, func(idx int, src domain.Job) JobVO {
+			return JobVO{
+				Id:       src.Id,
+				Name:     src.Name,
+				Cron:     src.Cron,
+				Timezone: src.Timezone,
+				Misfire:  src.Misfire.String(),
+				Executor: src.Executor,
+				Cfg:      src.Cfg,
+				Priority: src.Priority,
+				Group:    src.Group,
+				Status:   src.Status.String(),
+				Attempt:  src.Attempt,
+
+				Mode:       src.Mode.String(),
+				ShardCount: src.ShardCount,
+
+				MaxAttempts:      src.Retry.MaxAttempts,
+				Backoff:          src.Retry.Backoff.String(),
+				RetryInterval:    src.Retry.Interval.String(),
+				MaxRetryInterval: src.Retry.MaxInterval.String(),
+				Timeout:          src.Timeout.String(),
+
+				NextTime: src.NextRunTime.Format(time.DateTime),
+				Ctime:    src.Ctime.Format(time.DateTime),
+				Utime:    src.Utime.Format(time.DateTime),
+			}
+		}),
+	}, nil
+}
+
+// Runs 查询任务的执行记录，可以按照执行结果过滤
+func (h *JobHandler) Runs(ctx *gin.Context, req JobRunListReq) (ginx.Result, error) {
+	status := domain.JobRunStatusFromString(req.Status)
+	if req.JobId <= 0 || (req.Status != "" && status == domain.JobRunStatusUnknown) {
+		return ginx.Result{
+			Code: 4,
+			Msg:  "输入有误",
+		}, nil
+	}
+	if req.Limit <= 0 || req.Limit > 100 {
+		req.Limit = 100
+	}
+	runs, err := h.runSvc.List(ctx, req.JobId, status, req.Offset, req.Limit)
+	if err != nil {
+		return ginx.Result{
+			Code: 5,
+			Msg:  "系统错误",
+		}, err
+	}
+	return ginx.Result{
+		Data: slice.Map(runs, func(idx int, src domain.JobRun) JobRunVO {
+			vo := JobRunVO{
+				Id:        src.Id,
+				JobId:     src.JobId,
+				Instance:  src.Instance,
+				Attempt:   src.Attempt,
+				Status:    src.Status.String(),
+				ErrMsg:    src.ErrMsg,
+				StartTime: src.StartTime.Format(time.DateTime),
+				Duration:  src.Duration().Milliseconds(),
+			}
+			if !src.EndTime.IsZero() {
+				vo.EndTime = src.EndTime.Format(time.DateTime)
+			}
+			return vo
+		}),
+	}, nil
+}
+
+// WorkflowRuns 工作流的执行记录，带上每个节点的状态
+func (h *JobHandler) WorkflowRuns(ctx *gin.Context, req WorkflowRunListReq) (ginx.Result, error) {
+	if req.WorkflowId <= 0 {
+		return ginx.Result{
+			Code: 4,
+			Msg:  "输入有误",
+		}, nil
+	}
+	if req.Limit <= 0 || req.Limit > 100 {
+		req.Limit = 100
+	}
+	runs, err := h.wfSvc.List(ctx, req.WorkflowId, req.Offset, req.Limit)
+	if err != nil {
+		return ginx.Result{
+			Code: 5,
+			Msg:  "系统错误",
+		}, err
+	}
+	return ginx.Result{
+		Data: slice.Map(runs, func(idx int, src domain.WorkflowRun) WorkflowRunVO {
+			vo := WorkflowRunVO{
+				Id:        src.Id,
+				Status:    src.Status.String(),
+				StartTime: src.StartTime.Format(time.DateTime),
+				Nodes: slice.Map(src.Nodes, func(idx int, n domain.WorkflowNodeRun) WorkflowNodeRunVO {
+					nvo := WorkflowNodeRunVO{
+						JobId:     n.JobId,
+						Upstreams: n.Upstreams,
+						Status:    n.Status.String(),
+						Attempt:   n.Attempt,
+						ErrMsg:    n.ErrMsg,
+					}
+					if !n.StartTime.IsZero() {
+						nvo.StartTime = n.StartTime.Format(time.DateTime)
+					}
+					if !n.EndTime.IsZero() {
+						nvo.EndTime = n.EndTime.Format(time.DateTime)
+					}
+					return nvo
+				}),
+			}
+			if !src.EndTime.IsZero() {
+				vo.EndTime = src.EndTime.Format(time.DateTime)
+			}
+			return vo
+		}),
+	}, nil
+}
+
+// WorkflowRetry 重新执行失败的节点以及它们的下游
+func (h *JobHandler) WorkflowRetry(ctx *gin.Context, req WorkflowRetryReq) (ginx.Result, error) {
+	if req.RunId <= 0 {
+		return ginx.Result{
+			Code: 4,
+			Msg:  "输入有误",
+		}, nil
+	}
+	err := h.wfSvc.Retry(ctx, req.RunId)
+	if errors.Is(err, service.ErrWorkflowRunNotFound) {
+		return ginx.Result{
+			Code: 4,
+			Msg:  "执行记录不存在或者没有失败",
+		}, nil
+	}
+	if err != nil {
+		return jobErrResult(err)
+	}
+	return ginx.Result{
+		Msg: "OK",
+	}, nil
+}
+
+func (h *JobHandler) byId(ctx *gin.Context, req JobIdReq, fn func(ctx context.Context, id int64) error) (ginx.Result, error) {
+	if req.Id <= 0 {
+		return ginx.Result{
+			Code: 4,
+			Msg:  "输入有误",
+		}, nil
+	}
+	err := fn(ctx, req.Id)
+	if err != nil {
+		return jobErrResult(err)
+	}
+	return ginx.Result{
+		Msg: "OK",
+	}, nil
+}
+
+// jobErrResult 任务状态不对、cron 表达式有误这些都是前端输入的问题
+func jobErrResult(err error) (ginx.Result, error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidCron):
+		return ginx.Result{
+			Code: 4,
+			Msg:  "cron 表达式有误",
+		}, nil
+	case errors.Is(err, service.ErrInvalidTimezone):
+		return ginx.Result{
+			Code: 4,
+			Msg:  "时区有误",
+		}, nil
+	case errors.Is(err, service.ErrInvalidMisfire):
+		return ginx.Result{
+			Code: 4,
+			Msg:  "错过执行时间的处理方式有误",
+		}, nil
+	case errors.Is(err, service.ErrInvalidRetry):
+		return ginx.Result{
+			Code: 4,
+			Msg:  "重试策略有误",
+		}, nil
+	case errors.Is(err, service.ErrInvalidTimeout):
+		return ginx.Result{
+			Code: 4,
+			Msg:  "超时时间有误",
+		}, nil
+	case errors.Is(err, service.ErrJobNotRunning):
+		return ginx.Result{
+			Code: 4,
+			Msg:  "任务没有在执行",
+		}, nil
+	case errors.Is(err, service.ErrInvalidShard):
+		return ginx.Result{
+			Code: 4,
+			Msg:  "执行方式或者分片数量有误",
+		}, nil
+	case errors.Is(err, service.ErrInvalidWorkflow):
+		return ginx.Result{
+			Code: 4,
+			Msg:  err.Error(),
+		}, nil
+	case errors.Is(err, service.ErrJobDead):
+		return ginx.Result{
+			Code: 4,
+			Msg:  "任务已经死掉，需要先恢复",
+		}, nil
+	case errors.Is(err, service.ErrJobDuplicateName):
+		return ginx.Result{
+			Code: 4,
+			Msg:  "任务名称已存在",
+		}, nil
+	case errors.Is(err, service.ErrJobNotFound):
+		return ginx.Result{
+			Code: 4,
+			Msg:  "任务不存在",
+		}, nil
+	case errors.Is(err, service.ErrJobRunning):
+		return ginx.Result{
+			Code: 4,
+			Msg:  "任务正在执行",
+		}, nil
+	case errors.Is(err, service.ErrJobPaused):
+		return ginx.Result{
+			Code: 4,
+			Msg:  "任务已暂停",
+		}, nil
+	case errors.Is(err, service.ErrJobNotPaused):
+		return ginx.Result{
+			Code: 4,
+			Msg:  "任务没有暂停或者死掉",
+		}, nil
+	default:
+		return ginx.Result{
+			Code: 5,
+			Msg:  "系统错误",
+		}, err
+	}
+}
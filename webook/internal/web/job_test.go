@@ -0,0 +1,417 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"go-basic/webook/internal/domain"
+	"go-basic/webook/internal/service"
+	svcmocks "go-basic/webook/internal/service/mocks"
+	"go-basic/webook/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobHandler(t *testing.T) {
+	now := time.UnixMilli(time.Now().UnixMilli())
+	testCases := []struct {
+		name     string
+		mock     func(ctrl *gomock.Controller) service.JobService
+		url      string
+		reqBody  string
+		wantBody Result
+	}{
+		{
+			name: "创建任务",
+			mock: func(ctrl *gomock.Controller) service.JobService {
+				svc := svcmocks.NewMockJobService(ctrl)
+				svc.EXPECT().Create(gomock.Any(), domain.Job{
+					Name: "ranking", Cron: "0 */3 * * * ?", Executor: "local",
+				}).Return(int64(1), nil)
+				return svc
+			},
+			url:      "/jobs/create",
+			reqBody:  `{"name": "ranking", "cron": "0 */3 * * * ?", "executor": "local"}`,
+			wantBody: Result{Data: float64(1)},
+		},
+		{
+			name: "创建带重试的任务",
+			mock: func(ctrl *gomock.Controller) service.JobService {
+				svc := svcmocks.NewMockJobService(ctrl)
+				svc.EXPECT().Create(gomock.Any(), domain.Job{
+					Name: "sitemap", Cron: "@every 1h", Executor: "http",
+					Retry: domain.RetryPolicy{
+						MaxAttempts: 3,
+						Backoff:     domain.BackoffExponential,
+						Interval:    time.Second * 30,
+						MaxInterval: time.Minute * 5,
+					},
+				}).Return(int64(2), nil)
+				return svc
+			},
+			url: "/jobs/create",
+			reqBody: `{"name": "sitemap", "cron": "@every 1h", "executor": "http", "maxAttempts": 3,
+"backoff": "exponential", "retryInterval": "30s", "maxRetryInterval": "5m"}`,
+			wantBody: Result{Data: float64(2)},
+		},
+		{
+			name: "重试间隔有误",
+			mock: func(ctrl *gomock.Controller) service.JobService {
+				return svcmocks.NewMockJobService(ctrl)
+			},
+			url:      "/jobs/create",
+			reqBody:  `{"name": "sitemap", "cron": "@every 1h", "executor": "http", "retryInterval": "30"}`,
+			wantBody: Result{Code: 4, Msg: "重试间隔有误"},
+		},
+		{
+			name: "重试策略有误",
+			mock: func(ctrl *gomock.Controller) service.JobService {
+				svc := svcmocks.NewMockJobService(ctrl)
+				svc.EXPECT().Create(gomock.Any(), gomock.Any()).Return(int64(0), service.ErrInvalidRetry)
+				return svc
+			},
+			url:      "/jobs/create",
+			reqBody:  `{"name": "sitemap", "cron": "@every 1h", "executor": "http", "maxAttempts": 3}`,
+			wantBody: Result{Code: 4, Msg: "重试策略有误"},
+		},
+		{
+			name: "cron 表达式有误",
+			mock: func(ctrl *gomock.Controller) service.JobService {
+				svc := svcmocks.NewMockJobService(ctrl)
+				svc.EXPECT().Create(gomock.Any(), gomock.Any()).Return(int64(0), service.ErrInvalidCron)
+				return svc
+			},
+			url:      "/jobs/create",
+			reqBody:  `{"name": "ranking", "cron": "abc", "executor": "local"}`,
+			wantBody: Result{Code: 4, Msg: "cron 表达式有误"},
+		},
+		{
+			name: "缺少执行器",
+			mock: func(ctrl *gomock.Controller) service.JobService {
+				return svcmocks.NewMockJobService(ctrl)
+			},
+			url:      "/jobs/create",
+			reqBody:  `{"name": "ranking", "cron": "@every 1m"}`,
+			wantBody: Result{Code: 4, Msg: "任务名称和执行器不能为空"},
+		},
+		{
+			name: "暂停任务",
+			mock: func(ctrl *gomock.Controller) service.JobService {
+				svc := svcmocks.NewMockJobService(ctrl)
+				svc.EXPECT().Pause(gomock.Any(), int64(1)).Return(nil)
+				return svc
+			},
+			url:      "/jobs/pause",
+			reqBody:  `{"id": 1}`,
+			wantBody: Result{Msg: "OK"},
+		},
+		{
+			name: "立刻执行正在执行的任务",
+			mock: func(ctrl *gomock.Controller) service.JobService {
+				svc := svcmocks.NewMockJobService(ctrl)
+				svc.EXPECT().Trigger(gomock.Any(), int64(1)).Return(service.ErrJobRunning)
+				return svc
+			},
+			url:      "/jobs/trigger",
+			reqBody:  `{"id": 1}`,
+			wantBody: Result{Code: 4, Msg: "任务正在执行"},
+		},
+		{
+			name: "恢复不存在的任务",
+			mock: func(ctrl *gomock.Controller) service.JobService {
+				svc := svcmocks.NewMockJobService(ctrl)
+				svc.EXPECT().Resume(gomock.Any(), int64(2)).Return(service.ErrJobNotFound)
+				return svc
+			},
+			url:      "/jobs/resume",
+			reqBody:  `{"id": 2}`,
+			wantBody: Result{Code: 4, Msg: "任务不存在"},
+		},
+		{
+			name: "创建带超时的任务",
+			mock: func(ctrl *gomock.Controller) service.JobService {
+				svc := svcmocks.NewMockJobService(ctrl)
+				svc.EXPECT().Create(gomock.Any(), domain.Job{
+					Name: "sitemap", Cron: "@every 1h", Executor: "http", Timeout: time.Minute * 10,
+				}).Return(int64(3), nil)
+				return svc
+			},
+			url:      "/jobs/create",
+			reqBody:  `{"name": "sitemap", "cron": "@every 1h", "executor": "http", "timeout": "10m"}`,
+			wantBody: Result{Data: float64(3)},
+		},
+		{
+			name: "超时时间有误",
+			mock: func(ctrl *gomock.Controller) service.JobService {
+				return svcmocks.NewMockJobService(ctrl)
+			},
+			url:      "/jobs/create",
+			reqBody:  `{"name": "sitemap", "cron": "@every 1h", "executor": "http", "timeout": "ten minutes"}`,
+			wantBody: Result{Code: 4, Msg: "超时时间有误"},
+		},
+		{
+			name: "创建带时区的任务",
+			mock: func(ctrl *gomock.Controller) service.JobService {
+				svc := svcmocks.NewMockJobService(ctrl)
+				svc.EXPECT().Create(gomock.Any(), domain.Job{
+					Name: "report", Cron: "0 0 9 * * ?", Timezone: "Asia/Shanghai",
+					Misfire: domain.MisfireSkip, Executor: "http",
+				}).Return(int64(4), nil)
+				return svc
+			},
+			url: "/jobs/create",
+			reqBody: `{"name": "report", "cron": "0 0 9 * * ?", "timezone": "Asia/Shanghai",
+"misfire": "skip", "executor": "http"}`,
+			wantBody: Result{Data: float64(4)},
+		},
+		{
+			name: "创建带优先级的任务",
+			mock: func(ctrl *gomock.Controller) service.JobService {
+				svc := svcmocks.NewMockJobService(ctrl)
+				svc.EXPECT().Create(gomock.Any(), domain.Job{
+					Name: "settle", Cron: "@every 1h", Executor: "http", Priority: 10, Group: "payment",
+				}).Return(int64(5), nil)
+				return svc
+			},
+			url:      "/jobs/create",
+			reqBody:  `{"name": "settle", "cron": "@every 1h", "executor": "http", "priority": 10, "group": "payment"}`,
+			wantBody: Result{Data: float64(5)},
+		},
+		{
+			name: "时区有误",
+			mock: func(ctrl *gomock.Controller) service.JobService {
+				svc := svcmocks.NewMockJobService(ctrl)
+				svc.EXPECT().Create(gomock.Any(), gomock.Any()).Return(int64(0), service.ErrInvalidTimezone)
+				return svc
+			},
+			url:      "/jobs/create",
+			reqBody:  `{"name": "report", "cron": "0 0 9 * * ?", "timezone": "Mars/Olympus", "executor": "http"}`,
+			wantBody: Result{Code: 4, Msg: "时区有误"},
+		},
+		{
+			name: "取消正在执行的任务",
+			mock: func(ctrl *gomock.Controller) service.JobService {
+				svc := svcmocks.NewMockJobService(ctrl)
+				svc.EXPECT().Cancel(gomock.Any(), int64(1)).Return(nil)
+				return svc
+			},
+			url:      "/jobs/cancel",
+			reqBody:  `{"id": 1}`,
+			wantBody: Result{Msg: "OK"},
+		},
+		{
+			name: "取消没有在执行的任务",
+			mock: func(ctrl *gomock.Controller) service.JobService {
+				svc := svcmocks.NewMockJobService(ctrl)
+				svc.EXPECT().Cancel(gomock.Any(), int64(1)).Return(service.ErrJobNotRunning)
+				return svc
+			},
+			url:      "/jobs/cancel",
+			reqBody:  `{"id": 1}`,
+			wantBody: Result{Code: 4, Msg: "任务没有在执行"},
+		},
+		{
+			name: "查询任务列表",
+			mock: func(ctrl *gomock.Controller) service.JobService {
+				svc := svcmocks.NewMockJobService(ctrl)
+				svc.EXPECT().List(gomock.Any(), 0, 100).Return([]domain.Job{
+					{
+						Id: 1, Name: "ranking", Cron: "@every 1m", Executor: "local",
+						Status: domain.JobStatusDead, Attempt: 3, NextRunTime: now, Ctime: now, Utime: now,
+						Retry: domain.RetryPolicy{
+							MaxAttempts: 3, Backoff: domain.BackoffFixed, Interval: time.Minute,
+						},
+					},
+				}, nil)
+				return svc
+			},
+			url:     "/jobs/list",
+			reqBody: `{"offset": 0}`,
+			wantBody: Result{
+				Data: []any{
+					map[string]any{
+						"Id": float64(1), "Name": "ranking", "Cron": "@every 1m", "Executor": "local", "Cfg": "",
+						"Status": "dead", "Attempt": float64(3), "Mode": "normal", "ShardCount": float64(0), "Timeout": "0s",
+						"Timezone": "", "Misfire": "fire_once", "Priority": float64(0), "Group": "",
+						"MaxAttempts": float64(3), "Backoff": "fixed", "RetryInterval": "1m0s", "MaxRetryInterval": "0s",
+						"NextTime": now.Format(time.DateTime),
+						"Ctime":    now.Format(time.DateTime),
+						"Utime":    now.Format(time.DateTime),
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			server := gin.Default()
+			NewJobHandler(tc.mock(ctrl), svcmocks.NewMockJobRunService(ctrl), svcmocks.NewMockWorkflowService(ctrl), &logger.NopLogger{}).RegisterRoutes(server)
+			req, err := http.NewRequest(http.MethodPost, tc.url, bytes.NewBufferString(tc.reqBody))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			server.ServeHTTP(resp, req)
+			require.Equal(t, http.StatusOK, resp.Code)
+			var webRes Result
+			err = json.NewDecoder(resp.Body).Decode(&webRes)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantBody, webRes)
+		})
+	}
+}
+
+func TestJobHandler_Runs(t *testing.T) {
+	start := time.UnixMilli(time.Now().UnixMilli())
+	testCases := []struct {
+		name     string
+		mock     func(ctrl *gomock.Controller) service.JobRunService
+		reqBody  string
+		wantBody Result
+	}{
+		{
+			name: "查询失败的执行记录",
+			mock: func(ctrl *gomock.Controller) service.JobRunService {
+				svc := svcmocks.NewMockJobRunService(ctrl)
+				svc.EXPECT().List(gomock.Any(), int64(1), domain.JobRunStatusFailed, 0, 10).
+					Return([]domain.JobRun{
+						{
+							Id: 2, JobId: 1, Instance: "node_1", Attempt: 1, Status: domain.JobRunStatusFailed,
+							ErrMsg: "超时", StartTime: start, EndTime: start.Add(time.Second * 3),
+						},
+					}, nil)
+				return svc
+			},
+			reqBody: `{"jobId": 1, "status": "failed", "limit": 10}`,
+			wantBody: Result{
+				Data: []any{
+					map[string]any{
+						"Id": float64(2), "JobId": float64(1), "Instance": "node_1", "Attempt": float64(1),
+						"Status": "failed", "ErrMsg": "超时",
+						"StartTime": start.Format(time.DateTime),
+						"EndTime":   start.Add(time.Second * 3).Format(time.DateTime),
+						"Duration":  float64(3000),
+					},
+				},
+			},
+		},
+		{
+			name: "还在执行",
+			mock: func(ctrl *gomock.Controller) service.JobRunService {
+				svc := svcmocks.NewMockJobRunService(ctrl)
+				svc.EXPECT().List(gomock.Any(), int64(1), domain.JobRunStatusUnknown, 0, 100).
+					Return([]domain.JobRun{
+						{Id: 3, JobId: 1, Instance: "node_1", Attempt: 1, Status: domain.JobRunStatusRunning, StartTime: start},
+					}, nil)
+				return svc
+			},
+			reqBody: `{"jobId": 1}`,
+			wantBody: Result{
+				Data: []any{
+					map[string]any{
+						"Id": float64(3), "JobId": float64(1), "Instance": "node_1", "Attempt": float64(1),
+						"Status": "running", "ErrMsg": "",
+						"StartTime": start.Format(time.DateTime),
+						"EndTime":   "",
+						"Duration":  float64(0),
+					},
+				},
+			},
+		},
+		{
+			name: "不支持的状态",
+			mock: func(ctrl *gomock.Controller) service.JobRunService {
+				return svcmocks.NewMockJobRunService(ctrl)
+			},
+			reqBody:  `{"jobId": 1, "status": "dead"}`,
+			wantBody: Result{Code: 4, Msg: "输入有误"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			server := gin.Default()
+			NewJobHandler(svcmocks.NewMockJobService(ctrl), tc.mock(ctrl), svcmocks.NewMockWorkflowService(ctrl), &logger.NopLogger{}).RegisterRoutes(server)
+			req, err := http.NewRequest(http.MethodPost, "/jobs/runs", bytes.NewBufferString(tc.reqBody))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			server.ServeHTTP(resp, req)
+			require.Equal(t, http.StatusOK, resp.Code)
+			var webRes Result
+			err = json.NewDecoder(resp.Body).Decode(&webRes)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantBody, webRes)
+		})
+	}
+}
+
+func TestJobHandler_WorkflowRetry(t *testing.T) {
+	testCases := []struct {
+		name     string
+		mock     func(ctrl *gomock.Controller) service.WorkflowService
+		reqBody  string
+		wantBody Result
+	}{
+		{
+			name: "重新执行",
+			mock: func(ctrl *gomock.Controller) service.WorkflowService {
+				svc := svcmocks.NewMockWorkflowService(ctrl)
+				svc.EXPECT().Retry(gomock.Any(), int64(2)).Return(nil)
+				return svc
+			},
+			reqBody:  `{"runId": 2}`,
+			wantBody: Result{Msg: "OK"},
+		},
+		{
+			name: "执行没有失败",
+			mock: func(ctrl *gomock.Controller) service.WorkflowService {
+				svc := svcmocks.NewMockWorkflowService(ctrl)
+				svc.EXPECT().Retry(gomock.Any(), int64(2)).Return(service.ErrWorkflowRunNotFound)
+				return svc
+			},
+			reqBody:  `{"runId": 2}`,
+			wantBody: Result{Code: 4, Msg: "执行记录不存在或者没有失败"},
+		},
+		{
+			name: "工作流已暂停",
+			mock: func(ctrl *gomock.Controller) service.WorkflowService {
+				svc := svcmocks.NewMockWorkflowService(ctrl)
+				svc.EXPECT().Retry(gomock.Any(), int64(2)).Return(service.ErrJobPaused)
+				return svc
+			},
+			reqBody:  `{"runId": 2}`,
+			wantBody: Result{Code: 4, Msg: "任务已暂停"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			server := gin.Default()
+			NewJobHandler(svcmocks.NewMockJobService(ctrl), svcmocks.NewMockJobRunService(ctrl), tc.mock(ctrl),
+				&logger.NopLogger{}).RegisterRoutes(server)
+			req, err := http.NewRequest(http.MethodPost, "/jobs/workflow/retry", bytes.NewBufferString(tc.reqBody))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			server.ServeHTTP(resp, req)
+			require.Equal(t, http.StatusOK, resp.Code)
+			var webRes Result
+			err = json.NewDecoder(resp.Body).Decode(&webRes)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantBody, webRes)
+		})
+	}
+}
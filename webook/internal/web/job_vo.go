@@ -0,0 +1,176 @@
+package web
+
+import (
+	"errors"
+	"go-basic/webook/internal/domain"
+	"time"
+)
+
+// toDomain 解析时间失败的时候返回的错误，直接作为提示返回给前端
+var (
+	errInvalidRetryInterval = errors.New("重试间隔有误")
+	errInvalidTimeout       = errors.New("超时时间有误")
+)
+
+type JobReq struct {
+	// 创建的时候不需要传
+	Id   int64  `json:"id"`
+	Name string `json:"name"`
+	Cron string `json:"cron"`
+	// IANA 时区，例如 Asia/Shanghai，为空表示服务器的时区
+	Timezone string `json:"timezone"`
+	// fire_once, fire_all, skip，为空表示 fire_once
+	Misfire  string `json:"misfire"`
+	Executor string `json:"executor"`
+	// 原样交给执行器
+	Cfg string `json:"cfg"`
+	// 数字越大越先执行，默认是 0
+	Priority int `json:"priority"`
+	// 任务所属的分组，例如业务方，同样优先级的任务在分组之间公平地执行
+	Group string `json:"group"`
+
+	// normal, sharded, broadcast，为空表示 normal
+	Mode string `json:"mode"`
+	// 分片模式下每次执行分成多少片
+	ShardCount int `json:"shardCount"`
+
+	// 最多执行几次，为 0 表示失败了不重试
+	MaxAttempts int `json:"maxAttempts"`
+	// fixed, exponential
+	Backoff string `json:"backoff"`
+	// 例如 30s、5m
+	RetryInterval    string `json:"retryInterval"`
+	MaxRetryInterval string `json:"maxRetryInterval"`
+
+	// 单次执行的超时时间，例如 10m，为空表示不限制
+	Timeout string `json:"timeout"`
+}
+
+func (req JobReq) toDomain() (domain.Job, error) {
+	retry := domain.RetryPolicy{
+		MaxAttempts: req.MaxAttempts,
+		Backoff:     domain.BackoffTypeFromString(req.Backoff),
+	}
+	var err error
+	if req.RetryInterval != "" {
+		retry.Interval, err = time.ParseDuration(req.RetryInterval)
+		if err != nil {
+			return domain.Job{}, errInvalidRetryInterval
+		}
+	}
+	if req.MaxRetryInterval != "" {
+		retry.MaxInterval, err = time.ParseDuration(req.MaxRetryInterval)
+		if err != nil {
+			return domain.Job{}, errInvalidRetryInterval
+		}
+	}
+	var timeout time.Duration
+	if req.Timeout != "" {
+		timeout, err = time.ParseDuration(req.Timeout)
+		if err != nil {
+			return domain.Job{}, errInvalidTimeout
+		}
+	}
+	return domain.Job{
+		Id:         req.Id,
+		Name:       req.Name,
+		Cron:       req.Cron,
+		Timezone:   req.Timezone,
+		Misfire:    domain.MisfirePolicyFromString(req.Misfire),
+		Executor:   req.Executor,
+		Cfg:        req.Cfg,
+		Priority:   req.Priority,
+		Group:      req.Group,
+		Mode:       domain.JobModeFromString(req.Mode),
+		ShardCount: req.ShardCount,
+		Retry:      retry,
+		Timeout:    timeout,
+	}, nil
+}
+
+type JobIdReq struct {
+	Id int64 `json:"id"`
+}
+
+type JobVO struct {
+	Id       int64
+	Name     string
+	Cron     string
+	Timezone string
+	// fire_once, fire_all, skip
+	Misfire  string
+	Executor string
+	Cfg      string
+	Priority int
+	Group    string
+	// waiting, running, paused, dead
+	Status string
+	// normal, sharded, broadcast
+	Mode       string
+	ShardCount int
+	// 当前这一轮已经执行了几次
+	Attempt          int
+	MaxAttempts      int
+	Backoff          string
+	RetryInterval    string
+	MaxRetryInterval string
+	// 0s 表示不限制
+	Timeout  string
+	NextTime string
+	Ctime    string
+	Utime    string
+}
+
+type JobRunListReq struct {
+	JobId int64 `json:"jobId"`
+	// running, success, failed，为空表示全部
+	Status string `json:"status"`
+	Offset int    `json:"offset"`
+	Limit  int    `json:"limit"`
+}
+
+type JobRunVO struct {
+	Id        int64
+	JobId     int64
+	Instance  string
+	Attempt   int
+	Status    string
+	ErrMsg    string
+	StartTime string
+	// 还在执行的时候为空
+	EndTime string
+	// 毫秒
+	Duration int64
+}
+
+type WorkflowRunListReq struct {
+	// 工作流对应的任务 ID
+	WorkflowId int64 `json:"workflowId"`
+	Offset     int   `json:"offset"`
+	Limit      int   `json:"limit"`
+}
+
+type WorkflowRetryReq struct {
+	RunId int64 `json:"runId"`
+}
+
+type WorkflowRunVO struct {
+	Id int64
+	// running, success, failed
+	Status    string
+	StartTime string
+	// 还在执行的时候为空
+	EndTime string
+	Nodes   []WorkflowNodeRunVO
+}
+
+type WorkflowNodeRunVO struct {
+	JobId     int64
+	Upstreams []int64
+	// waiting, running, success, failed, skipped
+	Status    string
+	Attempt   int
+	ErrMsg    string
+	StartTime string
+	EndTime   string
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	ijwt "go-basic/webook/internal/web/jwt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminMiddlewareBuilder 管理接口只允许管理员访问，需要放在登录校验后面，
+// 依赖登录校验放进去的 claims
+type AdminMiddlewareBuilder struct {
+	prefixes []string
+	uids     map[int64]struct{}
+}
+
+func NewAdminMiddlewareBuilder(uids []int64) *AdminMiddlewareBuilder {
+	res := &AdminMiddlewareBuilder{
+		uids: make(map[int64]struct{}, len(uids)),
+	}
+	for _, uid := range uids {
+		res.uids[uid] = struct{}{}
+	}
+	return res
+}
+
+// Prefix 以 prefix 开头的路径都是管理接口
+func (b *AdminMiddlewareBuilder) Prefix(prefix string) *AdminMiddlewareBuilder {
+	b.prefixes = append(b.prefixes, prefix)
+	return b
+}
+
+func (b *AdminMiddlewareBuilder) Build() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !b.match(ctx.Request.URL.Path) {
+			return
+		}
+		c, _ := ctx.Get("claims")
+		claims, ok := c.(*ijwt.UserClaims)
+		if !ok {
+			ctx.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		if _, ok = b.uids[claims.Uid]; !ok {
+			ctx.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+	}
+}
+
+func (b *AdminMiddlewareBuilder) match(path string) bool {
+	for _, prefix := range b.prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
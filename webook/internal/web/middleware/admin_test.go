@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	ijwt "go-basic/webook/internal/web/jwt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminMiddlewareBuilder(t *testing.T) {
+	testCases := []struct {
+		name   string
+		path   string
+		claims *ijwt.UserClaims
+
+		wantCode int
+	}{
+		{
+			name:     "管理员",
+			path:     "/jobs/create",
+			claims:   &ijwt.UserClaims{Uid: 1},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "普通用户",
+			path:     "/jobs/create",
+			claims:   &ijwt.UserClaims{Uid: 2},
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "没有登录",
+			path:     "/jobs/create",
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "普通用户查看短信送达率",
+			path:     "/sms/delivery/stats",
+			claims:   &ijwt.UserClaims{Uid: 2},
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "不是管理接口",
+			path:     "/articles/list",
+			claims:   &ijwt.UserClaims{Uid: 2},
+			wantCode: http.StatusOK,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := gin.New()
+			server.Use(func(ctx *gin.Context) {
+				if tc.claims != nil {
+					ctx.Set("claims", tc.claims)
+				}
+			})
+			server.Use(NewAdminMiddlewareBuilder([]int64{1}).Prefix("/jobs/").Prefix("/sms/delivery/").Build())
+			server.POST(tc.path, func(ctx *gin.Context) {
+				ctx.Status(http.StatusOK)
+			})
+			req := httptest.NewRequest(http.MethodPost, tc.path, nil)
+			resp := httptest.NewRecorder()
+			server.ServeHTTP(resp, req)
+			assert.Equal(t, tc.wantCode, resp.Code)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package web
+
+import (
+	"go-basic/webook/internal/domain"
+	"go-basic/webook/internal/service"
+	"go-basic/webook/pkg/logger"
+	"net/http"
+	"time"
+
+	"github.com/ecodeclub/ekit/slice"
+	"github.com/gin-gonic/gin"
+)
+
+var _ handler = (*RankingHandler)(nil)
+
+// RankingHandler 热榜接口，不需要登录
+type RankingHandler struct {
+	svc service.RankingService
+	l   logger.Logger
+}
+
+func NewRankingHandler(svc service.RankingService, l logger.Logger) *RankingHandler {
+	return &RankingHandler{
+		svc: svc,
+		l:   l,
+	}
+}
+
+func (h *RankingHandler) RegisterRoutes(server *gin.Engine) {
+	server.GET("/articles/ranking", h.TopN)
+}
+
+// TopN 查询热榜，category 为空表示全站，window 可以是 24h, 7d, 30d，默认 7d
+func (h *RankingHandler) TopN(ctx *gin.Context) {
+	key := domain.RankingKey{
+		Category: ctx.Query("category"),
+		Window:   domain.RankingWindow(ctx.DefaultQuery("window", string(domain.DefaultRankingKey.Window))),
+	}
+	if !key.Window.Valid() {
+		ctx.JSON(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "不支持的时间窗口",
+		})
+		return
+	}
+	arts, err := h.svc.GetTopN(ctx, key)
+	if err != nil {
+		h.l.Error("查询热榜失败", logger.Error(err), logger.String("key", key.String()))
+		ctx.JSON(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	// 热榜缓存里面没有文章内容，只返回标题和作者
+	ctx.JSON(http.StatusOK, Result{
+		Data: slice.Map(arts, func(idx int, src domain.Article) ArticleVO {
+			return ArticleVO{
+				Id:       src.Id,
+				Title:    src.Title,
+				Author:   src.Author.Name,
+				Category: src.Category,
+				Status:   src.Status.ToUint8(),
+				Ctime:    src.Ctime.Format(time.DateTime),
+				Utime:    src.Utime.Format(time.DateTime),
+			}
+		}),
+	})
+}
@@ -0,0 +1,86 @@
+package web
+
+import (
+	"encoding/json"
+	"go-basic/webook/internal/domain"
+	"go-basic/webook/internal/service"
+	svcmocks "go-basic/webook/internal/service/mocks"
+	"go-basic/webook/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRankingHandler_TopN(t *testing.T) {
+	now := time.UnixMilli(time.Now().UnixMilli())
+	testCases := []struct {
+		name     string
+		mock     func(ctrl *gomock.Controller) service.RankingService
+		url      string
+		wantBody Result
+	}{
+		{
+			name: "默认查询全站七天的榜单",
+			mock: func(ctrl *gomock.Controller) service.RankingService {
+				svc := svcmocks.NewMockRankingService(ctrl)
+				svc.EXPECT().GetTopN(gomock.Any(), domain.RankingKey{Window: domain.RankingWindowWeek}).
+					Return([]domain.Article{{Id: 1, Title: "标题", Category: "go", Ctime: now, Utime: now}}, nil)
+				return svc
+			},
+			url: "/articles/ranking",
+			wantBody: Result{
+				Data: []any{
+					map[string]any{
+						"Id": float64(1), "Title": "标题", "Abstract": "", "Content": "", "Author": "", "Category": "go",
+						"Status": float64(0), "ReadCnt": float64(0), "LikeCnt": float64(0), "CollectCnt": float64(0),
+						"Liked": false, "Collected": false,
+						"Ctime": now.Format(time.DateTime), "Utime": now.Format(time.DateTime),
+					},
+				},
+			},
+		},
+		{
+			name: "按分类和时间窗口查询",
+			mock: func(ctrl *gomock.Controller) service.RankingService {
+				svc := svcmocks.NewMockRankingService(ctrl)
+				svc.EXPECT().GetTopN(gomock.Any(), domain.RankingKey{Category: "go", Window: domain.RankingWindowDay}).
+					Return([]domain.Article{}, nil)
+				return svc
+			},
+			url:      "/articles/ranking?category=go&window=24h",
+			wantBody: Result{Data: []any{}},
+		},
+		{
+			name: "不支持的时间窗口",
+			mock: func(ctrl *gomock.Controller) service.RankingService {
+				return svcmocks.NewMockRankingService(ctrl)
+			},
+			url:      "/articles/ranking?window=1y",
+			wantBody: Result{Code: 4, Msg: "不支持的时间窗口"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			server := gin.Default()
+			NewRankingHandler(tc.mock(ctrl), &logger.NopLogger{}).RegisterRoutes(server)
+			req, err := http.NewRequest(http.MethodGet, tc.url, nil)
+			require.NoError(t, err)
+			resp := httptest.NewRecorder()
+			server.ServeHTTP(resp, req)
+			require.Equal(t, http.StatusOK, resp.Code)
+			var webRes Result
+			err = json.NewDecoder(resp.Body).Decode(&webRes)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantBody, webRes)
+		})
+	}
+}
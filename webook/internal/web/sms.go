@@ -0,0 +1,168 @@
+package web
+
+import (
+	"errors"
+	"go-basic/webook/internal/domain"
+	"go-basic/webook/internal/service"
+	"go-basic/webook/internal/service/sms"
+	"go-basic/webook/pkg/ginx"
+	"go-basic/webook/pkg/logger"
+	"time"
+
+	"github.com/ecodeclub/ekit/slice"
+	"github.com/gin-gonic/gin"
+)
+
+var _ handler = (*SMSHandler)(nil)
+
+// SMSHandler 管理内部业务方发短信用的令牌，业务方拿着令牌通过 /sms/send 发短信
+type SMSHandler struct {
+	tokenSvc service.SMSBizTokenService
+	// smsSvc 按照令牌校验模板和额度，biz 就是令牌
+	smsSvc sms.Service
+	l      logger.Logger
+}
+
+func NewSMSHandler(tokenSvc service.SMSBizTokenService, smsSvc sms.Service, l logger.Logger) *SMSHandler {
+	return &SMSHandler{
+		tokenSvc: tokenSvc,
+		smsSvc:   smsSvc,
+		l:        l,
+	}
+}
+
+func (h *SMSHandler) RegisterRoutes(server *gin.Engine) {
+	// 内部业务方没有登录态，靠令牌鉴权
+	server.POST("/sms/send", ginx.WrapBody[SMSSendReq](h.Send))
+	g := server.Group("/sms/tokens")
+	g.POST("/issue", ginx.WrapBody[SMSTokenIssueReq](h.Issue))
+	g.POST("/revoke", ginx.WrapBody[SMSTokenIdReq](h.Revoke))
+	g.POST("/list", ginx.WrapBody[ListReq](h.List))
+}
+
+func (h *SMSHandler) Send(ctx *gin.Context, req SMSSendReq) (ginx.Result, error) {
+	if req.Token == "" || len(req.Numbers) == 0 {
+		return ginx.Result{
+			Code: 4,
+			Msg:  "输入有误",
+		}, nil
+	}
+	err := h.smsSvc.Send(ctx, req.Token, req.Args, req.Numbers...)
+	switch {
+	case errors.Is(err, service.ErrSMSBizTokenInvalid), errors.Is(err, service.ErrSMSBizTokenRevoked):
+		return ginx.Result{
+			Code: 4,
+			Msg:  "令牌不合法或者已经被吊销",
+		}, nil
+	case errors.Is(err, service.ErrSMSQuotaExceeded), errors.Is(err, sms.ErrRateLimited):
+		return ginx.Result{
+			Code: 4,
+			Msg:  "短信额度已经用完",
+		}, nil
+	case errors.Is(err, sms.ErrInvalidArgs), errors.Is(err, sms.ErrInvalidNumber):
+		return ginx.Result{
+			Code: 4,
+			Msg:  "短信参数或者号码有误",
+		}, nil
+	case err != nil:
+		return ginx.Result{
+			Code: 5,
+			Msg:  "系统错误",
+		}, err
+	}
+	return ginx.Result{
+		Msg: "OK",
+	}, nil
+}
+
+// Issue 令牌只在这里返回一次，丢了只能吊销之后重新签发
+func (h *SMSHandler) Issue(ctx *gin.Context, req SMSTokenIssueReq) (ginx.Result, error) {
+	if req.Biz == "" || req.Tpl == "" || len(req.Biz) > 64 {
+		return ginx.Result{
+			Code: 4,
+			Msg:  "输入有误",
+		}, nil
+	}
+	t, token, err := h.tokenSvc.Issue(ctx, domain.SMSBizToken{
+		Biz:        req.Biz,
+		Tpl:        req.Tpl,
+		DailyQuota: req.DailyQuota,
+	})
+	switch {
+	case errors.Is(err, service.ErrSMSTemplateNotFound):
+		return ginx.Result{
+			Code: 4,
+			Msg:  "短信模板不存在",
+		}, nil
+	case errors.Is(err, service.ErrInvalidSMSQuota):
+		return ginx.Result{
+			Code: 4,
+			Msg:  "短信额度不能小于 0",
+		}, nil
+	case err != nil:
+		return ginx.Result{
+			Code: 5,
+			Msg:  "系统错误",
+		}, err
+	}
+	vo := h.toVO(t)
+	vo.Token = token
+	return ginx.Result{
+		Data: vo,
+	}, nil
+}
+
+func (h *SMSHandler) Revoke(ctx *gin.Context, req SMSTokenIdReq) (ginx.Result, error) {
+	if req.Id <= 0 {
+		return ginx.Result{
+			Code: 4,
+			Msg:  "输入有误",
+		}, nil
+	}
+	err := h.tokenSvc.Revoke(ctx, req.Id)
+	switch {
+	case errors.Is(err, service.ErrSMSBizTokenNotFound):
+		return ginx.Result{
+			Code: 4,
+			Msg:  "令牌不存在或者已经被吊销",
+		}, nil
+	case err != nil:
+		return ginx.Result{
+			Code: 5,
+			Msg:  "系统错误",
+		}, err
+	}
+	return ginx.Result{
+		Msg: "OK",
+	}, nil
+}
+
+func (h *SMSHandler) List(ctx *gin.Context, req ListReq) (ginx.Result, error) {
+	if req.Limit <= 0 || req.Limit > 100 {
+		req.Limit = 100
+	}
+	ts, err := h.tokenSvc.List(ctx, req.Offset, req.Limit)
+	if err != nil {
+		return ginx.Result{
+			Code: 5,
+			Msg:  "系统错误",
+		}, err
+	}
+	return ginx.Result{
+		Data: slice.Map(ts, func(idx int, src domain.SMSBizToken) SMSTokenVO {
+			return h.toVO(src)
+		}),
+	}, nil
+}
+
+func (h *SMSHandler) toVO(t domain.SMSBizToken) SMSTokenVO {
+	return SMSTokenVO{
+		Id:         t.Id,
+		Biz:        t.Biz,
+		Tpl:        t.Tpl,
+		DailyQuota: t.DailyQuota,
+		Status:     t.Status.String(),
+		Ctime:      t.Ctime.Format(time.DateTime),
+		Utime:      t.Utime.Format(time.DateTime),
+	}
+}
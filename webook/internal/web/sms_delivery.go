@@ -0,0 +1,138 @@
+package web
+
+import (
+	"errors"
+	"go-basic/webook/internal/domain"
+	"go-basic/webook/internal/service"
+	"go-basic/webook/internal/service/sms"
+	"go-basic/webook/pkg/ginx"
+	"go-basic/webook/pkg/logger"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ecodeclub/ekit/slice"
+	"github.com/gin-gonic/gin"
+)
+
+// maxReceiptBodySize 一次推送的回执最多读这么多
+const maxReceiptBodySize = 1 << 20
+
+var _ handler = (*SMSDeliveryHandler)(nil)
+
+// SMSDeliveryHandler 接收服务商推送的短信回执，查询送达率
+type SMSDeliveryHandler struct {
+	svc service.SMSDeliveryService
+	// 服务商名字到这个服务商的回执解析
+	parsers map[string]sms.ReceiptParser
+	l       logger.Logger
+}
+
+func NewSMSDeliveryHandler(svc service.SMSDeliveryService, parsers map[string]sms.ReceiptParser,
+	l logger.Logger) *SMSDeliveryHandler {
+	return &SMSDeliveryHandler{
+		svc:     svc,
+		parsers: parsers,
+		l:       l,
+	}
+}
+
+func (h *SMSDeliveryHandler) RegisterRoutes(server *gin.Engine) {
+	// 回执是服务商调用的，不需要登录，靠每个服务商自己的签名校验
+	server.POST("/sms/receipts", h.Receive)
+	// 送达率是运营数据，只有管理员可以查看
+	server.POST("/sms/delivery/stats", ginx.WrapBody[SMSDeliveryStatsReq](h.Stats))
+}
+
+// Receive 服务商在 provider 参数里面带上自己在 sms.providers 里面的名字。
+// 处理失败的时候返回 5xx，服务商会重新推送
+func (h *SMSDeliveryHandler) Receive(ctx *gin.Context) {
+	provider := ctx.Query("provider")
+	parser, ok := h.parsers[provider]
+	if !ok {
+		ctx.JSON(http.StatusNotFound, Result{
+			Code: 4,
+			Msg:  "服务商不存在",
+		})
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxReceiptBodySize))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, Result{
+			Code: 4,
+			Msg:  "输入有误",
+		})
+		return
+	}
+	receipts, err := parser.Parse(ctx.Request, body)
+	if err != nil {
+		h.l.Warn("短信回执不合法", logger.String("provider", provider), logger.Error(err))
+		ctx.JSON(http.StatusUnauthorized, Result{
+			Code: 4,
+			Msg:  "回执不合法",
+		})
+		return
+	}
+	err = h.svc.Receive(ctx, provider, receipts)
+	if err != nil {
+		h.l.Error("处理短信回执失败", logger.String("provider", provider), logger.Error(err))
+		ctx.JSON(http.StatusInternalServerError, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Msg: "成功",
+	})
+}
+
+func (h *SMSDeliveryHandler) Stats(ctx *gin.Context, req SMSDeliveryStatsReq) (ginx.Result, error) {
+	end := time.Now()
+	start := end.Add(-time.Hour * 24)
+	var err error
+	if req.Start != "" {
+		start, err = time.ParseInLocation(time.DateTime, req.Start, time.Local)
+		if err != nil {
+			return ginx.Result{
+				Code: 4,
+				Msg:  "开始时间有误",
+			}, nil
+		}
+	}
+	if req.End != "" {
+		end, err = time.ParseInLocation(time.DateTime, req.End, time.Local)
+		if err != nil {
+			return ginx.Result{
+				Code: 4,
+				Msg:  "结束时间有误",
+			}, nil
+		}
+	}
+	stats, err := h.svc.Stats(ctx, start, end)
+	switch {
+	case errors.Is(err, service.ErrInvalidSMSDeliveryRange):
+		return ginx.Result{
+			Code: 4,
+			Msg:  "开始时间要早于结束时间",
+		}, nil
+	case err != nil:
+		return ginx.Result{
+			Code: 5,
+			Msg:  "系统错误",
+		}, err
+	}
+	return ginx.Result{
+		Data: slice.Map(stats, func(idx int, src domain.SMSDeliveryStats) SMSDeliveryStatsVO {
+			return SMSDeliveryStatsVO{
+				Provider:     src.Provider,
+				Biz:          src.Biz,
+				Total:        src.Total,
+				Delivered:    src.Delivered,
+				Failed:       src.Failed,
+				Pending:      src.Total - src.Delivered - src.Failed,
+				DeliveryRate: src.DeliveryRate(),
+			}
+		}),
+	}, nil
+}
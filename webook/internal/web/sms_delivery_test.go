@@ -0,0 +1,126 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"go-basic/webook/internal/domain"
+	"go-basic/webook/internal/service"
+	svcmocks "go-basic/webook/internal/service/mocks"
+	"go-basic/webook/internal/service/sms"
+	"go-basic/webook/internal/service/sms/aliyun"
+	"go-basic/webook/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSMSDeliveryHandler(t *testing.T) {
+	receipt := `[{"phone_number":"152","report_time":"2024-01-02 15:04:05","success":true,"biz_id":"biz_1"}]`
+	testCases := []struct {
+		name     string
+		mock     func(ctrl *gomock.Controller) service.SMSDeliveryService
+		url      string
+		reqBody  string
+		wantCode int
+		wantBody Result
+	}{
+		{
+			name: "接收回执",
+			mock: func(ctrl *gomock.Controller) service.SMSDeliveryService {
+				svc := svcmocks.NewMockSMSDeliveryService(ctrl)
+				svc.EXPECT().Receive(gomock.Any(), "aliyun", []sms.Receipt{
+					{
+						MessageId: "biz_1", Number: "152", Delivered: true,
+						Time: time.Date(2024, 1, 2, 15, 4, 5, 0, time.FixedZone("CST", 8*3600)),
+					},
+				}).Return(nil)
+				return svc
+			},
+			url:      "/sms/receipts?provider=aliyun&token=receipt-token",
+			reqBody:  receipt,
+			wantCode: http.StatusOK,
+			wantBody: Result{Msg: "成功"},
+		},
+		{
+			name: "回执 token 不对",
+			mock: func(ctrl *gomock.Controller) service.SMSDeliveryService {
+				return svcmocks.NewMockSMSDeliveryService(ctrl)
+			},
+			url:      "/sms/receipts?provider=aliyun&token=wrong",
+			reqBody:  receipt,
+			wantCode: http.StatusUnauthorized,
+			wantBody: Result{Code: 4, Msg: "回执不合法"},
+		},
+		{
+			name: "服务商不存在",
+			mock: func(ctrl *gomock.Controller) service.SMSDeliveryService {
+				return svcmocks.NewMockSMSDeliveryService(ctrl)
+			},
+			url:      "/sms/receipts?provider=tencent",
+			reqBody:  receipt,
+			wantCode: http.StatusNotFound,
+			wantBody: Result{Code: 4, Msg: "服务商不存在"},
+		},
+		{
+			name: "查询送达率",
+			mock: func(ctrl *gomock.Controller) service.SMSDeliveryService {
+				svc := svcmocks.NewMockSMSDeliveryService(ctrl)
+				svc.EXPECT().Stats(gomock.Any(),
+					time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local),
+					time.Date(2024, 1, 2, 0, 0, 0, 0, time.Local)).
+					Return([]domain.SMSDeliveryStats{
+						{Provider: "aliyun", Biz: "code", Total: 4, Delivered: 2, Failed: 1},
+					}, nil)
+				return svc
+			},
+			url:      "/sms/delivery/stats",
+			reqBody:  `{"start": "2024-01-01 00:00:00", "end": "2024-01-02 00:00:00"}`,
+			wantCode: http.StatusOK,
+			wantBody: Result{
+				Data: []any{
+					map[string]any{
+						"Provider": "aliyun", "Biz": "code", "Total": float64(4), "Delivered": float64(2),
+						"Failed": float64(1), "Pending": float64(1), "DeliveryRate": 0.5,
+					},
+				},
+			},
+		},
+		{
+			name: "时间有误",
+			mock: func(ctrl *gomock.Controller) service.SMSDeliveryService {
+				return svcmocks.NewMockSMSDeliveryService(ctrl)
+			},
+			url:      "/sms/delivery/stats",
+			reqBody:  `{"start": "2024-01-01"}`,
+			wantCode: http.StatusOK,
+			wantBody: Result{Code: 4, Msg: "开始时间有误"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			server := gin.Default()
+			NewSMSDeliveryHandler(tc.mock(ctrl), map[string]sms.ReceiptParser{
+				"aliyun": aliyun.NewReceiptParser("receipt-token"),
+			}, &logger.NopLogger{}).RegisterRoutes(server)
+			req, err := http.NewRequest(http.MethodPost, tc.url, bytes.NewBufferString(tc.reqBody))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			server.ServeHTTP(resp, req)
+			require.Equal(t, tc.wantCode, resp.Code)
+			var webRes Result
+			err = json.NewDecoder(resp.Body).Decode(&webRes)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantBody, webRes)
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"go-basic/webook/internal/domain"
+	"go-basic/webook/internal/service"
+	svcmocks "go-basic/webook/internal/service/mocks"
+	"go-basic/webook/internal/service/sms"
+	smsmocks "go-basic/webook/internal/service/sms/mocks"
+	"go-basic/webook/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSMSHandler(t *testing.T) {
+	now := time.UnixMilli(time.Now().UnixMilli())
+	testCases := []struct {
+		name     string
+		mock     func(ctrl *gomock.Controller) service.SMSBizTokenService
+		url      string
+		reqBody  string
+		wantBody Result
+	}{
+		{
+			name: "签发令牌",
+			mock: func(ctrl *gomock.Controller) service.SMSBizTokenService {
+				svc := svcmocks.NewMockSMSBizTokenService(ctrl)
+				svc.EXPECT().Issue(gomock.Any(), domain.SMSBizToken{Biz: "order", Tpl: "code", DailyQuota: 100}).
+					Return(domain.SMSBizToken{
+						Id: 1, Biz: "order", Tpl: "code", DailyQuota: 100,
+						Status: domain.SMSBizTokenStatusActive, Ctime: now, Utime: now,
+					}, "token", nil)
+				return svc
+			},
+			url:     "/sms/tokens/issue",
+			reqBody: `{"biz": "order", "tpl": "code", "dailyQuota": 100}`,
+			wantBody: Result{
+				Data: map[string]any{
+					"Id": float64(1), "Biz": "order", "Tpl": "code", "DailyQuota": float64(100),
+					"Status": "active", "Token": "token",
+					"Ctime": now.Format(time.DateTime),
+					"Utime": now.Format(time.DateTime),
+				},
+			},
+		},
+		{
+			name: "模板不存在",
+			mock: func(ctrl *gomock.Controller) service.SMSBizTokenService {
+				svc := svcmocks.NewMockSMSBizTokenService(ctrl)
+				svc.EXPECT().Issue(gomock.Any(), gomock.Any()).
+					Return(domain.SMSBizToken{}, "", service.ErrSMSTemplateNotFound)
+				return svc
+			},
+			url:      "/sms/tokens/issue",
+			reqBody:  `{"biz": "order", "tpl": "notice"}`,
+			wantBody: Result{Code: 4, Msg: "短信模板不存在"},
+		},
+		{
+			name: "缺少业务方",
+			mock: func(ctrl *gomock.Controller) service.SMSBizTokenService {
+				return svcmocks.NewMockSMSBizTokenService(ctrl)
+			},
+			url:      "/sms/tokens/issue",
+			reqBody:  `{"tpl": "code"}`,
+			wantBody: Result{Code: 4, Msg: "输入有误"},
+		},
+		{
+			name: "吊销令牌",
+			mock: func(ctrl *gomock.Controller) service.SMSBizTokenService {
+				svc := svcmocks.NewMockSMSBizTokenService(ctrl)
+				svc.EXPECT().Revoke(gomock.Any(), int64(1)).Return(nil)
+				return svc
+			},
+			url:      "/sms/tokens/revoke",
+			reqBody:  `{"id": 1}`,
+			wantBody: Result{Msg: "OK"},
+		},
+		{
+			name: "吊销不存在的令牌",
+			mock: func(ctrl *gomock.Controller) service.SMSBizTokenService {
+				svc := svcmocks.NewMockSMSBizTokenService(ctrl)
+				svc.EXPECT().Revoke(gomock.Any(), int64(2)).Return(service.ErrSMSBizTokenNotFound)
+				return svc
+			},
+			url:      "/sms/tokens/revoke",
+			reqBody:  `{"id": 2}`,
+			wantBody: Result{Code: 4, Msg: "令牌不存在或者已经被吊销"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			server := gin.Default()
+			NewSMSHandler(tc.mock(ctrl), smsmocks.NewMockService(ctrl), &logger.NopLogger{}).RegisterRoutes(server)
+			req, err := http.NewRequest(http.MethodPost, tc.url, bytes.NewBufferString(tc.reqBody))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			server.ServeHTTP(resp, req)
+			require.Equal(t, http.StatusOK, resp.Code)
+			var webRes Result
+			err = json.NewDecoder(resp.Body).Decode(&webRes)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantBody, webRes)
+		})
+	}
+}
+
+func TestSMSHandler_Send(t *testing.T) {
+	testCases := []struct {
+		name     string
+		mock     func(ctrl *gomock.Controller) sms.Service
+		reqBody  string
+		wantBody Result
+	}{
+		{
+			name: "发送成功",
+			mock: func(ctrl *gomock.Controller) sms.Service {
+				svc := smsmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "token", []string{"123456"}, "152").Return(nil)
+				return svc
+			},
+			reqBody:  `{"token": "token", "args": ["123456"], "numbers": ["152"]}`,
+			wantBody: Result{Msg: "OK"},
+		},
+		{
+			name: "没有号码",
+			mock: func(ctrl *gomock.Controller) sms.Service {
+				return smsmocks.NewMockService(ctrl)
+			},
+			reqBody:  `{"token": "token", "args": ["123456"]}`,
+			wantBody: Result{Code: 4, Msg: "输入有误"},
+		},
+		{
+			name: "令牌被吊销",
+			mock: func(ctrl *gomock.Controller) sms.Service {
+				svc := smsmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "token", []string{"123456"}, "152").Return(service.ErrSMSBizTokenRevoked)
+				return svc
+			},
+			reqBody:  `{"token": "token", "args": ["123456"], "numbers": ["152"]}`,
+			wantBody: Result{Code: 4, Msg: "令牌不合法或者已经被吊销"},
+		},
+		{
+			name: "额度用完",
+			mock: func(ctrl *gomock.Controller) sms.Service {
+				svc := smsmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "token", []string{"123456"}, "152").Return(service.ErrSMSQuotaExceeded)
+				return svc
+			},
+			reqBody:  `{"token": "token", "args": ["123456"], "numbers": ["152"]}`,
+			wantBody: Result{Code: 4, Msg: "短信额度已经用完"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			server := gin.Default()
+			NewSMSHandler(svcmocks.NewMockSMSBizTokenService(ctrl), tc.mock(ctrl), &logger.NopLogger{}).RegisterRoutes(server)
+			req, err := http.NewRequest(http.MethodPost, "/sms/send", bytes.NewBufferString(tc.reqBody))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			server.ServeHTTP(resp, req)
+			require.Equal(t, http.StatusOK, resp.Code)
+			var webRes Result
+			err = json.NewDecoder(resp.Body).Decode(&webRes)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantBody, webRes)
+		})
+	}
+}
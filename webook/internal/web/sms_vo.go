@@ -0,0 +1,50 @@
+package web
+
+type SMSTokenIssueReq struct {
+	// 业务方的名字
+	Biz string `json:"biz"`
+	// 模板注册中心里面的模板名字
+	Tpl string `json:"tpl"`
+	// 每天最多发送多少条，0 表示不限制
+	DailyQuota int `json:"dailyQuota"`
+}
+
+// SMSSendReq 内部业务方发短信，模板由令牌决定
+type SMSSendReq struct {
+	Token   string   `json:"token"`
+	Args    []string `json:"args"`
+	Numbers []string `json:"numbers"`
+}
+
+type SMSTokenIdReq struct {
+	Id int64 `json:"id"`
+}
+
+type SMSTokenVO struct {
+	Id         int64
+	Biz        string
+	Tpl        string
+	DailyQuota int
+	Status     string
+	// 只有签发的时候返回，之后查不到
+	Token string `json:",omitempty"`
+	Ctime string
+	Utime string
+}
+
+// SMSDeliveryStatsReq 时间格式是 2006-01-02 15:04:05，都不传的时候统计最近一天
+type SMSDeliveryStatsReq struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+type SMSDeliveryStatsVO struct {
+	Provider  string
+	Biz       string
+	Total     int64
+	Delivered int64
+	Failed    int64
+	// Pending 还没有收到回执的
+	Pending      int64
+	DeliveryRate float64
+}
@@ -4,6 +4,8 @@ import (
 	"go-basic/webook/internal/domain"
 	"go-basic/webook/internal/errs"
 	"go-basic/webook/internal/service"
+	emailquota "go-basic/webook/internal/service/email/quota"
+	"go-basic/webook/internal/service/sms/quota"
 	ijwt "go-basic/webook/internal/web/jwt"
 	"go-basic/webook/pkg/ginx"
 	"go-basic/webook/pkg/logger"
@@ -25,7 +27,10 @@ var (
 	ErrCodeVerifyTooManyTimes = service.ErrCodeVerifyTooManyTimes
 )
 
-const biz = "login"
+const (
+	biz       = "login"
+	signUpBiz = "signup"
+)
 
 type UserHandler struct {
 	svc         service.UserService
@@ -37,12 +42,15 @@ type UserHandler struct {
 	phoneExp    *regexp.Regexp
 	codeExp     *regexp.Regexp
 	codeSvc     service.CodeService
+	// emailCodeSvc 邮箱验证码，注册和邮箱登录的时候用
+	emailCodeSvc service.EmailCodeService
 	ijwt.Handler
 	cmd redis.Cmdable
 	l   logger.Logger
 }
 
-func NewUserHandler(svc service.UserService, codeSvc service.CodeService, ijwtHdl ijwt.Handler, l logger.Logger) *UserHandler {
+func NewUserHandler(svc service.UserService, codeSvc service.CodeService, emailCodeSvc service.EmailCodeService,
+	ijwtHdl ijwt.Handler, l logger.Logger) *UserHandler {
 	const (
 		emailRegexPattern = `^[a-zA-Z0-9_-]+@[a-zA-Z0-9_-]+(\.[a-zA-Z0-9_-]+)+$`
 		passwordPattern   = `^[a-zA-Z0-9_-]{6,18}$`
@@ -60,17 +68,18 @@ func NewUserHandler(svc service.UserService, codeSvc service.CodeService, ijwtHd
 	phoneExp := regexp.MustCompile(phonePattern, regexp.None)
 	codeExp := regexp.MustCompile(codePattern, regexp.None)
 	return &UserHandler{
-		svc:         svc,
-		emailExp:    emailExp,
-		passwordExp: passwordExp,
-		nicknameExp: nicknameExp,
-		birthdayExp: birthdayExp,
-		descExp:     descExp,
-		phoneExp:    phoneExp,
-		codeExp:     codeExp,
-		codeSvc:     codeSvc,
-		Handler:     ijwtHdl,
-		l:           l,
+		svc:          svc,
+		emailExp:     emailExp,
+		passwordExp:  passwordExp,
+		nicknameExp:  nicknameExp,
+		birthdayExp:  birthdayExp,
+		descExp:      descExp,
+		phoneExp:     phoneExp,
+		codeExp:      codeExp,
+		codeSvc:      codeSvc,
+		emailCodeSvc: emailCodeSvc,
+		Handler:      ijwtHdl,
+		l:            l,
 	}
 }
 
@@ -78,11 +87,15 @@ func (u *UserHandler) RegisterRoutes(server *gin.Engine) {
 	ug := server.Group("/users")
 	ug.POST("/login", ginx.WrapBody[LoginReq](u.LoginJWT))
 	ug.POST("/signup", u.SignUp)
+	ug.POST("/signup/code/send", ginx.WrapBody[SendEmailCodeReq](u.SendSignUpEmailCode))
 	ug.PUT("/edit", u.Edit)
 	ug.GET("/profile", u.ProfileJWT)
 	ug.POST("/login_sms/code/send", u.SendLoginSMSCode)
 	ug.POST("/login_sms", ginx.WrapBody[LoginSMSReq](u.LoginSMS))
+	ug.POST("/login_email/code/send", ginx.WrapBody[SendEmailCodeReq](u.SendLoginEmailCode))
+	ug.POST("/login_email", ginx.WrapBody[LoginEmailReq](u.LoginEmail))
 	ug.POST("/refresh_token", u.RefreshToken)
+	ug.POST("/privacy", ginx.WrapBodyAndToken[PrivacyReq, ijwt.UserClaims](u.Privacy))
 }
 
 func (u *UserHandler) Logout(ctx *gin.Context) {
@@ -122,6 +135,19 @@ func (u *UserHandler) RefreshToken(ctx *gin.Context) {
 	})
 }
 
+type PrivacyReq struct {
+	HideLikes bool `json:"hideLikes"`
+}
+
+// Privacy 修改隐私设置
+func (u *UserHandler) Privacy(ctx *gin.Context, req PrivacyReq, uc ijwt.UserClaims) (Result, error) {
+	err := u.svc.UpdatePrivacy(ctx, uc.Uid, req.HideLikes)
+	if err != nil {
+		return Result{Code: 5, Msg: "系统错误"}, err
+	}
+	return Result{Msg: "修改成功"}, nil
+}
+
 type LoginSMSReq struct {
 	Phone string `json:"phone"`
 	Code  string `json:"code"`
@@ -162,6 +188,83 @@ func (u *UserHandler) LoginSMS(ctx *gin.Context, req LoginSMSReq) (Result, error
 	return Result{Msg: "登录成功"}, nil
 }
 
+type LoginEmailReq struct {
+	Email string `json:"email"`
+	Code  string `json:"code"`
+}
+
+func (u *UserHandler) LoginEmail(ctx *gin.Context, req LoginEmailReq) (Result, error) {
+	ok, err := u.emailExp.MatchString(req.Email)
+	if err != nil {
+		return Result{Code: 5, Msg: "系统错误"}, err
+	}
+	if !ok {
+		return Result{Code: 4, Msg: "输入有误"}, nil
+	}
+	ok, err = u.codeExp.MatchString(req.Code)
+	if err != nil {
+		return Result{Code: 5, Msg: "系统错误"}, err
+	}
+	if !ok {
+		return Result{Code: 4, Msg: "输入有误"}, nil
+	}
+	ok, err = u.emailCodeSvc.Verify(ctx, biz, req.Email, req.Code)
+	if err == service.ErrCodeVerifyTooManyTimes {
+		return Result{Code: 4, Msg: "验证码错误次数太多，请重新获取"}, nil
+	}
+	if err != nil {
+		return Result{Code: 5, Msg: "系统错误"}, err
+	}
+	if !ok {
+		return Result{Code: 4, Msg: "验证码错误"}, nil
+	}
+	user, err := u.svc.FindOrCreateByEmail(ctx, req.Email)
+	if err != nil {
+		return Result{Code: 5, Msg: "系统错误"}, err
+	}
+	if err = u.SetLoginToken(ctx, user.Id); err != nil {
+		return Result{Code: 5, Msg: "系统错误"}, err
+	}
+	return Result{Msg: "登录成功"}, nil
+}
+
+type SendEmailCodeReq struct {
+	Email string `json:"email"`
+}
+
+func (u *UserHandler) SendLoginEmailCode(ctx *gin.Context, req SendEmailCodeReq) (Result, error) {
+	return u.sendEmailCode(ctx, biz, req.Email)
+}
+
+// SendSignUpEmailCode 注册之前先验证邮箱
+func (u *UserHandler) SendSignUpEmailCode(ctx *gin.Context, req SendEmailCodeReq) (Result, error) {
+	return u.sendEmailCode(ctx, signUpBiz, req.Email)
+}
+
+func (u *UserHandler) sendEmailCode(ctx *gin.Context, biz, email string) (Result, error) {
+	ok, err := u.emailExp.MatchString(email)
+	if err != nil {
+		return Result{Code: 5, Msg: "系统错误"}, err
+	}
+	if !ok {
+		return Result{Code: 4, Msg: "邮箱输入有误"}, nil
+	}
+	// 带上 IP，邮件服务按照 IP 限制发送数量
+	err = u.emailCodeSvc.Send(emailquota.WithIP(ctx, ctx.ClientIP()), biz, email)
+	switch err {
+	case nil:
+		return Result{Msg: "发送成功"}, nil
+	case service.ErrCodeSendTooMany:
+		return Result{Code: 3, Msg: "发送太频繁，请稍后再试"}, nil
+	case service.ErrCodeEmailAddressQuotaExceeded:
+		return Result{Code: 3, Msg: "这个邮箱今天收到的验证码太多，请明天再试"}, nil
+	case service.ErrCodeEmailIPQuotaExceeded:
+		return Result{Code: 3, Msg: "当前网络发送的验证码太多，请稍后再试"}, nil
+	default:
+		return Result{Code: 5, Msg: "系统错误"}, err
+	}
+}
+
 func (u *UserHandler) SendLoginSMSCode(ctx *gin.Context) {
 	type Req struct {
 		Phone string `json:"phone"`
@@ -186,7 +289,8 @@ func (u *UserHandler) SendLoginSMSCode(ctx *gin.Context) {
 		})
 		return
 	}
-	err = u.codeSvc.Send(ctx, biz, req.Phone)
+	// 带上 IP，短信服务按照 IP 限制发送数量
+	err = u.codeSvc.Send(quota.WithIP(ctx, ctx.ClientIP()), biz, req.Phone)
 	switch err {
 	case nil:
 		ctx.JSON(http.StatusOK, Result{
@@ -198,6 +302,24 @@ func (u *UserHandler) SendLoginSMSCode(ctx *gin.Context) {
 			Msg:  "发送太频繁，请稍后再试",
 		})
 		return
+	case service.ErrCodePhoneQuotaExceeded:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 3,
+			Msg:  "这个手机号今天收到的验证码太多，请明天再试",
+		})
+		return
+	case service.ErrCodeIPQuotaExceeded:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 3,
+			Msg:  "当前网络发送的验证码太多，请稍后再试",
+		})
+		return
+	case service.ErrCodeBizQuotaExceeded:
+		ctx.JSON(http.StatusOK, Result{
+			Code: 3,
+			Msg:  "验证码发送繁忙，请稍后再试",
+		})
+		return
 	default:
 		ctx.JSON(http.StatusOK, Result{
 			Code: 5,
@@ -270,6 +392,8 @@ func (u *UserHandler) SignUp(ctx *gin.Context) {
 		Email           string `json:"email"`
 		Password        string `json:"password"`
 		ConfirmPassword string `json:"confirmPassword"`
+		// Code 发到邮箱的验证码
+		Code string `json:"code"`
 	}
 
 	var req SignUpReq
@@ -301,6 +425,20 @@ func (u *UserHandler) SignUp(ctx *gin.Context) {
 		ctx.String(http.StatusOK, "密码格式错误")
 		return
 	}
+	// 验证邮箱确实属于注册的人
+	ok, err = u.emailCodeSvc.Verify(ctx, signUpBiz, req.Email, req.Code)
+	if err == service.ErrCodeVerifyTooManyTimes {
+		ctx.String(http.StatusOK, "验证码错误次数太多，请重新获取")
+		return
+	}
+	if err != nil {
+		ctx.String(http.StatusOK, "系统错误")
+		return
+	}
+	if !ok {
+		ctx.String(http.StatusOK, "验证码错误")
+		return
+	}
 	// 调用 service 层的注册方法
 	err = u.svc.SignUp(ctx, domain.User{
 		Email:    req.Email,
@@ -399,11 +537,12 @@ func (u *UserHandler) Profile(ctx *gin.Context) {
 
 func (u *UserHandler) ProfileJWT(ctx *gin.Context) {
 	type Profile struct {
-		Email    string
-		Phone    string
-		Nickname string
-		Birthday string
-		Desc     string
+		Email     string
+		Phone     string
+		Nickname  string
+		Birthday  string
+		Desc      string
+		HideLikes bool
 	}
 	uc := ctx.MustGet("user").(ijwt.UserClaims)
 	user, err := u.svc.Profile(ctx, uc.Uid)
@@ -415,11 +554,12 @@ func (u *UserHandler) ProfileJWT(ctx *gin.Context) {
 	}
 	ctx.JSON(http.StatusOK, Result{
 		Data: Profile{
-			Email:    user.Email,
-			Phone:    user.Phone,
-			Nickname: user.Nickname,
-			Birthday: user.Birthday,
-			Desc:     user.Desc,
+			Email:     user.Email,
+			Phone:     user.Phone,
+			Nickname:  user.Nickname,
+			Birthday:  user.Birthday,
+			Desc:      user.Desc,
+			HideLikes: user.HideLikes,
 		},
 	})
 }
@@ -4,10 +4,17 @@ import (
 	"bytes"
 	"errors"
 	"go-basic/webook/internal/domain"
+	repomocks "go-basic/webook/internal/repository/mocks"
 	"go-basic/webook/internal/service"
+	emailmocks "go-basic/webook/internal/service/email/mocks"
+	emailquota "go-basic/webook/internal/service/email/quota"
 	svcmocks "go-basic/webook/internal/service/mocks"
+	smsmocks "go-basic/webook/internal/service/sms/mocks"
+	"go-basic/webook/internal/service/sms/quota"
 	"go-basic/webook/internal/web/jwt"
 	jwtmocks "go-basic/webook/internal/web/jwt/mocks"
+	"go-basic/webook/pkg/logger"
+	limitmocks "go-basic/webook/pkg/ratelimit/mocks"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -23,7 +30,7 @@ func TestUserHandler_SignUp(t *testing.T) {
 	testCases := []struct {
 		name string
 
-		mock func(ctrl *gomock.Controller) (service.UserService, service.CodeService, jwt.Handler)
+		mock func(ctrl *gomock.Controller) (service.UserService, service.EmailCodeService, jwt.Handler)
 
 		reqBody string
 
@@ -32,11 +39,12 @@ func TestUserHandler_SignUp(t *testing.T) {
 	}{
 		{
 			name: "注册成功",
-			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService, jwt.Handler) {
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.EmailCodeService, jwt.Handler) {
 				usersvc := svcmocks.NewMockUserService(ctrl)
-				codesvc := svcmocks.NewMockCodeService(ctrl)
+				codesvc := svcmocks.NewMockEmailCodeService(ctrl)
 				jwthdl := jwtmocks.NewMockHandler(ctrl)
 
+				codesvc.EXPECT().Verify(gomock.Any(), "signup", "12312121@qq.com", "123456").Return(true, nil)
 				usersvc.EXPECT().SignUp(gomock.Any(), domain.User{
 					Email:    "12312121@qq.com",
 					Password: "123456789",
@@ -47,16 +55,17 @@ func TestUserHandler_SignUp(t *testing.T) {
 					{
 						"email": "12312121@qq.com",
 						"password": "123456789",
-						"confirmPassword": "123456789"
+						"confirmPassword": "123456789",
+						"code": "123456"
 					}`,
 			wantCode: http.StatusOK,
 			wantBody: "注册成功",
 		},
 		{
 			name: "参数错误, bind失败",
-			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService, jwt.Handler) {
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.EmailCodeService, jwt.Handler) {
 				usersvc := svcmocks.NewMockUserService(ctrl)
-				codesvc := svcmocks.NewMockCodeService(ctrl)
+				codesvc := svcmocks.NewMockEmailCodeService(ctrl)
 				jwthdl := jwtmocks.NewMockHandler(ctrl)
 				return usersvc, codesvc, jwthdl
 			},
@@ -68,9 +77,9 @@ func TestUserHandler_SignUp(t *testing.T) {
 		},
 		{
 			name: "邮箱格式错误",
-			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService, jwt.Handler) {
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.EmailCodeService, jwt.Handler) {
 				usersvc := svcmocks.NewMockUserService(ctrl)
-				codesvc := svcmocks.NewMockCodeService(ctrl)
+				codesvc := svcmocks.NewMockEmailCodeService(ctrl)
 				jwthdl := jwtmocks.NewMockHandler(ctrl)
 				return usersvc, codesvc, jwthdl
 			},
@@ -78,16 +87,17 @@ func TestUserHandler_SignUp(t *testing.T) {
 					{
 						"email": "12312121",
 						"password": "123456789",
-						"confirmPassword": "123456789"
+						"confirmPassword": "123456789",
+						"code": "123456"
 					}`,
 			wantCode: http.StatusOK,
 			wantBody: "邮箱格式错误",
 		},
 		{
 			name: "密码格式错误",
-			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService, jwt.Handler) {
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.EmailCodeService, jwt.Handler) {
 				usersvc := svcmocks.NewMockUserService(ctrl)
-				codesvc := svcmocks.NewMockCodeService(ctrl)
+				codesvc := svcmocks.NewMockEmailCodeService(ctrl)
 				jwthdl := jwtmocks.NewMockHandler(ctrl)
 				return usersvc, codesvc, jwthdl
 			},
@@ -102,9 +112,9 @@ func TestUserHandler_SignUp(t *testing.T) {
 		},
 		{
 			name: "两次密码不一致",
-			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService, jwt.Handler) {
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.EmailCodeService, jwt.Handler) {
 				usersvc := svcmocks.NewMockUserService(ctrl)
-				codesvc := svcmocks.NewMockCodeService(ctrl)
+				codesvc := svcmocks.NewMockEmailCodeService(ctrl)
 				jwthdl := jwtmocks.NewMockHandler(ctrl)
 				return usersvc, codesvc, jwthdl
 			},
@@ -117,12 +127,32 @@ func TestUserHandler_SignUp(t *testing.T) {
 			wantCode: http.StatusOK,
 			wantBody: "两次密码不一致",
 		},
+		{
+			name: "验证码错误",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.EmailCodeService, jwt.Handler) {
+				usersvc := svcmocks.NewMockUserService(ctrl)
+				codesvc := svcmocks.NewMockEmailCodeService(ctrl)
+				jwthdl := jwtmocks.NewMockHandler(ctrl)
+				codesvc.EXPECT().Verify(gomock.Any(), "signup", "123@qq.com", "654321").Return(false, nil)
+				return usersvc, codesvc, jwthdl
+			},
+			reqBody: `
+					{
+						"email": "123@qq.com",
+						"password": "123456789",
+						"confirmPassword": "123456789",
+						"code": "654321"
+					}`,
+			wantCode: http.StatusOK,
+			wantBody: "验证码错误",
+		},
 		{
 			name: "邮箱已存在",
-			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService, jwt.Handler) {
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.EmailCodeService, jwt.Handler) {
 				usersvc := svcmocks.NewMockUserService(ctrl)
-				codesvc := svcmocks.NewMockCodeService(ctrl)
+				codesvc := svcmocks.NewMockEmailCodeService(ctrl)
 				jwthdl := jwtmocks.NewMockHandler(ctrl)
+				codesvc.EXPECT().Verify(gomock.Any(), "signup", "123@qq.com", "123456").Return(true, nil)
 				usersvc.EXPECT().SignUp(gomock.Any(), domain.User{
 					Email:    "123@qq.com",
 					Password: "123456789",
@@ -133,17 +163,19 @@ func TestUserHandler_SignUp(t *testing.T) {
 				{
 					"email": "123@qq.com",
 					"password": "123456789",
-					"confirmPassword": "123456789"
+					"confirmPassword": "123456789",
+					"code": "123456"
 				}`,
 			wantCode: http.StatusOK,
 			wantBody: "邮箱已存在",
 		},
 		{
 			name: "系统错误",
-			mock: func(ctrl *gomock.Controller) (service.UserService, service.CodeService, jwt.Handler) {
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.EmailCodeService, jwt.Handler) {
 				usersvc := svcmocks.NewMockUserService(ctrl)
-				codesvc := svcmocks.NewMockCodeService(ctrl)
+				codesvc := svcmocks.NewMockEmailCodeService(ctrl)
 				jwthdl := jwtmocks.NewMockHandler(ctrl)
+				codesvc.EXPECT().Verify(gomock.Any(), "signup", "123@qq.com", "123456").Return(true, nil)
 				usersvc.EXPECT().SignUp(gomock.Any(), domain.User{
 					Email:    "123@qq.com",
 					Password: "123456789",
@@ -154,7 +186,8 @@ func TestUserHandler_SignUp(t *testing.T) {
 				{
 					"email": "123@qq.com",
 					"password": "123456789",
-					"confirmPassword": "123456789"
+					"confirmPassword": "123456789",
+					"code": "123456"
 				}`,
 			wantCode: http.StatusOK,
 			wantBody: "系统错误",
@@ -166,7 +199,7 @@ func TestUserHandler_SignUp(t *testing.T) {
 			defer ctrl.Finish()
 			server := gin.Default()
 			usersvc, codesvc, jwthdl := tc.mock(ctrl)
-			h := NewUserHandler(usersvc, codesvc, jwthdl)
+			h := NewUserHandler(usersvc, svcmocks.NewMockCodeService(ctrl), codesvc, jwthdl, &logger.NopLogger{})
 			h.RegisterRoutes(server)
 
 			// 创建一个请求
@@ -222,7 +255,247 @@ func TestUserHandler_LoginJWT(t *testing.T) {
 				codeExp:     tt.fields.codeExp,
 				codeSvc:     tt.fields.codeSvc,
 			}
-			u.LoginJWT(tt.args.ctx)
+			u.LoginJWT(tt.args.ctx, LoginReq{})
+		})
+	}
+}
+
+func TestUserHandler_SendLoginSMSCode(t *testing.T) {
+	testCases := []struct {
+		name     string
+		mock     func(ctrl *gomock.Controller) service.CodeService
+		reqBody  string
+		wantBody string
+	}{
+		{
+			name: "发送成功",
+			mock: func(ctrl *gomock.Controller) service.CodeService {
+				codesvc := svcmocks.NewMockCodeService(ctrl)
+				codesvc.EXPECT().Send(gomock.Any(), "login", "15212345678").Return(nil)
+				return codesvc
+			},
+			reqBody:  `{"phone": "15212345678"}`,
+			wantBody: `{"code":0,"msg":"发送成功","data":null}`,
+		},
+		{
+			name: "手机号码超过配额",
+			mock: func(ctrl *gomock.Controller) service.CodeService {
+				codesvc := svcmocks.NewMockCodeService(ctrl)
+				codesvc.EXPECT().Send(gomock.Any(), "login", "15212345678").Return(service.ErrCodePhoneQuotaExceeded)
+				return codesvc
+			},
+			reqBody:  `{"phone": "15212345678"}`,
+			wantBody: `{"code":3,"msg":"这个手机号今天收到的验证码太多，请明天再试","data":null}`,
+		},
+		{
+			name: "IP 超过配额",
+			mock: func(ctrl *gomock.Controller) service.CodeService {
+				codesvc := svcmocks.NewMockCodeService(ctrl)
+				codesvc.EXPECT().Send(gomock.Any(), "login", "15212345678").Return(service.ErrCodeIPQuotaExceeded)
+				return codesvc
+			},
+			reqBody:  `{"phone": "15212345678"}`,
+			wantBody: `{"code":3,"msg":"当前网络发送的验证码太多，请稍后再试","data":null}`,
+		},
+		{
+			name: "业务超过配额",
+			mock: func(ctrl *gomock.Controller) service.CodeService {
+				codesvc := svcmocks.NewMockCodeService(ctrl)
+				codesvc.EXPECT().Send(gomock.Any(), "login", "15212345678").Return(service.ErrCodeBizQuotaExceeded)
+				return codesvc
+			},
+			reqBody:  `{"phone": "15212345678"}`,
+			wantBody: `{"code":3,"msg":"验证码发送繁忙，请稍后再试","data":null}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			server := gin.Default()
+			h := NewUserHandler(svcmocks.NewMockUserService(ctrl), tc.mock(ctrl),
+				svcmocks.NewMockEmailCodeService(ctrl), jwtmocks.NewMockHandler(ctrl), &logger.NopLogger{})
+			h.RegisterRoutes(server)
+			req, err := http.NewRequest(http.MethodPost, "/users/login_sms/code/send",
+				bytes.NewBuffer([]byte(tc.reqBody)))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			server.ServeHTTP(resp, req)
+			assert.Equal(t, http.StatusOK, resp.Code)
+			assert.Equal(t, tc.wantBody, resp.Body.String())
+		})
+	}
+}
+
+// 没有配置信任的代理的时候，客户端伪造 X-Forwarded-For 也还是按照连接的地址判断配额
+func TestUserHandler_SendCodeSpoofedIP(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (service.CodeService, service.EmailCodeService)
+		url  string
+		body string
+
+		wantBody string
+	}{
+		{
+			name: "短信验证码",
+			mock: func(ctrl *gomock.Controller) (service.CodeService, service.EmailCodeService) {
+				repo := repomocks.NewMockCodeRepository(ctrl)
+				repo.EXPECT().Store(gomock.Any(), "login", "15212345678", gomock.Any()).Return(nil)
+				limiter := limitmocks.NewMockLimiter(ctrl)
+				limiter.EXPECT().Limit(gomock.Any(), "sms:quota:ip:192.0.2.1").Return(true, nil)
+				codeSvc := service.NewCodeService(repo, quota.NewIPQuotaSMSService(smsmocks.NewMockService(ctrl), limiter))
+				return codeSvc, svcmocks.NewMockEmailCodeService(ctrl)
+			},
+			url:      "/users/login_sms/code/send",
+			body:     `{"phone": "15212345678"}`,
+			wantBody: `{"code":3,"msg":"当前网络发送的验证码太多，请稍后再试","data":null}`,
+		},
+		{
+			name: "邮件验证码",
+			mock: func(ctrl *gomock.Controller) (service.CodeService, service.EmailCodeService) {
+				repo := repomocks.NewMockCodeRepository(ctrl)
+				repo.EXPECT().Store(gomock.Any(), "login", "123@qq.com", gomock.Any()).Return(nil)
+				limiter := limitmocks.NewMockLimiter(ctrl)
+				limiter.EXPECT().Limit(gomock.Any(), "email:quota:ip:192.0.2.1").Return(true, nil)
+				emailSvc := service.NewEmailCodeService(repo,
+					emailquota.NewIPQuotaService(emailmocks.NewMockService(ctrl), limiter))
+				return svcmocks.NewMockCodeService(ctrl), emailSvc
+			},
+			url:      "/users/login_email/code/send",
+			body:     `{"email": "123@qq.com"}`,
+			wantBody: `{"code":3,"msg":"当前网络发送的验证码太多，请稍后再试","data":null}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			server := gin.Default()
+			// 和 InitWebServer 没有配置 web.trustedProxies 的时候一样
+			err := server.SetTrustedProxies(nil)
+			require.NoError(t, err)
+			codeSvc, emailCodeSvc := tc.mock(ctrl)
+			h := NewUserHandler(svcmocks.NewMockUserService(ctrl), codeSvc, emailCodeSvc,
+				jwtmocks.NewMockHandler(ctrl), &logger.NopLogger{})
+			h.RegisterRoutes(server)
+			req, err := http.NewRequest(http.MethodPost, tc.url, bytes.NewBufferString(tc.body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-Forwarded-For", "198.51.100.7")
+			req.RemoteAddr = "192.0.2.1:52000"
+			resp := httptest.NewRecorder()
+			server.ServeHTTP(resp, req)
+			assert.Equal(t, http.StatusOK, resp.Code)
+			assert.Equal(t, tc.wantBody, resp.Body.String())
+		})
+	}
+}
+
+func TestUserHandler_LoginEmail(t *testing.T) {
+	testCases := []struct {
+		name     string
+		mock     func(ctrl *gomock.Controller) (service.UserService, service.EmailCodeService, jwt.Handler)
+		url      string
+		reqBody  string
+		wantBody string
+	}{
+		{
+			name: "发送登录验证码",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.EmailCodeService, jwt.Handler) {
+				codesvc := svcmocks.NewMockEmailCodeService(ctrl)
+				codesvc.EXPECT().Send(gomock.Any(), "login", "123@qq.com").Return(nil)
+				return svcmocks.NewMockUserService(ctrl), codesvc, jwtmocks.NewMockHandler(ctrl)
+			},
+			url:      "/users/login_email/code/send",
+			reqBody:  `{"email": "123@qq.com"}`,
+			wantBody: `{"code":0,"msg":"发送成功","data":null}`,
+		},
+		{
+			name: "发送注册验证码太频繁",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.EmailCodeService, jwt.Handler) {
+				codesvc := svcmocks.NewMockEmailCodeService(ctrl)
+				codesvc.EXPECT().Send(gomock.Any(), "signup", "123@qq.com").Return(service.ErrCodeSendTooMany)
+				return svcmocks.NewMockUserService(ctrl), codesvc, jwtmocks.NewMockHandler(ctrl)
+			},
+			url:      "/users/signup/code/send",
+			reqBody:  `{"email": "123@qq.com"}`,
+			wantBody: `{"code":3,"msg":"发送太频繁，请稍后再试","data":null}`,
+		},
+		{
+			name: "邮箱超过配额",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.EmailCodeService, jwt.Handler) {
+				codesvc := svcmocks.NewMockEmailCodeService(ctrl)
+				codesvc.EXPECT().Send(gomock.Any(), "login", "123@qq.com").Return(service.ErrCodeEmailAddressQuotaExceeded)
+				return svcmocks.NewMockUserService(ctrl), codesvc, jwtmocks.NewMockHandler(ctrl)
+			},
+			url:      "/users/login_email/code/send",
+			reqBody:  `{"email": "123@qq.com"}`,
+			wantBody: `{"code":3,"msg":"这个邮箱今天收到的验证码太多，请明天再试","data":null}`,
+		},
+		{
+			name: "IP 超过配额",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.EmailCodeService, jwt.Handler) {
+				codesvc := svcmocks.NewMockEmailCodeService(ctrl)
+				codesvc.EXPECT().Send(gomock.Any(), "signup", "123@qq.com").Return(service.ErrCodeEmailIPQuotaExceeded)
+				return svcmocks.NewMockUserService(ctrl), codesvc, jwtmocks.NewMockHandler(ctrl)
+			},
+			url:      "/users/signup/code/send",
+			reqBody:  `{"email": "123@qq.com"}`,
+			wantBody: `{"code":3,"msg":"当前网络发送的验证码太多，请稍后再试","data":null}`,
+		},
+		{
+			name: "邮箱格式错误",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.EmailCodeService, jwt.Handler) {
+				return svcmocks.NewMockUserService(ctrl), svcmocks.NewMockEmailCodeService(ctrl), jwtmocks.NewMockHandler(ctrl)
+			},
+			url:      "/users/login_email/code/send",
+			reqBody:  `{"email": "123"}`,
+			wantBody: `{"code":4,"msg":"邮箱输入有误","data":null}`,
+		},
+		{
+			name: "登录成功",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.EmailCodeService, jwt.Handler) {
+				usersvc := svcmocks.NewMockUserService(ctrl)
+				codesvc := svcmocks.NewMockEmailCodeService(ctrl)
+				jwthdl := jwtmocks.NewMockHandler(ctrl)
+				codesvc.EXPECT().Verify(gomock.Any(), "login", "123@qq.com", "123456").Return(true, nil)
+				usersvc.EXPECT().FindOrCreateByEmail(gomock.Any(), "123@qq.com").Return(domain.User{Id: 1}, nil)
+				jwthdl.EXPECT().SetLoginToken(gomock.Any(), int64(1)).Return(nil)
+				return usersvc, codesvc, jwthdl
+			},
+			url:      "/users/login_email",
+			reqBody:  `{"email": "123@qq.com", "code": "123456"}`,
+			wantBody: `{"code":0,"msg":"登录成功","data":null}`,
+		},
+		{
+			name: "验证码错误",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.EmailCodeService, jwt.Handler) {
+				codesvc := svcmocks.NewMockEmailCodeService(ctrl)
+				codesvc.EXPECT().Verify(gomock.Any(), "login", "123@qq.com", "123456").Return(false, nil)
+				return svcmocks.NewMockUserService(ctrl), codesvc, jwtmocks.NewMockHandler(ctrl)
+			},
+			url:      "/users/login_email",
+			reqBody:  `{"email": "123@qq.com", "code": "123456"}`,
+			wantBody: `{"code":4,"msg":"验证码错误","data":null}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			server := gin.Default()
+			usersvc, codesvc, jwthdl := tc.mock(ctrl)
+			h := NewUserHandler(usersvc, svcmocks.NewMockCodeService(ctrl), codesvc, jwthdl, &logger.NopLogger{})
+			h.RegisterRoutes(server)
+			req, err := http.NewRequest(http.MethodPost, tc.url, bytes.NewBuffer([]byte(tc.reqBody)))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			server.ServeHTTP(resp, req)
+			assert.Equal(t, http.StatusOK, resp.Code)
+			assert.Equal(t, tc.wantBody, resp.Body.String())
 		})
 	}
 }
@@ -1,7 +1,13 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -12,7 +18,8 @@ import (
 func main() {
 	initViper()
 	initPrometheus()
-	app := InitWebServer()
+	app, cleanup := InitWebServer()
+	defer cleanup()
 	for _, c := range app.consumers {
 		err := c.Start()
 		if err != nil {
@@ -21,13 +28,43 @@ func main() {
 	}
 	// 启动定时任务
 	app.cron.Start()
+	// 启动 MySQL 调度器，任务通过 /jobs 接口管理
+	schedCtx, schedCancel := context.WithCancel(context.Background())
+	schedDone := make(chan struct{})
+	go func() {
+		defer close(schedDone)
+		err := app.scheduler.Scheduler(schedCtx)
+		// 退出的时候 ctx 被取消，不算错误
+		if err != nil && !errors.Is(err, context.Canceled) {
+			panic(err)
+		}
+	}()
 
 	server := app.server
 	server.GET("/hello", func(ctx *gin.Context) {
 		ctx.String(http.StatusOK, "hello，启动成功了！")
 	})
-	// 启动服务器
-	server.Run(":8080")
+	// 收到退出信号之后先停止接收请求，再停止调度器
+	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	srv := &http.Server{Addr: ":8080", Handler: server}
+	go func() {
+		err := srv.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			panic(err)
+		}
+	}()
+	<-sigCtx.Done()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	err := srv.Shutdown(shutdownCtx)
+	cancel()
+	if err != nil {
+		log.Println("关闭 HTTP 服务器失败", err)
+	}
+
+	// 不再抢新的任务，等执行中的任务结束并且释放租约
+	schedCancel()
+	<-schedDone
 
 	ctx := app.cron.Stop()
 	// 超时强制退出，防止有些任务执行时间过长
@@ -0,0 +1,15 @@
+package stringx
+
+import "unicode/utf8"
+
+// Truncate 按字节截断到最多 maxLen 个字节，但是不会截断半个汉字
+func Truncate(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	s = s[:maxLen]
+	for !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return s
+}
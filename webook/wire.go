@@ -4,6 +4,7 @@ package main
 
 import (
 	artEvt "go-basic/webook/events/article"
+	intrEvt "go-basic/webook/events/interactive"
 	"go-basic/webook/internal/ioc"
 	"go-basic/webook/internal/repository"
 	artRepo "go-basic/webook/internal/repository/article"
@@ -20,13 +21,60 @@ import (
 var rankingServiceSet = wire.NewSet(
 	repository.NewRankingRepository,
 	cache.NewRankingRedisCache,
-	service.NewBatchRankingService,
+	cache.NewRankingLocalCache,
+	ioc.InitRankingService,
+	ioc.InitStreamingRankingService,
+	ioc.InitRankingScorer,
+	ioc.InitRankingEventConsumer,
 )
 
-func InitWebServer() *App {
+var jobSchedulerSet = wire.NewSet(
+	dao.NewGORMJobDAO,
+	repository.NewPreemptCronJobRepository,
+	service.NewCronJobService,
+	dao.NewGORMJobRunDAO,
+	repository.NewJobRunRepository,
+	service.NewJobRunService,
+	dao.NewGORMJobShardDAO,
+	repository.NewJobShardRepository,
+	service.NewJobShardService,
+	dao.NewGORMWorkflowRunDAO,
+	repository.NewWorkflowRunRepository,
+	service.NewWorkflowService,
+	ioc.InitWorkflowExecutor,
+	ioc.InitJobRunCleanupJob,
+	ioc.InitLocalFuncExecutor,
+	ioc.InitHttpExecutor,
+	ioc.InitScheduler,
+	web.NewJobHandler,
+)
+
+var smsBizTokenSet = wire.NewSet(
+	dao.NewGORMSMSBizTokenDAO,
+	cache.NewRedisSMSQuotaCache,
+	repository.NewSMSBizTokenRepository,
+	ioc.InitSMSBizTokenService,
+	ioc.InitSMSHandler,
+)
+
+var smsDeliverySet = wire.NewSet(
+	dao.NewGORMSMSDeliveryDAO,
+	repository.NewSMSDeliveryRepository,
+	service.NewSMSDeliveryService,
+	ioc.InitSMSReceiptParsers,
+	web.NewSMSDeliveryHandler,
+)
+
+var interactiveReconcileSet = wire.NewSet(
+	service.NewInteractiveReconcileService,
+	ioc.InitInteractiveReconcileJob,
+)
+
+func InitWebServer() (*App, func()) {
 	wire.Build(
 		ioc.InitDB,
 		ioc.InitRedis,
+		ioc.InitRLockClient,
 		ioc.NewWechatHandlerConfig,
 		ioc.InitLogger,
 		ioc.InitSaramaClient,
@@ -36,40 +84,54 @@ func InitWebServer() *App {
 		rankingServiceSet,
 		ioc.InitJob,
 		ioc.InitRankingJob,
+		ioc.InitNodeLoadReporter,
+		interactiveReconcileSet,
+		jobSchedulerSet,
+		smsBizTokenSet,
+		smsDeliverySet,
 
 		// consumer
 		artEvt.NewKafkaProducer,
+		intrEvt.NewKafkaProducer,
 		artEvt.NewInteractiveReadEventBatchConsumer,
 
 		dao.NewUserDAO,
 		dao.NewGORMInteractiveDAO,
+		dao.NewSMSAysncReqDAO,
 		cache.NewUserCache,
 		cache.NewCodeCache,
 		cache.NewRedisArticleCache,
-		cache.NewInteractiveRedisCache,
+		ioc.InitInteractiveCache,
 		articleDAO.NewGORMArticleDAO,
 		articleDAO.NewReaderDAO,
 		articleDAO.NewAuthorDAO,
 
 		repository.NewUserRepository,
 		repository.NewCodeRepository,
+		repository.NewSMSAysncReqRepository,
 		repository.NewCachedInteractiveRepository,
 		artRepo.NewArticleRepository,
 
 		ioc.InitOAuth2WechatService,
 		service.NewUserService,
 		service.NewCodeService,
+		ioc.InitEmailService,
+		ioc.InitEmailCodeService,
 		service.NewArticleService,
 		service.NewInteractiveService,
+		ioc.InitInteractiveBizRegistry,
+		ioc.InitSMSTemplateRegistry,
 		ioc.InitSMSService,
 		ijwt.NewRedisJWTHandler,
 
 		web.NewUserHandler,
 		web.NewArticleHandler,
+		web.NewInteractiveHandler,
+		web.NewRankingHandler,
 		web.NewOAuth2WechatHandler,
 		ioc.InitWebServer,
 		ioc.InitMiddlewares,
 		wire.Struct(new(App), "*"),
 	)
-	return new(App)
+	return new(App), nil
 }
@@ -7,7 +7,9 @@
 package main
 
 import (
+	"github.com/google/wire"
 	article3 "go-basic/webook/events/article"
+	"go-basic/webook/events/interactive"
 	"go-basic/webook/internal/ioc"
 	"go-basic/webook/internal/repository"
 	article2 "go-basic/webook/internal/repository/article"
@@ -21,7 +23,7 @@ import (
 
 // Injectors from wire.go:
 
-func InitWebServer() *App {
+func InitWebServer() (*App, func()) {
 	cmdable := ioc.InitRedis()
 	handler := jwt.NewRedisJWTHandler(cmdable)
 	logger := ioc.InitLogger()
@@ -33,9 +35,16 @@ func InitWebServer() *App {
 	userService := service.NewUserService(userRepository, logger)
 	codeCache := cache.NewCodeCache(cmdable)
 	codeRepository := repository.NewCodeRepository(codeCache)
-	smsService := ioc.InitSMSService(cmdable)
+	smsdao := dao.NewSMSAysncReqDAO(db)
+	smsRepository := repository.NewSMSAysncReqRepository(smsdao)
+	smsDeliveryDAO := dao.NewGORMSMSDeliveryDAO(db)
+	smsDeliveryRepository := repository.NewSMSDeliveryRepository(smsDeliveryDAO)
+	registry := ioc.InitSMSTemplateRegistry()
+	smsService := ioc.InitSMSService(cmdable, smsRepository, smsDeliveryRepository, registry, logger)
 	codeService := service.NewCodeService(codeRepository, smsService)
-	userHandler := web.NewUserHandler(userService, codeService, handler, logger)
+	emailService := ioc.InitEmailService()
+	emailCodeService := ioc.InitEmailCodeService(cmdable, emailService)
+	userHandler := web.NewUserHandler(userService, codeService, emailCodeService, handler, logger)
 	wechatService := ioc.InitOAuth2WechatService()
 	stateConfig := ioc.NewWechatHandlerConfig()
 	oAuth2WechatHandler := web.NewOAuth2WechatHandler(wechatService, userService, handler, stateConfig)
@@ -49,16 +58,76 @@ func InitWebServer() *App {
 	producer := article3.NewKafkaProducer(syncProducer)
 	articleService := service.NewArticleService(articleRepository, logger, producer)
 	interactiveDAO := dao.NewGORMInteractiveDAO(db)
-	interactiveCache := cache.NewInteractiveRedisCache(cmdable)
+	interactiveBizRegistry := ioc.InitInteractiveBizRegistry(articleService)
+	interactiveCache := ioc.InitInteractiveCache(cmdable, interactiveBizRegistry)
 	interactiveRepository := repository.NewCachedInteractiveRepository(interactiveDAO, interactiveCache, logger)
-	interactiveService := service.NewInteractiveService(interactiveRepository)
+	interactiveProducer := interactive.NewKafkaProducer(syncProducer)
+	interactiveService := service.NewInteractiveService(interactiveRepository, interactiveBizRegistry, interactiveProducer, logger)
 	articleHandler := web.NewArticleHandler(articleService, logger, interactiveService)
-	engine := ioc.InitWebServer(v, userHandler, oAuth2WechatHandler, articleHandler)
+	interactiveHandler := web.NewInteractiveHandler(interactiveService, userService, articleService, logger)
+	rankingCache := cache.NewRankingRedisCache(cmdable)
+	rankingLocalCache := cache.NewRankingLocalCache()
+	rankingRepository := repository.NewRankingRepository(rankingCache, rankingLocalCache)
+	rankingScorer := ioc.InitRankingScorer()
+	streamingRankingService := ioc.InitStreamingRankingService(articleService, interactiveService, rankingRepository)
+	rankingService := ioc.InitRankingService(articleService, interactiveService, rankingRepository, rankingScorer, streamingRankingService)
+	rankingHandler := web.NewRankingHandler(rankingService, logger)
+	jobDAO := dao.NewGORMJobDAO(db)
+	jobRepository := repository.NewPreemptCronJobRepository(jobDAO)
+	jobService := service.NewCronJobService(jobRepository, logger)
+	jobRunDAO := dao.NewGORMJobRunDAO(db)
+	jobRunRepository := repository.NewJobRunRepository(jobRunDAO)
+	jobRunService := service.NewJobRunService(jobRunRepository)
+	workflowRunDAO := dao.NewGORMWorkflowRunDAO(db)
+	workflowRunRepository := repository.NewWorkflowRunRepository(workflowRunDAO)
+	workflowService := service.NewWorkflowService(workflowRunRepository, jobService)
+	jobHandler := web.NewJobHandler(jobService, jobRunService, workflowService, logger)
+	smsBizTokenDAO := dao.NewGORMSMSBizTokenDAO(db)
+	smsQuotaCache := cache.NewRedisSMSQuotaCache(cmdable)
+	smsBizTokenRepository := repository.NewSMSBizTokenRepository(smsBizTokenDAO, smsQuotaCache)
+	smsBizTokenService := ioc.InitSMSBizTokenService(smsBizTokenRepository, registry)
+	smsHandler := ioc.InitSMSHandler(smsBizTokenService, smsService, registry, logger)
+	smsDeliveryService := service.NewSMSDeliveryService(smsDeliveryRepository)
+	v2 := ioc.InitSMSReceiptParsers()
+	smsDeliveryHandler := web.NewSMSDeliveryHandler(smsDeliveryService, v2, logger)
+	engine := ioc.InitWebServer(v, userHandler, oAuth2WechatHandler, articleHandler, interactiveHandler, rankingHandler, jobHandler, smsHandler, smsDeliveryHandler)
 	interactiveReadEventBatchConsumer := article3.NewInteractiveReadEventBatchConsumer(client, interactiveRepository, logger)
-	v2 := ioc.InitConsumers(interactiveReadEventBatchConsumer)
+	rankingEventConsumer := ioc.InitRankingEventConsumer(client, streamingRankingService, logger)
+	v3 := ioc.InitConsumers(interactiveReadEventBatchConsumer, rankingEventConsumer)
+	rlockClient := ioc.InitRLockClient(cmdable)
+	nodeLoadReporter, cleanup := ioc.InitNodeLoadReporter(cmdable, logger)
+	rankingJob, cleanup2 := ioc.InitRankingJob(rankingService, logger, rlockClient, nodeLoadReporter)
+	interactiveReconcileService := service.NewInteractiveReconcileService(interactiveRepository, logger)
+	interactiveReconcileJob := ioc.InitInteractiveReconcileJob(interactiveReconcileService, rlockClient, logger)
+	jobRunCleanupJob := ioc.InitJobRunCleanupJob(jobRunService, logger)
+	cron := ioc.InitJob(logger, rankingJob, interactiveReconcileJob, jobRunCleanupJob)
+	jobShardDAO := dao.NewGORMJobShardDAO(db)
+	jobShardRepository := repository.NewJobShardRepository(jobShardDAO)
+	jobShardService := service.NewJobShardService(jobShardRepository, jobRepository, logger)
+	localFuncExecter := ioc.InitLocalFuncExecutor(rankingService)
+	httpExecutor := ioc.InitHttpExecutor(logger)
+	workflowExecutor := ioc.InitWorkflowExecutor(logger, workflowService, jobService, localFuncExecter, httpExecutor)
+	scheduler := ioc.InitScheduler(logger, jobService, jobRunService, jobShardService, nodeLoadReporter, localFuncExecter, httpExecutor, workflowExecutor)
 	app := &App{
 		server:    engine,
-		consumers: v2,
+		consumers: v3,
+		cron:      cron,
+		scheduler: scheduler,
+	}
+	return app, func() {
+		cleanup2()
+		cleanup()
 	}
-	return app
 }
+
+// wire.go:
+
+var rankingServiceSet = wire.NewSet(repository.NewRankingRepository, cache.NewRankingRedisCache, cache.NewRankingLocalCache, ioc.InitRankingService, ioc.InitStreamingRankingService, ioc.InitRankingScorer, ioc.InitRankingEventConsumer)
+
+var jobSchedulerSet = wire.NewSet(dao.NewGORMJobDAO, repository.NewPreemptCronJobRepository, service.NewCronJobService, dao.NewGORMJobRunDAO, repository.NewJobRunRepository, service.NewJobRunService, dao.NewGORMJobShardDAO, repository.NewJobShardRepository, service.NewJobShardService, dao.NewGORMWorkflowRunDAO, repository.NewWorkflowRunRepository, service.NewWorkflowService, ioc.InitWorkflowExecutor, ioc.InitJobRunCleanupJob, ioc.InitLocalFuncExecutor, ioc.InitHttpExecutor, ioc.InitScheduler, web.NewJobHandler)
+
+var smsBizTokenSet = wire.NewSet(dao.NewGORMSMSBizTokenDAO, cache.NewRedisSMSQuotaCache, repository.NewSMSBizTokenRepository, ioc.InitSMSBizTokenService, ioc.InitSMSHandler)
+
+var smsDeliverySet = wire.NewSet(dao.NewGORMSMSDeliveryDAO, repository.NewSMSDeliveryRepository, service.NewSMSDeliveryService, ioc.InitSMSReceiptParsers, web.NewSMSDeliveryHandler)
+
+var interactiveReconcileSet = wire.NewSet(service.NewInteractiveReconcileService, ioc.InitInteractiveReconcileJob)